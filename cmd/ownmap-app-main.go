@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,7 +27,11 @@ import (
 	"github.com/jamesrr39/ownmap-app/ownmap"
 	"github.com/jamesrr39/ownmap-app/ownmapdal"
 	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/nodelocations"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmaposm2pgsql"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
 	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb/ownmappostgresql"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb/ownmapsqlite"
 	"github.com/jamesrr39/ownmap-app/ownmaprenderer"
 	"github.com/jamesrr39/ownmap-app/styling"
 	"github.com/jamesrr39/ownmap-app/styling/mapboxglstyle"
@@ -60,6 +66,10 @@ func main() {
 
 		setupServe()
 		setupImport()
+		setupApplyChanges()
+		setupReplicate()
+		setupStats()
+		setupDB()
 
 		kingpin.Parse()
 	}
@@ -72,11 +82,12 @@ func ensureDefaultPathsConfig() (*ownmapdal.PathsConfig, errorsx.Error) {
 	}
 
 	pathsConfig := &ownmapdal.PathsConfig{
-		StylesDir:       filepath.Join(rootDir, "styles"),
-		DataDir:         filepath.Join(rootDir, "data_files"),
-		RawDataFilesDir: filepath.Join(rootDir, "raw_data_files"),
-		TempDir:         filepath.Join(rootDir, "tmp"),
-		TraceDir:        filepath.Join(rootDir, "trace"),
+		StylesDir:          filepath.Join(rootDir, "styles"),
+		DataDir:            filepath.Join(rootDir, "data_files"),
+		RawDataFilesDir:    filepath.Join(rootDir, "raw_data_files"),
+		TempDir:            filepath.Join(rootDir, "tmp"),
+		TraceDir:           filepath.Join(rootDir, "trace"),
+		DatasetsConfigPath: filepath.Join(rootDir, "datasets.json"),
 	}
 
 	err = pathsConfig.EnsurePaths()
@@ -87,54 +98,24 @@ func ensureDefaultPathsConfig() (*ownmapdal.PathsConfig, errorsx.Error) {
 	return pathsConfig, nil
 }
 
-func loadStylesFromDir(dir string) (*styling.StyleSet, errorsx.Error) {
-	fileInfos, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return nil, errorsx.Wrap(err)
-	}
-
-	styles := []styling.Style{&styling.CustomBasicStyle{}}
-	for _, fileInfo := range fileInfos {
-		style, err := loadStyle(filepath.Join(dir, fileInfo.Name()))
-		if err != nil {
-			log.Printf("error loading styles from %q. Error: %q\n", filepath.Join(dir, fileInfo.Name()), err)
-			continue
-		}
+// watchDataAndStylesDirs loads the datasets in the data directory and the styles in the styles directory, and then keeps polling the directories, so that datasets and styles that are copied in, replaced or deleted are picked up without a restart
+func watchDataAndStylesDirs(logger *logpkg.Logger, pathsConfig *ownmapdal.PathsConfig, dbConnSet *ownmapdal.DBConnSet, styleSet *styling.StyleSet, changeLog *ownmapdal.DirChangeLog, ownmapDBFileHandlerLimit uint) {
+	fs := gofs.NewOsFs()
 
-		styles = append(styles, style)
+	loadDataFileFunc := func(path string) (ownmapdal.DataSourceConn, errorsx.Error) {
+		dbFileType, _ := ownmapdal.DBFileTypeForDataFile(path)
+		return loadDBConn(string(dbFileType)+ownmapdal.ConnectionPathSeparator+path, ownmapDBFileHandlerLimit)
 	}
 
-	sort.Slice(styles, func(a, b int) bool {
-		return styles[a].GetStyleID() > styles[b].GetStyleID()
-	})
+	dataDirWatcher := ownmapdal.NewDirWatcher(logger, fs, pathsConfig.DataDir, ownmapdal.NewDataDirHandler(logger, dbConnSet, loadDataFileFunc), changeLog)
+	stylesDirWatcher := ownmapdal.NewDirWatcher(logger, fs, pathsConfig.StylesDir, styling.NewStyleDirHandler(styleSet, loadStyle), changeLog)
 
-	styleSet, err := styling.NewStyleSet(styles, styling.BUILTIN_STYLEID)
-	if err != nil {
-		return nil, errorsx.Wrap(err)
-	}
+	// the first poll loads what is already there
+	dataDirWatcher.Poll()
+	stylesDirWatcher.Poll()
 
-	return styleSet, nil
-}
-
-func loadDBConnsFromDir(logger *logpkg.Logger, pathsConfig *ownmapdal.PathsConfig, ownmapDBFileHandlerLimit uint) (*ownmapdal.DBConnSet, errorsx.Error) {
-	dirItems, err := ioutil.ReadDir(pathsConfig.DataDir)
-	if err != nil {
-		return nil, errorsx.Wrap(err)
-	}
-
-	var conns []ownmapdal.DataSourceConn
-	for _, dirItem := range dirItems {
-		filePath := filepath.Join(pathsConfig.DataDir, dirItem.Name())
-		dbConn, err := loadDBConn(filePath, ownmapDBFileHandlerLimit)
-		if err != nil {
-			logger.Error("failed to load %q as Bolt DB. Error: %q\nStack: %s", filePath, err.Error(), err.Stack())
-			continue
-		}
-
-		conns = append(conns, dbConn)
-	}
-
-	return ownmapdal.NewDBConnSet(conns), nil
+	go dataDirWatcher.Run(context.Background(), DIR_WATCH_INTERVAL)
+	go stylesDirWatcher.Run(context.Background(), DIR_WATCH_INTERVAL)
 }
 
 func setupDesktopMode() errorsx.Error {
@@ -145,18 +126,24 @@ func setupDesktopMode() errorsx.Error {
 		return errorsx.Wrap(err)
 	}
 
-	styleSet, err := loadStylesFromDir(pathsConfig.StylesDir)
+	styleSet, err := styling.NewStyleSet([]styling.Style{&styling.CustomBasicStyle{}}, styling.BUILTIN_STYLEID)
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
 
-	dbConns, err := loadDBConnsFromDir(logger, pathsConfig, DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)
+	datasetConfigs, err := ownmapdal.ReadDatasetConfigsFile(gofs.NewOsFs(), pathsConfig.DatasetsConfigPath)
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
 
+	dbConns := ownmapdal.NewDBConnSet(nil)
+	dbConns.SetDatasetConfigs(datasetConfigs)
+
+	changeLog := ownmapdal.NewDirChangeLog(DIR_CHANGE_LOG_LIMIT)
+	watchDataAndStylesDirs(logger, pathsConfig, dbConns, styleSet, changeLog, DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)
+
 	shouldProfile := false
-	router, err := createServer(dbConns, styleSet, pathsConfig, logger, DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT, shouldProfile)
+	router, err := createServer(dbConns, styleSet, changeLog, pathsConfig, logger, DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT, shouldProfile)
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
@@ -228,6 +215,8 @@ func setupDesktopMode() errorsx.Error {
 const (
 	DEFAULT_PORT                           = 9000
 	DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT = 20
+	DIR_WATCH_INTERVAL                     = 5 * time.Second
+	DIR_CHANGE_LOG_LIMIT                   = 100
 )
 
 var addrHelp = fmt.Sprintf(
@@ -235,70 +224,149 @@ var addrHelp = fmt.Sprintf(
 	DEFAULT_PORT, DEFAULT_PORT, DEFAULT_PORT, DEFAULT_PORT,
 )
 
+type serveOptions struct {
+	addr, dbFilePath, defaultStyleID, extraStyleDefinitionPathsStr, datasetsConfigPath *string
+	ownmapDBFileHandlerLimit                                                           *uint
+	shouldProfile                                                                      *bool
+}
+
+func addServeFlags(cmd *kingpin.CmdClause) *serveOptions {
+	return &serveOptions{
+		addr:                         cmd.Flag("addr", addrHelp).Default(fmt.Sprintf(":%d", DEFAULT_PORT)).String(),
+		dbFilePath:                   cmd.Arg("db-file", "DB file to read from").Required().String(),
+		defaultStyleID:               cmd.Flag("default-style-id", "default style type to use to render").Default(styling.BUILTIN_STYLEID).String(),
+		extraStyleDefinitionPathsStr: cmd.Flag("extra-styles", "comma separated list of paths to folder containing style definitions (currently supports only mapbox GL styles)").String(),
+		ownmapDBFileHandlerLimit:     cmd.Flag("ownmapdb-file-handler-limit", "maximum amount of file handlers per ownmap DB").Default(fmt.Sprintf("%d", DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)).Uint(),
+		shouldProfile:                cmd.Flag("profile", "profile the request performance").Bool(),
+		datasetsConfigPath:           cmd.Flag("datasets-config", `JSON file with the config of each dataset, by dataset name. For example {"england.ownmapdb": {"priority": 1}}. Where datasets overlap, objects are taken from the dataset with the highest priority, and then the most recently updated one`).String(),
+	}
+}
+
+// runServe loads the DB file and serves it. onDBConnLoaded (optional) is called before the server starts listening.
+func runServe(opts *serveOptions, onDBConnLoaded func(dbConnSet *ownmapdal.DBConnSet, dbConn ownmapdal.DataSourceConn) errorsx.Error) errorsx.Error {
+	var err error
+	var extraStyleDefinitionPaths []string
+	for _, path := range strings.Split(*opts.extraStyleDefinitionPathsStr, ",") {
+		if path == "" {
+			continue
+		}
+		extraStyleDefinitionPaths = append(extraStyleDefinitionPaths, path)
+	}
+
+	// create the style set
+	styles := []styling.Style{&styling.CustomBasicStyle{}}
+	for _, styleDefinitionPath := range extraStyleDefinitionPaths {
+		style, err := loadStyle(styleDefinitionPath)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		styles = append(styles, style)
+	}
+
+	styleSet, err := styling.NewStyleSet(styles, *opts.defaultStyleID)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelDebug)
+
+	dbConn, err := loadDBConn(*opts.dbFilePath, *opts.ownmapDBFileHandlerLimit)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	dbConnSet := ownmapdal.NewDBConnSet([]ownmapdal.DataSourceConn{dbConn})
+
+	if *opts.datasetsConfigPath != "" {
+		datasetConfigs, err := ownmapdal.ReadDatasetConfigsFile(gofs.NewOsFs(), *opts.datasetsConfigPath)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		dbConnSet.SetDatasetConfigs(datasetConfigs)
+	}
+
+	if onDBConnLoaded != nil {
+		err = onDBConnLoaded(dbConnSet, dbConn)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	// create the router
+	router, err := createServer(dbConnSet, styleSet, nil, nil, logger, *opts.ownmapDBFileHandlerLimit, *opts.shouldProfile)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	server := httpextra.NewServerWithTimeouts()
+	server.Addr = *opts.addr
+	server.Handler = router
+
+	logger.Info("about to start serving on %q", *opts.addr)
+
+	err = server.ListenAndServe()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+	return nil
+}
+
 func setupServe() {
 	cmd := kingpin.Command("serve", "serve webserver")
-	addr := cmd.Flag("addr", addrHelp).Default(fmt.Sprintf(":%d", DEFAULT_PORT)).String()
-	dbFilePath := cmd.Arg("db-file", "DB file to read from").Required().String()
-	defaultStyleID := cmd.Flag("default-style-id", "default style type to use to render").Default(styling.BUILTIN_STYLEID).String()
-	extraStyleDefinitionPathsStr := cmd.Flag("extra-styles", "comma separated list of paths to folder containing style definitions (currently supports only mapbox GL styles)").String()
-	ownmapDBFileHandlerLimit := cmd.Flag("ownmapdb-file-handler-limit", "maximum amount of file handlers per ownmap DB").Default(fmt.Sprintf("%d", DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)).Uint()
-	shouldProfile := cmd.Flag("profile", "profile the request performance").Bool()
+	opts := addServeFlags(cmd)
 	cmd.Action(func(ctx *kingpin.ParseContext) error {
-		run := func() errorsx.Error {
-			var err error
-			var extraStyleDefinitionPaths []string
-			for _, path := range strings.Split(*extraStyleDefinitionPathsStr, ",") {
-				if path == "" {
-					continue
-				}
-				extraStyleDefinitionPaths = append(extraStyleDefinitionPaths, path)
-			}
-
-			// create the style set
-			styles := []styling.Style{&styling.CustomBasicStyle{}}
-			for _, styleDefinitionPath := range extraStyleDefinitionPaths {
-				style, err := loadStyle(styleDefinitionPath)
-				if err != nil {
-					return errorsx.Wrap(err)
-				}
-
-				styles = append(styles, style)
-			}
+		err := runServe(opts, nil)
+		if err != nil {
+			return fmt.Errorf("error: %q\nStack trace:\n%s", err.Error(), err.Stack())
+		}
+		return nil
+	})
+}
 
-			styleSet, err := styling.NewStyleSet(styles, *defaultStyleID)
+func setupReplicate() {
+	cmd := kingpin.Command("replicate", "serve a dataset, and keep it up to date with changes from an OSM replication source")
+	opts := addServeFlags(cmd)
+	replicationSourceFlag := cmd.Flag("replication-source", "replication base URL (e.g. https://planet.openstreetmap.org/replication/minute/) or local mirror directory. Defaults to the replication URL recorded at import").String()
+	interval := cmd.Flag("interval", "how often to check for new changes").Default("1m").Duration()
+	startSequenceNumber := cmd.Flag("start-sequence-number", "sequence number the dataset is up to date with. Only needed if the dataset was imported without replication information").Uint64()
+	tmpDirFlag := cmd.Flag("tmp-dir", "temp dir to use, if applicable for this DB file type (note: recommended to be in the same partition as the DB file").String()
+	importMemoryMB := cmd.Flag("import-memory-mb", "roughly how much memory (in MB) the ownmapdb import caches can use. Lower this on machines with little RAM").Default(fmt.Sprintf("%d", ownmapdb.DefaultImportMemoryBytes/(1024*1024))).Int64()
+	cmd.Action(func(ctx *kingpin.ParseContext) error {
+		onDBConnLoaded := func(dbConnSet *ownmapdal.DBConnSet, dbConn ownmapdal.DataSourceConn) errorsx.Error {
+			datasetInfo, err := dbConn.DatasetInfo()
 			if err != nil {
 				return errorsx.Wrap(err)
 			}
 
-			logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelDebug)
+			replicationSourceLocation := *replicationSourceFlag
+			if replicationSourceLocation == "" {
+				replicationSourceLocation = datasetInfo.ReplicationBaseURL
+			}
+			if replicationSourceLocation == "" {
+				return errorsx.Errorf("no replication source given, and the dataset doesn't have one recorded")
+			}
 
-			dbConn, err := loadDBConn(*dbFilePath, *ownmapDBFileHandlerLimit)
+			changeApplier, err := newChangeApplier(gofs.NewOsFs(), *opts.dbFilePath, *tmpDirFlag, false, *opts.ownmapDBFileHandlerLimit, *importMemoryMB)
 			if err != nil {
 				return errorsx.Wrap(err)
 			}
 
-			dbConnSet := ownmapdal.NewDBConnSet([]ownmapdal.DataSourceConn{dbConn})
-
-			// create the router
-			router, err := createServer(dbConnSet, styleSet, nil, logger, *ownmapDBFileHandlerLimit, *shouldProfile)
-			if err != nil {
-				return errorsx.Wrap(err)
+			client := &http.Client{
+				Timeout: time.Minute,
 			}
 
-			server := httpextra.NewServerWithTimeouts()
-			server.Addr = *addr
-			server.Handler = router
+			source := ownmapdal.NewReplicationSource(gofs.NewOsFs(), client, replicationSourceLocation)
+			follower := ownmapdal.NewReplicationFollower(logger, source, changeApplier, dbConnSet, dbConn, *startSequenceNumber)
 
-			logger.Info("about to start serving on %q", *addr)
+			logger.Info("following replication source %q every %s", replicationSourceLocation, *interval)
+			go follower.Run(context.Background(), *interval)
 
-			err = server.ListenAndServe()
-			if err != nil {
-				return errorsx.Wrap(err)
-			}
 			return nil
 		}
 
-		err := run()
+		err := runServe(opts, onDBConnLoaded)
 		if err != nil {
 			return fmt.Errorf("error: %q\nStack trace:\n%s", err.Error(), err.Stack())
 		}
@@ -349,12 +417,16 @@ var dbFileHelp = fmt.Sprintf("DB file to import into. It should be the type, fol
 )
 
 func setupImport() {
-	cmd := kingpin.Command("import", "import PBF file")
+	cmd := kingpin.Command("import", "import OSM data file")
 	dbFileConnString := cmd.Arg("db-file", dbFileHelp).Required().String()
-	filePath := cmd.Arg("file", "PBF file to import").Required().String()
+	filePaths := cmd.Arg("files", "OSM data files to import (.pbf, .osm, .osm.gz or .osm.bz2). Several files (e.g. neighbouring regions) are imported into one dataset; objects in more than one of them are only imported once").Required().Strings()
 	tmpDirFlag := cmd.Flag("tmp-dir", "temp dir to use, if applicable for this DB file type (note: recommended to be in the same partition as the resulting outputted file").String()
 	boundsStr := cmd.Flag("bounds", "set the bounds that the importer should import within. [W,N,E,S] Example: -1,1,1,-1").Default("").String()
 	keepWorkDirFlag := cmd.Flag("keep-work-dir", "keep the working directory used during the import (for debugging)").Bool()
+	importMemoryMB := cmd.Flag("import-memory-mb", "roughly how much memory (in MB) the ownmapdb import caches can use. Lower this on machines with little RAM").Default(fmt.Sprintf("%d", ownmapdb.DefaultImportMemoryBytes/(1024*1024))).Int64()
+	keepMetadata := cmd.Flag("keep-metadata", "keep the version, timestamp, changeset and user of each object in the ownmapdb file. Change files applied to it later keep the metadata too, and skip changes older than the objects in the file").Bool()
+	nodeLocationStoreType := cmd.Flag("node-location-store", "how the ownmapdb importer keeps node locations while building ways. 'sparse' (in memory) for extracts, 'dense' (a memory-mapped file in the tmp dir, indexed by node ID) for the planet or big extracts").Default(string(nodelocations.StoreTypeSparse)).Enum(nodeLocationStoreTypeStrings()...)
+	importCollectionType := cmd.Flag("import-collection", "how the ownmapdb importer keeps the objects on disk while importing. 'buckets' (the default) for extracts, 'sorted-runs' (sorted files merged at the end) for big imports with a lot of objects in the same area").Default(string(ownmapdb.ImportCollectionTypeBuckets)).Enum(importCollectionTypeStrings()...)
 	ownmapDBFileHandlerLimit := cmd.Flag("ownmapdb-file-handler-limit", "maximum amount of file handlers per ownmap DB").Default(fmt.Sprintf("%d", DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)).Uint()
 	shouldProfile := cmd.Flag("profile", "profile the import performance").Bool()
 	cmd.Action(func(ctx *kingpin.ParseContext) (err error) {
@@ -368,7 +440,7 @@ func setupImport() {
 		fs := gofs.NewOsFs()
 
 		var workDirPath string
-		if tmpDirFlag != nil {
+		if *tmpDirFlag != "" {
 			workDirPath = *tmpDirFlag
 		} else {
 			workDirPath, err = ioutil.TempDir("", "")
@@ -389,31 +461,37 @@ func setupImport() {
 
 		startTime := time.Now()
 
-		file, err := fs.Open(*filePath)
-		if err != nil {
-			return errorsx.Wrap(err)
-		}
-		defer file.Close()
+		var pbfReaders []ownmapdal.PBFReader
+		for _, filePath := range *filePaths {
+			file, err := fs.Open(filePath)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+			defer file.Close()
 
-		fileInfo, err := file.Stat()
-		if err != nil {
-			return errorsx.Wrap(err)
+			fileReader, err := ownmapdal.NewPBFReaderForFile(file, filePath)
+			if err != nil {
+				return errorsx.Wrap(err, "file path", filePath)
+			}
+			defer fileReader.Close()
+
+			pbfReaders = append(pbfReaders, fileReader)
 		}
 
-		pbfReader, err := ownmapdal.NewDefaultPBFReader(file)
-		if err != nil {
-			return errorsx.Wrap(err)
+		var pbfReader ownmapdal.PBFReader = pbfReaders[0]
+		if len(pbfReaders) > 1 {
+			pbfReader, err = ownmapdal.NewMultiFilePBFReader(pbfReaders)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
 		}
-		defer pbfReader.Close()
 
 		pbfHeader, err := pbfReader.Header()
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 
-		finishedChan := make(chan bool)
-
-		go runLogProgress(pbfReader, finishedChan, fileInfo.Size())
+		progressTracker := ownmapdal.NewImportProgressTracker(pbfReader.TotalSize(), newLogProgressListener(5*time.Second))
 
 		dbConnConfig, err := ownmapdal.ParseDBConnFilePath(*dbFileConnString)
 		if err != nil {
@@ -424,7 +502,12 @@ func setupImport() {
 		switch ownmapdal.DBFileType(dbConnConfig.Type) {
 		case ownmapdal.DBFileTypeMapmakerDB:
 			options := ownmapdb.ImportOptions{
-				KeepWorkDir: *keepWorkDirFlag,
+				KeepWorkDir:           *keepWorkDirFlag,
+				ProgressTracker:       progressTracker,
+				ImportMemoryBytes:     *importMemoryMB * 1024 * 1024,
+				NodeLocationStoreType: nodelocations.StoreType(*nodeLocationStoreType),
+				KeepMetadata:          *keepMetadata,
+				CollectionType:        ownmapdb.ImportCollectionType(*importCollectionType),
 			}
 
 			importer, err = ownmapdb.NewImporter(logger, fs, workDirPath, dbConnConfig.ConnectionPath, *ownmapDBFileHandlerLimit, pbfHeader, options)
@@ -432,28 +515,354 @@ func setupImport() {
 				return errorsx.Wrap(err)
 			}
 
-		case ownmapdal.DBFileTypePostgresql:
-			importer, err = ownmappostgresql.NewImporter(dbConnConfig.ConnectionPath, pbfHeader)
+		case ownmapdal.DBFileTypePostgresql, ownmapdal.DBFileTypePostGIS:
+			importer, err = ownmappostgresql.NewImporter(dbConnConfig.ConnectionPath, pbfHeader, dbConnConfig.Type == ownmapdal.DBFileTypePostGIS)
 			if err != nil {
 				return errorsx.Wrap(err)
 			}
+		case ownmapdal.DBFileTypeSQLite:
+			importer, err = ownmapsqlite.NewImporter(dbConnConfig.ConnectionPath, pbfHeader)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		case ownmapdal.DBFileTypeOsm2pgsql:
+			return errorsx.Errorf("osm2pgsql databases are read only. Import into them with osm2pgsql")
 		default:
 			return errorsx.Errorf("unknown DB file type: %q\n", dbConnConfig.Type)
 		}
 
-		_, err = ownmapdal.Import(logger, pbfReader, fs, importer, bounds)
+		// stop and roll back the import on Ctrl-C
+		importCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		_, err = ownmapdal.Import(importCtx, logger, pbfReader, fs, workDirPath, importer, bounds, progressTracker)
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 
 		logger.Info("import finished in %s", time.Now().Sub(startTime))
 
-		finishedChan <- true
+		return nil
+	})
+}
+
+func importCollectionTypeStrings() []string {
+	var collectionTypes []string
+	for _, collectionType := range ownmapdb.ImportCollectionTypes {
+		collectionTypes = append(collectionTypes, string(collectionType))
+	}
+	return collectionTypes
+}
+
+func nodeLocationStoreTypeStrings() []string {
+	var storeTypes []string
+	for _, storeType := range nodelocations.StoreTypes {
+		storeTypes = append(storeTypes, string(storeType))
+	}
+	return storeTypes
+}
+
+func setupApplyChanges() {
+	cmd := kingpin.Command("apply-changes", "apply OSM change files (.osc or .osc.gz) to an existing dataset")
+	dbFileConnString := cmd.Arg("db-file", "DB file to apply the changes to, in the same format as for the import command").Required().String()
+	changeFilePaths := cmd.Arg("change-files", "OSM change files to apply, in order").Required().Strings()
+	tmpDirFlag := cmd.Flag("tmp-dir", "temp dir to use, if applicable for this DB file type (note: recommended to be in the same partition as the resulting outputted file").String()
+	keepWorkDirFlag := cmd.Flag("keep-work-dir", "keep the working directory used while applying the changes (for debugging)").Bool()
+	importMemoryMB := cmd.Flag("import-memory-mb", "roughly how much memory (in MB) the ownmapdb import caches can use. Lower this on machines with little RAM").Default(fmt.Sprintf("%d", ownmapdb.DefaultImportMemoryBytes/(1024*1024))).Int64()
+	ownmapDBFileHandlerLimit := cmd.Flag("ownmapdb-file-handler-limit", "maximum amount of file handlers per ownmap DB").Default(fmt.Sprintf("%d", DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)).Uint()
+	cmd.Action(func(ctx *kingpin.ParseContext) (err error) {
+		defer func() {
+			errorx, ok := err.(errorsx.Error)
+			if ok {
+				log.Printf("%s\n%s\n", errorx.Error(), errorx.Stack())
+			}
+		}()
+
+		fs := gofs.NewOsFs()
+
+		// stop and roll back the change file being applied on Ctrl-C
+		applyCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var changeApplier ownmapdal.ChangeApplier
+		changeApplier, err = newChangeApplier(fs, *dbFileConnString, *tmpDirFlag, *keepWorkDirFlag, *ownmapDBFileHandlerLimit, *importMemoryMB)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		for _, changeFilePath := range *changeFilePaths {
+			startTime := time.Now()
+
+			var changeIndex *ownmapdal.ChangeIndex
+			changeIndex, err = readChangeIndex(fs, changeFilePath)
+			if err != nil {
+				return errorsx.Wrap(err, "change file", changeFilePath)
+			}
+
+			var updatedConn ownmapdal.DataSourceConn
+			updatedConn, err = changeApplier.ApplyChanges(applyCtx, changeIndex)
+			if err != nil {
+				return errorsx.Wrap(err, "change file", changeFilePath)
+			}
+
+			// the updated dataset isn't used here, so close its file handles straight away, rather than keeping them open for every change file
+			if closable, ok := updatedConn.(interface{ Close() errorsx.Error }); ok {
+				err = closable.Close()
+				if err != nil {
+					return errorsx.Wrap(err, "change file", changeFilePath)
+				}
+			}
+
+			logger.Info(
+				"applied %q in %s (%d nodes, %d ways, %d relations created or modified; %d nodes, %d ways, %d relations deleted)",
+				changeFilePath,
+				time.Now().Sub(startTime),
+				len(changeIndex.UpsertedNodes),
+				len(changeIndex.UpsertedWays),
+				len(changeIndex.UpsertedRelations),
+				len(changeIndex.DeletedNodes),
+				len(changeIndex.DeletedWays),
+				len(changeIndex.DeletedRelations),
+			)
+		}
+
+		return nil
+	})
+}
+
+func setupDB() {
+	cmd := kingpin.Command("db", "manage SQL databases")
+
+	migrateCmd := cmd.Command("migrate", "bring the schema of a SQL database up to date. This is also done when importing into or connecting to the database, so it is only needed to migrate ahead of time")
+	dbConnString := migrateCmd.Arg("db-file", "SQL database (postgresql://, postgis:// or sqlite://), in the same format as for the import command").Required().String()
+	migrateCmd.Action(func(ctx *kingpin.ParseContext) (err error) {
+		defer func() {
+			errorx, ok := err.(errorsx.Error)
+			if ok {
+				log.Printf("%s\n%s\n", errorx.Error(), errorx.Stack())
+			}
+		}()
+
+		dbConnConfig, err := ownmapdal.ParseDBConnFilePath(*dbConnString)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		var results []ownmapsqldb.MigrationResult
+		switch dbConnConfig.Type {
+		case ownmapdal.DBFileTypePostgresql, ownmapdal.DBFileTypePostGIS:
+			results, err = ownmappostgresql.Migrate(dbConnConfig.ConnectionPath, dbConnConfig.Type == ownmapdal.DBFileTypePostGIS)
+		case ownmapdal.DBFileTypeSQLite:
+			results, err = ownmapsqlite.Migrate(dbConnConfig.ConnectionPath)
+		default:
+			return errorsx.Errorf("%q databases don't have migrations", dbConnConfig.Type)
+		}
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		for _, result := range results {
+			if result.FromVersion == result.ToVersion {
+				logger.Info("%s: up to date (version %d)", result.Name, result.ToVersion)
+				continue
+			}
+
+			logger.Info("%s: migrated from version %d to version %d", result.Name, result.FromVersion, result.ToVersion)
+		}
 
 		return nil
 	})
 }
 
+func setupStats() {
+	cmd := kingpin.Command("stats", "report what is in an OSM data file or an ownmapdb file, without importing it")
+	source := cmd.Arg("source", "OSM data file (.pbf, .osm, .osm.gz or .osm.bz2), or DB file in the same format as for the import command (ownmapdb only)").Required().String()
+	boundsStr := cmd.Flag("bounds", "only count objects within the bounds. [W,N,E,S] Example: -1,1,1,-1").Default("").String()
+	polyFilePath := cmd.Flag("poly", "only count objects within the polygon in this Osmosis .poly file").String()
+	topN := cmd.Flag("top", "how many of the most used tag keys and values to show").Default("20").Int()
+	outputJSON := cmd.Flag("json", "output the report as JSON").Bool()
+	ownmapDBFileHandlerLimit := cmd.Flag("ownmapdb-file-handler-limit", "maximum amount of file handlers per ownmap DB").Default(fmt.Sprintf("%d", DEFAULT_MAPMAKER_DB_FILE_HANDLER_LIMIT)).Uint()
+	cmd.Action(func(ctx *kingpin.ParseContext) (err error) {
+		defer func() {
+			errorx, ok := err.(errorsx.Error)
+			if ok {
+				log.Printf("%s\n%s\n", errorx.Error(), errorx.Stack())
+			}
+		}()
+
+		fs := gofs.NewOsFs()
+
+		statsCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var filter *ownmapdal.StatsFilter
+		if *boundsStr != "" {
+			var bounds osm.Bounds
+			bounds, err = boundsStrToOSMBounds(strings.Split(*boundsStr, ","))
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+			filter = &ownmapdal.StatsFilter{Bounds: &bounds}
+		}
+
+		if *polyFilePath != "" {
+			var polyFile gofs.File
+			polyFile, err = fs.Open(*polyFilePath)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+			defer polyFile.Close()
+
+			var area *ownmap.AreaGeometry
+			area, err = ownmapdal.ParsePolyFile(polyFile)
+			if err != nil {
+				return errorsx.Wrap(err, "poly file", *polyFilePath)
+			}
+
+			if filter == nil {
+				filter = new(ownmapdal.StatsFilter)
+			}
+			filter.Area = area
+		}
+
+		var stats *ownmapdal.DatasetStats
+		if strings.Contains(*source, ownmapdal.ConnectionPathSeparator) {
+			stats, err = getDBStats(statsCtx, *source, filter, *topN, *ownmapDBFileHandlerLimit)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		} else {
+			var file gofs.File
+			file, err = fs.Open(*source)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+			defer file.Close()
+
+			var pbfReader ownmapdal.PBFReader
+			pbfReader, err = ownmapdal.NewPBFReaderForFile(file, *source)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+			defer pbfReader.Close()
+
+			stats, err = ownmapdal.GetStatsFromPBFReader(statsCtx, pbfReader, filter, *topN)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		}
+
+		if *outputJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "\t")
+			err = encoder.Encode(stats)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+
+			return nil
+		}
+
+		printStats(stats)
+
+		return nil
+	})
+}
+
+func getDBStats(ctx context.Context, dbConfigString string, filter *ownmapdal.StatsFilter, topN int, ownmapDBFileHandlerLimit uint) (*ownmapdal.DatasetStats, errorsx.Error) {
+	dbConn, err := loadDBConn(dbConfigString, ownmapDBFileHandlerLimit)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	switch t := dbConn.(type) {
+	case *ownmapdb.MapmakerDBConn:
+		defer t.Close()
+		return t.GetStats(ctx, filter, topN)
+	default:
+		return nil, errorsx.Errorf("stats are not supported for DB type %T", dbConn)
+	}
+}
+
+func printStats(stats *ownmapdal.DatasetStats) {
+	formatBounds := func(bounds *osm.Bounds) string {
+		if bounds == nil {
+			return "(none)"
+		}
+		return fmt.Sprintf("W: %f, N: %f, E: %f, S: %f", bounds.MinLon, bounds.MaxLat, bounds.MaxLon, bounds.MinLat)
+	}
+
+	fmt.Printf("Nodes:     %d\n", stats.Nodes)
+	fmt.Printf("Ways:      %d\n", stats.Ways)
+	fmt.Printf("Relations: %d\n", stats.Relations)
+	fmt.Println()
+	fmt.Printf("Header bounds: %s\n", formatBounds(stats.HeaderBounds))
+	fmt.Printf("Data bounds:   %s\n", formatBounds(stats.DataBounds))
+	fmt.Println()
+	fmt.Printf("Max relation depth:       %d\n", stats.MaxRelationDepth)
+	fmt.Println()
+	fmt.Println("Top tag keys:")
+	for _, tagCount := range stats.TopTagKeys {
+		fmt.Printf("\t%d\t%s\n", tagCount.Count, tagCount.Key)
+	}
+	fmt.Println()
+	fmt.Println("Top tag values:")
+	for _, tagCount := range stats.TopTagValues {
+		fmt.Printf("\t%d\t%s=%s\n", tagCount.Count, tagCount.Key, tagCount.Value)
+	}
+}
+
+func newChangeApplier(fs gofs.Fs, dbConfigString, tmpDir string, keepWorkDir bool, ownmapDBFileHandlerLimit uint, importMemoryMB int64) (ownmapdal.ChangeApplier, errorsx.Error) {
+	dbConnConfig, err := ownmapdal.ParseDBConnFilePath(dbConfigString)
+	if err != nil {
+		return nil, errorsx.Wrap(err, "db file path", dbConfigString)
+	}
+
+	switch dbConnConfig.Type {
+	case ownmapdal.DBFileTypeMapmakerDB:
+		workDirPath := tmpDir
+		if workDirPath == "" {
+			var tempDirErr error
+			workDirPath, tempDirErr = ioutil.TempDir("", "")
+			if tempDirErr != nil {
+				return nil, errorsx.Wrap(tempDirErr)
+			}
+		}
+
+		options := ownmapdb.ImportOptions{
+			KeepWorkDir:       keepWorkDir,
+			ImportMemoryBytes: importMemoryMB * 1024 * 1024,
+		}
+
+		return ownmapdb.NewChangeApplier(logger, fs, workDirPath, dbConnConfig.ConnectionPath, ownmapDBFileHandlerLimit, options), nil
+	case ownmapdal.DBFileTypePostgresql, ownmapdal.DBFileTypePostGIS:
+		return ownmappostgresql.NewChangeApplier(dbConnConfig.ConnectionPath, dbConnConfig.Type == ownmapdal.DBFileTypePostGIS)
+	case ownmapdal.DBFileTypeSQLite:
+		return ownmapsqlite.NewChangeApplier(dbConnConfig.ConnectionPath)
+	case ownmapdal.DBFileTypeOsm2pgsql:
+		return nil, errorsx.Errorf("osm2pgsql databases are read only. Keep them up to date with osm2pgsql-replication")
+	default:
+		return nil, errorsx.Errorf("unknown DB file type: %q", dbConnConfig.Type)
+	}
+}
+
+func readChangeIndex(fs gofs.Fs, changeFilePath string) (*ownmapdal.ChangeIndex, errorsx.Error) {
+	var err error
+
+	file, err := fs.Open(changeFilePath)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer file.Close()
+
+	change, err := ownmapdal.ReadChangeFile(file, changeFilePath)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return ownmapdal.NewChangeIndex(change), nil
+}
+
 func loadStyle(styleDefinitionPath string) (styling.Style, errorsx.Error) {
 	file, err := os.Open(filepath.Join(styleDefinitionPath, "style.json"))
 	if err != nil {
@@ -492,7 +901,8 @@ const (
 	adminPath = "admin"
 )
 
-func createServer(dbConns *ownmapdal.DBConnSet, styleSet *styling.StyleSet, pathsConfig *ownmapdal.PathsConfig, logger *logpkg.Logger, ownmapDBFileHandlerLimit uint, shouldProfile bool) (chi.Router, errorsx.Error) {
+// createServer creates the router. changeLog and pathsConfig are only set in desktop mode.
+func createServer(dbConns *ownmapdal.DBConnSet, styleSet *styling.StyleSet, changeLog *ownmapdal.DirChangeLog, pathsConfig *ownmapdal.PathsConfig, logger *logpkg.Logger, ownmapDBFileHandlerLimit uint, shouldProfile bool) (chi.Router, errorsx.Error) {
 	var err error
 
 	renderer := ownmaprenderer.NewRasterRenderer(fonts.DefaultFont())
@@ -526,7 +936,7 @@ func createServer(dbConns *ownmapdal.DBConnSet, styleSet *styling.StyleSet, path
 	router.Use(middleware.DefaultLogger)
 	router.Use(tracing.Middleware(tracer))
 	router.Route("/api/", func(r chi.Router) {
-		r.Mount("/info", webservices.NewInfoService(logger, dbConns, styleSet))
+		r.Mount("/info", webservices.NewInfoService(logger, dbConns, styleSet, changeLog))
 		r.Mount("/tiles/", webservices.NewTileService(logger, dbConns, renderer, styleSet, shouldProfile))
 		r.Mount("/nearby/", webservices.NewNearbyThingsWebService(logger, dbConns))
 	})
@@ -540,16 +950,40 @@ func createServer(dbConns *ownmapdal.DBConnSet, styleSet *styling.StyleSet, path
 	return router, nil
 }
 
-func runLogProgress(pbfReader *ownmapdal.DefaultPBFReader, finishedChan chan bool, totalBytes int64) {
-	for {
-		time.Sleep(time.Second * 5)
-		select {
-		case <-finishedChan:
-			log.Println("finished scanning the PBF file. Now committing to storage. This make take several minutes...")
+// newLogProgressListener logs the import progress whenever the phase changes, and otherwise at most once every logInterval
+func newLogProgressListener(logInterval time.Duration) ownmapdal.ImportProgressListener {
+	var lastLogTime time.Time
+	var lastPhaseDescription string
+	var warningsLogged int
+
+	return func(progress ownmapdal.ImportProgress) {
+		for _, warning := range progress.Warnings[warningsLogged:] {
+			log.Printf("WARN: %s\n", warning)
+		}
+		warningsLogged = len(progress.Warnings)
+
+		phaseDescription := progress.PhaseDescription()
+		if phaseDescription == lastPhaseDescription && time.Now().Sub(lastLogTime) < logInterval {
 			return
+		}
+		lastLogTime = time.Now()
+		lastPhaseDescription = phaseDescription
+
+		switch progress.Phase {
+		case ownmapdal.ImportPhaseFirstPass, ownmapdal.ImportPhaseWayPass, ownmapdal.ImportPhaseRelationPass:
+			log.Printf(
+				"%s: scanned %d/%d bytes (%0.02f%%), estimated time remaining for this pass: %s. Imported %d nodes, %d ways, %d relations\n",
+				phaseDescription,
+				progress.ScannedBytes,
+				progress.TotalBytes,
+				progress.PassPercent(),
+				progress.EstimatedTimeRemaining.Round(time.Second),
+				progress.NodesImported,
+				progress.WaysImported,
+				progress.RelationsImported,
+			)
 		default:
-			fullyScannedBytes := pbfReader.FullyScannedBytes()
-			log.Printf("scanned bytes so far: %d/%d (%0.02f%%)\n", fullyScannedBytes, totalBytes, float64(fullyScannedBytes)*100/float64(totalBytes))
+			log.Printf("%s (%s since the import started)\n", phaseDescription, time.Now().Sub(progress.StartTime).Round(time.Second))
 		}
 	}
 }
@@ -572,8 +1006,12 @@ func loadDBConn(dbConfigString string, ownmapDBFileHandlerLimit uint) (ownmapdal
 		}
 
 		return ownmapdb.NewMapmakerDBConn(openFileFunc, filepath.Base(dbConfigString), ownmapDBFileHandlerLimit)
-	case ownmapdal.DBFileTypePostgresql:
-		return ownmappostgresql.NewDBConn(dbConnConfig.ConnectionPath)
+	case ownmapdal.DBFileTypePostgresql, ownmapdal.DBFileTypePostGIS:
+		return ownmappostgresql.NewDBConn(dbConnConfig.ConnectionPath, dbConnConfig.Type == ownmapdal.DBFileTypePostGIS)
+	case ownmapdal.DBFileTypeSQLite:
+		return ownmapsqlite.NewDBConn(dbConnConfig.ConnectionPath)
+	case ownmapdal.DBFileTypeOsm2pgsql:
+		return ownmaposm2pgsql.NewDBConn(dbConnConfig.ConnectionPath)
 	default:
 		return nil, errorsx.Errorf("unrecognized db connection type: %q", dbConnConfig.Type)
 	}
@@ -0,0 +1,239 @@
+package ownmap
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	RelationMemberRoleOuter = "outer"
+	RelationMemberRoleInner = "inner"
+)
+
+// IsAreaRelation returns true for relations that describe an area (multipolygons and boundaries), and so should have their member ways assembled into polygons
+func IsAreaRelation(tags []*OSMTag) bool {
+	for _, tag := range tags {
+		if tag.Key != "type" {
+			continue
+		}
+
+		return tag.Value == "multipolygon" || tag.Value == "boundary"
+	}
+
+	return false
+}
+
+// AssembleAreaGeometry joins the outer and inner ways of an area end-to-end into closed rings, orients them (outer rings counter-clockwise, inner rings clockwise)
+// and puts each inner ring into the smallest outer ring that contains it.
+// Ways that can't be joined into a closed ring (e.g. because part of the area is outside of the dataset) are closed with a straight line.
+// Inner rings that aren't inside any outer ring are dropped. Returns nil if no outer rings could be assembled.
+func AssembleAreaGeometry(outerWays, innerWays [][]*Location) *AreaGeometry {
+	outerRings := joinRings(outerWays)
+	if len(outerRings) == 0 {
+		return nil
+	}
+
+	type polygonWithArea struct {
+		polygon *AreaPolygon
+		area    float64
+	}
+
+	var polygons []*polygonWithArea
+	for _, ring := range outerRings {
+		area := signedRingArea(ring)
+		if area < 0 {
+			reverseLocations(ring)
+		}
+
+		polygons = append(polygons, &polygonWithArea{
+			polygon: &AreaPolygon{Outer: &AreaRing{Points: ring}},
+			area:    math.Abs(area),
+		})
+	}
+
+	// smallest outer ring first, so that inner rings are assigned to the innermost outer ring containing them (for example an island in a lake in a forest)
+	sort.SliceStable(polygons, func(a, b int) bool {
+		return polygons[a].area < polygons[b].area
+	})
+
+	for _, ring := range joinRings(innerWays) {
+		if signedRingArea(ring) > 0 {
+			reverseLocations(ring)
+		}
+
+		for _, p := range polygons {
+			if isRingInsideRing(ring, p.polygon.Outer.Points) {
+				p.polygon.Inners = append(p.polygon.Inners, &AreaRing{Points: ring})
+				break
+			}
+		}
+	}
+
+	area := new(AreaGeometry)
+	for _, p := range polygons {
+		area.Polygons = append(area.Polygons, p.polygon)
+	}
+
+	return area
+}
+
+// AssembleAreaGeometryFromMembers assembles the area from the way members of a relation. Ways without a role are treated as outer ways.
+func AssembleAreaGeometryFromMembers(members []*RelationMemberData) *AreaGeometry {
+	var outerWays, innerWays [][]*Location
+	for _, member := range members {
+		way, ok := member.Object.(*OSMWay)
+		if !ok || way == nil {
+			continue
+		}
+
+		switch member.Role {
+		case RelationMemberRoleOuter, "":
+			outerWays = append(outerWays, WayPointsToLocations(way.WayPoints))
+		case RelationMemberRoleInner:
+			innerWays = append(innerWays, WayPointsToLocations(way.WayPoints))
+		}
+	}
+
+	return AssembleAreaGeometry(outerWays, innerWays)
+}
+
+func WayPointsToLocations(wayPoints []*WayPoint) []*Location {
+	var locations []*Location
+	for _, wayPoint := range wayPoints {
+		locations = append(locations, wayPoint.Point)
+	}
+	return locations
+}
+
+func isSameLocation(a, b *Location) bool {
+	return a.Lat == b.Lat && a.Lon == b.Lon
+}
+
+// joinRings joins ways that share end points into closed rings
+func joinRings(ways [][]*Location) [][]*Location {
+	var unusedWays [][]*Location
+	for _, way := range ways {
+		if len(way) < 2 {
+			continue
+		}
+		// copy, so that the way itself is not modified when reversing or appending
+		unusedWays = append(unusedWays, append([]*Location{}, way...))
+	}
+
+	// popJoiningWay finds a way with an end at the location and removes it from the unused ways.
+	// The way is returned starting with that location.
+	popJoiningWay := func(location *Location) []*Location {
+		for i, way := range unusedWays {
+			switch {
+			case isSameLocation(way[0], location):
+			case isSameLocation(way[len(way)-1], location):
+				reverseLocations(way)
+			default:
+				continue
+			}
+
+			unusedWays = append(unusedWays[:i], unusedWays[i+1:]...)
+			return way
+		}
+		return nil
+	}
+
+	var rings [][]*Location
+	for len(unusedWays) != 0 {
+		ring := unusedWays[0]
+		unusedWays = unusedWays[1:]
+
+		// extend from the end of the ring
+		for !isSameLocation(ring[0], ring[len(ring)-1]) {
+			nextWay := popJoiningWay(ring[len(ring)-1])
+			if nextWay == nil {
+				break
+			}
+			ring = append(ring, nextWay[1:]...)
+		}
+
+		// then from the start, in case the ring was started in the middle of an unclosed chain
+		for !isSameLocation(ring[0], ring[len(ring)-1]) {
+			previousWay := popJoiningWay(ring[0])
+			if previousWay == nil {
+				break
+			}
+			reverseLocations(previousWay)
+			ring = append(previousWay, ring[1:]...)
+		}
+
+		if !isSameLocation(ring[0], ring[len(ring)-1]) {
+			// couldn't close the ring from the available ways; close it with a straight line
+			ring = append(ring, ring[0])
+		}
+
+		if len(ring) < 4 {
+			// not enough points to enclose an area
+			continue
+		}
+
+		rings = append(rings, ring)
+	}
+
+	return rings
+}
+
+// signedRingArea calculates the area of a closed ring, with longitude as x and latitude as y. Positive for counter-clockwise rings, negative for clockwise rings.
+func signedRingArea(ring []*Location) float64 {
+	var area float64
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i].Lon*ring[i+1].Lat - ring[i+1].Lon*ring[i].Lat
+	}
+	return area / 2
+}
+
+func reverseLocations(locations []*Location) {
+	for i, j := 0, len(locations)-1; i < j; i, j = i+1, j-1 {
+		locations[i], locations[j] = locations[j], locations[i]
+	}
+}
+
+// IsPointInArea checks if a point is inside one of the outer rings of the area, and not inside one of that polygon's inner rings
+func IsPointInArea(point *Location, area *AreaGeometry) bool {
+	for _, polygon := range area.Polygons {
+		if !isPointInRing(point, polygon.Outer.Points) {
+			continue
+		}
+
+		isInInner := false
+		for _, inner := range polygon.Inners {
+			if isPointInRing(point, inner.Points) {
+				isInInner = true
+				break
+			}
+		}
+
+		if !isInInner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRingInsideRing checks if the inner ring is inside the outer ring. Since the rings in a valid area don't cross each other, it is enough to check one point.
+func isRingInsideRing(inner, outer []*Location) bool {
+	return isPointInRing(inner[0], outer)
+}
+
+// isPointInRing uses the ray casting algorithm to check if a point is inside a closed ring
+func isPointInRing(point *Location, ring []*Location) bool {
+	isInside := false
+	for i := 0; i < len(ring)-1; i++ {
+		a, b := ring[i], ring[i+1]
+		if (a.Lat > point.Lat) == (b.Lat > point.Lat) {
+			continue
+		}
+
+		lonAtPointLat := a.Lon + (point.Lat-a.Lat)*(b.Lon-a.Lon)/(b.Lat-a.Lat)
+		if point.Lon < lonAtPointLat {
+			isInside = !isInside
+		}
+	}
+	return isInside
+}
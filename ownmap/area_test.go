@@ -0,0 +1,195 @@
+package ownmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func locations(latLons ...float64) []*Location {
+	var locations []*Location
+	for i := 0; i < len(latLons); i += 2 {
+		locations = append(locations, &Location{Lat: latLons[i], Lon: latLons[i+1]})
+	}
+	return locations
+}
+
+func TestIsAreaRelation(t *testing.T) {
+	assert.True(t, IsAreaRelation([]*OSMTag{{Key: "landuse", Value: "forest"}, {Key: "type", Value: "multipolygon"}}))
+	assert.True(t, IsAreaRelation([]*OSMTag{{Key: "type", Value: "boundary"}}))
+	assert.False(t, IsAreaRelation([]*OSMTag{{Key: "type", Value: "route"}}))
+	assert.False(t, IsAreaRelation([]*OSMTag{{Key: "landuse", Value: "forest"}}))
+}
+
+func TestAssembleAreaGeometry(t *testing.T) {
+	// counter-clockwise square, from (0,0) to (1,1)
+	outerSquare := locations(0, 0, 0, 1, 1, 1, 1, 0, 0, 0)
+	// clockwise square, from (0.2,0.2) to (0.4,0.4)
+	innerSquare := locations(0.2, 0.2, 0.4, 0.2, 0.4, 0.4, 0.2, 0.4, 0.2, 0.2)
+
+	type args struct {
+		outerWays, innerWays [][]*Location
+	}
+	tests := []struct {
+		name string
+		args args
+		want *AreaGeometry
+	}{
+		{
+			name: "no outer ways",
+			args: args{innerWays: [][]*Location{innerSquare}},
+			want: nil,
+		}, {
+			name: "single closed way",
+			args: args{outerWays: [][]*Location{outerSquare}},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}},
+			}},
+		}, {
+			name: "clockwise outer way is reversed",
+			args: args{outerWays: [][]*Location{locations(0, 0, 1, 0, 1, 1, 0, 1, 0, 0)}},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}},
+			}},
+		}, {
+			name: "split ring, with the second way in the opposite direction",
+			args: args{outerWays: [][]*Location{
+				locations(0, 0, 0, 1, 1, 1),
+				locations(0, 0, 1, 0, 1, 1),
+			}},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}},
+			}},
+		}, {
+			name: "split ring, starting in the middle of the chain",
+			args: args{outerWays: [][]*Location{
+				locations(0, 1, 1, 1),
+				locations(1, 1, 1, 0, 0, 0),
+				locations(0, 0, 0, 1),
+			}},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: locations(0, 1, 1, 1, 1, 0, 0, 0, 0, 1)}},
+			}},
+		}, {
+			name: "unclosed ring is closed",
+			args: args{outerWays: [][]*Location{locations(0, 0, 0, 1, 1, 1, 1, 0)}},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}},
+			}},
+		}, {
+			name: "counter-clockwise inner way is reversed",
+			args: args{
+				outerWays: [][]*Location{outerSquare},
+				innerWays: [][]*Location{locations(0.2, 0.2, 0.2, 0.4, 0.4, 0.4, 0.4, 0.2, 0.2, 0.2)},
+			},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}, Inners: []*AreaRing{{Points: innerSquare}}},
+			}},
+		}, {
+			name: "inner rings are put in the outer ring containing them, and dropped if there isn't one",
+			args: args{
+				outerWays: [][]*Location{
+					outerSquare,
+					locations(10, 10, 10, 11, 11, 11, 11, 10, 10, 10),
+				},
+				innerWays: [][]*Location{
+					locations(10.2, 10.2, 10.4, 10.2, 10.4, 10.4, 10.2, 10.4, 10.2, 10.2),
+					locations(20.2, 20.2, 20.4, 20.2, 20.4, 20.4, 20.2, 20.4, 20.2, 20.2),
+				},
+			},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}},
+				{
+					Outer:  &AreaRing{Points: locations(10, 10, 10, 11, 11, 11, 11, 10, 10, 10)},
+					Inners: []*AreaRing{{Points: locations(10.2, 10.2, 10.4, 10.2, 10.4, 10.4, 10.2, 10.4, 10.2, 10.2)}},
+				},
+			}},
+		}, {
+			name: "inner ring goes in the smallest containing outer ring",
+			args: args{
+				outerWays: [][]*Location{
+					locations(-5, -5, -5, 5, 5, 5, 5, -5, -5, -5),
+					outerSquare,
+				},
+				innerWays: [][]*Location{innerSquare},
+			},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: outerSquare}, Inners: []*AreaRing{{Points: innerSquare}}},
+				{Outer: &AreaRing{Points: locations(-5, -5, -5, 5, 5, 5, 5, -5, -5, -5)}},
+			}},
+		}, {
+			name: "ways with too few points are dropped",
+			args: args{outerWays: [][]*Location{locations(0, 0), locations(0, 0, 1, 1)}},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AssembleAreaGeometry(tt.args.outerWays, tt.args.innerWays)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAssembleAreaGeometry_doesNotModifyInput(t *testing.T) {
+	way1 := locations(0, 0, 0, 1, 1, 1)
+	way2 := locations(0, 0, 1, 0, 1, 1)
+
+	area := AssembleAreaGeometry([][]*Location{way1, way2}, nil)
+	require.NotNil(t, area)
+
+	assert.Equal(t, locations(0, 0, 0, 1, 1, 1), way1)
+	assert.Equal(t, locations(0, 0, 1, 0, 1, 1), way2)
+}
+
+func TestAssembleAreaGeometryFromMembers(t *testing.T) {
+	wayPoints := func(locations []*Location) []*WayPoint {
+		var wayPoints []*WayPoint
+		for _, location := range locations {
+			wayPoints = append(wayPoints, &WayPoint{Point: location})
+		}
+		return wayPoints
+	}
+
+	members := []*RelationMemberData{
+		// ways without a role are treated as outer ways
+		{Role: "", Object: &OSMWay{WayPoints: wayPoints(locations(0, 0, 0, 1, 1, 1))}},
+		{Role: "outer", Object: &OSMWay{WayPoints: wayPoints(locations(1, 1, 1, 0, 0, 0))}},
+		{Role: "inner", Object: &OSMWay{WayPoints: wayPoints(locations(0.2, 0.2, 0.4, 0.2, 0.4, 0.4, 0.2, 0.4, 0.2, 0.2))}},
+		// ignored members
+		{Role: "label", Object: &OSMNode{Lat: 0.5, Lon: 0.5}},
+		{Role: "outer", Object: (*OSMWay)(nil)},
+	}
+
+	got := AssembleAreaGeometryFromMembers(members)
+	want := &AreaGeometry{Polygons: []*AreaPolygon{{
+		Outer:  &AreaRing{Points: locations(0, 0, 0, 1, 1, 1, 1, 0, 0, 0)},
+		Inners: []*AreaRing{{Points: locations(0.2, 0.2, 0.4, 0.2, 0.4, 0.4, 0.2, 0.4, 0.2, 0.2)}},
+	}}}
+	assert.Equal(t, want, got)
+}
+
+func TestIsPointInArea(t *testing.T) {
+	// square from 0,0 to 10,10 with a hole from 4,4 to 6,6
+	area := AssembleAreaGeometry(
+		[][]*Location{locations(0, 0, 0, 10, 10, 10, 10, 0, 0, 0)},
+		[][]*Location{locations(4, 4, 4, 6, 6, 6, 6, 4, 4, 4)},
+	)
+	require.NotNil(t, area)
+
+	tests := []struct {
+		name  string
+		point *Location
+		want  bool
+	}{
+		{"inside", &Location{Lat: 2, Lon: 2}, true},
+		{"in hole", &Location{Lat: 5, Lon: 5}, false},
+		{"outside", &Location{Lat: 11, Lon: 5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsPointInArea(tt.point, area))
+		})
+	}
+}
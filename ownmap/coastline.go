@@ -0,0 +1,379 @@
+package ownmap
+
+import (
+	"math"
+
+	"github.com/paulmach/osm"
+)
+
+// GeneratedRelationIDStart is the first ID used for relations generated at import time (for example water polygons from coastlines),
+// so that they don't clash with OSM relation IDs
+const GeneratedRelationIDStart int64 = 1 << 62
+
+// IsCoastline returns true for coastline ways. Coastline ways have the land on their left, and the water on their right.
+func IsCoastline(tags []*OSMTag) bool {
+	for _, tag := range tags {
+		if tag.Key == "natural" && tag.Value == "coastline" {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCoastlineWaterTags gives the tags of the water areas generated from coastlines
+func NewCoastlineWaterTags() []*OSMTag {
+	return []*OSMTag{
+		{Key: "natural", Value: "water"},
+		{Key: "water", Value: "ocean"},
+	}
+}
+
+// BuildWaterAreaFromCoastlines stitches coastline ways together, and builds the water polygons inside the bounds.
+// Coastlines that don't form a closed ring inside the bounds (because they go outside of the dataset) have their open ends extended to the nearest edge of the bounds,
+// and are then closed along the edge of the bounds.
+// If there are only islands, the rest of the bounds is assumed to be water. Returns nil if there is no water.
+func BuildWaterAreaFromCoastlines(coastlines [][]*Location, bounds osm.Bounds) *AreaGeometry {
+	var islandRings, waterRings [][]*Location
+	var openChains []*coastlineChain
+
+	for _, chain := range joinCoastlines(coastlines) {
+		if isSameLocation(chain[0], chain[len(chain)-1]) {
+			if len(chain) < 4 {
+				continue
+			}
+
+			if signedRingArea(chain) > 0 {
+				// counter-clockwise, so the land is inside
+				islandRings = append(islandRings, chain)
+			} else {
+				waterRings = append(waterRings, chain)
+			}
+			continue
+		}
+
+		openChains = append(openChains, newCoastlineChain(chain, bounds))
+	}
+
+	waterRings = append(waterRings, closeCoastlineChainsAlongBounds(openChains, bounds)...)
+
+	if len(openChains) == 0 && len(waterRings) == 0 && len(islandRings) != 0 {
+		// nothing but islands, so everything around them is water
+		waterRings = append(waterRings, []*Location{
+			{Lat: bounds.MinLat, Lon: bounds.MinLon},
+			{Lat: bounds.MinLat, Lon: bounds.MaxLon},
+			{Lat: bounds.MaxLat, Lon: bounds.MaxLon},
+			{Lat: bounds.MaxLat, Lon: bounds.MinLon},
+			{Lat: bounds.MinLat, Lon: bounds.MinLon},
+		})
+	}
+
+	return AssembleAreaGeometry(waterRings, islandRings)
+}
+
+// joinCoastlines joins coastline ways end-to-end into chains. Unlike with relation members, the direction of coastlines is significant, so ways are not reversed.
+func joinCoastlines(ways [][]*Location) [][]*Location {
+	var unusedWays [][]*Location
+	for _, way := range ways {
+		if len(way) < 2 {
+			continue
+		}
+		unusedWays = append(unusedWays, way)
+	}
+
+	popWay := func(isWanted func(way []*Location) bool) []*Location {
+		for i, way := range unusedWays {
+			if isWanted(way) {
+				unusedWays = append(unusedWays[:i], unusedWays[i+1:]...)
+				return way
+			}
+		}
+		return nil
+	}
+
+	var chains [][]*Location
+	for len(unusedWays) != 0 {
+		chain := append([]*Location{}, unusedWays[0]...)
+		unusedWays = unusedWays[1:]
+
+		for !isSameLocation(chain[0], chain[len(chain)-1]) {
+			lastLocation := chain[len(chain)-1]
+			nextWay := popWay(func(way []*Location) bool {
+				return isSameLocation(way[0], lastLocation)
+			})
+			if nextWay == nil {
+				break
+			}
+			chain = append(chain, nextWay[1:]...)
+		}
+
+		for !isSameLocation(chain[0], chain[len(chain)-1]) {
+			firstLocation := chain[0]
+			previousWay := popWay(func(way []*Location) bool {
+				return isSameLocation(way[len(way)-1], firstLocation)
+			})
+			if previousWay == nil {
+				break
+			}
+			chain = append(append([]*Location{}, previousWay...), chain[1:]...)
+		}
+
+		chains = append(chains, chain)
+	}
+
+	return chains
+}
+
+// coastlineChain is an unclosed coastline, with its ends extended to the edge of the bounds
+type coastlineChain struct {
+	points []*Location
+	// entryPosition and exitPosition are the positions on the edge of the bounds that the chain starts and ends at (see boundsEdgePosition)
+	entryPosition, exitPosition float64
+}
+
+func newCoastlineChain(points []*Location, bounds osm.Bounds) *coastlineChain {
+	var clampedPoints []*Location
+	for _, point := range points {
+		clampedPoints = append(clampedPoints, &Location{
+			Lat: math.Max(bounds.MinLat, math.Min(bounds.MaxLat, point.Lat)),
+			Lon: math.Max(bounds.MinLon, math.Min(bounds.MaxLon, point.Lon)),
+		})
+	}
+
+	entryLocation, entryPosition := projectOntoBoundsEdge(clampedPoints[0], bounds)
+	exitLocation, exitPosition := projectOntoBoundsEdge(clampedPoints[len(clampedPoints)-1], bounds)
+
+	var chainPoints []*Location
+	if !isSameLocation(entryLocation, clampedPoints[0]) {
+		chainPoints = append(chainPoints, entryLocation)
+	}
+	chainPoints = append(chainPoints, clampedPoints...)
+	if !isSameLocation(exitLocation, clampedPoints[len(clampedPoints)-1]) {
+		chainPoints = append(chainPoints, exitLocation)
+	}
+
+	return &coastlineChain{chainPoints, entryPosition, exitPosition}
+}
+
+// projectOntoBoundsEdge moves a point inside the bounds onto the nearest edge of the bounds.
+// It also returns the position of the point going clockwise around the edge of the bounds, starting from the top-left corner:
+// [0,1) is the top edge, [1,2) the right edge, [2,3) the bottom edge and [3,4) the left edge.
+func projectOntoBoundsEdge(point *Location, bounds osm.Bounds) (*Location, float64) {
+	latRange := bounds.MaxLat - bounds.MinLat
+	lonRange := bounds.MaxLon - bounds.MinLon
+
+	distanceToTop := bounds.MaxLat - point.Lat
+	distanceToRight := bounds.MaxLon - point.Lon
+	distanceToBottom := point.Lat - bounds.MinLat
+	distanceToLeft := point.Lon - bounds.MinLon
+
+	switch math.Min(math.Min(distanceToTop, distanceToRight), math.Min(distanceToBottom, distanceToLeft)) {
+	case distanceToTop:
+		return &Location{Lat: bounds.MaxLat, Lon: point.Lon}, distanceToLeft / lonRange
+	case distanceToRight:
+		return &Location{Lat: point.Lat, Lon: bounds.MaxLon}, 1 + distanceToTop/latRange
+	case distanceToBottom:
+		return &Location{Lat: bounds.MinLat, Lon: point.Lon}, 2 + distanceToRight/lonRange
+	default:
+		return &Location{Lat: point.Lat, Lon: bounds.MinLon}, math.Mod(3+distanceToBottom/latRange, 4)
+	}
+}
+
+func boundsCorner(cornerIndex int, bounds osm.Bounds) *Location {
+	switch cornerIndex % 4 {
+	case 0:
+		return &Location{Lat: bounds.MaxLat, Lon: bounds.MinLon}
+	case 1:
+		return &Location{Lat: bounds.MaxLat, Lon: bounds.MaxLon}
+	case 2:
+		return &Location{Lat: bounds.MinLat, Lon: bounds.MaxLon}
+	default:
+		return &Location{Lat: bounds.MinLat, Lon: bounds.MinLon}
+	}
+}
+
+// clockwiseDistance is how far to go clockwise around the edge of the bounds to get from one position to another
+func clockwiseDistance(from, to float64) float64 {
+	distance := to - from
+	if distance < 0 {
+		distance += 4
+	}
+	return distance
+}
+
+// closeCoastlineChainsAlongBounds builds water rings from unclosed coastlines.
+// Since the water is on the right of a coastline, when a coastline leaves the bounds, the water carries on clockwise along the edge of the bounds,
+// until the next coastline comes into the bounds.
+func closeCoastlineChainsAlongBounds(chains []*coastlineChain, bounds osm.Bounds) [][]*Location {
+	unusedChains := make(map[*coastlineChain]bool)
+	for _, chain := range chains {
+		unusedChains[chain] = true
+	}
+
+	var rings [][]*Location
+	for _, startChain := range chains {
+		if !unusedChains[startChain] {
+			continue
+		}
+		delete(unusedChains, startChain)
+
+		ring := append([]*Location{}, startChain.points...)
+		currentChain := startChain
+		for {
+			// find the next coastline coming into the bounds, going clockwise. If there isn't a nearer one, that is the start of this ring.
+			nextChain := startChain
+			nextDistance := clockwiseDistance(currentChain.exitPosition, startChain.entryPosition)
+			for chain := range unusedChains {
+				distance := clockwiseDistance(currentChain.exitPosition, chain.entryPosition)
+				if distance < nextDistance {
+					nextChain = chain
+					nextDistance = distance
+				}
+			}
+
+			// add the corners passed on the way
+			for cornerIndex := int(math.Floor(currentChain.exitPosition)) + 1; float64(cornerIndex)-currentChain.exitPosition < nextDistance; cornerIndex++ {
+				ring = append(ring, boundsCorner(cornerIndex, bounds))
+			}
+
+			if nextChain == startChain {
+				ring = append(ring, startChain.points[0])
+				break
+			}
+
+			delete(unusedChains, nextChain)
+			ring = append(ring, nextChain.points...)
+			currentChain = nextChain
+		}
+
+		rings = append(rings, ring)
+	}
+
+	return rings
+}
+
+// SplitAreaIntoCells splits an area up into a grid of cells of cellSize degrees, starting from the bottom left of the bounds.
+// This keeps each part small, so only the parts of a big area (e.g. the sea) near the requested bounds need to be fetched.
+// Cells with no part of the area in them are left out.
+func SplitAreaIntoCells(area *AreaGeometry, bounds osm.Bounds, cellSize float64) []*AreaGeometry {
+	latCellCount := int(math.Ceil((bounds.MaxLat - bounds.MinLat) / cellSize))
+	lonCellCount := int(math.Ceil((bounds.MaxLon - bounds.MinLon) / cellSize))
+
+	var cells []*AreaGeometry
+	for latCell := 0; latCell < latCellCount; latCell++ {
+		for lonCell := 0; lonCell < lonCellCount; lonCell++ {
+			cellBounds := osm.Bounds{
+				MinLat: bounds.MinLat + float64(latCell)*cellSize,
+				MaxLat: math.Min(bounds.MaxLat, bounds.MinLat+float64(latCell+1)*cellSize),
+				MinLon: bounds.MinLon + float64(lonCell)*cellSize,
+				MaxLon: math.Min(bounds.MaxLon, bounds.MinLon+float64(lonCell+1)*cellSize),
+			}
+
+			cellArea := new(AreaGeometry)
+			for _, polygon := range area.Polygons {
+				if !Overlaps(cellBounds, GetRingBounds(polygon.Outer.Points)) {
+					continue
+				}
+
+				outer := clipRingToBounds(polygon.Outer.Points, cellBounds)
+				if outer == nil {
+					continue
+				}
+
+				cellPolygon := &AreaPolygon{Outer: &AreaRing{Points: outer}}
+				for _, inner := range polygon.Inners {
+					clippedInner := clipRingToBounds(inner.Points, cellBounds)
+					if clippedInner == nil {
+						continue
+					}
+					cellPolygon.Inners = append(cellPolygon.Inners, &AreaRing{Points: clippedInner})
+				}
+
+				cellArea.Polygons = append(cellArea.Polygons, cellPolygon)
+			}
+
+			if len(cellArea.Polygons) == 0 {
+				continue
+			}
+
+			cells = append(cells, cellArea)
+		}
+	}
+
+	return cells
+}
+
+// GetRingBounds gives the bounding box of a ring
+func GetRingBounds(ring []*Location) osm.Bounds {
+	bounds := osm.Bounds{
+		MinLat: math.Inf(1),
+		MaxLat: math.Inf(-1),
+		MinLon: math.Inf(1),
+		MaxLon: math.Inf(-1),
+	}
+	for _, point := range ring {
+		bounds.MinLat = math.Min(bounds.MinLat, point.Lat)
+		bounds.MaxLat = math.Max(bounds.MaxLat, point.Lat)
+		bounds.MinLon = math.Min(bounds.MinLon, point.Lon)
+		bounds.MaxLon = math.Max(bounds.MaxLon, point.Lon)
+	}
+	return bounds
+}
+
+// clipRingToBounds clips a closed ring to a rectangle, with the Sutherland-Hodgman algorithm.
+// Concave rings can end up with zero-width parts along the edge of the bounds, which don't affect how they are filled.
+// Returns nil if none of the ring is inside the bounds.
+func clipRingToBounds(ring []*Location, bounds osm.Bounds) []*Location {
+	type edge struct {
+		isInside     func(point *Location) bool
+		intersection func(a, b *Location) *Location
+	}
+
+	intersectAtLat := func(lat float64) func(a, b *Location) *Location {
+		return func(a, b *Location) *Location {
+			return &Location{Lat: lat, Lon: a.Lon + (lat-a.Lat)*(b.Lon-a.Lon)/(b.Lat-a.Lat)}
+		}
+	}
+	intersectAtLon := func(lon float64) func(a, b *Location) *Location {
+		return func(a, b *Location) *Location {
+			return &Location{Lat: a.Lat + (lon-a.Lon)*(b.Lat-a.Lat)/(b.Lon-a.Lon), Lon: lon}
+		}
+	}
+
+	edges := []edge{
+		{func(point *Location) bool { return point.Lat <= bounds.MaxLat }, intersectAtLat(bounds.MaxLat)},
+		{func(point *Location) bool { return point.Lon <= bounds.MaxLon }, intersectAtLon(bounds.MaxLon)},
+		{func(point *Location) bool { return point.Lat >= bounds.MinLat }, intersectAtLat(bounds.MinLat)},
+		{func(point *Location) bool { return point.Lon >= bounds.MinLon }, intersectAtLon(bounds.MinLon)},
+	}
+
+	// work on the ring without the closing point
+	points := ring[:len(ring)-1]
+	for _, e := range edges {
+		if len(points) == 0 {
+			return nil
+		}
+
+		var clippedPoints []*Location
+		previousPoint := points[len(points)-1]
+		for _, point := range points {
+			isPointInside := e.isInside(point)
+			isPreviousPointInside := e.isInside(previousPoint)
+
+			if isPointInside != isPreviousPointInside {
+				clippedPoints = append(clippedPoints, e.intersection(previousPoint, point))
+			}
+			if isPointInside {
+				clippedPoints = append(clippedPoints, point)
+			}
+
+			previousPoint = point
+		}
+		points = clippedPoints
+	}
+
+	if len(points) < 3 || signedRingArea(append(points, points[0])) == 0 {
+		return nil
+	}
+
+	return append(points, points[0])
+}
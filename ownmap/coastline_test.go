@@ -0,0 +1,119 @@
+package ownmap
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCoastline(t *testing.T) {
+	assert.True(t, IsCoastline([]*OSMTag{{Key: "name", Value: "x"}, {Key: "natural", Value: "coastline"}}))
+	assert.False(t, IsCoastline([]*OSMTag{{Key: "natural", Value: "water"}}))
+	assert.False(t, IsCoastline(nil))
+}
+
+func TestBuildWaterAreaFromCoastlines(t *testing.T) {
+	bounds := osm.Bounds{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1}
+
+	// counter-clockwise, so land inside
+	island := locations(0.4, 0.4, 0.4, 0.6, 0.6, 0.6, 0.6, 0.4, 0.4, 0.4)
+
+	tests := []struct {
+		name       string
+		coastlines [][]*Location
+		want       *AreaGeometry
+	}{
+		{
+			name: "no coastlines",
+			want: nil,
+		}, {
+			name: "coastline going east, with the sea to the south",
+			coastlines: [][]*Location{
+				locations(0.5, 0.1, 0.5, 0.9),
+			},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: locations(0.5, 0, 0, 0, 0, 1, 0.5, 1, 0.5, 0.9, 0.5, 0.1, 0.5, 0)}},
+			}},
+		}, {
+			name: "coastline split into ways going west, with the sea to the north",
+			coastlines: [][]*Location{
+				locations(0.5, 0.5, 0.5, 0.1),
+				locations(0.5, 0.9, 0.5, 0.5),
+			},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{Outer: &AreaRing{Points: locations(0.5, 1, 1, 1, 1, 0, 0.5, 0, 0.5, 0.1, 0.5, 0.5, 0.5, 0.9, 0.5, 1)}},
+			}},
+		}, {
+			name:       "only an island",
+			coastlines: [][]*Location{island},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{
+					Outer:  &AreaRing{Points: locations(0, 0, 0, 1, 1, 1, 1, 0, 0, 0)},
+					Inners: []*AreaRing{{Points: locations(0.4, 0.4, 0.6, 0.4, 0.6, 0.6, 0.4, 0.6, 0.4, 0.4)}},
+				},
+			}},
+		}, {
+			name: "island in the sea off an unclosed coastline",
+			coastlines: [][]*Location{
+				locations(0.8, 0.1, 0.8, 0.9),
+				island,
+			},
+			want: &AreaGeometry{Polygons: []*AreaPolygon{
+				{
+					Outer:  &AreaRing{Points: locations(0.8, 0, 0, 0, 0, 1, 0.8, 1, 0.8, 0.9, 0.8, 0.1, 0.8, 0)},
+					Inners: []*AreaRing{{Points: locations(0.4, 0.4, 0.6, 0.4, 0.6, 0.6, 0.4, 0.6, 0.4, 0.4)}},
+				},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildWaterAreaFromCoastlines(tt.coastlines, bounds)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitAreaIntoCells(t *testing.T) {
+	bounds := osm.Bounds{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1}
+
+	t.Run("area covering the bottom half", func(t *testing.T) {
+		area := &AreaGeometry{Polygons: []*AreaPolygon{
+			{Outer: &AreaRing{Points: locations(0, 0, 0, 1, 0.5, 1, 0.5, 0, 0, 0)}},
+		}}
+
+		cells := SplitAreaIntoCells(area, bounds, 0.5)
+		require.Len(t, cells, 2)
+
+		assert.Equal(t, osm.Bounds{MinLat: 0, MaxLat: 0.5, MinLon: 0, MaxLon: 0.5}, GetRingBounds(cells[0].Polygons[0].Outer.Points))
+		assert.Equal(t, osm.Bounds{MinLat: 0, MaxLat: 0.5, MinLon: 0.5, MaxLon: 1}, GetRingBounds(cells[1].Polygons[0].Outer.Points))
+	})
+
+	t.Run("holes are clipped with the cells they are in", func(t *testing.T) {
+		area := &AreaGeometry{Polygons: []*AreaPolygon{
+			{
+				Outer:  &AreaRing{Points: locations(0, 0, 0, 1, 1, 1, 1, 0, 0, 0)},
+				Inners: []*AreaRing{{Points: locations(0.1, 0.1, 0.3, 0.1, 0.3, 0.3, 0.1, 0.3, 0.1, 0.1)}},
+			},
+		}}
+
+		cells := SplitAreaIntoCells(area, bounds, 0.5)
+		require.Len(t, cells, 4)
+
+		for i, cell := range cells {
+			require.Len(t, cell.Polygons, 1)
+			outer := cell.Polygons[0].Outer.Points
+			assert.True(t, isSameLocation(outer[0], outer[len(outer)-1]))
+			assert.Greater(t, signedRingArea(outer), 0.0)
+
+			if i == 0 {
+				require.Len(t, cell.Polygons[0].Inners, 1)
+				assert.Equal(t, locations(0.1, 0.1, 0.3, 0.1, 0.3, 0.3, 0.1, 0.3, 0.1, 0.1), cell.Polygons[0].Inners[0].Points)
+			} else {
+				assert.Empty(t, cell.Polygons[0].Inners)
+			}
+		}
+	})
+}
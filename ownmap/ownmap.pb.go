@@ -52,7 +52,7 @@ var OSMRelationMember_OSMMemberType_value = map[string]int32{
 }
 
 func (OSMRelationMember_OSMMemberType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{5, 0}
+	return fileDescriptor_7e6171d0cad86ce0, []int{6, 0}
 }
 
 type OSMRelationMember_OSMMemberOrientation int32
@@ -76,7 +76,35 @@ var OSMRelationMember_OSMMemberOrientation_value = map[string]int32{
 }
 
 func (OSMRelationMember_OSMMemberOrientation) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{5, 1}
+	return fileDescriptor_7e6171d0cad86ce0, []int{6, 1}
+}
+
+// where the bounds came from. The tightest of these is used
+type DatasetInfo_BoundsSource int32
+
+const (
+	BOUNDS_SOURCE_UNKNOWN     DatasetInfo_BoundsSource = 0
+	BOUNDS_SOURCE_DATA_EXTENT DatasetInfo_BoundsSource = 1
+	BOUNDS_SOURCE_HEADER      DatasetInfo_BoundsSource = 2
+	BOUNDS_SOURCE_REQUESTED   DatasetInfo_BoundsSource = 3
+)
+
+var DatasetInfo_BoundsSource_name = map[int32]string{
+	0: "BOUNDS_SOURCE_UNKNOWN",
+	1: "BOUNDS_SOURCE_DATA_EXTENT",
+	2: "BOUNDS_SOURCE_HEADER",
+	3: "BOUNDS_SOURCE_REQUESTED",
+}
+
+var DatasetInfo_BoundsSource_value = map[string]int32{
+	"BOUNDS_SOURCE_UNKNOWN":     0,
+	"BOUNDS_SOURCE_DATA_EXTENT": 1,
+	"BOUNDS_SOURCE_HEADER":      2,
+	"BOUNDS_SOURCE_REQUESTED":   3,
+}
+
+func (DatasetInfo_BoundsSource) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_7e6171d0cad86ce0, []int{12, 0}
 }
 
 type OSMNode struct {
@@ -84,6 +112,8 @@ type OSMNode struct {
 	Tags []*OSMTag `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
 	Lat  float64   `protobuf:"fixed64,3,opt,name=lat,proto3" json:"lat,omitempty"`
 	Lon  float64   `protobuf:"fixed64,4,opt,name=lon,proto3" json:"lon,omitempty"`
+	// metadata is only set if the dataset was imported with metadata
+	Metadata *OSMObjectMetadata `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
 }
 
 func (m *OSMNode) Reset()      { *m = OSMNode{} }
@@ -146,6 +176,90 @@ func (m *OSMNode) GetLon() float64 {
 	return 0
 }
 
+func (m *OSMNode) GetMetadata() *OSMObjectMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+// OSMObjectMetadata is the editing history information of an object, from the raw data file
+type OSMObjectMetadata struct {
+	Version int64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// timestamp_ms is when this version of the object was made, in milliseconds since the Unix epoch
+	TimestampMs int64  `protobuf:"varint,2,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestampMs"`
+	ChangesetID int64  `protobuf:"varint,3,opt,name=changeset_id,json=changesetId,proto3" json:"changesetId"`
+	User        string `protobuf:"bytes,4,opt,name=user,proto3" json:"user,omitempty"`
+	UserID      int64  `protobuf:"varint,5,opt,name=user_id,json=userId,proto3" json:"userId"`
+}
+
+func (m *OSMObjectMetadata) Reset()      { *m = OSMObjectMetadata{} }
+func (*OSMObjectMetadata) ProtoMessage() {}
+func (*OSMObjectMetadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7e6171d0cad86ce0, []int{1}
+}
+func (m *OSMObjectMetadata) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *OSMObjectMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_OSMObjectMetadata.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *OSMObjectMetadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OSMObjectMetadata.Merge(m, src)
+}
+func (m *OSMObjectMetadata) XXX_Size() int {
+	return m.Size()
+}
+func (m *OSMObjectMetadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_OSMObjectMetadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OSMObjectMetadata proto.InternalMessageInfo
+
+func (m *OSMObjectMetadata) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *OSMObjectMetadata) GetTimestampMs() int64 {
+	if m != nil {
+		return m.TimestampMs
+	}
+	return 0
+}
+
+func (m *OSMObjectMetadata) GetChangesetID() int64 {
+	if m != nil {
+		return m.ChangesetID
+	}
+	return 0
+}
+
+func (m *OSMObjectMetadata) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *OSMObjectMetadata) GetUserID() int64 {
+	if m != nil {
+		return m.UserID
+	}
+	return 0
+}
+
 type OSMTag struct {
 	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -154,7 +268,7 @@ type OSMTag struct {
 func (m *OSMTag) Reset()      { *m = OSMTag{} }
 func (*OSMTag) ProtoMessage() {}
 func (*OSMTag) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{1}
+	return fileDescriptor_7e6171d0cad86ce0, []int{2}
 }
 func (m *OSMTag) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -205,7 +319,7 @@ type Location struct {
 func (m *Location) Reset()      { *m = Location{} }
 func (*Location) ProtoMessage() {}
 func (*Location) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{2}
+	return fileDescriptor_7e6171d0cad86ce0, []int{3}
 }
 func (m *Location) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -256,7 +370,7 @@ type WayPoint struct {
 func (m *WayPoint) Reset()      { *m = WayPoint{} }
 func (*WayPoint) ProtoMessage() {}
 func (*WayPoint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{3}
+	return fileDescriptor_7e6171d0cad86ce0, []int{4}
 }
 func (m *WayPoint) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -303,12 +417,14 @@ type OSMWay struct {
 	ID        int64       `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Tags      []*OSMTag   `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
 	WayPoints []*WayPoint `protobuf:"bytes,3,rep,name=way_points,json=wayPoints,proto3" json:"wayPoints"`
+	// metadata is only set if the dataset was imported with metadata
+	Metadata *OSMObjectMetadata `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
 }
 
 func (m *OSMWay) Reset()      { *m = OSMWay{} }
 func (*OSMWay) ProtoMessage() {}
 func (*OSMWay) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{4}
+	return fileDescriptor_7e6171d0cad86ce0, []int{5}
 }
 func (m *OSMWay) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -358,6 +474,13 @@ func (m *OSMWay) GetWayPoints() []*WayPoint {
 	return nil
 }
 
+func (m *OSMWay) GetMetadata() *OSMObjectMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
 type OSMRelationMember struct {
 	ObjectID    int64                                  `protobuf:"varint,1,opt,name=object_id,json=objectId,proto3" json:"objectId"`
 	MemberType  OSMRelationMember_OSMMemberType        `protobuf:"varint,2,opt,name=member_type,json=memberType,proto3,enum=ownmap.OSMRelationMember_OSMMemberType" json:"memberType"`
@@ -368,7 +491,7 @@ type OSMRelationMember struct {
 func (m *OSMRelationMember) Reset()      { *m = OSMRelationMember{} }
 func (*OSMRelationMember) ProtoMessage() {}
 func (*OSMRelationMember) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{5}
+	return fileDescriptor_7e6171d0cad86ce0, []int{6}
 }
 func (m *OSMRelationMember) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -429,12 +552,16 @@ type OSMRelation struct {
 	ID      int64                `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Tags    []*OSMTag            `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
 	Members []*OSMRelationMember `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`
+	// area is the assembled geometry of multipolygon and boundary relations. Not set for other relations.
+	Area *AreaGeometry `protobuf:"bytes,4,opt,name=area,proto3" json:"area,omitempty"`
+	// metadata is only set if the dataset was imported with metadata
+	Metadata *OSMObjectMetadata `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
 }
 
 func (m *OSMRelation) Reset()      { *m = OSMRelation{} }
 func (*OSMRelation) ProtoMessage() {}
 func (*OSMRelation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{6}
+	return fileDescriptor_7e6171d0cad86ce0, []int{7}
 }
 func (m *OSMRelation) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -484,6 +611,159 @@ func (m *OSMRelation) GetMembers() []*OSMRelationMember {
 	return nil
 }
 
+func (m *OSMRelation) GetArea() *AreaGeometry {
+	if m != nil {
+		return m.Area
+	}
+	return nil
+}
+
+func (m *OSMRelation) GetMetadata() *OSMObjectMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+// AreaRing is a closed ring of points; the first and last points are the same.
+type AreaRing struct {
+	Points []*Location `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+}
+
+func (m *AreaRing) Reset()      { *m = AreaRing{} }
+func (*AreaRing) ProtoMessage() {}
+func (*AreaRing) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7e6171d0cad86ce0, []int{8}
+}
+func (m *AreaRing) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AreaRing) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AreaRing.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AreaRing) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AreaRing.Merge(m, src)
+}
+func (m *AreaRing) XXX_Size() int {
+	return m.Size()
+}
+func (m *AreaRing) XXX_DiscardUnknown() {
+	xxx_messageInfo_AreaRing.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AreaRing proto.InternalMessageInfo
+
+func (m *AreaRing) GetPoints() []*Location {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+// AreaPolygon is an outer ring (counter-clockwise), with optional holes (clockwise).
+type AreaPolygon struct {
+	Outer  *AreaRing   `protobuf:"bytes,1,opt,name=outer,proto3" json:"outer,omitempty"`
+	Inners []*AreaRing `protobuf:"bytes,2,rep,name=inners,proto3" json:"inners,omitempty"`
+}
+
+func (m *AreaPolygon) Reset()      { *m = AreaPolygon{} }
+func (*AreaPolygon) ProtoMessage() {}
+func (*AreaPolygon) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7e6171d0cad86ce0, []int{9}
+}
+func (m *AreaPolygon) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AreaPolygon) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AreaPolygon.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AreaPolygon) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AreaPolygon.Merge(m, src)
+}
+func (m *AreaPolygon) XXX_Size() int {
+	return m.Size()
+}
+func (m *AreaPolygon) XXX_DiscardUnknown() {
+	xxx_messageInfo_AreaPolygon.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AreaPolygon proto.InternalMessageInfo
+
+func (m *AreaPolygon) GetOuter() *AreaRing {
+	if m != nil {
+		return m.Outer
+	}
+	return nil
+}
+
+func (m *AreaPolygon) GetInners() []*AreaRing {
+	if m != nil {
+		return m.Inners
+	}
+	return nil
+}
+
+type AreaGeometry struct {
+	Polygons []*AreaPolygon `protobuf:"bytes,1,rep,name=polygons,proto3" json:"polygons,omitempty"`
+}
+
+func (m *AreaGeometry) Reset()      { *m = AreaGeometry{} }
+func (*AreaGeometry) ProtoMessage() {}
+func (*AreaGeometry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7e6171d0cad86ce0, []int{10}
+}
+func (m *AreaGeometry) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AreaGeometry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AreaGeometry.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AreaGeometry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AreaGeometry.Merge(m, src)
+}
+func (m *AreaGeometry) XXX_Size() int {
+	return m.Size()
+}
+func (m *AreaGeometry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AreaGeometry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AreaGeometry proto.InternalMessageInfo
+
+func (m *AreaGeometry) GetPolygons() []*AreaPolygon {
+	if m != nil {
+		return m.Polygons
+	}
+	return nil
+}
+
 type IndexIDList struct {
 	IDs []int64 `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
 }
@@ -491,7 +771,7 @@ type IndexIDList struct {
 func (m *IndexIDList) Reset()      { *m = IndexIDList{} }
 func (*IndexIDList) ProtoMessage() {}
 func (*IndexIDList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{7}
+	return fileDescriptor_7e6171d0cad86ce0, []int{11}
 }
 func (m *IndexIDList) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -528,14 +808,23 @@ func (m *IndexIDList) GetIDs() []int64 {
 }
 
 type DatasetInfo struct {
-	Bounds            *DatasetInfo_Bounds `protobuf:"bytes,1,opt,name=bounds,proto3" json:"bounds,omitempty"`
-	ReplicationTimeMs uint64              `protobuf:"varint,2,opt,name=replication_time_ms,json=replicationTimeMs,proto3" json:"replicationTimeMs"`
+	Bounds                    *DatasetInfo_Bounds      `protobuf:"bytes,1,opt,name=bounds,proto3" json:"bounds,omitempty"`
+	ReplicationTimeMs         uint64                   `protobuf:"varint,2,opt,name=replication_time_ms,json=replicationTimeMs,proto3" json:"replicationTimeMs"`
+	ReplicationSequenceNumber uint64                   `protobuf:"varint,3,opt,name=replication_sequence_number,json=replicationSequenceNumber,proto3" json:"replicationSequenceNumber"`
+	ReplicationBaseURL        string                   `protobuf:"bytes,4,opt,name=replication_base_url,json=replicationBaseUrl,proto3" json:"replicationBaseUrl"`
+	BoundsSource              DatasetInfo_BoundsSource `protobuf:"varint,5,opt,name=bounds_source,json=boundsSource,proto3,enum=ownmap.DatasetInfo_BoundsSource" json:"boundsSource"`
+	// has_metadata is true if the objects have their metadata (version, timestamp, changeset, user)
+	HasMetadata bool `protobuf:"varint,6,opt,name=has_metadata,json=hasMetadata,proto3" json:"hasMetadata"`
+	// import_bounds are the bounds the data was imported with (the tighter of the header bounds and the requested bounds).
+	// Unlike bounds, they aren't narrowed to the extent of the imported data, so they are used to pick out new objects from changes.
+	// Not set for datasets imported before they were recorded.
+	ImportBounds *DatasetInfo_Bounds `protobuf:"bytes,7,opt,name=import_bounds,json=importBounds,proto3" json:"importBounds"`
 }
 
 func (m *DatasetInfo) Reset()      { *m = DatasetInfo{} }
 func (*DatasetInfo) ProtoMessage() {}
 func (*DatasetInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{8}
+	return fileDescriptor_7e6171d0cad86ce0, []int{12}
 }
 func (m *DatasetInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -578,6 +867,41 @@ func (m *DatasetInfo) GetReplicationTimeMs() uint64 {
 	return 0
 }
 
+func (m *DatasetInfo) GetReplicationSequenceNumber() uint64 {
+	if m != nil {
+		return m.ReplicationSequenceNumber
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetReplicationBaseURL() string {
+	if m != nil {
+		return m.ReplicationBaseURL
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetBoundsSource() DatasetInfo_BoundsSource {
+	if m != nil {
+		return m.BoundsSource
+	}
+	return BOUNDS_SOURCE_UNKNOWN
+}
+
+func (m *DatasetInfo) GetHasMetadata() bool {
+	if m != nil {
+		return m.HasMetadata
+	}
+	return false
+}
+
+func (m *DatasetInfo) GetImportBounds() *DatasetInfo_Bounds {
+	if m != nil {
+		return m.ImportBounds
+	}
+	return nil
+}
+
 type DatasetInfo_Bounds struct {
 	MinLat float64 `protobuf:"fixed64,1,opt,name=min_lat,json=minLat,proto3" json:"minLat"`
 	MaxLat float64 `protobuf:"fixed64,2,opt,name=max_lat,json=maxLat,proto3" json:"maxLat"`
@@ -588,7 +912,7 @@ type DatasetInfo_Bounds struct {
 func (m *DatasetInfo_Bounds) Reset()      { *m = DatasetInfo_Bounds{} }
 func (*DatasetInfo_Bounds) ProtoMessage() {}
 func (*DatasetInfo_Bounds) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{8, 0}
+	return fileDescriptor_7e6171d0cad86ce0, []int{12, 0}
 }
 func (m *DatasetInfo_Bounds) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -653,7 +977,7 @@ type KVPair struct {
 func (m *KVPair) Reset()      { *m = KVPair{} }
 func (*KVPair) ProtoMessage() {}
 func (*KVPair) Descriptor() ([]byte, []int) {
-	return fileDescriptor_7e6171d0cad86ce0, []int{9}
+	return fileDescriptor_7e6171d0cad86ce0, []int{13}
 }
 func (m *KVPair) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -699,13 +1023,18 @@ func (m *KVPair) GetValue() []byte {
 func init() {
 	proto.RegisterEnum("ownmap.OSMRelationMember_OSMMemberType", OSMRelationMember_OSMMemberType_name, OSMRelationMember_OSMMemberType_value)
 	proto.RegisterEnum("ownmap.OSMRelationMember_OSMMemberOrientation", OSMRelationMember_OSMMemberOrientation_name, OSMRelationMember_OSMMemberOrientation_value)
+	proto.RegisterEnum("ownmap.DatasetInfo_BoundsSource", DatasetInfo_BoundsSource_name, DatasetInfo_BoundsSource_value)
 	proto.RegisterType((*OSMNode)(nil), "ownmap.OSMNode")
+	proto.RegisterType((*OSMObjectMetadata)(nil), "ownmap.OSMObjectMetadata")
 	proto.RegisterType((*OSMTag)(nil), "ownmap.OSMTag")
 	proto.RegisterType((*Location)(nil), "ownmap.Location")
 	proto.RegisterType((*WayPoint)(nil), "ownmap.WayPoint")
 	proto.RegisterType((*OSMWay)(nil), "ownmap.OSMWay")
 	proto.RegisterType((*OSMRelationMember)(nil), "ownmap.OSMRelationMember")
 	proto.RegisterType((*OSMRelation)(nil), "ownmap.OSMRelation")
+	proto.RegisterType((*AreaRing)(nil), "ownmap.AreaRing")
+	proto.RegisterType((*AreaPolygon)(nil), "ownmap.AreaPolygon")
+	proto.RegisterType((*AreaGeometry)(nil), "ownmap.AreaGeometry")
 	proto.RegisterType((*IndexIDList)(nil), "ownmap.IndexIDList")
 	proto.RegisterType((*DatasetInfo)(nil), "ownmap.DatasetInfo")
 	proto.RegisterType((*DatasetInfo_Bounds)(nil), "ownmap.DatasetInfo.Bounds")
@@ -715,62 +1044,90 @@ func init() {
 func init() { proto.RegisterFile("ownmap/ownmap.proto", fileDescriptor_7e6171d0cad86ce0) }
 
 var fileDescriptor_7e6171d0cad86ce0 = []byte{
-	// 867 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xcd, 0x6e, 0xdb, 0x46,
-	0x10, 0xd6, 0x92, 0x8a, 0x6c, 0x8d, 0x52, 0x43, 0x5e, 0x1b, 0x0d, 0xe3, 0x06, 0xa4, 0x40, 0x14,
-	0xa9, 0x50, 0x34, 0x72, 0x21, 0xb7, 0x87, 0x1e, 0xa3, 0x48, 0x07, 0xc2, 0x92, 0x68, 0xac, 0x94,
-	0x0a, 0x09, 0x0a, 0x10, 0x2b, 0x73, 0xad, 0x32, 0x15, 0xb9, 0x02, 0x49, 0xc7, 0xd6, 0xa1, 0x45,
-	0x9f, 0xa0, 0x68, 0xdf, 0x22, 0x0f, 0xd2, 0x43, 0x8f, 0x3e, 0xe6, 0x44, 0xd4, 0x74, 0x0f, 0x85,
-	0x4f, 0x79, 0x84, 0x82, 0x4b, 0x52, 0x62, 0xec, 0x1a, 0x08, 0x90, 0xd3, 0xfc, 0x7d, 0x33, 0xdf,
-	0xce, 0xfe, 0xcc, 0xc2, 0x0e, 0x3f, 0xf3, 0x5c, 0xba, 0xd8, 0x4f, 0x45, 0x6b, 0xe1, 0xf3, 0x90,
-	0xe3, 0x4a, 0x6a, 0xed, 0x7d, 0xf3, 0x9a, 0x79, 0x36, 0xf7, 0xf7, 0x67, 0x4e, 0xf8, 0xe3, 0xe9,
-	0xb4, 0x75, 0xcc, 0xdd, 0xfd, 0x19, 0x9f, 0xf1, 0x7d, 0x81, 0x9a, 0x9e, 0x9e, 0x08, 0x4b, 0x18,
-	0x42, 0x4b, 0xb3, 0x75, 0x07, 0x36, 0xcc, 0xd1, 0x60, 0xc8, 0x6d, 0x86, 0x3f, 0x05, 0xc9, 0xb1,
-	0x15, 0xd4, 0x40, 0x4d, 0xb9, 0x53, 0x89, 0x23, 0x4d, 0x32, 0xba, 0x44, 0x72, 0x6c, 0xac, 0x43,
-	0x39, 0xa4, 0xb3, 0x40, 0x91, 0x1a, 0x72, 0xb3, 0xd6, 0xde, 0x6a, 0x65, 0xec, 0xe6, 0x68, 0x30,
-	0xa6, 0x33, 0x22, 0x62, 0xb8, 0x0e, 0xf2, 0x9c, 0x86, 0x8a, 0xdc, 0x40, 0x4d, 0x44, 0x12, 0x55,
-	0x78, 0xb8, 0xa7, 0x94, 0x33, 0x0f, 0xf7, 0xf4, 0xaf, 0xa1, 0x92, 0xe6, 0x24, 0xb1, 0x9f, 0xd8,
-	0x52, 0x50, 0x55, 0x49, 0xa2, 0xe2, 0x5d, 0xb8, 0xf7, 0x9a, 0xce, 0x4f, 0x99, 0x22, 0x09, 0x5f,
-	0x6a, 0xe8, 0x2d, 0xd8, 0xec, 0xf3, 0x63, 0x1a, 0x3a, 0xdc, 0xcb, 0x19, 0xd0, 0x2d, 0x06, 0x69,
-	0xcd, 0x40, 0x61, 0x73, 0x42, 0x97, 0x47, 0xdc, 0xf1, 0x42, 0xfc, 0x04, 0x36, 0x3c, 0x6e, 0x33,
-	0x6b, 0xd5, 0xd2, 0x6e, 0x1c, 0x69, 0x95, 0xa4, 0x51, 0xa3, 0x7b, 0x1d, 0x69, 0x95, 0x24, 0x68,
-	0xd8, 0x24, 0x93, 0xf8, 0x31, 0xdc, 0x5b, 0x24, 0x79, 0xa2, 0x5c, 0xad, 0x5d, 0xcf, 0xbb, 0xcc,
-	0xf9, 0x49, 0x1a, 0xd6, 0xff, 0x40, 0xa2, 0x8b, 0x09, 0x5d, 0x7e, 0xd4, 0x7e, 0x1d, 0x02, 0x9c,
-	0xd1, 0xa5, 0x25, 0x6a, 0x06, 0x8a, 0x2c, 0x90, 0x2b, 0xce, 0xbc, 0x87, 0xce, 0x5e, 0x1c, 0x69,
-	0xd5, 0xdc, 0x0a, 0xae, 0x23, 0xad, 0x7a, 0x96, 0x1b, 0x64, 0xad, 0xea, 0x6f, 0xca, 0xb0, 0x6d,
-	0x8e, 0x06, 0x84, 0xcd, 0xc5, 0x52, 0x07, 0xcc, 0x9d, 0x32, 0x1f, 0x7f, 0x0b, 0x55, 0x3e, 0x7d,
-	0xc5, 0x8e, 0xc3, 0xf5, 0x16, 0x28, 0x71, 0xa4, 0x6d, 0x9a, 0xc2, 0x29, 0x36, 0x61, 0x33, 0x05,
-	0x18, 0x36, 0x59, 0x69, 0xf8, 0x04, 0x6a, 0xae, 0x28, 0x60, 0x85, 0xcb, 0x45, 0x7a, 0x1e, 0x5b,
-	0xed, 0x2f, 0x0a, 0x4d, 0xbc, 0x4f, 0x93, 0x78, 0x52, 0x6d, 0xbc, 0x5c, 0xb0, 0xce, 0xa3, 0x38,
-	0xd2, 0x60, 0x6d, 0x5f, 0x47, 0x1a, 0xb8, 0x2b, 0x8b, 0x14, 0x74, 0x8c, 0xa1, 0xec, 0xf3, 0x39,
-	0x13, 0x57, 0xa6, 0x4a, 0x84, 0x8e, 0x8f, 0xa0, 0xc6, 0x7d, 0x87, 0x79, 0xa1, 0x20, 0x10, 0x77,
-	0x67, 0xab, 0xdd, 0xfa, 0x00, 0x6e, 0x73, 0x9d, 0x45, 0x8a, 0x25, 0xf4, 0x9f, 0xe1, 0x93, 0xf7,
-	0x16, 0x88, 0x3f, 0x83, 0x07, 0xe6, 0x68, 0x60, 0x0d, 0x7a, 0x83, 0x4e, 0x8f, 0x58, 0xe3, 0x17,
-	0x47, 0x3d, 0xeb, 0xf9, 0xf0, 0x70, 0x68, 0x4e, 0x86, 0xf5, 0x12, 0x56, 0x60, 0xf7, 0x66, 0x70,
-	0x68, 0x76, 0x7b, 0x75, 0x84, 0x1f, 0xc0, 0xce, 0xcd, 0xc8, 0xe4, 0xe9, 0x8b, 0xba, 0x84, 0x1f,
-	0x81, 0x72, 0x33, 0x40, 0x7a, 0xfd, 0xa7, 0x63, 0xc3, 0x1c, 0xd6, 0x65, 0xfd, 0x37, 0x24, 0x2a,
-	0xde, 0x5a, 0x24, 0xd6, 0x41, 0x2d, 0xa4, 0x99, 0xc4, 0xe8, 0x0d, 0xc7, 0x22, 0xa9, 0xb0, 0x9a,
-	0xcf, 0xa1, 0x71, 0x07, 0xe6, 0x59, 0xdf, 0x7c, 0x76, 0x38, 0x31, 0x46, 0xc9, 0xca, 0xbe, 0x82,
-	0xe6, 0x5d, 0x28, 0xf3, 0xf9, 0x70, 0xdc, 0x23, 0x05, 0xb4, 0xa4, 0xff, 0x02, 0xb5, 0xc2, 0x36,
-	0x7e, 0xd4, 0x15, 0x3e, 0x80, 0x8d, 0xf4, 0x38, 0xf3, 0xfb, 0xfb, 0xf0, 0xce, 0x83, 0x22, 0x39,
-	0x52, 0x6f, 0x42, 0xcd, 0xf0, 0x6c, 0x76, 0x6e, 0x74, 0xfb, 0x4e, 0x10, 0xe2, 0x87, 0x20, 0x3b,
-	0x76, 0xa0, 0xa0, 0x86, 0xdc, 0x94, 0x3b, 0x1b, 0x71, 0xa4, 0xc9, 0x46, 0x37, 0x20, 0x89, 0x4f,
-	0xff, 0x47, 0x82, 0x5a, 0x97, 0x86, 0x34, 0x60, 0xa1, 0xe1, 0x9d, 0x70, 0xdc, 0x86, 0xca, 0x94,
-	0x9f, 0x7a, 0x02, 0x9d, 0xbc, 0xd0, 0xbd, 0x9c, 0xad, 0x00, 0x6a, 0x75, 0x04, 0x82, 0x64, 0x48,
-	0xfc, 0x12, 0x76, 0x7c, 0xb6, 0x98, 0x3b, 0xe9, 0x13, 0xb6, 0x42, 0xc7, 0x65, 0x96, 0x1b, 0x88,
-	0x3b, 0x5d, 0xee, 0x7c, 0x19, 0x47, 0xda, 0x36, 0x59, 0x87, 0xc7, 0x8e, 0xcb, 0x06, 0xc9, 0x23,
-	0xdb, 0xf6, 0x6f, 0x3a, 0xc9, 0x6d, 0xd7, 0xde, 0x9f, 0x08, 0x2a, 0x29, 0x5d, 0x32, 0x6a, 0x5c,
-	0xc7, 0xb3, 0x56, 0xe3, 0x29, 0x1d, 0x35, 0x03, 0xc7, 0xeb, 0xd3, 0x30, 0x19, 0x35, 0xae, 0xd0,
-	0x48, 0x26, 0x05, 0x9c, 0x9e, 0x0b, 0xb8, 0x54, 0x80, 0xd3, 0xf3, 0x1c, 0x2e, 0x34, 0x92, 0xc9,
-	0x55, 0x75, 0xee, 0xa5, 0xe3, 0x75, 0x5d, 0x9d, 0x7b, 0x79, 0x75, 0xee, 0x91, 0x4c, 0xae, 0xaa,
-	0xe7, 0xb3, 0x77, 0x5d, 0x3d, 0x83, 0x0b, 0x8d, 0x64, 0x32, 0x19, 0xca, 0x87, 0xdf, 0x1f, 0x51,
-	0xc7, 0x2f, 0x0e, 0xe5, 0xfb, 0xff, 0x33, 0x94, 0xef, 0x67, 0x43, 0xb9, 0xf3, 0xc3, 0xc5, 0xa5,
-	0x5a, 0x7a, 0x7b, 0xa9, 0x96, 0xde, 0x5d, 0xaa, 0xe8, 0xd7, 0x58, 0x45, 0x6f, 0x62, 0x15, 0xfd,
-	0x15, 0xab, 0xe8, 0x22, 0x56, 0xd1, 0xdf, 0xb1, 0x8a, 0xfe, 0x8d, 0xd5, 0xd2, 0xbb, 0x58, 0x45,
-	0xbf, 0x5f, 0xa9, 0xa5, 0x8b, 0x2b, 0xb5, 0xf4, 0xf6, 0x4a, 0x2d, 0xbd, 0x7c, 0x5c, 0xf8, 0x96,
-	0x5e, 0x51, 0x97, 0x05, 0xbe, 0x7f, 0xf0, 0x5d, 0xf6, 0x91, 0x3d, 0xa1, 0x8b, 0xfc, 0x4f, 0x9b,
-	0x56, 0xc4, 0xb7, 0x74, 0xf0, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x42, 0x81, 0xc5, 0xb4, 0xeb,
-	0x06, 0x00, 0x00,
+	// 1324 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0xcb, 0x6e, 0xdb, 0x46,
+	0x17, 0x36, 0x45, 0x59, 0x96, 0x47, 0x8a, 0x7f, 0x65, 0xec, 0xbf, 0x91, 0x9c, 0x94, 0x14, 0x88,
+	0x22, 0x15, 0x8a, 0xc4, 0x2e, 0x94, 0x64, 0xd1, 0x6e, 0x0a, 0xd1, 0x22, 0x5a, 0xc1, 0xba, 0x38,
+	0x23, 0xa9, 0x6e, 0x82, 0xa2, 0xc4, 0xc8, 0x9a, 0xc8, 0x4c, 0x45, 0x52, 0x25, 0xa9, 0x24, 0x2a,
+	0x50, 0xb4, 0x4f, 0x50, 0xf4, 0x11, 0xda, 0x5d, 0x1e, 0xa4, 0x8b, 0x2c, 0xb3, 0xcc, 0x8a, 0x6d,
+	0x98, 0x45, 0x0b, 0xaf, 0xf2, 0x08, 0xc5, 0xcc, 0x90, 0xf4, 0xc8, 0x8e, 0xd1, 0xa0, 0x59, 0xcd,
+	0xb9, 0x7c, 0xe7, 0x3a, 0x87, 0x9c, 0x03, 0x36, 0xdd, 0xc7, 0x8e, 0x8d, 0x67, 0xbb, 0xfc, 0xd8,
+	0x99, 0x79, 0x6e, 0xe0, 0xc2, 0x1c, 0xe7, 0xb6, 0x6f, 0x3f, 0x22, 0xce, 0xd8, 0xf5, 0x76, 0x27,
+	0x56, 0x70, 0x3c, 0x1f, 0xed, 0x1c, 0xb9, 0xf6, 0xee, 0xc4, 0x9d, 0xb8, 0xbb, 0x0c, 0x35, 0x9a,
+	0x3f, 0x60, 0x1c, 0x63, 0x18, 0xc5, 0xad, 0xb5, 0x5f, 0x25, 0xb0, 0xd6, 0xeb, 0x77, 0xba, 0xee,
+	0x98, 0xc0, 0xf7, 0x40, 0xc6, 0x1a, 0x97, 0xa5, 0xaa, 0x54, 0x93, 0xf5, 0x5c, 0x14, 0xaa, 0x99,
+	0x56, 0x13, 0x65, 0xac, 0x31, 0xd4, 0x40, 0x36, 0xc0, 0x13, 0xbf, 0x9c, 0xa9, 0xca, 0xb5, 0x42,
+	0x7d, 0x63, 0x27, 0x0e, 0xdf, 0xeb, 0x77, 0x06, 0x78, 0x82, 0x98, 0x0e, 0x96, 0x80, 0x3c, 0xc5,
+	0x41, 0x59, 0xae, 0x4a, 0x35, 0x09, 0x51, 0x92, 0x49, 0x5c, 0xa7, 0x9c, 0x8d, 0x25, 0xae, 0x03,
+	0xef, 0x80, 0xbc, 0x4d, 0x02, 0x3c, 0xc6, 0x01, 0x2e, 0xaf, 0x56, 0xa5, 0x5a, 0xa1, 0x5e, 0x11,
+	0x7c, 0xf5, 0x46, 0x0f, 0xc9, 0x51, 0xd0, 0x89, 0x01, 0x28, 0x85, 0x6a, 0x7f, 0x49, 0xe0, 0xf2,
+	0x39, 0x3d, 0x2c, 0x83, 0xb5, 0x47, 0xc4, 0xf3, 0x2d, 0xd7, 0xe1, 0x19, 0xa3, 0x84, 0x85, 0x75,
+	0x50, 0x0c, 0x2c, 0x9b, 0xf8, 0x01, 0xb6, 0x67, 0xa6, 0x4d, 0xd3, 0xa6, 0x05, 0xfd, 0xef, 0x24,
+	0x54, 0x0b, 0xa9, 0xbc, 0xe3, 0x23, 0x91, 0x81, 0x0d, 0x50, 0x3c, 0x3a, 0xc6, 0xce, 0x84, 0xf8,
+	0x24, 0x30, 0xad, 0x31, 0xab, 0x43, 0xd6, 0x95, 0x28, 0x54, 0x0b, 0x7b, 0x89, 0xbc, 0xd5, 0xa4,
+	0x2e, 0x52, 0x58, 0x6b, 0x8c, 0x44, 0x06, 0x42, 0x90, 0x9d, 0xfb, 0xc4, 0x63, 0x05, 0xaf, 0x23,
+	0x46, 0xc3, 0x9b, 0x60, 0x8d, 0x9e, 0xd4, 0xe3, 0x2a, 0xf3, 0xb8, 0x15, 0x85, 0x6a, 0x6e, 0xe8,
+	0x13, 0x8f, 0x39, 0xcb, 0x51, 0x65, 0x6b, 0x8c, 0xe2, 0x53, 0xfb, 0x18, 0xe4, 0x78, 0x53, 0x69,
+	0xf3, 0xbe, 0x25, 0x0b, 0x56, 0xd9, 0x3a, 0xa2, 0x24, 0xdc, 0x02, 0xab, 0x8f, 0xf0, 0x74, 0x4e,
+	0x58, 0x39, 0xeb, 0x88, 0x33, 0xda, 0x0e, 0xc8, 0xb7, 0xdd, 0x23, 0x1c, 0xd0, 0xba, 0xe3, 0x2b,
+	0x90, 0xce, 0x5d, 0x41, 0x26, 0xbd, 0x02, 0x0d, 0x83, 0xfc, 0x21, 0x5e, 0x1c, 0xb8, 0x96, 0x13,
+	0xd0, 0xe4, 0x1c, 0x77, 0x4c, 0xcc, 0xf4, 0xce, 0x59, 0x72, 0x74, 0x12, 0x78, 0x72, 0x54, 0x49,
+	0x93, 0xe3, 0x27, 0xbc, 0x0e, 0x56, 0x67, 0xd4, 0x8e, 0xb9, 0x2b, 0xd4, 0x4b, 0xc9, 0xd5, 0x25,
+	0xf1, 0x11, 0x57, 0x6b, 0xcf, 0x24, 0x56, 0xc5, 0x21, 0x5e, 0xbc, 0xd3, 0x40, 0xed, 0x03, 0xf0,
+	0x18, 0x2f, 0x4c, 0xe6, 0xd3, 0x2f, 0xcb, 0x55, 0x59, 0x8c, 0x99, 0xd4, 0xa0, 0x6f, 0x47, 0xa1,
+	0xba, 0x9e, 0x70, 0xfe, 0x49, 0xa8, 0xae, 0x3f, 0x4e, 0x18, 0x74, 0x4a, 0x2e, 0x4d, 0x5e, 0xf6,
+	0xed, 0x27, 0xef, 0x69, 0x96, 0x4d, 0x1e, 0x22, 0x53, 0x56, 0x61, 0x87, 0xd8, 0x23, 0xe2, 0xc1,
+	0x3b, 0x60, 0xdd, 0x65, 0x16, 0xa7, 0x9d, 0x2b, 0x47, 0xa1, 0x9a, 0xe7, 0x6e, 0x58, 0xef, 0xf2,
+	0x1c, 0xd0, 0x1a, 0xa3, 0x94, 0x82, 0x0f, 0x40, 0xc1, 0x66, 0x0e, 0xcc, 0x60, 0x31, 0xe3, 0xd7,
+	0xb8, 0x51, 0xff, 0x50, 0x48, 0x63, 0x39, 0x0c, 0x95, 0x70, 0x6a, 0xb0, 0x98, 0x11, 0xfd, 0x5a,
+	0x14, 0xaa, 0xe0, 0x94, 0x3f, 0x09, 0x55, 0x60, 0xa7, 0x1c, 0x12, 0x68, 0x3a, 0x87, 0x9e, 0x3b,
+	0x25, 0x6c, 0x84, 0xd7, 0x11, 0xa3, 0xe1, 0x01, 0x28, 0xb8, 0x9e, 0x45, 0x9c, 0x80, 0x05, 0x60,
+	0x2d, 0xd8, 0xa8, 0xef, 0xbc, 0x45, 0xec, 0xde, 0xa9, 0x15, 0x12, 0x5d, 0x68, 0x3f, 0x80, 0x4b,
+	0x4b, 0x09, 0xc2, 0xab, 0xe0, 0x4a, 0xaf, 0xdf, 0x31, 0x3b, 0x46, 0x47, 0x37, 0x90, 0x39, 0xb8,
+	0x77, 0x60, 0x98, 0xc3, 0xee, 0x7e, 0xb7, 0x77, 0xd8, 0x2d, 0xad, 0xc0, 0x32, 0xd8, 0x3a, 0xab,
+	0xec, 0xf6, 0x9a, 0x46, 0x49, 0x82, 0x57, 0xc0, 0xe6, 0x59, 0xcd, 0x61, 0xe3, 0x5e, 0x29, 0x03,
+	0xaf, 0x81, 0xf2, 0x59, 0x05, 0x32, 0xda, 0x8d, 0x41, 0xab, 0xd7, 0x2d, 0xc9, 0xda, 0xcf, 0x12,
+	0xd8, 0x4a, 0xe3, 0x0b, 0x49, 0x42, 0x0d, 0x28, 0x82, 0x59, 0x0f, 0xb5, 0x8c, 0xee, 0x80, 0x19,
+	0x09, 0xd9, 0x7c, 0x00, 0xaa, 0x17, 0x60, 0xf6, 0xda, 0xbd, 0xbd, 0xfd, 0xc3, 0x56, 0x9f, 0x66,
+	0x76, 0x03, 0xd4, 0x2e, 0x42, 0xf5, 0x86, 0xdd, 0x81, 0x81, 0x04, 0x74, 0x46, 0xfb, 0x43, 0x02,
+	0x05, 0xa1, 0x8f, 0xef, 0x34, 0xfa, 0xb7, 0xc0, 0x1a, 0xbf, 0xcf, 0x64, 0xee, 0x2b, 0x17, 0xde,
+	0x14, 0x4a, 0x90, 0xb0, 0x06, 0xb2, 0xd8, 0x23, 0xc9, 0x78, 0x6f, 0x25, 0x16, 0x0d, 0x8f, 0xe0,
+	0xcf, 0x89, 0x6b, 0x93, 0xc0, 0x5b, 0x20, 0x86, 0xf8, 0xaf, 0xbf, 0xe1, 0xdb, 0x20, 0x4f, 0x9d,
+	0x21, 0xcb, 0x99, 0xc0, 0x1a, 0xc8, 0xc5, 0x1f, 0xa6, 0x54, 0x95, 0xdf, 0xf8, 0x33, 0x88, 0xf5,
+	0x9a, 0x09, 0x0a, 0xd4, 0xea, 0xc0, 0x9d, 0x2e, 0x26, 0xae, 0x43, 0x7f, 0x22, 0xee, 0x3c, 0x20,
+	0x1e, 0xeb, 0x8c, 0x60, 0x97, 0x78, 0x46, 0x5c, 0x4d, 0x03, 0x58, 0x8e, 0x43, 0xbc, 0xa4, 0x51,
+	0xe7, 0x81, 0xb1, 0x5e, 0xfb, 0x0c, 0x14, 0xc5, 0x1a, 0xe1, 0x2e, 0xc8, 0xcf, 0x78, 0xb0, 0x24,
+	0xb9, 0x4d, 0xd1, 0x36, 0x4e, 0x04, 0xa5, 0x20, 0xad, 0x06, 0x0a, 0x2d, 0x67, 0x4c, 0x9e, 0xb4,
+	0x9a, 0x6d, 0xcb, 0x0f, 0x60, 0x05, 0xc8, 0xd6, 0x98, 0x9b, 0xca, 0xfa, 0x5a, 0x14, 0xaa, 0x72,
+	0xab, 0xe9, 0x23, 0x2a, 0xd3, 0x7e, 0x5b, 0x03, 0x85, 0x26, 0x0e, 0x30, 0xfd, 0xdf, 0x3b, 0x0f,
+	0x5c, 0x58, 0x07, 0xb9, 0x91, 0x3b, 0x77, 0xc6, 0x7e, 0x5c, 0xcd, 0x76, 0x12, 0x48, 0x00, 0xed,
+	0xe8, 0x0c, 0x81, 0x62, 0x24, 0xbc, 0x0f, 0x36, 0x3d, 0x32, 0x9b, 0x5a, 0xbc, 0x4d, 0x26, 0x7d,
+	0x83, 0x92, 0x37, 0x2a, 0xab, 0x7f, 0x14, 0x85, 0xea, 0x65, 0x74, 0xaa, 0x1e, 0x58, 0x36, 0xe9,
+	0xd0, 0xbf, 0xda, 0x65, 0xef, 0xac, 0x10, 0x9d, 0x17, 0xc1, 0xef, 0xc1, 0x55, 0xd1, 0xb7, 0x4f,
+	0xbe, 0x9b, 0x13, 0xe7, 0x88, 0x98, 0xce, 0x9c, 0x8e, 0x08, 0xfb, 0x21, 0x64, 0xf5, 0x4f, 0xa3,
+	0x50, 0xad, 0x08, 0x31, 0xfa, 0x31, 0xaa, 0xcb, 0x40, 0x27, 0xa1, 0x5a, 0xf1, 0x2e, 0x52, 0xa2,
+	0x8b, 0x55, 0xf0, 0x1b, 0xb0, 0x25, 0xc6, 0x1e, 0x61, 0x9f, 0x98, 0x73, 0x6f, 0xca, 0x5f, 0x43,
+	0xfd, 0x46, 0x14, 0xaa, 0x50, 0x08, 0xaa, 0x63, 0x9f, 0x0c, 0x51, 0xfb, 0x24, 0x54, 0xa1, 0x77,
+	0x46, 0xea, 0x4d, 0xd1, 0x1b, 0x64, 0xf0, 0x10, 0x5c, 0xe2, 0x1d, 0x34, 0x7d, 0x77, 0xee, 0x1d,
+	0x11, 0x36, 0xb9, 0x1b, 0xf5, 0xea, 0xc5, 0x2d, 0xef, 0x33, 0x9c, 0x5e, 0x3a, 0x09, 0xd5, 0xe2,
+	0x48, 0x90, 0xa0, 0x25, 0x8e, 0x6e, 0x0b, 0xc7, 0xd8, 0x37, 0xd3, 0x2f, 0x22, 0x57, 0x95, 0x6a,
+	0x79, 0xbe, 0x2d, 0x1c, 0x63, 0x3f, 0xfd, 0x0e, 0x44, 0x06, 0xde, 0x05, 0x97, 0x2c, 0x7b, 0xe6,
+	0x7a, 0x81, 0x19, 0xdf, 0xff, 0xda, 0xbf, 0xdd, 0x3f, 0x4f, 0x83, 0x1b, 0x71, 0x09, 0x5a, 0xe2,
+	0xb6, 0x7f, 0x97, 0x40, 0x8e, 0x93, 0xf4, 0x5d, 0xb6, 0x2d, 0xc7, 0x4c, 0xdf, 0x72, 0xfe, 0x2e,
+	0x77, 0x2c, 0xa7, 0x8d, 0x03, 0xfa, 0x2e, 0xdb, 0x8c, 0x42, 0xf1, 0xc9, 0xe0, 0xf8, 0x09, 0x83,
+	0x67, 0x04, 0x38, 0x7e, 0x92, 0xc0, 0x19, 0x85, 0xe2, 0x33, 0xf5, 0xee, 0x3a, 0x65, 0x59, 0x80,
+	0x5b, 0x4e, 0xdb, 0x75, 0x12, 0xef, 0xf4, 0xfb, 0xe5, 0x67, 0xea, 0x3d, 0xd9, 0xe4, 0x4e, 0xbd,
+	0xc7, 0x70, 0x46, 0xa1, 0xf8, 0xd4, 0x7e, 0x04, 0x45, 0xb1, 0xfb, 0xb0, 0x02, 0xfe, 0xaf, 0xf7,
+	0x86, 0xdd, 0x66, 0xdf, 0xec, 0xf7, 0x86, 0x68, 0x4f, 0x7c, 0x13, 0xde, 0x07, 0x95, 0x65, 0x55,
+	0xb3, 0x31, 0x68, 0x98, 0xc6, 0x57, 0x03, 0xa3, 0x3b, 0x28, 0x49, 0xf4, 0xc9, 0x58, 0x56, 0x7f,
+	0x61, 0x34, 0x9a, 0x06, 0x2a, 0x65, 0xe8, 0x4b, 0xb3, 0xac, 0x41, 0xc6, 0xdd, 0xa1, 0xd1, 0x1f,
+	0x18, 0xcd, 0x92, 0x4c, 0x57, 0xa8, 0xfd, 0x2f, 0x0f, 0xb0, 0xe5, 0x89, 0x2b, 0x54, 0xf1, 0x0d,
+	0x2b, 0x54, 0x31, 0x5e, 0xa1, 0xf4, 0xaf, 0x9f, 0xbf, 0x54, 0x56, 0x5e, 0xbc, 0x54, 0x56, 0x5e,
+	0xbf, 0x54, 0xa4, 0x9f, 0x22, 0x45, 0x7a, 0x1a, 0x29, 0xd2, 0xb3, 0x48, 0x91, 0x9e, 0x47, 0x8a,
+	0xf4, 0x67, 0xa4, 0x48, 0x7f, 0x47, 0xca, 0xca, 0xeb, 0x48, 0x91, 0x7e, 0x79, 0xa5, 0xac, 0x3c,
+	0x7f, 0xa5, 0xac, 0xbc, 0x78, 0xa5, 0xac, 0xdc, 0xbf, 0x2e, 0xac, 0xd9, 0x0f, 0xb1, 0x4d, 0x7c,
+	0xcf, 0xbb, 0xf5, 0x49, 0xbc, 0x98, 0xdf, 0xc4, 0xb3, 0x64, 0x47, 0x1f, 0xe5, 0xd8, 0x9a, 0x7d,
+	0xeb, 0x9f, 0x01, 0x00, 0x24, 0x62, 0x8d, 0x57, 0xbb, 0x0b, 0x00, 0x00,
 }
 
 func (x OSMRelationMember_OSMMemberType) String() string {
@@ -787,6 +1144,13 @@ func (x OSMRelationMember_OSMMemberOrientation) String() string {
 	}
 	return strconv.Itoa(int(x))
 }
+func (x DatasetInfo_BoundsSource) String() string {
+	s, ok := DatasetInfo_BoundsSource_name[int32(x)]
+	if ok {
+		return s
+	}
+	return strconv.Itoa(int(x))
+}
 func (this *OSMNode) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
@@ -823,6 +1187,45 @@ func (this *OSMNode) Equal(that interface{}) bool {
 	if this.Lon != that1.Lon {
 		return false
 	}
+	if !this.Metadata.Equal(that1.Metadata) {
+		return false
+	}
+	return true
+}
+func (this *OSMObjectMetadata) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*OSMObjectMetadata)
+	if !ok {
+		that2, ok := that.(OSMObjectMetadata)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if this.Version != that1.Version {
+		return false
+	}
+	if this.TimestampMs != that1.TimestampMs {
+		return false
+	}
+	if this.ChangesetID != that1.ChangesetID {
+		return false
+	}
+	if this.User != that1.User {
+		return false
+	}
+	if this.UserID != that1.UserID {
+		return false
+	}
 	return true
 }
 func (this *OSMTag) Equal(that interface{}) bool {
@@ -944,6 +1347,9 @@ func (this *OSMWay) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if !this.Metadata.Equal(that1.Metadata) {
+		return false
+	}
 	return true
 }
 func (this *OSMRelationMember) Equal(that interface{}) bool {
@@ -1017,16 +1423,22 @@ func (this *OSMRelation) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if !this.Area.Equal(that1.Area) {
+		return false
+	}
+	if !this.Metadata.Equal(that1.Metadata) {
+		return false
+	}
 	return true
 }
-func (this *IndexIDList) Equal(that interface{}) bool {
+func (this *AreaRing) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
 	}
 
-	that1, ok := that.(*IndexIDList)
+	that1, ok := that.(*AreaRing)
 	if !ok {
-		that2, ok := that.(IndexIDList)
+		that2, ok := that.(AreaRing)
 		if ok {
 			that1 = &that2
 		} else {
@@ -1038,24 +1450,24 @@ func (this *IndexIDList) Equal(that interface{}) bool {
 	} else if this == nil {
 		return false
 	}
-	if len(this.IDs) != len(that1.IDs) {
+	if len(this.Points) != len(that1.Points) {
 		return false
 	}
-	for i := range this.IDs {
-		if this.IDs[i] != that1.IDs[i] {
+	for i := range this.Points {
+		if !this.Points[i].Equal(that1.Points[i]) {
 			return false
 		}
 	}
 	return true
 }
-func (this *DatasetInfo) Equal(that interface{}) bool {
+func (this *AreaPolygon) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
 	}
 
-	that1, ok := that.(*DatasetInfo)
+	that1, ok := that.(*AreaPolygon)
 	if !ok {
-		that2, ok := that.(DatasetInfo)
+		that2, ok := that.(AreaPolygon)
 		if ok {
 			that1 = &that2
 		} else {
@@ -1067,15 +1479,120 @@ func (this *DatasetInfo) Equal(that interface{}) bool {
 	} else if this == nil {
 		return false
 	}
-	if !this.Bounds.Equal(that1.Bounds) {
+	if !this.Outer.Equal(that1.Outer) {
 		return false
 	}
-	if this.ReplicationTimeMs != that1.ReplicationTimeMs {
+	if len(this.Inners) != len(that1.Inners) {
 		return false
 	}
+	for i := range this.Inners {
+		if !this.Inners[i].Equal(that1.Inners[i]) {
+			return false
+		}
+	}
 	return true
 }
-func (this *DatasetInfo_Bounds) Equal(that interface{}) bool {
+func (this *AreaGeometry) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*AreaGeometry)
+	if !ok {
+		that2, ok := that.(AreaGeometry)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Polygons) != len(that1.Polygons) {
+		return false
+	}
+	for i := range this.Polygons {
+		if !this.Polygons[i].Equal(that1.Polygons[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *IndexIDList) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*IndexIDList)
+	if !ok {
+		that2, ok := that.(IndexIDList)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.IDs) != len(that1.IDs) {
+		return false
+	}
+	for i := range this.IDs {
+		if this.IDs[i] != that1.IDs[i] {
+			return false
+		}
+	}
+	return true
+}
+func (this *DatasetInfo) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*DatasetInfo)
+	if !ok {
+		that2, ok := that.(DatasetInfo)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.Bounds.Equal(that1.Bounds) {
+		return false
+	}
+	if this.ReplicationTimeMs != that1.ReplicationTimeMs {
+		return false
+	}
+	if this.ReplicationSequenceNumber != that1.ReplicationSequenceNumber {
+		return false
+	}
+	if this.ReplicationBaseURL != that1.ReplicationBaseURL {
+		return false
+	}
+	if this.BoundsSource != that1.BoundsSource {
+		return false
+	}
+	if this.HasMetadata != that1.HasMetadata {
+		return false
+	}
+	if !this.ImportBounds.Equal(that1.ImportBounds) {
+		return false
+	}
+	return true
+}
+func (this *DatasetInfo_Bounds) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
 	}
@@ -1139,7 +1656,7 @@ func (this *OSMNode) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 9)
 	s = append(s, "&ownmap.OSMNode{")
 	s = append(s, "ID: "+fmt.Sprintf("%#v", this.ID)+",\n")
 	if this.Tags != nil {
@@ -1147,6 +1664,23 @@ func (this *OSMNode) GoString() string {
 	}
 	s = append(s, "Lat: "+fmt.Sprintf("%#v", this.Lat)+",\n")
 	s = append(s, "Lon: "+fmt.Sprintf("%#v", this.Lon)+",\n")
+	if this.Metadata != nil {
+		s = append(s, "Metadata: "+fmt.Sprintf("%#v", this.Metadata)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *OSMObjectMetadata) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 9)
+	s = append(s, "&ownmap.OSMObjectMetadata{")
+	s = append(s, "Version: "+fmt.Sprintf("%#v", this.Version)+",\n")
+	s = append(s, "TimestampMs: "+fmt.Sprintf("%#v", this.TimestampMs)+",\n")
+	s = append(s, "ChangesetID: "+fmt.Sprintf("%#v", this.ChangesetID)+",\n")
+	s = append(s, "User: "+fmt.Sprintf("%#v", this.User)+",\n")
+	s = append(s, "UserID: "+fmt.Sprintf("%#v", this.UserID)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1189,7 +1723,7 @@ func (this *OSMWay) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 7)
+	s := make([]string, 0, 8)
 	s = append(s, "&ownmap.OSMWay{")
 	s = append(s, "ID: "+fmt.Sprintf("%#v", this.ID)+",\n")
 	if this.Tags != nil {
@@ -1198,6 +1732,9 @@ func (this *OSMWay) GoString() string {
 	if this.WayPoints != nil {
 		s = append(s, "WayPoints: "+fmt.Sprintf("%#v", this.WayPoints)+",\n")
 	}
+	if this.Metadata != nil {
+		s = append(s, "Metadata: "+fmt.Sprintf("%#v", this.Metadata)+",\n")
+	}
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1218,7 +1755,7 @@ func (this *OSMRelation) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 7)
+	s := make([]string, 0, 9)
 	s = append(s, "&ownmap.OSMRelation{")
 	s = append(s, "ID: "+fmt.Sprintf("%#v", this.ID)+",\n")
 	if this.Tags != nil {
@@ -1227,6 +1764,51 @@ func (this *OSMRelation) GoString() string {
 	if this.Members != nil {
 		s = append(s, "Members: "+fmt.Sprintf("%#v", this.Members)+",\n")
 	}
+	if this.Area != nil {
+		s = append(s, "Area: "+fmt.Sprintf("%#v", this.Area)+",\n")
+	}
+	if this.Metadata != nil {
+		s = append(s, "Metadata: "+fmt.Sprintf("%#v", this.Metadata)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *AreaRing) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&ownmap.AreaRing{")
+	if this.Points != nil {
+		s = append(s, "Points: "+fmt.Sprintf("%#v", this.Points)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *AreaPolygon) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&ownmap.AreaPolygon{")
+	if this.Outer != nil {
+		s = append(s, "Outer: "+fmt.Sprintf("%#v", this.Outer)+",\n")
+	}
+	if this.Inners != nil {
+		s = append(s, "Inners: "+fmt.Sprintf("%#v", this.Inners)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *AreaGeometry) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&ownmap.AreaGeometry{")
+	if this.Polygons != nil {
+		s = append(s, "Polygons: "+fmt.Sprintf("%#v", this.Polygons)+",\n")
+	}
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1244,12 +1826,19 @@ func (this *DatasetInfo) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 6)
+	s := make([]string, 0, 11)
 	s = append(s, "&ownmap.DatasetInfo{")
 	if this.Bounds != nil {
 		s = append(s, "Bounds: "+fmt.Sprintf("%#v", this.Bounds)+",\n")
 	}
 	s = append(s, "ReplicationTimeMs: "+fmt.Sprintf("%#v", this.ReplicationTimeMs)+",\n")
+	s = append(s, "ReplicationSequenceNumber: "+fmt.Sprintf("%#v", this.ReplicationSequenceNumber)+",\n")
+	s = append(s, "ReplicationBaseURL: "+fmt.Sprintf("%#v", this.ReplicationBaseURL)+",\n")
+	s = append(s, "BoundsSource: "+fmt.Sprintf("%#v", this.BoundsSource)+",\n")
+	s = append(s, "HasMetadata: "+fmt.Sprintf("%#v", this.HasMetadata)+",\n")
+	if this.ImportBounds != nil {
+		s = append(s, "ImportBounds: "+fmt.Sprintf("%#v", this.ImportBounds)+",\n")
+	}
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1305,6 +1894,18 @@ func (m *OSMNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Metadata != nil {
+		{
+			size, err := m.Metadata.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintOwnmap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x2a
+	}
 	if m.Lon != 0 {
 		i -= 8
 		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Lon))))
@@ -1339,6 +1940,56 @@ func (m *OSMNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *OSMObjectMetadata) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *OSMObjectMetadata) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *OSMObjectMetadata) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.UserID != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.UserID))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.User) > 0 {
+		i -= len(m.User)
+		copy(dAtA[i:], m.User)
+		i = encodeVarintOwnmap(dAtA, i, uint64(len(m.User)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.ChangesetID != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.ChangesetID))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.TimestampMs != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.TimestampMs))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Version != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.Version))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *OSMTag) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1471,6 +2122,18 @@ func (m *OSMWay) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Metadata != nil {
+		{
+			size, err := m.Metadata.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintOwnmap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
 	if len(m.WayPoints) > 0 {
 		for iNdEx := len(m.WayPoints) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1572,6 +2235,30 @@ func (m *OSMRelation) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Metadata != nil {
+		{
+			size, err := m.Metadata.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintOwnmap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.Area != nil {
+		{
+			size, err := m.Area.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintOwnmap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
 	if len(m.Members) > 0 {
 		for iNdEx := len(m.Members) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1608,7 +2295,7 @@ func (m *OSMRelation) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *IndexIDList) Marshal() (dAtA []byte, err error) {
+func (m *AreaRing) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1618,39 +2305,34 @@ func (m *IndexIDList) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *IndexIDList) MarshalTo(dAtA []byte) (int, error) {
+func (m *AreaRing) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *IndexIDList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *AreaRing) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if len(m.IDs) > 0 {
-		dAtA3 := make([]byte, len(m.IDs)*10)
-		var j2 int
-		for _, num1 := range m.IDs {
-			num := uint64(num1)
-			for num >= 1<<7 {
-				dAtA3[j2] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j2++
+	if len(m.Points) > 0 {
+		for iNdEx := len(m.Points) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Points[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintOwnmap(dAtA, i, uint64(size))
 			}
-			dAtA3[j2] = uint8(num)
-			j2++
+			i--
+			dAtA[i] = 0xa
 		}
-		i -= j2
-		copy(dAtA[i:], dAtA3[:j2])
-		i = encodeVarintOwnmap(dAtA, i, uint64(j2))
-		i--
-		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *DatasetInfo) Marshal() (dAtA []byte, err error) {
+func (m *AreaPolygon) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1660,24 +2342,33 @@ func (m *DatasetInfo) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *DatasetInfo) MarshalTo(dAtA []byte) (int, error) {
+func (m *AreaPolygon) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *DatasetInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *AreaPolygon) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if m.ReplicationTimeMs != 0 {
-		i = encodeVarintOwnmap(dAtA, i, uint64(m.ReplicationTimeMs))
-		i--
-		dAtA[i] = 0x10
+	if len(m.Inners) > 0 {
+		for iNdEx := len(m.Inners) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Inners[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintOwnmap(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
 	}
-	if m.Bounds != nil {
+	if m.Outer != nil {
 		{
-			size, err := m.Bounds.MarshalToSizedBuffer(dAtA[:i])
+			size, err := m.Outer.MarshalToSizedBuffer(dAtA[:i])
 			if err != nil {
 				return 0, err
 			}
@@ -1690,7 +2381,7 @@ func (m *DatasetInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *DatasetInfo_Bounds) Marshal() (dAtA []byte, err error) {
+func (m *AreaGeometry) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1700,27 +2391,185 @@ func (m *DatasetInfo_Bounds) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *DatasetInfo_Bounds) MarshalTo(dAtA []byte) (int, error) {
+func (m *AreaGeometry) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *DatasetInfo_Bounds) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *AreaGeometry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if m.MaxLon != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.MaxLon))))
-		i--
-		dAtA[i] = 0x21
-	}
-	if m.MinLon != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.MinLon))))
-		i--
-		dAtA[i] = 0x19
+	if len(m.Polygons) > 0 {
+		for iNdEx := len(m.Polygons) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Polygons[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintOwnmap(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *IndexIDList) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IndexIDList) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IndexIDList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.IDs) > 0 {
+		dAtA8 := make([]byte, len(m.IDs)*10)
+		var j7 int
+		for _, num1 := range m.IDs {
+			num := uint64(num1)
+			for num >= 1<<7 {
+				dAtA8[j7] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j7++
+			}
+			dAtA8[j7] = uint8(num)
+			j7++
+		}
+		i -= j7
+		copy(dAtA[i:], dAtA8[:j7])
+		i = encodeVarintOwnmap(dAtA, i, uint64(j7))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DatasetInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DatasetInfo) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DatasetInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.ImportBounds != nil {
+		{
+			size, err := m.ImportBounds.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintOwnmap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.HasMetadata {
+		i--
+		if m.HasMetadata {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.BoundsSource != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.BoundsSource))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.ReplicationBaseURL) > 0 {
+		i -= len(m.ReplicationBaseURL)
+		copy(dAtA[i:], m.ReplicationBaseURL)
+		i = encodeVarintOwnmap(dAtA, i, uint64(len(m.ReplicationBaseURL)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.ReplicationSequenceNumber != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.ReplicationSequenceNumber))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.ReplicationTimeMs != 0 {
+		i = encodeVarintOwnmap(dAtA, i, uint64(m.ReplicationTimeMs))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Bounds != nil {
+		{
+			size, err := m.Bounds.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintOwnmap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DatasetInfo_Bounds) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DatasetInfo_Bounds) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DatasetInfo_Bounds) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.MaxLon != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.MaxLon))))
+		i--
+		dAtA[i] = 0x21
+	}
+	if m.MinLon != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.MinLon))))
+		i--
+		dAtA[i] = 0x19
 	}
 	if m.MaxLat != 0 {
 		i -= 8
@@ -1806,6 +2655,35 @@ func (m *OSMNode) Size() (n int) {
 	if m.Lon != 0 {
 		n += 9
 	}
+	if m.Metadata != nil {
+		l = m.Metadata.Size()
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
+	return n
+}
+
+func (m *OSMObjectMetadata) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Version != 0 {
+		n += 1 + sovOwnmap(uint64(m.Version))
+	}
+	if m.TimestampMs != 0 {
+		n += 1 + sovOwnmap(uint64(m.TimestampMs))
+	}
+	if m.ChangesetID != 0 {
+		n += 1 + sovOwnmap(uint64(m.ChangesetID))
+	}
+	l = len(m.User)
+	if l > 0 {
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
+	if m.UserID != 0 {
+		n += 1 + sovOwnmap(uint64(m.UserID))
+	}
 	return n
 }
 
@@ -1878,6 +2756,10 @@ func (m *OSMWay) Size() (n int) {
 			n += 1 + l + sovOwnmap(uint64(l))
 		}
 	}
+	if m.Metadata != nil {
+		l = m.Metadata.Size()
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
 	return n
 }
 
@@ -1924,6 +2806,63 @@ func (m *OSMRelation) Size() (n int) {
 			n += 1 + l + sovOwnmap(uint64(l))
 		}
 	}
+	if m.Area != nil {
+		l = m.Area.Size()
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
+	if m.Metadata != nil {
+		l = m.Metadata.Size()
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
+	return n
+}
+
+func (m *AreaRing) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Points) > 0 {
+		for _, e := range m.Points {
+			l = e.Size()
+			n += 1 + l + sovOwnmap(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *AreaPolygon) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Outer != nil {
+		l = m.Outer.Size()
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
+	if len(m.Inners) > 0 {
+		for _, e := range m.Inners {
+			l = e.Size()
+			n += 1 + l + sovOwnmap(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *AreaGeometry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Polygons) > 0 {
+		for _, e := range m.Polygons {
+			l = e.Size()
+			n += 1 + l + sovOwnmap(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -1956,6 +2895,23 @@ func (m *DatasetInfo) Size() (n int) {
 	if m.ReplicationTimeMs != 0 {
 		n += 1 + sovOwnmap(uint64(m.ReplicationTimeMs))
 	}
+	if m.ReplicationSequenceNumber != 0 {
+		n += 1 + sovOwnmap(uint64(m.ReplicationSequenceNumber))
+	}
+	l = len(m.ReplicationBaseURL)
+	if l > 0 {
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
+	if m.BoundsSource != 0 {
+		n += 1 + sovOwnmap(uint64(m.BoundsSource))
+	}
+	if m.HasMetadata {
+		n += 2
+	}
+	if m.ImportBounds != nil {
+		l = m.ImportBounds.Size()
+		n += 1 + l + sovOwnmap(uint64(l))
+	}
 	return n
 }
 
@@ -2017,6 +2973,21 @@ func (this *OSMNode) String() string {
 		`Tags:` + repeatedStringForTags + `,`,
 		`Lat:` + fmt.Sprintf("%v", this.Lat) + `,`,
 		`Lon:` + fmt.Sprintf("%v", this.Lon) + `,`,
+		`Metadata:` + strings.Replace(this.Metadata.String(), "OSMObjectMetadata", "OSMObjectMetadata", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *OSMObjectMetadata) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&OSMObjectMetadata{`,
+		`Version:` + fmt.Sprintf("%v", this.Version) + `,`,
+		`TimestampMs:` + fmt.Sprintf("%v", this.TimestampMs) + `,`,
+		`ChangesetID:` + fmt.Sprintf("%v", this.ChangesetID) + `,`,
+		`User:` + fmt.Sprintf("%v", this.User) + `,`,
+		`UserID:` + fmt.Sprintf("%v", this.UserID) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2072,6 +3043,7 @@ func (this *OSMWay) String() string {
 		`ID:` + fmt.Sprintf("%v", this.ID) + `,`,
 		`Tags:` + repeatedStringForTags + `,`,
 		`WayPoints:` + repeatedStringForWayPoints + `,`,
+		`Metadata:` + strings.Replace(this.Metadata.String(), "OSMObjectMetadata", "OSMObjectMetadata", 1) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2107,6 +3079,54 @@ func (this *OSMRelation) String() string {
 		`ID:` + fmt.Sprintf("%v", this.ID) + `,`,
 		`Tags:` + repeatedStringForTags + `,`,
 		`Members:` + repeatedStringForMembers + `,`,
+		`Area:` + strings.Replace(this.Area.String(), "AreaGeometry", "AreaGeometry", 1) + `,`,
+		`Metadata:` + strings.Replace(this.Metadata.String(), "OSMObjectMetadata", "OSMObjectMetadata", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *AreaRing) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForPoints := "[]*Location{"
+	for _, f := range this.Points {
+		repeatedStringForPoints += strings.Replace(f.String(), "Location", "Location", 1) + ","
+	}
+	repeatedStringForPoints += "}"
+	s := strings.Join([]string{`&AreaRing{`,
+		`Points:` + repeatedStringForPoints + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *AreaPolygon) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForInners := "[]*AreaRing{"
+	for _, f := range this.Inners {
+		repeatedStringForInners += strings.Replace(f.String(), "AreaRing", "AreaRing", 1) + ","
+	}
+	repeatedStringForInners += "}"
+	s := strings.Join([]string{`&AreaPolygon{`,
+		`Outer:` + strings.Replace(this.Outer.String(), "AreaRing", "AreaRing", 1) + `,`,
+		`Inners:` + repeatedStringForInners + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *AreaGeometry) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForPolygons := "[]*AreaPolygon{"
+	for _, f := range this.Polygons {
+		repeatedStringForPolygons += strings.Replace(f.String(), "AreaPolygon", "AreaPolygon", 1) + ","
+	}
+	repeatedStringForPolygons += "}"
+	s := strings.Join([]string{`&AreaGeometry{`,
+		`Polygons:` + repeatedStringForPolygons + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2128,6 +3148,11 @@ func (this *DatasetInfo) String() string {
 	s := strings.Join([]string{`&DatasetInfo{`,
 		`Bounds:` + strings.Replace(fmt.Sprintf("%v", this.Bounds), "DatasetInfo_Bounds", "DatasetInfo_Bounds", 1) + `,`,
 		`ReplicationTimeMs:` + fmt.Sprintf("%v", this.ReplicationTimeMs) + `,`,
+		`ReplicationSequenceNumber:` + fmt.Sprintf("%v", this.ReplicationSequenceNumber) + `,`,
+		`ReplicationBaseURL:` + fmt.Sprintf("%v", this.ReplicationBaseURL) + `,`,
+		`BoundsSource:` + fmt.Sprintf("%v", this.BoundsSource) + `,`,
+		`HasMetadata:` + fmt.Sprintf("%v", this.HasMetadata) + `,`,
+		`ImportBounds:` + strings.Replace(fmt.Sprintf("%v", this.ImportBounds), "DatasetInfo_Bounds", "DatasetInfo_Bounds", 1) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2268,19 +3293,213 @@ func (m *OSMNode) Unmarshal(dAtA []byte) error {
 			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
 			iNdEx += 8
 			m.Lon = float64(math.Float64frombits(v))
-		default:
-			iNdEx = preIndex
-			skippy, err := skipOwnmap(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
 			}
-			if (skippy < 0) || (iNdEx+skippy) < 0 {
-				return ErrInvalidLengthOwnmap
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
 			}
-			iNdEx += skippy
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Metadata == nil {
+				m.Metadata = &OSMObjectMetadata{}
+			}
+			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOwnmap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *OSMObjectMetadata) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowOwnmap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: OSMObjectMetadata: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: OSMObjectMetadata: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			m.Version = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Version |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TimestampMs", wireType)
+			}
+			m.TimestampMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TimestampMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChangesetID", wireType)
+			}
+			m.ChangesetID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ChangesetID |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field User", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.User = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UserID", wireType)
+			}
+			m.UserID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UserID |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOwnmap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
 		}
 	}
 
@@ -2696,6 +3915,42 @@ func (m *OSMWay) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Metadata == nil {
+				m.Metadata = &OSMObjectMetadata{}
+			}
+			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOwnmap(dAtA[iNdEx:])
@@ -2972,45 +4227,405 @@ func (m *OSMRelation) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipOwnmap(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Area", wireType)
 			}
-			if (skippy < 0) || (iNdEx+skippy) < 0 {
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
 				return ErrInvalidLengthOwnmap
 			}
-			if (iNdEx + skippy) > l {
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *IndexIDList) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowOwnmap
+			if m.Area == nil {
+				m.Area = &AreaGeometry{}
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			if err := m.Area.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Metadata == nil {
+				m.Metadata = &OSMObjectMetadata{}
+			}
+			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOwnmap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AreaRing) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowOwnmap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AreaRing: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AreaRing: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Points", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Points = append(m.Points, &Location{})
+			if err := m.Points[len(m.Points)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOwnmap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AreaPolygon) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowOwnmap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AreaPolygon: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AreaPolygon: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Outer", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Outer == nil {
+				m.Outer = &AreaRing{}
+			}
+			if err := m.Outer.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Inners", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Inners = append(m.Inners, &AreaRing{})
+			if err := m.Inners[len(m.Inners)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOwnmap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AreaGeometry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowOwnmap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AreaGeometry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AreaGeometry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Polygons", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Polygons = append(m.Polygons, &AreaPolygon{})
+			if err := m.Polygons[len(m.Polygons)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOwnmap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IndexIDList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowOwnmap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
 			}
 		}
 		fieldNum := int32(wire >> 3)
@@ -3203,6 +4818,132 @@ func (m *DatasetInfo) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicationSequenceNumber", wireType)
+			}
+			m.ReplicationSequenceNumber = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicationSequenceNumber |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicationBaseURL", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ReplicationBaseURL = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BoundsSource", wireType)
+			}
+			m.BoundsSource = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BoundsSource |= DatasetInfo_BoundsSource(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HasMetadata", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.HasMetadata = bool(v != 0)
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ImportBounds", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOwnmap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthOwnmap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ImportBounds == nil {
+				m.ImportBounds = &DatasetInfo_Bounds{}
+			}
+			if err := m.ImportBounds.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOwnmap(dAtA[iNdEx:])
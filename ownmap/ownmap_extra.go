@@ -4,6 +4,15 @@ import (
 	"github.com/paulmach/osm"
 )
 
+func NewDatasetInfoBounds(bounds osm.Bounds) *DatasetInfo_Bounds {
+	return &DatasetInfo_Bounds{
+		MinLat: bounds.MinLat,
+		MaxLat: bounds.MaxLat,
+		MinLon: bounds.MinLon,
+		MaxLon: bounds.MaxLon,
+	}
+}
+
 func (m *DatasetInfo_Bounds) ToOSMBounds() osm.Bounds {
 	return osm.Bounds{
 		MinLat: m.MinLat,
@@ -13,6 +22,16 @@ func (m *DatasetInfo_Bounds) ToOSMBounds() osm.Bounds {
 	}
 }
 
+// GetBoundsForNewObjects returns the bounds that new objects from changes must be in to be added to the dataset.
+// These are the bounds the dataset was imported with, or the dataset bounds for datasets imported before those were recorded.
+func (m *DatasetInfo) GetBoundsForNewObjects() osm.Bounds {
+	if m.ImportBounds != nil {
+		return m.ImportBounds.ToOSMBounds()
+	}
+
+	return m.Bounds.ToOSMBounds()
+}
+
 type OSMObject interface {
 	OSMObjectID() int64
 	GetTags() []*OSMTag // "GetTags" is a protoc-generated method (Tags() is not)
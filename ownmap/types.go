@@ -25,6 +25,7 @@ type RelationData struct {
 	RelationID int64
 	Tags       []*OSMTag
 	Members    []*RelationMemberData
+	Area       *AreaGeometry // assembled at import time for multipolygons and boundaries. nil otherwise
 }
 
 func (rd *RelationData) OSMObjectID() int64 {
@@ -34,3 +35,12 @@ func (rd *RelationData) OSMObjectID() int64 {
 func (rd *RelationData) GetTags() []*OSMTag {
 	return rd.Tags
 }
+
+// GetAreaGeometry returns the area assembled at import time. Datasets imported before areas were assembled don't have it, so it is assembled from the members instead.
+func (rd *RelationData) GetAreaGeometry() *AreaGeometry {
+	if rd.Area != nil {
+		return rd.Area
+	}
+
+	return AssembleAreaGeometryFromMembers(rd.Members)
+}
@@ -1,6 +1,8 @@
 package ownmap
 
 import (
+	"time"
+
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/paulmach/osm"
 )
@@ -102,8 +104,38 @@ func NewMapmakerRelationFromOSMRelation(osmRelation *osm.Relation) (*OSMRelation
 	}
 
 	return &OSMRelation{
-		ID:      int64(osmRelation.ID),
-		Tags:    NewMapmakerTagsFromOSMTags(osmRelation.Tags),
-		Members: members,
+		ID:       int64(osmRelation.ID),
+		Tags:     NewMapmakerTagsFromOSMTags(osmRelation.Tags),
+		Members:  members,
+		Metadata: NewOSMObjectMetadata(osmRelation.Version, osmRelation.Timestamp, osmRelation.ChangesetID, osmRelation.User, osmRelation.UserID),
 	}, nil
 }
+
+// NewOSMObjectMetadata creates the metadata for an object. It returns nil if the raw data has no metadata for the object (e.g. files exported without it).
+func NewOSMObjectMetadata(version int, timestamp time.Time, changesetID osm.ChangesetID, user string, userID osm.UserID) *OSMObjectMetadata {
+	if version == 0 && timestamp.IsZero() && changesetID == 0 && user == "" && userID == 0 {
+		return nil
+	}
+
+	metadata := &OSMObjectMetadata{
+		Version:     int64(version),
+		ChangesetID: int64(changesetID),
+		User:        user,
+		UserID:      int64(userID),
+	}
+
+	if !timestamp.IsZero() {
+		metadata.TimestampMs = timestamp.UnixNano() / int64(time.Millisecond)
+	}
+
+	return metadata
+}
+
+// Time is when this version of the object was made. Zero if not known.
+func (m *OSMObjectMetadata) Time() time.Time {
+	if m == nil || m.TimestampMs == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, m.TimestampMs*int64(time.Millisecond)).UTC()
+}
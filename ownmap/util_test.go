@@ -2,8 +2,10 @@ package ownmap
 
 import (
 	"testing"
+	"time"
 
 	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
 )
 
 // 1: item above container: false
@@ -352,3 +354,21 @@ func TestIsTotallyInside(t *testing.T) {
 		})
 	}
 }
+
+func TestNewOSMObjectMetadata(t *testing.T) {
+	assert.Nil(t, NewOSMObjectMetadata(0, time.Time{}, 0, "", 0))
+
+	timestamp := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	metadata := NewOSMObjectMetadata(3, timestamp, 123, "mapper", 45)
+	assert.Equal(t, &OSMObjectMetadata{
+		Version:     3,
+		TimestampMs: timestamp.UnixNano() / int64(time.Millisecond),
+		ChangesetID: 123,
+		User:        "mapper",
+		UserID:      45,
+	}, metadata)
+	assert.Equal(t, timestamp, metadata.Time())
+
+	var nilMetadata *OSMObjectMetadata
+	assert.True(t, nilMetadata.Time().IsZero())
+}
@@ -0,0 +1,40 @@
+package ownmapdal
+
+import (
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// SetRelationAreaGeometry assembles the area of multipolygon and boundary relations from their member ways, and sets it on the relation.
+// The member ways need to be imported already; ways that are not in the dataset are left out. Other relations are left as they are.
+func SetRelationAreaGeometry(getter objectByIDGetter, relation *ownmap.OSMRelation) errorsx.Error {
+	if !ownmap.IsAreaRelation(relation.Tags) {
+		return nil
+	}
+
+	var members []*ownmap.RelationMemberData
+	for _, member := range relation.Members {
+		if member.MemberType != ownmap.OSM_MEMBER_TYPE_WAY {
+			continue
+		}
+
+		way, err := getter.GetWayByID(member.ObjectID)
+		if err != nil {
+			if errorsx.Cause(err) != errorsx.ObjectNotFound {
+				return errorsx.Wrap(err)
+			}
+
+			// way not in dataset, skip this way
+			continue
+		}
+
+		members = append(members, &ownmap.RelationMemberData{
+			Role:   member.Role,
+			Object: way,
+		})
+	}
+
+	relation.Area = ownmap.AssembleAreaGeometryFromMembers(members)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package ownmapdal
+
+import (
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapObjectGetter struct {
+	ways map[int64]*ownmap.OSMWay
+}
+
+func (g *mapObjectGetter) GetNodeByID(id int64) (*ownmap.OSMNode, error) {
+	return nil, errorsx.Wrap(errorsx.ObjectNotFound)
+}
+
+func (g *mapObjectGetter) GetWayByID(id int64) (*ownmap.OSMWay, error) {
+	way, ok := g.ways[id]
+	if !ok {
+		return nil, errorsx.Wrap(errorsx.ObjectNotFound)
+	}
+	return way, nil
+}
+
+func (g *mapObjectGetter) GetRelationByID(id int64) (*ownmap.OSMRelation, error) {
+	return nil, errorsx.Wrap(errorsx.ObjectNotFound)
+}
+
+func TestSetRelationAreaGeometry(t *testing.T) {
+	wayPoints := func(latLons ...float64) []*ownmap.WayPoint {
+		var wayPoints []*ownmap.WayPoint
+		for i := 0; i < len(latLons); i += 2 {
+			wayPoints = append(wayPoints, &ownmap.WayPoint{Point: &ownmap.Location{Lat: latLons[i], Lon: latLons[i+1]}})
+		}
+		return wayPoints
+	}
+
+	getter := &mapObjectGetter{ways: map[int64]*ownmap.OSMWay{
+		1: {ID: 1, WayPoints: wayPoints(0, 0, 0, 1, 1, 1)},
+		2: {ID: 2, WayPoints: wayPoints(1, 1, 1, 0, 0, 0)},
+	}}
+
+	members := []*ownmap.OSMRelationMember{
+		{ObjectID: 1, MemberType: ownmap.OSM_MEMBER_TYPE_WAY, Role: "outer"},
+		{ObjectID: 2, MemberType: ownmap.OSM_MEMBER_TYPE_WAY, Role: "outer"},
+		// not in the dataset
+		{ObjectID: 3, MemberType: ownmap.OSM_MEMBER_TYPE_WAY, Role: "inner"},
+	}
+
+	t.Run("multipolygon", func(t *testing.T) {
+		relation := &ownmap.OSMRelation{
+			ID:      100,
+			Tags:    []*ownmap.OSMTag{{Key: "type", Value: "multipolygon"}},
+			Members: members,
+		}
+
+		err := SetRelationAreaGeometry(getter, relation)
+		require.NoError(t, err)
+
+		require.NotNil(t, relation.Area)
+		require.Len(t, relation.Area.Polygons, 1)
+		assert.Len(t, relation.Area.Polygons[0].Outer.Points, 5)
+		assert.Empty(t, relation.Area.Polygons[0].Inners)
+	})
+
+	t.Run("not an area", func(t *testing.T) {
+		relation := &ownmap.OSMRelation{
+			ID:      101,
+			Tags:    []*ownmap.OSMTag{{Key: "type", Value: "route"}},
+			Members: members,
+		}
+
+		err := SetRelationAreaGeometry(getter, relation)
+		require.NoError(t, err)
+		assert.Nil(t, relation.Area)
+	})
+}
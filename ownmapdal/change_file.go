@@ -0,0 +1,290 @@
+package ownmapdal
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+)
+
+const (
+	OSMChangeFileSuffix     = ".osc"
+	OSMChangeGzipFileSuffix = ".osc.gz"
+)
+
+// ChangeApplier applies the changes from an OSM change file to an existing dataset.
+// The returned DataSourceConn reflects the dataset after the changes have been applied.
+type ChangeApplier interface {
+	ApplyChanges(ctx context.Context, changeIndex *ChangeIndex) (DataSourceConn, errorsx.Error)
+}
+
+// ReadChangeFile reads an osmChange (.osc) file, optionally gzipped (.osc.gz)
+func ReadChangeFile(reader io.Reader, fileName string) (*osm.Change, errorsx.Error) {
+	var err error
+
+	switch {
+	case strings.HasSuffix(fileName, OSMChangeGzipFileSuffix):
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	case strings.HasSuffix(fileName, OSMChangeFileSuffix):
+		// plain XML, nothing to do
+	default:
+		return nil, errorsx.Errorf("unrecognised change file type: %q. Expected a %s or %s file", fileName, OSMChangeFileSuffix, OSMChangeGzipFileSuffix)
+	}
+
+	change := new(osm.Change)
+	err = xml.NewDecoder(reader).Decode(change)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return change, nil
+}
+
+// ChangeIndex is the result of an OSM change, keyed by object ID.
+// If an object appears several times in a change (e.g. created, then modified), only the latest version is kept.
+type ChangeIndex struct {
+	UpsertedNodes     map[int64]*osm.Node
+	UpsertedWays      map[int64]*osm.Way
+	UpsertedRelations map[int64]*osm.Relation
+	DeletedNodes      map[int64]bool
+	DeletedWays       map[int64]bool
+	DeletedRelations  map[int64]bool
+	// LatestTimestamp is the timestamp of the newest object in the change
+	LatestTimestamp time.Time
+	// ReplicationSequenceNumber is the sequence number of the change, if it came from a replication source. 0 otherwise.
+	ReplicationSequenceNumber uint64
+
+	// versions are the versions of the changed objects, keyed by object type and ID
+	versions map[osm.FeatureID]int
+}
+
+func NewChangeIndex(change *osm.Change) *ChangeIndex {
+	changeIndex := &ChangeIndex{
+		UpsertedNodes:     make(map[int64]*osm.Node),
+		UpsertedWays:      make(map[int64]*osm.Way),
+		UpsertedRelations: make(map[int64]*osm.Relation),
+		DeletedNodes:      make(map[int64]bool),
+		DeletedWays:       make(map[int64]bool),
+		DeletedRelations:  make(map[int64]bool),
+		versions:          make(map[osm.FeatureID]int),
+	}
+
+	// isNewestVersion records the version of the object, and returns true if it is the newest version seen so far.
+	// If the versions are equal, the later one in the file wins.
+	isNewestVersion := func(featureID osm.FeatureID, version int, timestamp time.Time) bool {
+		if timestamp.After(changeIndex.LatestTimestamp) {
+			changeIndex.LatestTimestamp = timestamp
+		}
+
+		existingVersion, ok := changeIndex.versions[featureID]
+		if ok && existingVersion > version {
+			return false
+		}
+
+		changeIndex.versions[featureID] = version
+		return true
+	}
+
+	for _, osmData := range []*osm.OSM{change.Create, change.Modify, change.Delete} {
+		if osmData == nil {
+			continue
+		}
+
+		isDelete := osmData == change.Delete
+
+		for _, node := range osmData.Nodes {
+			if !isNewestVersion(node.FeatureID(), node.Version, node.Timestamp) {
+				continue
+			}
+
+			id := int64(node.ID)
+			delete(changeIndex.UpsertedNodes, id)
+			delete(changeIndex.DeletedNodes, id)
+			if isDelete {
+				changeIndex.DeletedNodes[id] = true
+			} else {
+				changeIndex.UpsertedNodes[id] = node
+			}
+		}
+
+		for _, way := range osmData.Ways {
+			if !isNewestVersion(way.FeatureID(), way.Version, way.Timestamp) {
+				continue
+			}
+
+			id := int64(way.ID)
+			delete(changeIndex.UpsertedWays, id)
+			delete(changeIndex.DeletedWays, id)
+			if isDelete {
+				changeIndex.DeletedWays[id] = true
+			} else {
+				changeIndex.UpsertedWays[id] = way
+			}
+		}
+
+		for _, relation := range osmData.Relations {
+			if !isNewestVersion(relation.FeatureID(), relation.Version, relation.Timestamp) {
+				continue
+			}
+
+			id := int64(relation.ID)
+			delete(changeIndex.UpsertedRelations, id)
+			delete(changeIndex.DeletedRelations, id)
+			if isDelete {
+				changeIndex.DeletedRelations[id] = true
+			} else {
+				changeIndex.UpsertedRelations[id] = relation
+			}
+		}
+	}
+
+	return changeIndex
+}
+
+// DropChangeIfNotNewer is for datasets imported with metadata. If the object in the dataset is at the same version as the change to it, or a newer one (e.g. when change files overlap),
+// the change is dropped from the index and true is returned; the object in the dataset should be kept as it is.
+// If the dataset doesn't have the metadata, the change can't be checked, so it is kept.
+func (ci *ChangeIndex) DropChangeIfNotNewer(featureID osm.FeatureID, existingMetadata *ownmap.OSMObjectMetadata) bool {
+	changeVersion, ok := ci.versions[featureID]
+	if !ok || existingMetadata == nil || existingMetadata.Version < int64(changeVersion) {
+		return false
+	}
+
+	id := featureID.Ref()
+	switch featureID.Type() {
+	case osm.TypeNode:
+		delete(ci.UpsertedNodes, id)
+		delete(ci.DeletedNodes, id)
+	case osm.TypeWay:
+		delete(ci.UpsertedWays, id)
+		delete(ci.DeletedWays, id)
+	case osm.TypeRelation:
+		delete(ci.UpsertedRelations, id)
+		delete(ci.DeletedRelations, id)
+	}
+	delete(ci.versions, featureID)
+
+	return true
+}
+
+func (ci *ChangeIndex) IsNodeChanged(id int64) bool {
+	return ci.UpsertedNodes[id] != nil || ci.DeletedNodes[id]
+}
+
+func (ci *ChangeIndex) IsWayChanged(id int64) bool {
+	return ci.UpsertedWays[id] != nil || ci.DeletedWays[id]
+}
+
+func (ci *ChangeIndex) IsRelationChanged(id int64) bool {
+	return ci.UpsertedRelations[id] != nil || ci.DeletedRelations[id]
+}
+
+// ReplicationTimeAfterChange returns the replication time a dataset should have after the changes have been applied to it.
+// The replication time is never moved backwards.
+func (ci *ChangeIndex) ReplicationTimeAfterChange(datasetInfo *ownmap.DatasetInfo) time.Time {
+	existingReplicationTime := time.Unix(0, int64(datasetInfo.ReplicationTimeMs)*int64(time.Millisecond)).UTC()
+	if existingReplicationTime.After(ci.LatestTimestamp) {
+		return existingReplicationTime
+	}
+
+	return ci.LatestTimestamp.UTC()
+}
+
+// ReplicationSequenceNumberAfterChange returns the replication sequence number a dataset should have after the changes have been applied to it.
+func (ci *ChangeIndex) ReplicationSequenceNumberAfterChange(datasetInfo *ownmap.DatasetInfo) uint64 {
+	if ci.ReplicationSequenceNumber == 0 {
+		return datasetInfo.ReplicationSequenceNumber
+	}
+
+	return ci.ReplicationSequenceNumber
+}
+
+type objectByIDGetter interface {
+	GetNodeByID(id int64) (*ownmap.OSMNode, error)
+	GetWayByID(id int64) (*ownmap.OSMWay, error)
+	GetRelationByID(id int64) (*ownmap.OSMRelation, error)
+}
+
+// ResolveWayPoints looks up the locations of the nodes in a way.
+// Nodes that are not in the dataset (e.g. out of bounds) are left out.
+func ResolveWayPoints(getter objectByIDGetter, nodeIDs []int64) ([]*ownmap.WayPoint, errorsx.Error) {
+	var wayPoints []*ownmap.WayPoint
+	for _, nodeID := range nodeIDs {
+		node, err := getter.GetNodeByID(nodeID)
+		if err != nil {
+			if errorsx.Cause(err) != errorsx.ObjectNotFound {
+				return nil, errorsx.Wrap(err)
+			}
+
+			// node not in dataset, skip this node
+			continue
+		}
+
+		wayPoints = append(wayPoints, &ownmap.WayPoint{
+			NodeID: node.ID,
+			Point: &ownmap.Location{
+				Lat: node.Lat,
+				Lon: node.Lon,
+			},
+		})
+	}
+
+	return wayPoints, nil
+}
+
+// IsAnyRelationMemberInDataset returns true if at least one member of the relation can be found in the dataset
+func IsAnyRelationMemberInDataset(getter objectByIDGetter, relation *ownmap.OSMRelation) (bool, errorsx.Error) {
+	for _, member := range relation.Members {
+		var err error
+		switch member.MemberType {
+		case ownmap.OSM_MEMBER_TYPE_NODE:
+			_, err = getter.GetNodeByID(member.ObjectID)
+		case ownmap.OSM_MEMBER_TYPE_WAY:
+			_, err = getter.GetWayByID(member.ObjectID)
+		case ownmap.OSM_MEMBER_TYPE_RELATION:
+			_, err = getter.GetRelationByID(member.ObjectID)
+		default:
+			return false, errorsx.Errorf("unrecognised member type: %v", member.MemberType)
+		}
+
+		if err == nil {
+			return true, nil
+		}
+
+		if errorsx.Cause(err) != errorsx.ObjectNotFound {
+			return false, errorsx.Wrap(err)
+		}
+	}
+
+	return false, nil
+}
+
+func NewOSMNodeFromChangeNode(obj *osm.Node) *ownmap.OSMNode {
+	return &ownmap.OSMNode{
+		ID:       int64(obj.ID),
+		Lat:      obj.Lat,
+		Lon:      obj.Lon,
+		Tags:     ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
+		Metadata: ownmap.NewOSMObjectMetadata(obj.Version, obj.Timestamp, obj.ChangesetID, obj.User, obj.UserID),
+	}
+}
+
+func WayNodeIDs(obj *osm.Way) []int64 {
+	var nodeIDs []int64
+	for _, wayNode := range obj.Nodes {
+		nodeIDs = append(nodeIDs, int64(wayNode.ID))
+	}
+	return nodeIDs
+}
@@ -0,0 +1,169 @@
+package ownmapdal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOSMChange = `<?xml version="1.0" encoding="UTF-8"?>
+<osmChange version="0.6" generator="test">
+	<create>
+		<node id="1" version="1" timestamp="2021-01-02T10:00:00Z" lat="51.15" lon="-0.15">
+			<tag k="place" v="village"/>
+		</node>
+		<node id="2" version="1" timestamp="2021-01-02T10:00:00Z" lat="51.16" lon="-0.16"/>
+	</create>
+	<modify>
+		<node id="1" version="2" timestamp="2021-01-02T11:00:00Z" lat="51.17" lon="-0.17">
+			<tag k="place" v="town"/>
+		</node>
+		<way id="10" version="3" timestamp="2021-01-02T10:30:00Z">
+			<nd ref="1"/>
+			<nd ref="2"/>
+			<tag k="highway" v="residential"/>
+		</way>
+	</modify>
+	<delete>
+		<node id="2" version="2" timestamp="2021-01-02T12:00:00Z" lat="51.16" lon="-0.16"/>
+		<relation id="100" version="5" timestamp="2021-01-02T09:00:00Z"/>
+	</delete>
+</osmChange>`
+
+func TestReadChangeFile(t *testing.T) {
+	gzipBuffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(gzipBuffer)
+	_, err := gzipWriter.Write([]byte(testOSMChange))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+	}{
+		{"uncompressed", "123.osc", []byte(testOSMChange)},
+		{"gzip", "123.osc.gz", gzipBuffer.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := ReadChangeFile(bytes.NewReader(tt.data), tt.fileName)
+			require.NoError(t, err)
+
+			assert.Len(t, change.Create.Nodes, 2)
+			assert.Len(t, change.Modify.Nodes, 1)
+			assert.Len(t, change.Modify.Ways, 1)
+			assert.Len(t, change.Delete.Nodes, 1)
+			assert.Len(t, change.Delete.Relations, 1)
+		})
+	}
+
+	t.Run("unknown suffix", func(t *testing.T) {
+		_, err := ReadChangeFile(bytes.NewReader([]byte(testOSMChange)), "123.xml")
+		require.Error(t, err)
+	})
+}
+
+func TestNewChangeIndex(t *testing.T) {
+	change, err := ReadChangeFile(bytes.NewReader([]byte(testOSMChange)), "123.osc")
+	require.NoError(t, err)
+
+	changeIndex := NewChangeIndex(change)
+
+	// node 1 was created and then modified; the modified version wins
+	require.Len(t, changeIndex.UpsertedNodes, 1)
+	assert.Equal(t, 51.17, changeIndex.UpsertedNodes[1].Lat)
+
+	// node 2 was created and then deleted
+	assert.Equal(t, map[int64]bool{2: true}, changeIndex.DeletedNodes)
+	assert.True(t, changeIndex.IsNodeChanged(2))
+	assert.False(t, changeIndex.IsNodeChanged(3))
+
+	require.Len(t, changeIndex.UpsertedWays, 1)
+	assert.True(t, changeIndex.IsWayChanged(10))
+	assert.Empty(t, changeIndex.DeletedWays)
+
+	assert.Empty(t, changeIndex.UpsertedRelations)
+	assert.True(t, changeIndex.IsRelationChanged(100))
+
+	assert.Equal(t, time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC), changeIndex.LatestTimestamp)
+}
+
+func TestChangeIndex_ReplicationTimeAfterChange(t *testing.T) {
+	changeTime := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+	changeIndex := &ChangeIndex{LatestTimestamp: changeTime}
+
+	tests := []struct {
+		name              string
+		replicationTimeMs uint64
+		want              time.Time
+	}{
+		{"no existing replication time", 0, changeTime},
+		{"older dataset", uint64(changeTime.Add(-time.Hour).UnixNano() / int64(time.Millisecond)), changeTime},
+		{"newer dataset", uint64(changeTime.Add(time.Hour).UnixNano() / int64(time.Millisecond)), changeTime.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeIndex.ReplicationTimeAfterChange(&ownmap.DatasetInfo{ReplicationTimeMs: tt.replicationTimeMs})
+			assert.True(t, tt.want.Equal(got), "expected %s but got %s", tt.want, got)
+		})
+	}
+}
+
+func TestNewChangeIndex_olderVersionLaterInFile(t *testing.T) {
+	changeIndex := NewChangeIndex(&osm.Change{
+		Modify: &osm.OSM{
+			Nodes: osm.Nodes{{ID: 5, Version: 3, Lat: 51.5, Lon: 0.5}},
+		},
+		Delete: &osm.OSM{
+			Nodes: osm.Nodes{{ID: 5, Version: 2}},
+		},
+	})
+
+	// the delete is of an older version, so is ignored
+	require.Len(t, changeIndex.UpsertedNodes, 1)
+	assert.Empty(t, changeIndex.DeletedNodes)
+}
+
+func TestChangeIndex_DropChangeIfNotNewer(t *testing.T) {
+	tests := []struct {
+		name             string
+		existingMetadata *ownmap.OSMObjectMetadata
+		want             bool
+	}{
+		{"no metadata in the dataset", nil, false},
+		{"dataset has an older version", &ownmap.OSMObjectMetadata{Version: 1}, false},
+		{"dataset has the same version", &ownmap.OSMObjectMetadata{Version: 2}, true},
+		{"dataset has a newer version", &ownmap.OSMObjectMetadata{Version: 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := ReadChangeFile(bytes.NewReader([]byte(testOSMChange)), "123.osc")
+			require.NoError(t, err)
+
+			changeIndex := NewChangeIndex(change)
+
+			// node 1 is at version 2 in the change
+			dropped := changeIndex.DropChangeIfNotNewer(osm.NodeID(1).FeatureID(), tt.existingMetadata)
+			assert.Equal(t, tt.want, dropped)
+			assert.Equal(t, !tt.want, changeIndex.IsNodeChanged(1))
+
+			// other changes are not affected
+			assert.True(t, changeIndex.IsNodeChanged(2))
+		})
+	}
+
+	t.Run("object not in the change", func(t *testing.T) {
+		change, err := ReadChangeFile(bytes.NewReader([]byte(testOSMChange)), "123.osc")
+		require.NoError(t, err)
+
+		changeIndex := NewChangeIndex(change)
+		assert.False(t, changeIndex.DropChangeIfNotNewer(osm.WayID(99).FeatureID(), &ownmap.OSMObjectMetadata{Version: 10}))
+	})
+}
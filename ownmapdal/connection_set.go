@@ -3,6 +3,7 @@ package ownmapdal
 import (
 	"context"
 	"log"
+	"sort"
 	"sync"
 
 	"github.com/jamesrr39/goutil/errorsx"
@@ -20,12 +21,26 @@ type DataSourceConn interface {
 }
 
 type DBConnSet struct {
-	conns []DataSourceConn
-	mu    *sync.RWMutex
+	conns          []DataSourceConn
+	datasetConfigs DatasetConfigs
+	mu             *sync.RWMutex
 }
 
 func NewDBConnSet(conns []DataSourceConn) *DBConnSet {
-	return &DBConnSet{conns, new(sync.RWMutex)}
+	return &DBConnSet{conns, make(DatasetConfigs), new(sync.RWMutex)}
+}
+
+func (dbcs *DBConnSet) SetDatasetConfigs(datasetConfigs DatasetConfigs) {
+	dbcs.mu.Lock()
+	defer dbcs.mu.Unlock()
+	dbcs.datasetConfigs = datasetConfigs
+}
+
+// GetDatasetConfig returns the config for the dataset, or the default config if it doesn't have one
+func (dbcs *DBConnSet) GetDatasetConfig(name string) DatasetConfig {
+	dbcs.mu.RLock()
+	defer dbcs.mu.RUnlock()
+	return dbcs.datasetConfigs[name]
 }
 
 func (dbcs *DBConnSet) GetConns() []DataSourceConn {
@@ -40,6 +55,49 @@ func (dbcs *DBConnSet) AddDBConn(conn DataSourceConn) {
 	dbcs.conns = append(dbcs.conns, conn)
 }
 
+// ReplaceDBConn swaps a connection for a new one, for example after the dataset has been updated.
+// Callers that already got the old connection from GetConns can carry on using it.
+func (dbcs *DBConnSet) ReplaceDBConn(oldConn, newConn DataSourceConn) errorsx.Error {
+	dbcs.mu.Lock()
+	defer dbcs.mu.Unlock()
+
+	// copy the slice, since readers may still be iterating over the old one
+	conns := make([]DataSourceConn, len(dbcs.conns))
+	copy(conns, dbcs.conns)
+
+	for i, conn := range conns {
+		if conn == oldConn {
+			conns[i] = newConn
+			dbcs.conns = conns
+			return nil
+		}
+	}
+
+	return errorsx.Errorf("connection %q not found in the connection set", oldConn.Name())
+}
+
+// RemoveDBConn removes a connection, for example after its file has been deleted.
+// Callers that already got the connection from GetConns can carry on using it.
+func (dbcs *DBConnSet) RemoveDBConn(oldConn DataSourceConn) errorsx.Error {
+	dbcs.mu.Lock()
+	defer dbcs.mu.Unlock()
+
+	var conns []DataSourceConn
+	for _, conn := range dbcs.conns {
+		if conn != oldConn {
+			conns = append(conns, conn)
+		}
+	}
+
+	if len(conns) == len(dbcs.conns) {
+		return errorsx.Errorf("connection %q not found in the connection set", oldConn.Name())
+	}
+
+	dbcs.conns = conns
+
+	return nil
+}
+
 //go:generate stringer -type=MatchLevel
 type MatchLevel int
 
@@ -51,6 +109,7 @@ const (
 
 type ChosenConnForBounds struct {
 	MatchLevel MatchLevel
+	Priority   int
 	DataSourceConn
 }
 
@@ -75,11 +134,17 @@ func getMatchLevel(conn DataSourceConn, bounds osm.Bounds) (MatchLevel, errorsx.
 	return MatchLevelPartial, nil
 }
 
-// GetConnForBounds selects a connection to use to provide data for a given bounds
-func (dbcs *DBConnSet) GetConnsForBounds(bounds osm.Bounds) ([]*ChosenConnForBounds, errorsx.Error) {
+// GetConnsForBounds selects the connections to use to provide data for the given bounds at the given zoom level: the datasets that overlap the bounds and that are used at the zoom level (from their dataset configs).
+// They are ordered by priority, highest first, and then by name, like layers on top of each other.
+func (dbcs *DBConnSet) GetConnsForBounds(bounds osm.Bounds, zoomLevel ownmap.ZoomLevel) ([]*ChosenConnForBounds, errorsx.Error) {
 	var chosen []*ChosenConnForBounds
 
 	for _, conn := range dbcs.GetConns() {
+		datasetConfig := dbcs.GetDatasetConfig(conn.Name())
+		if !datasetConfig.IsUsedAtZoomLevel(zoomLevel) {
+			continue
+		}
+
 		matchLevel, err := getMatchLevel(conn, bounds)
 		if err != nil {
 			return nil, err
@@ -94,8 +159,17 @@ func (dbcs *DBConnSet) GetConnsForBounds(bounds osm.Bounds) ([]*ChosenConnForBou
 		chosen = append(chosen, &ChosenConnForBounds{
 			DataSourceConn: conn,
 			MatchLevel:     matchLevel,
+			Priority:       datasetConfig.Priority,
 		})
 	}
 
+	sort.SliceStable(chosen, func(i, j int) bool {
+		if chosen[i].Priority != chosen[j].Priority {
+			return chosen[i].Priority > chosen[j].Priority
+		}
+
+		return chosen[i].Name() < chosen[j].Name()
+	})
+
 	return chosen, nil
 }
@@ -0,0 +1,59 @@
+package ownmapdal
+
+import (
+	"testing"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBConnSet_GetConnsForBounds(t *testing.T) {
+	zoomLevel := func(zoomLevel ownmap.ZoomLevel) *ownmap.ZoomLevel {
+		return &zoomLevel
+	}
+
+	newConn := func(name string, bounds *ownmap.DatasetInfo_Bounds) *fakeDataSourceConn {
+		return &fakeDataSourceConn{name: name, datasetInfo: &ownmap.DatasetInfo{Bounds: bounds}}
+	}
+
+	world := newConn("world.ownmapdb", &ownmap.DatasetInfo_Bounds{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180})
+	england := newConn("england.ownmapdb", &ownmap.DatasetInfo_Bounds{MinLat: 49.8, MaxLat: 55.9, MinLon: -6.5, MaxLon: 1.8})
+	wales := newConn("wales.ownmapdb", &ownmap.DatasetInfo_Bounds{MinLat: 51.3, MaxLat: 53.5, MinLon: -5.4, MaxLon: -2.6})
+	france := newConn("france.ownmapdb", &ownmap.DatasetInfo_Bounds{MinLat: 41.3, MaxLat: 51.1, MinLon: -5.2, MaxLon: 9.6})
+
+	dbConnSet := NewDBConnSet([]DataSourceConn{world, england, wales, france})
+	dbConnSet.SetDatasetConfigs(DatasetConfigs{
+		"world.ownmapdb":   {MaxZoom: zoomLevel(8)},
+		"england.ownmapdb": {MinZoom: zoomLevel(9)},
+		"wales.ownmapdb":   {MinZoom: zoomLevel(9), Priority: 1},
+	})
+
+	// on the border between England and Wales
+	borderBounds := osm.Bounds{MinLat: 51.6, MaxLat: 51.7, MinLon: -2.8, MaxLon: -2.6}
+
+	tests := []struct {
+		name      string
+		bounds    osm.Bounds
+		zoomLevel ownmap.ZoomLevel
+		want      []string
+	}{
+		{"coarse dataset at low zoom levels", borderBounds, 8, []string{"world.ownmapdb"}},
+		{"detailed datasets at high zoom levels, by priority", borderBounds, 9, []string{"wales.ownmapdb", "england.ownmapdb"}},
+		{"datasets without a config at every zoom level", osm.Bounds{MinLat: 48, MaxLat: 48.1, MinLon: 2, MaxLon: 2.1}, 2, []string{"france.ownmapdb", "world.ownmapdb"}},
+		{"no overlapping datasets", osm.Bounds{MinLat: 40, MaxLat: 40.1, MinLon: -74, MaxLon: -73.9}, 12, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chosen, err := dbConnSet.GetConnsForBounds(tt.bounds, tt.zoomLevel)
+			require.NoError(t, err)
+
+			var names []string
+			for _, conn := range chosen {
+				names = append(names, conn.Name())
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package ownmapdal
+
+import (
+	"math"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+)
+
+// DatasetBounds are the bounds recorded for a dataset, and where they came from
+type DatasetBounds struct {
+	Bounds osm.Bounds
+	Source ownmap.DatasetInfo_BoundsSource
+	// ImportBounds are the bounds the data was imported with, before narrowing to the data extent. New objects from changes are added if they are inside these.
+	ImportBounds osm.Bounds
+}
+
+func (b DatasetBounds) ToDatasetInfoBounds() *ownmap.DatasetInfo_Bounds {
+	return ownmap.NewDatasetInfoBounds(b.Bounds)
+}
+
+func (b DatasetBounds) ImportBoundsToDatasetInfoBounds() *ownmap.DatasetInfo_Bounds {
+	return ownmap.NewDatasetInfoBounds(b.ImportBounds)
+}
+
+// ChooseDatasetBounds picks the tightest (smallest area) of the extent of the imported nodes, the bounds in the file header and the bounds the import was asked for.
+// The data extent and header bounds are optional; many files don't have bounds in the header.
+// The import bounds are the tighter of the header bounds and the requested bounds.
+func ChooseDatasetBounds(dataExtent, headerBounds *osm.Bounds, requestedBounds osm.Bounds) DatasetBounds {
+	chosen := DatasetBounds{Bounds: requestedBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED}
+
+	// when the areas are the same, the later candidates win
+	candidates := []struct {
+		bounds *osm.Bounds
+		source ownmap.DatasetInfo_BoundsSource
+	}{
+		{headerBounds, ownmap.BOUNDS_SOURCE_HEADER},
+		{dataExtent, ownmap.BOUNDS_SOURCE_DATA_EXTENT},
+	}
+
+	for _, candidate := range candidates {
+		if candidate.bounds == nil {
+			continue
+		}
+
+		if getBoundsArea(*candidate.bounds) <= getBoundsArea(chosen.Bounds) {
+			chosen = DatasetBounds{Bounds: *candidate.bounds, Source: candidate.source}
+		}
+	}
+
+	chosen.ImportBounds = requestedBounds
+	if headerBounds != nil && getBoundsArea(*headerBounds) <= getBoundsArea(requestedBounds) {
+		chosen.ImportBounds = *headerBounds
+	}
+
+	return chosen
+}
+
+// getBoundsArea is the area of the bounds in square degrees. Only useful to compare bounds with each other.
+func getBoundsArea(bounds osm.Bounds) float64 {
+	return (bounds.MaxLat - bounds.MinLat) * (bounds.MaxLon - bounds.MinLon)
+}
+
+// extendBounds grows the bounds to include the location. If the bounds are nil, new bounds containing just the location are returned.
+func extendBounds(bounds *osm.Bounds, lat, lon float64) *osm.Bounds {
+	if bounds == nil {
+		return &osm.Bounds{MinLat: lat, MaxLat: lat, MinLon: lon, MaxLon: lon}
+	}
+
+	bounds.MinLat = math.Min(bounds.MinLat, lat)
+	bounds.MaxLat = math.Max(bounds.MaxLat, lat)
+	bounds.MinLon = math.Min(bounds.MinLon, lon)
+	bounds.MaxLon = math.Max(bounds.MaxLon, lon)
+
+	return bounds
+}
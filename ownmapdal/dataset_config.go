@@ -0,0 +1,75 @@
+package ownmapdal
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// DatasetConfig is the user's config for a dataset
+type DatasetConfig struct {
+	// Priority decides which dataset's version of an object is used, when the object is in more than one dataset (where extracts overlap).
+	// The highest priority wins. Datasets without a priority have a priority of 0.
+	Priority int `json:"priority"`
+	// MinZoom and MaxZoom are the zoom levels the dataset is used at, inclusive. Without them, the dataset is used at every zoom level.
+	// For example, a coarse whole-world dataset can be used up to zoom 8, and detailed regional datasets from zoom 9.
+	MinZoom *ownmap.ZoomLevel `json:"minZoom,omitempty"`
+	MaxZoom *ownmap.ZoomLevel `json:"maxZoom,omitempty"`
+}
+
+// GetZoomRange returns the zoom levels the dataset is used at
+func (c DatasetConfig) GetZoomRange() (ownmap.ZoomLevel, ownmap.ZoomLevel) {
+	minZoom, maxZoom := ownmap.MinZoomLevel, ownmap.MaxZoomLevel
+	if c.MinZoom != nil {
+		minZoom = *c.MinZoom
+	}
+	if c.MaxZoom != nil {
+		maxZoom = *c.MaxZoom
+	}
+
+	return minZoom, maxZoom
+}
+
+// IsUsedAtZoomLevel returns whether the dataset is used at the zoom level
+func (c DatasetConfig) IsUsedAtZoomLevel(zoomLevel ownmap.ZoomLevel) bool {
+	minZoom, maxZoom := c.GetZoomRange()
+
+	return zoomLevel >= minZoom && zoomLevel <= maxZoom
+}
+
+// DatasetConfigs are the configs of the datasets, by dataset name (as in DataSourceConn.Name(); the file name for ownmapdb and SQLite files)
+type DatasetConfigs map[string]DatasetConfig
+
+// ReadDatasetConfigsFile reads a JSON file of dataset configs, for example:
+//
+//	{"world.ownmapdb": {"maxZoom": 8}, "england.ownmapdb": {"priority": 1, "minZoom": 9}}
+//
+// A file that doesn't exist is the same as an empty one.
+func ReadDatasetConfigsFile(fs gofs.Fs, path string) (DatasetConfigs, errorsx.Error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(DatasetConfigs), nil
+		}
+
+		return nil, errorsx.Wrap(err)
+	}
+
+	configs := make(DatasetConfigs)
+	err = json.Unmarshal(data, &configs)
+	if err != nil {
+		return nil, errorsx.Wrap(err, "path", path)
+	}
+
+	for name, config := range configs {
+		minZoom, maxZoom := config.GetZoomRange()
+		if minZoom > maxZoom {
+			return nil, errorsx.Errorf("dataset %q has a minZoom (%v) greater than its maxZoom (%v)", name, minZoom, maxZoom)
+		}
+	}
+
+	return configs, nil
+}
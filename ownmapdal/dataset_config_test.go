@@ -0,0 +1,60 @@
+package ownmapdal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDatasetConfigsFile(t *testing.T) {
+	fs := gofs.NewOsFs()
+	dir := t.TempDir()
+
+	configs, err := ReadDatasetConfigsFile(fs, filepath.Join(dir, "not-there.json"))
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+
+	path := filepath.Join(dir, "datasets.json")
+	require.NoError(t, fs.WriteFile(path, []byte(`{"england.ownmapdb": {"priority": 2}, "world.ownmapdb": {}}`), 0644))
+
+	configs, err = ReadDatasetConfigsFile(fs, path)
+	require.NoError(t, err)
+	assert.Equal(t, DatasetConfigs{"england.ownmapdb": {Priority: 2}, "world.ownmapdb": {}}, configs)
+
+	require.NoError(t, fs.WriteFile(path, []byte(`{"world.ownmapdb": {"minZoom": 9, "maxZoom": 8}}`), 0644))
+	_, err = ReadDatasetConfigsFile(fs, path)
+	require.Error(t, err)
+
+	require.NoError(t, fs.WriteFile(path, []byte(`not json`), 0644))
+	_, err = ReadDatasetConfigsFile(fs, path)
+	require.Error(t, err)
+}
+
+func TestDatasetConfig_IsUsedAtZoomLevel(t *testing.T) {
+	zoomLevel := func(zoomLevel ownmap.ZoomLevel) *ownmap.ZoomLevel {
+		return &zoomLevel
+	}
+
+	tests := []struct {
+		name      string
+		config    DatasetConfig
+		zoomLevel ownmap.ZoomLevel
+		want      bool
+	}{
+		{"no zoom range", DatasetConfig{}, 18, true},
+		{"below min zoom", DatasetConfig{MinZoom: zoomLevel(9)}, 8, false},
+		{"at min zoom", DatasetConfig{MinZoom: zoomLevel(9)}, 9, true},
+		{"at max zoom", DatasetConfig{MaxZoom: zoomLevel(8)}, 8, true},
+		{"above max zoom", DatasetConfig{MaxZoom: zoomLevel(8)}, 9, false},
+		{"max zoom of 0", DatasetConfig{MaxZoom: zoomLevel(0)}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.IsUsedAtZoomLevel(tt.zoomLevel))
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package ownmapdal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/goutil/logpkg"
+)
+
+type DirChangeAction string
+
+const (
+	DirChangeActionAdded    DirChangeAction = "added"
+	DirChangeActionReplaced DirChangeAction = "replaced"
+	DirChangeActionRemoved  DirChangeAction = "removed"
+	DirChangeActionFailed   DirChangeAction = "failed"
+)
+
+// DirChange is a change to a watched directory, and what was done about it
+type DirChange struct {
+	Time   time.Time       `json:"time"`
+	Path   string          `json:"path"`
+	Action DirChangeAction `json:"action"`
+	// Error is why loading the entry failed, for the failed action
+	Error string `json:"error,omitempty"`
+}
+
+// DirChangeLog keeps the most recent changes to the watched directories
+type DirChangeLog struct {
+	mu      sync.RWMutex
+	changes []*DirChange
+	limit   int
+}
+
+func NewDirChangeLog(limit int) *DirChangeLog {
+	return &DirChangeLog{limit: limit}
+}
+
+func (l *DirChangeLog) add(change *DirChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.changes = append(l.changes, change)
+	if len(l.changes) > l.limit {
+		l.changes = l.changes[len(l.changes)-l.limit:]
+	}
+}
+
+// GetChanges returns the changes, oldest first
+func (l *DirChangeLog) GetChanges() []*DirChange {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// initialise the slice, so that no changes are serialised as [] rather than null
+	return append([]*DirChange{}, l.changes...)
+}
+
+// DirEntryHandler loads the entries of a watched directory
+type DirEntryHandler interface {
+	// Watches returns whether the entry should be watched, for example only the files with a dataset file extension
+	Watches(fileInfo os.FileInfo) bool
+	// StatPath is the path checked for changes to the entry, for example the style.json file in a style directory
+	StatPath(entryPath string) string
+	Add(entryPath string) errorsx.Error
+	// Replace loads the new version of an entry. If it fails, the old version should be kept.
+	Replace(entryPath string) errorsx.Error
+	Remove(entryPath string) errorsx.Error
+}
+
+type watchedEntry struct {
+	size    int64
+	modTime time.Time
+	// handled is true once the handler has been called for this size and modification time
+	handled bool
+	// loaded is true if the handler added the entry successfully, and it hasn't been removed since
+	loaded bool
+}
+
+// DirWatcher polls a directory for entries being added, changed or removed, and passes them on to the handler.
+// A new or changed entry is only passed on once it has stayed the same between two polls, so that files that are still being copied in aren't loaded.
+// The entries that are there at the first poll are loaded straight away.
+type DirWatcher struct {
+	logger     *logpkg.Logger
+	fs         gofs.Fs
+	dirPath    string
+	handler    DirEntryHandler
+	changeLog  *DirChangeLog
+	entries    map[string]*watchedEntry
+	polledOnce bool
+}
+
+func NewDirWatcher(logger *logpkg.Logger, fs gofs.Fs, dirPath string, handler DirEntryHandler, changeLog *DirChangeLog) *DirWatcher {
+	return &DirWatcher{
+		logger:    logger,
+		fs:        fs,
+		dirPath:   dirPath,
+		handler:   handler,
+		changeLog: changeLog,
+		entries:   make(map[string]*watchedEntry),
+	}
+}
+
+// Run polls the directory every interval, until the context is cancelled
+func (w *DirWatcher) Run(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		w.Poll()
+	}
+}
+
+// Poll checks the directory for changes once. It is not safe to call from more than one goroutine at a time.
+func (w *DirWatcher) Poll() {
+	fileInfos, err := w.fs.ReadDir(w.dirPath)
+	if err != nil {
+		w.logger.Error("error reading watched directory %q: %q", w.dirPath, err.Error())
+		return
+	}
+
+	seenPaths := make(map[string]bool)
+	for _, fileInfo := range fileInfos {
+		// hidden files are often temporary files, e.g. while a file is being copied in
+		if strings.HasPrefix(fileInfo.Name(), ".") || !w.handler.Watches(fileInfo) {
+			continue
+		}
+
+		entryPath := filepath.Join(w.dirPath, fileInfo.Name())
+
+		statInfo, err := w.fs.Stat(w.handler.StatPath(entryPath))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				w.logger.Error("error checking %q: %q", w.handler.StatPath(entryPath), err.Error())
+			}
+			// if the entry was loaded, it is removed
+			continue
+		}
+
+		seenPaths[entryPath] = true
+
+		entry, ok := w.entries[entryPath]
+		if !ok {
+			entry = &watchedEntry{size: statInfo.Size(), modTime: statInfo.ModTime()}
+			w.entries[entryPath] = entry
+			if w.polledOnce {
+				// wait until the next poll, in case it is still being written
+				continue
+			}
+		}
+
+		if entry.size != statInfo.Size() || !entry.modTime.Equal(statInfo.ModTime()) {
+			entry.size = statInfo.Size()
+			entry.modTime = statInfo.ModTime()
+			entry.handled = false
+			continue
+		}
+
+		if entry.handled {
+			continue
+		}
+		entry.handled = true
+
+		if entry.loaded {
+			w.recordChange(entryPath, DirChangeActionReplaced, w.handler.Replace(entryPath))
+			continue
+		}
+
+		addErr := w.handler.Add(entryPath)
+		if addErr == nil {
+			entry.loaded = true
+		}
+		w.recordChange(entryPath, DirChangeActionAdded, addErr)
+	}
+
+	for entryPath, entry := range w.entries {
+		if seenPaths[entryPath] {
+			continue
+		}
+
+		delete(w.entries, entryPath)
+		if entry.loaded {
+			w.recordChange(entryPath, DirChangeActionRemoved, w.handler.Remove(entryPath))
+		}
+	}
+
+	w.polledOnce = true
+}
+
+func (w *DirWatcher) recordChange(entryPath string, action DirChangeAction, err errorsx.Error) {
+	change := &DirChange{Time: time.Now(), Path: entryPath, Action: action}
+	if err != nil {
+		change.Action = DirChangeActionFailed
+		change.Error = err.Error()
+		w.logger.Error("failed to load %q (%s). Error: %q\nStack: %s", entryPath, action, err.Error(), err.Stack())
+	} else {
+		w.logger.Info("%s %q", action, entryPath)
+	}
+
+	if w.changeLog != nil {
+		w.changeLog.add(change)
+	}
+}
+
+// LoadDataSourceConnFunc loads the dataset in a file of the data directory
+type LoadDataSourceConnFunc func(path string) (DataSourceConn, errorsx.Error)
+
+// DataDirHandler keeps the connections in a DBConnSet up to date with the dataset files in the data directory.
+// A replaced file is swapped in atomically; requests that already have the old connection carry on using it, and it is closed once they have finished.
+type DataDirHandler struct {
+	logger    *logpkg.Logger
+	dbConnSet *DBConnSet
+	loadFunc  LoadDataSourceConnFunc
+	mu        sync.Mutex
+	conns     map[string]DataSourceConn
+}
+
+func NewDataDirHandler(logger *logpkg.Logger, dbConnSet *DBConnSet, loadFunc LoadDataSourceConnFunc) *DataDirHandler {
+	return &DataDirHandler{
+		logger:    logger,
+		dbConnSet: dbConnSet,
+		loadFunc:  loadFunc,
+		conns:     make(map[string]DataSourceConn),
+	}
+}
+
+func (h *DataDirHandler) Watches(fileInfo os.FileInfo) bool {
+	_, ok := DBFileTypeForDataFile(fileInfo.Name())
+	return ok && !fileInfo.IsDir()
+}
+
+func (h *DataDirHandler) StatPath(entryPath string) string {
+	return entryPath
+}
+
+func (h *DataDirHandler) Add(entryPath string) errorsx.Error {
+	conn, err := h.loadFunc(entryPath)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.conns[entryPath] = conn
+	h.dbConnSet.AddDBConn(conn)
+
+	return nil
+}
+
+func (h *DataDirHandler) Replace(entryPath string) errorsx.Error {
+	newConn, err := h.loadFunc(entryPath)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	oldConn := h.conns[entryPath]
+	err = h.dbConnSet.ReplaceDBConn(oldConn, newConn)
+	if err != nil {
+		h.closeInBackground(newConn)
+		return errorsx.Wrap(err)
+	}
+
+	h.conns[entryPath] = newConn
+	h.closeInBackground(oldConn)
+
+	return nil
+}
+
+func (h *DataDirHandler) Remove(entryPath string) errorsx.Error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	oldConn := h.conns[entryPath]
+	delete(h.conns, entryPath)
+
+	err := h.dbConnSet.RemoveDBConn(oldConn)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.closeInBackground(oldConn)
+
+	return nil
+}
+
+// closeInBackground closes the connection, if it can be closed. This waits for any in-flight requests on it to finish.
+func (h *DataDirHandler) closeInBackground(conn DataSourceConn) {
+	closable, ok := conn.(closableDataSourceConn)
+	if !ok {
+		return
+	}
+
+	go func() {
+		err := closable.Close()
+		if err != nil {
+			h.logger.Error("error closing connection %q: %q\nStack:\n%s", conn.Name(), err.Error(), err.Stack())
+		}
+	}()
+}
@@ -0,0 +1,120 @@
+package ownmapdal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirWatcher(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+	fs := gofs.NewOsFs()
+	dir := t.TempDir()
+
+	// each load of a file gets a new connection, named after the file's contents
+	loadFunc := func(path string) (DataSourceConn, errorsx.Error) {
+		data, err := fs.ReadFile(path)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		if string(data) == "broken" {
+			return nil, errorsx.Errorf("broken file")
+		}
+
+		return &fakeDataSourceConn{name: string(data), datasetInfo: &ownmap.DatasetInfo{}}, nil
+	}
+
+	writeFile := func(name, contents string) {
+		require.NoError(t, fs.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	connNames := func(dbConnSet *DBConnSet) []string {
+		var names []string
+		for _, conn := range dbConnSet.GetConns() {
+			names = append(names, conn.Name())
+		}
+		return names
+	}
+
+	actions := func(changeLog *DirChangeLog) []DirChangeAction {
+		var actions []DirChangeAction
+		for _, change := range changeLog.GetChanges() {
+			actions = append(actions, change.Action)
+		}
+		return actions
+	}
+
+	writeFile("england.ownmapdb", "england v1")
+	writeFile(".france.ownmapdb", "france v1")
+	writeFile("notes.txt", "not a dataset")
+
+	dbConnSet := NewDBConnSet(nil)
+	changeLog := NewDirChangeLog(100)
+	watcher := NewDirWatcher(logger, fs, dir, NewDataDirHandler(logger, dbConnSet, loadFunc), changeLog)
+
+	// the first poll loads what is already there, apart from hidden files and files that aren't datasets
+	watcher.Poll()
+	assert.Equal(t, []string{"england v1"}, connNames(dbConnSet))
+
+	// a new file is loaded once it has stayed the same for a poll
+	writeFile("wales.ownmapdb", "wales v1")
+	watcher.Poll()
+	assert.Equal(t, []string{"england v1"}, connNames(dbConnSet))
+	watcher.Poll()
+	assert.Equal(t, []string{"england v1", "wales v1"}, connNames(dbConnSet))
+
+	// a replaced file is swapped in (the contents are a different size, in case the modification time has a coarse resolution)
+	writeFile("england.ownmapdb", "england v2, updated")
+	watcher.Poll()
+	watcher.Poll()
+	assert.Equal(t, []string{"england v2, updated", "wales v1"}, connNames(dbConnSet))
+
+	// if the new version can't be loaded, the old version is kept
+	writeFile("wales.ownmapdb", "broken")
+	watcher.Poll()
+	watcher.Poll()
+	assert.Equal(t, []string{"england v2, updated", "wales v1"}, connNames(dbConnSet))
+
+	// a removed file is removed
+	require.NoError(t, os.Remove(filepath.Join(dir, "england.ownmapdb")))
+	watcher.Poll()
+	assert.Equal(t, []string{"wales v1"}, connNames(dbConnSet))
+
+	// nothing has changed, so nothing is done
+	watcher.Poll()
+
+	assert.Equal(t, []DirChangeAction{
+		DirChangeActionAdded,
+		DirChangeActionAdded,
+		DirChangeActionReplaced,
+		DirChangeActionFailed,
+		DirChangeActionRemoved,
+	}, actions(changeLog))
+
+	failedChange := changeLog.GetChanges()[3]
+	assert.Equal(t, filepath.Join(dir, "wales.ownmapdb"), failedChange.Path)
+	assert.Contains(t, failedChange.Error, "broken file")
+}
+
+func TestDirChangeLog(t *testing.T) {
+	changeLog := NewDirChangeLog(2)
+	assert.Equal(t, []*DirChange{}, changeLog.GetChanges())
+
+	for _, path := range []string{"a", "b", "c"} {
+		changeLog.add(&DirChange{Path: path, Action: DirChangeActionAdded})
+	}
+
+	var paths []string
+	for _, change := range changeLog.GetChanges() {
+		paths = append(paths, change.Path)
+	}
+	assert.Equal(t, []string{"b", "c"}, paths)
+}
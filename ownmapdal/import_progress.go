@@ -0,0 +1,175 @@
+package ownmapdal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+type ImportPhase string
+
+const (
+	ImportPhaseFirstPass       ImportPhase = "first_pass"
+	ImportPhaseWayPass         ImportPhase = "way_pass"
+	ImportPhaseRelationPass    ImportPhase = "relation_pass"
+	ImportPhaseBuildingSection ImportPhase = "building_section"
+	ImportPhaseWriting         ImportPhase = "writing"
+	ImportPhaseDone            ImportPhase = "done"
+)
+
+// ImportProgress is a snapshot of how far along an import is
+type ImportProgress struct {
+	Phase ImportPhase `json:"phase"`
+	// SectionName is set in the building section phase
+	SectionName string `json:"sectionName,omitempty"`
+
+	NodesImported     uint64 `json:"nodesImported"`
+	WaysImported      uint64 `json:"waysImported"`
+	RelationsImported uint64 `json:"relationsImported"`
+
+	// ScannedBytes is how far through the raw data file the current pass is. Each pass starts again from 0.
+	ScannedBytes int64 `json:"scannedBytes"`
+	TotalBytes   int64 `json:"totalBytes"`
+
+	StartTime      time.Time `json:"startTime"`
+	PhaseStartTime time.Time `json:"phaseStartTime"`
+	// EstimatedTimeRemaining is the estimated time left in the current pass through the raw data file (0 when not scanning the file, or when there isn't enough data yet).
+	// The raw data file is read 3 times (first pass, way pass, relation pass), and the passes can take quite different amounts of time, so this is not an estimate for the whole import.
+	EstimatedTimeRemaining time.Duration `json:"estimatedTimeRemainingNs"`
+
+	Warnings []string `json:"warnings"`
+}
+
+// PassPercent is how far through the raw data file the current pass is
+func (p ImportProgress) PassPercent() float64 {
+	if p.TotalBytes == 0 {
+		return 0
+	}
+	return float64(p.ScannedBytes) * 100 / float64(p.TotalBytes)
+}
+
+// PhaseDescription gives a human-readable description of the phase, e.g. "way pass"
+func (p ImportProgress) PhaseDescription() string {
+	switch p.Phase {
+	case ImportPhaseFirstPass:
+		return "first pass"
+	case ImportPhaseWayPass:
+		return "way pass"
+	case ImportPhaseRelationPass:
+		return "relation pass"
+	case ImportPhaseBuildingSection:
+		return fmt.Sprintf("building section %q", p.SectionName)
+	case ImportPhaseWriting:
+		return "writing"
+	case ImportPhaseDone:
+		return "done"
+	default:
+		return string(p.Phase)
+	}
+}
+
+// ImportProgressListener is called with every update to the import progress.
+// Listeners are called one at a time, from the goroutine running the import, so they should return quickly.
+type ImportProgressListener func(progress ImportProgress)
+
+// ImportProgressTracker keeps track of the progress of an import and sends it on to listeners as it changes
+type ImportProgressTracker struct {
+	mu        *sync.RWMutex
+	progress  ImportProgress
+	listeners []ImportProgressListener
+	nowFunc   func() time.Time
+}
+
+func NewImportProgressTracker(totalBytes int64, listeners ...ImportProgressListener) *ImportProgressTracker {
+	return newImportProgressTracker(totalBytes, time.Now, listeners...)
+}
+
+func newImportProgressTracker(totalBytes int64, nowFunc func() time.Time, listeners ...ImportProgressListener) *ImportProgressTracker {
+	now := nowFunc()
+	return &ImportProgressTracker{
+		mu: new(sync.RWMutex),
+		progress: ImportProgress{
+			Phase:          ImportPhaseFirstPass,
+			TotalBytes:     totalBytes,
+			StartTime:      now,
+			PhaseStartTime: now,
+			Warnings:       []string{},
+		},
+		listeners: listeners,
+		nowFunc:   nowFunc,
+	}
+}
+
+// Progress returns a copy of the current progress
+func (t *ImportProgressTracker) Progress() ImportProgress {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.copyProgress()
+}
+
+// SetPhase moves the import on to the next phase. sectionName is only used for the building section phase.
+func (t *ImportProgressTracker) SetPhase(phase ImportPhase, sectionName string) {
+	t.update(func(p *ImportProgress) {
+		p.Phase = phase
+		p.SectionName = sectionName
+		p.PhaseStartTime = t.nowFunc()
+		p.ScannedBytes = 0
+		p.EstimatedTimeRemaining = 0
+	})
+}
+
+// SetScannedBytes sets how far through the raw data file the current pass is
+func (t *ImportProgressTracker) SetScannedBytes(scannedBytes int64) {
+	t.update(func(p *ImportProgress) {
+		p.ScannedBytes = scannedBytes
+
+		p.EstimatedTimeRemaining = 0
+		if scannedBytes > 0 && scannedBytes <= p.TotalBytes {
+			timeTaken := t.nowFunc().Sub(p.PhaseStartTime)
+			p.EstimatedTimeRemaining = time.Duration(float64(timeTaken) * float64(p.TotalBytes-scannedBytes) / float64(scannedBytes))
+		}
+	})
+}
+
+// AddImportedObjects adds to the count of imported objects of a type.
+// Since this is called for every object, listeners are not called; they get the new counts with the next update.
+func (t *ImportProgressTracker) AddImportedObjects(objectType ownmap.ObjectType, count uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch objectType {
+	case ownmap.ObjectTypeNode:
+		t.progress.NodesImported += count
+	case ownmap.ObjectTypeWay:
+		t.progress.WaysImported += count
+	case ownmap.ObjectTypeRelation:
+		t.progress.RelationsImported += count
+	}
+}
+
+func (t *ImportProgressTracker) AddWarning(warning string) {
+	t.update(func(p *ImportProgress) {
+		p.Warnings = append(p.Warnings, warning)
+	})
+}
+
+func (t *ImportProgressTracker) update(updateFunc func(p *ImportProgress)) {
+	t.mu.Lock()
+	updateFunc(&t.progress)
+	progress := t.copyProgress()
+	t.mu.Unlock()
+
+	for _, listener := range t.listeners {
+		listener(progress)
+	}
+}
+
+// copyProgress copies the progress, so that the copy can be handed out without the warnings slice being shared. The caller must hold the lock.
+func (t *ImportProgressTracker) copyProgress() ImportProgress {
+	progress := t.progress
+	progress.Warnings = append([]string{}, t.progress.Warnings...)
+	return progress
+}
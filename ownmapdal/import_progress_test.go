@@ -0,0 +1,60 @@
+package ownmapdal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportProgressTracker(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := startTime
+	nowFunc := func() time.Time {
+		return now
+	}
+
+	var events []ImportProgress
+	tracker := newImportProgressTracker(1000, nowFunc, func(progress ImportProgress) {
+		events = append(events, progress)
+	})
+
+	// object counts are sent with the next update
+	tracker.AddImportedObjects(ownmap.ObjectTypeNode, 10)
+	require.Empty(t, events)
+
+	now = startTime.Add(10 * time.Second)
+	tracker.SetScannedBytes(250)
+	require.Len(t, events, 1)
+	assert.Equal(t, ImportPhaseFirstPass, events[0].Phase)
+	assert.Equal(t, uint64(10), events[0].NodesImported)
+	assert.Equal(t, 25.0, events[0].PassPercent())
+	assert.Equal(t, 30*time.Second, events[0].EstimatedTimeRemaining)
+
+	tracker.SetPhase(ImportPhaseWayPass, "")
+	tracker.AddImportedObjects(ownmap.ObjectTypeWay, 3)
+	tracker.AddImportedObjects(ownmap.ObjectTypeRelation, 1)
+	tracker.AddWarning("something went wrong")
+	require.Len(t, events, 3)
+
+	wayPassEvent := events[2]
+	assert.Equal(t, "way pass", wayPassEvent.PhaseDescription())
+	assert.Equal(t, int64(0), wayPassEvent.ScannedBytes)
+	assert.Equal(t, time.Duration(0), wayPassEvent.EstimatedTimeRemaining)
+	assert.Equal(t, uint64(10), wayPassEvent.NodesImported)
+	assert.Equal(t, uint64(3), wayPassEvent.WaysImported)
+	assert.Equal(t, uint64(1), wayPassEvent.RelationsImported)
+	assert.Equal(t, []string{"something went wrong"}, wayPassEvent.Warnings)
+	assert.Equal(t, startTime, wayPassEvent.StartTime)
+	assert.Equal(t, now, wayPassEvent.PhaseStartTime)
+
+	tracker.SetPhase(ImportPhaseBuildingSection, "ways")
+	assert.Equal(t, `building section "ways"`, tracker.Progress().PhaseDescription())
+
+	// progress handed out is a copy
+	progress := tracker.Progress()
+	progress.Warnings[0] = "changed"
+	assert.Equal(t, []string{"something went wrong"}, tracker.Progress().Warnings)
+}
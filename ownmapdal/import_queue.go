@@ -1,6 +1,7 @@
 package ownmapdal
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -13,16 +14,14 @@ import (
 	"github.com/jamesrr39/goutil/errorsx"
 )
 
-const (
-	PBFFileSuffix = ".pbf"
-)
-
 type ImportStatus int
 
 const (
 	ImportStatusQueued     ImportStatus = 1
 	ImportStatusInProgress ImportStatus = 2
 	ImportStatusDone       ImportStatus = 3
+	ImportStatusFailed     ImportStatus = 4
+	ImportStatusCancelled  ImportStatus = 5
 )
 
 var importStatusNames = []string{
@@ -30,39 +29,61 @@ var importStatusNames = []string{
 	"Queued",
 	"In Progress",
 	"Done",
+	"Failed",
+	"Cancelled",
 }
 
 func (i ImportStatus) String() string {
 	return importStatusNames[i]
 }
 
+func (i ImportStatus) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
 type OnImportedSuccessfullyFunc func(dataSource DataSourceConn)
 
-type ProcessImportFunc func(pbfReader PBFReader) (DataSourceConn, errorsx.Error)
+// ProcessImportFunc runs the import. It should stop and roll back the import if the context is cancelled.
+type ProcessImportFunc func(ctx context.Context, pbfReader PBFReader, progressTracker *ImportProgressTracker) (DataSourceConn, errorsx.Error)
 
 type ImportQueueItem struct {
-	datasource      *DataSourceConn
-	RawDataFilePath string
-	Status          ImportStatus
-	ProgressPercent float64
-	TimeInProgress  time.Duration
-	processFunc     ProcessImportFunc
+	ID                     uint64         `json:"id"`
+	RawDataFilePath        string         `json:"rawDataFilePath"`
+	Status                 ImportStatus   `json:"status"`
+	Progress               ImportProgress `json:"progress"`
+	TimeInProgress         time.Duration  `json:"timeInProgressNs"`
+	processFunc            ProcessImportFunc
+	onImportedSuccessfully OnImportedSuccessfullyFunc
+	ctx                    context.Context
+	cancel                 context.CancelFunc
 }
 
 type ImportQueue struct {
 	items       []*ImportQueueItem
 	mu          *sync.RWMutex
 	pathsConfig *PathsConfig
+	lastItemID  uint64
 }
 
 func NewImportQueue(pathsConfig *PathsConfig) *ImportQueue {
-	return &ImportQueue{[]*ImportQueueItem{}, new(sync.RWMutex), pathsConfig}
+	return &ImportQueue{[]*ImportQueueItem{}, new(sync.RWMutex), pathsConfig, 0}
 }
 
+// GetItems returns a snapshot of the items in the queue
 func (q *ImportQueue) GetItems() []*ImportQueueItem {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-	return q.items
+
+	var items []*ImportQueueItem
+	for _, item := range q.items {
+		itemCopy := *item
+		if item.Status == ImportStatusInProgress {
+			itemCopy.TimeInProgress = time.Now().Sub(item.Progress.StartTime)
+		}
+		itemCopy.Progress.Warnings = append([]string{}, item.Progress.Warnings...)
+		items = append(items, &itemCopy)
+	}
+	return items
 }
 
 func (q *ImportQueue) AddItemToQueue(rawData io.Reader, fileName string, processFunc ProcessImportFunc, onImportedSuccessfully OnImportedSuccessfullyFunc) errorsx.Error {
@@ -73,7 +94,12 @@ func (q *ImportQueue) AddItemToQueue(rawData io.Reader, fileName string, process
 		return errorsx.Errorf("not allowed to traverse up with filename %q", fileName)
 	}
 
-	rawDataFilePath, err := GenerateFilePathForNewDiskFile(q.pathsConfig.RawDataFilesDir, fileName, PBFFileSuffix)
+	fileNameWithoutSuffix, suffix, err := SplitRawDataFileSuffix(fileName)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	rawDataFilePath, err := GenerateFilePathForNewDiskFile(q.pathsConfig.RawDataFilesDir, fileNameWithoutSuffix, suffix)
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
@@ -89,35 +115,84 @@ func (q *ImportQueue) AddItemToQueue(rawData io.Reader, fileName string, process
 		return errorsx.Wrap(err)
 	}
 
-	item := &ImportQueueItem{
-		RawDataFilePath: rawDataFilePath,
-		Status:          ImportStatusQueued,
-		ProgressPercent: 0,
-		TimeInProgress:  0,
-		processFunc:     processFunc,
-	}
+	ctx, cancel := context.WithCancel(context.Background())
 
 	q.mu.Lock()
+	q.lastItemID++
+	item := &ImportQueueItem{
+		ID:                     q.lastItemID,
+		RawDataFilePath:        rawDataFilePath,
+		Status:                 ImportStatusQueued,
+		processFunc:            processFunc,
+		onImportedSuccessfully: onImportedSuccessfully,
+		ctx:                    ctx,
+		cancel:                 cancel,
+	}
 	q.items = append(q.items, item)
 	q.mu.Unlock()
 
-	nextItem := q.getNextDatasourceToProcess()
-	if nextItem != nil {
-		go func() {
-			dataSource, err := q.importQueueItem(nextItem)
-			if err != nil {
-				log.Printf(
-					"ERROR: failed to import queue item. Raw Data file: %q.\nError: %q\nStack: %s\n",
-					nextItem.RawDataFilePath, err.Error(), err.Stack())
+	q.startNextItem()
+
+	return nil
+}
+
+// CancelItem cancels a queued or in-progress import. An import in progress is stopped and rolled back.
+func (q *ImportQueue) CancelItem(id uint64) errorsx.Error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, item := range q.items {
+		if item.ID != id {
+			continue
+		}
+
+		switch item.Status {
+		case ImportStatusQueued:
+			item.Status = ImportStatusCancelled
+			item.cancel()
+		case ImportStatusInProgress:
+			// the status is set to cancelled when the import has stopped
+			item.cancel()
+		default:
+			return errorsx.Errorf("cannot cancel an import with status %q", item.Status)
+		}
+
+		return nil
+	}
+
+	return errorsx.Wrap(errorsx.ObjectNotFound, "id", id)
+}
+
+// startNextItem starts importing the next queued item in the background, if there is no import already in progress.
+// When that import has finished, the next queued item is started.
+func (q *ImportQueue) startNextItem() {
+	nextItem := q.getNextItemToProcess()
+	if nextItem == nil {
+		return
+	}
+
+	go func() {
+		defer q.startNextItem()
+
+		dataSource, err := q.importQueueItem(nextItem)
+		q.finishItem(nextItem, err)
+		if err != nil {
+			if nextItem.ctx.Err() != nil {
+				log.Printf("import cancelled. Raw Data file: %q\n", nextItem.RawDataFilePath)
 				return
 			}
-			onImportedSuccessfully(dataSource)
-		}()
-	}
-	return nil
+
+			log.Printf(
+				"ERROR: failed to import queue item. Raw Data file: %q.\nError: %q\nStack: %s\n",
+				nextItem.RawDataFilePath, err.Error(), err.Stack())
+			return
+		}
+		nextItem.onImportedSuccessfully(dataSource)
+	}()
 }
 
-func (q *ImportQueue) getNextDatasourceToProcess() *ImportQueueItem {
+// getNextItemToProcess picks the next queued item and marks it as in progress. It returns nil if there is already an import in progress, or nothing queued.
+func (q *ImportQueue) getNextItemToProcess() *ImportQueueItem {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	for _, item := range q.items {
@@ -130,6 +205,7 @@ func (q *ImportQueue) getNextDatasourceToProcess() *ImportQueueItem {
 	// no imports in progress. Now go through the list and pick the fist one to move to in progress
 	for _, item := range q.items {
 		if item.Status == ImportStatusQueued {
+			item.Status = ImportStatusInProgress
 			return item
 		}
 	}
@@ -138,6 +214,24 @@ func (q *ImportQueue) getNextDatasourceToProcess() *ImportQueueItem {
 	return nil
 }
 
+// finishItem sets the status of the item once the import has stopped
+func (q *ImportQueue) finishItem(item *ImportQueueItem, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case err == nil:
+		item.Status = ImportStatusDone
+	case item.ctx.Err() != nil:
+		item.Status = ImportStatusCancelled
+	default:
+		item.Status = ImportStatusFailed
+	}
+
+	item.TimeInProgress = time.Now().Sub(item.Progress.StartTime)
+	item.cancel()
+}
+
 func (q *ImportQueue) importQueueItem(item *ImportQueueItem) (DataSourceConn, errorsx.Error) {
 	var err error
 
@@ -147,37 +241,28 @@ func (q *ImportQueue) importQueueItem(item *ImportQueueItem) (DataSourceConn, er
 	}
 	defer rawDataFile.Close()
 
-	pbfReader, err := NewDefaultPBFReader(rawDataFile)
+	pbfReader, err := NewPBFReaderForFile(rawDataFile, item.RawDataFilePath)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
+	defer pbfReader.Close()
 
-	item.Status = ImportStatusInProgress
-	startTime := time.Now()
+	progressTracker := NewImportProgressTracker(pbfReader.TotalSize(), func(progress ImportProgress) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
 
-	go func() {
-		totalSize := pbfReader.TotalSize()
-		for {
-			time.Sleep(2 * time.Second)
+		item.Progress = progress
+	})
 
-			item.TimeInProgress = time.Now().Sub(startTime)
-			if item.Status == ImportStatusDone {
-				item.ProgressPercent = 100
-				return
-			}
-
-			progressPercent := float64(pbfReader.FullyScannedBytes()) * 100 / float64(totalSize)
-			item.ProgressPercent = progressPercent
-		}
-	}()
+	q.mu.Lock()
+	item.Progress = progressTracker.Progress()
+	q.mu.Unlock()
 
-	dataSource, err := item.processFunc(pbfReader)
+	dataSource, err := item.processFunc(item.ctx, pbfReader, progressTracker)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	item.Status = ImportStatusDone
-
 	return dataSource, nil
 }
 
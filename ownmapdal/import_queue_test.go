@@ -0,0 +1,64 @@
+package ownmapdal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportQueue_CancelItem(t *testing.T) {
+	queue := NewImportQueue(&PathsConfig{RawDataFilesDir: t.TempDir()})
+
+	started := make(chan struct{})
+	blockUntilCancelled := func(ctx context.Context, pbfReader PBFReader, progressTracker *ImportProgressTracker) (DataSourceConn, errorsx.Error) {
+		close(started)
+		<-ctx.Done()
+		return nil, errorsx.Wrap(ctx.Err())
+	}
+	shouldNotRun := func(ctx context.Context, pbfReader PBFReader, progressTracker *ImportProgressTracker) (DataSourceConn, errorsx.Error) {
+		t.Error("cancelled import should not be run")
+		return nil, nil
+	}
+	onImportedSuccessfully := func(dataSource DataSourceConn) {
+		t.Error("cancelled import should not be successful")
+	}
+
+	err := queue.AddItemToQueue(bytes.NewBufferString(testOSMXML), "first.osm", blockUntilCancelled, onImportedSuccessfully)
+	require.NoError(t, err)
+	err = queue.AddItemToQueue(bytes.NewBufferString(testOSMXML), "second.osm", shouldNotRun, onImportedSuccessfully)
+	require.NoError(t, err)
+
+	<-started
+
+	items := queue.GetItems()
+	require.Len(t, items, 2)
+	assert.Equal(t, ImportStatusInProgress, items[0].Status)
+	assert.Equal(t, ImportStatusQueued, items[1].Status)
+
+	// queued item
+	err = queue.CancelItem(items[1].ID)
+	require.NoError(t, err)
+
+	// in progress item
+	err = queue.CancelItem(items[0].ID)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return queue.GetItems()[0].Status == ImportStatusCancelled
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, ImportStatusCancelled, queue.GetItems()[1].Status)
+
+	// already cancelled
+	err = queue.CancelItem(items[0].ID)
+	require.Error(t, err)
+
+	err = queue.CancelItem(1000)
+	require.Error(t, err)
+	assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+}
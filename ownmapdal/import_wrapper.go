@@ -1,6 +1,10 @@
 package ownmapdal
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/jamesrr39/goutil/gofs"
 	"github.com/jamesrr39/goutil/logpkg"
@@ -8,24 +12,44 @@ import (
 	"github.com/paulmach/osm"
 )
 
+// Importer stores the objects of an import. The objects have their metadata set if the raw data has it; importers that aren't keeping the metadata should leave it out.
 type Importer interface {
 	GetNodeByID(id int64) (*ownmap.OSMNode, error)
+	// GetNodeLocation is like GetNodeByID, but only gets the location. It is used when building way geometries, so it should be fast.
+	GetNodeLocation(id int64) (*ownmap.Location, error)
 	GetWayByID(id int64) (*ownmap.OSMWay, error)
 	GetRelationByID(id int64) (*ownmap.OSMRelation, error)
-	ImportNode(obj *ownmap.OSMNode) errorsx.Error
-	ImportWay(obj *ownmap.OSMWay) errorsx.Error
-	ImportRelation(obj *ownmap.OSMRelation) errorsx.Error
-	Commit() (DataSourceConn, errorsx.Error)
+	ImportNode(ctx context.Context, obj *ownmap.OSMNode) errorsx.Error
+	ImportWay(ctx context.Context, obj *ownmap.OSMWay) errorsx.Error
+	// ImportRelation imports a relation. Relations are imported in the order they are in the file, so sub-relations can be imported after the relations containing them, and relations can contain each other.
+	// Anything worked out from a relation's sub-relations (e.g. where it is) has to wait until Commit.
+	ImportRelation(ctx context.Context, obj *ownmap.OSMRelation) errorsx.Error
+	// Commit finishes the import. The dataset bounds are recorded in the dataset info.
+	Commit(ctx context.Context, datasetBounds DatasetBounds) (DataSourceConn, errorsx.Error)
+	// Rollback takes no context, since it needs to run after the import context has been cancelled
 	Rollback() errorsx.Error
 }
 
-type scanObjectFunc func(obj osm.Object) errorsx.Error
+type scanObjectFunc func(ctx context.Context, obj osm.Object) errorsx.Error
+
+// isAlreadyImported takes the result of one of the importer Get functions, and returns whether the object was found
+func isAlreadyImported(obj interface{}, err error) (bool, errorsx.Error) {
+	if err != nil {
+		if errorsx.Cause(err) == errorsx.ObjectNotFound {
+			return false, nil
+		}
+		return false, errorsx.Wrap(err)
+	}
+
+	return true, nil
+}
 
+// createScanFirstPassFunc imports the nodes in bounds, and adds the members of every relation to the relation membership index
 func createScanFirstPassFunc(
 	importRun *ImportRunType,
 	importer Importer,
 ) scanObjectFunc {
-	return func(obj osm.Object) errorsx.Error {
+	return func(ctx context.Context, obj osm.Object) errorsx.Error {
 		var err errorsx.Error
 
 		switch obj := obj.(type) {
@@ -34,43 +58,72 @@ func createScanFirstPassFunc(
 				return nil
 			}
 
+			if importRun.DeduplicateByID {
+				alreadyImported, err := isAlreadyImported(importer.GetNodeLocation(int64(obj.ID)))
+				if err != nil {
+					return errorsx.Wrap(err)
+				}
+				if alreadyImported {
+					return nil
+				}
+			}
+
 			node := &ownmap.OSMNode{
-				ID:   int64(obj.ID),
-				Lat:  obj.Lat,
-				Lon:  obj.Lon,
-				Tags: ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
+				ID:       int64(obj.ID),
+				Lat:      obj.Lat,
+				Lon:      obj.Lon,
+				Tags:     ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
+				Metadata: ownmap.NewOSMObjectMetadata(obj.Version, obj.Timestamp, obj.ChangesetID, obj.User, obj.UserID),
 			}
 
-			err = importer.ImportNode(node)
+			err = importer.ImportNode(ctx, node)
 			if err != nil {
 				return err
 			}
+
+			importRun.Progress.AddImportedObjects(ownmap.ObjectTypeNode, 1)
+			importRun.DataExtent = extendBounds(importRun.DataExtent, obj.Lat, obj.Lon)
+		case *osm.Relation:
+			err = importRun.relationMemberships.addRelation(obj)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
 		}
 
 		return nil
 	}
 }
 
-func createRescanRunFunc(
+// createScanWayPassFunc imports the ways with at least one node in bounds (all the nodes in bounds have been imported in the first pass).
+// The relations containing them, and the relations with a node member in bounds, are marked as in bounds in the relation membership index.
+func createScanWayPassFunc(
 	logger *logpkg.Logger,
 	importRun *ImportRunType,
 	importer Importer,
 ) scanObjectFunc {
-	return func(obj osm.Object) errorsx.Error {
+	return func(ctx context.Context, obj osm.Object) errorsx.Error {
 		switch obj := obj.(type) {
-		case *osm.Node:
-			// not interested in nodes on rescan
 		case *osm.Way:
 			logger.Debug("scanning way ID: %d", obj.ID)
 			var err error
 			wayID := int64(obj.ID)
 
+			if importRun.DeduplicateByID {
+				alreadyImported, err := isAlreadyImported(importer.GetWayByID(wayID))
+				if err != nil {
+					return errorsx.Wrap(err)
+				}
+				if alreadyImported {
+					return nil
+				}
+			}
+
 			var wayPoints []*ownmap.WayPoint
 			var points []*ownmap.Location
 			for _, n := range obj.Nodes {
-				node, err := importer.GetNodeByID(int64(n.ID))
+				point, err := importer.GetNodeLocation(int64(n.ID))
 				if err != nil {
-					if errorsx.Cause(err) == errorsx.ObjectNotFound {
+					if errorsx.Cause(err) != errorsx.ObjectNotFound {
 						return errorsx.Wrap(err)
 					}
 
@@ -78,24 +131,15 @@ func createRescanRunFunc(
 					continue
 				}
 
-				point := &ownmap.Location{
-					Lat: node.Lat,
-					Lon: node.Lon,
-				}
-
 				points = append(points, point)
 				wayPoints = append(wayPoints, &ownmap.WayPoint{
-					NodeID: node.ID,
+					NodeID: int64(n.ID),
 					Point:  point,
 				})
 			}
 
 			if !atLeastOneNodeInBounds(points, importRun.Bounds) {
-				// way not in bounds. See if we should 'remember it' in the import run, if it is requested by relations scanner
-				if importRun.Rescan.WayRescanMap.IsItemRequestedForRescan(wayID) {
-					importRun.Rescan.WayRescanMap.MarkIDAsScannedButNotInBounds(wayID)
-				}
-
+				// way not in bounds
 				return nil
 			}
 
@@ -103,144 +147,143 @@ func createRescanRunFunc(
 				ID:        wayID,
 				Tags:      ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
 				WayPoints: wayPoints,
+				Metadata:  ownmap.NewOSMObjectMetadata(obj.Version, obj.Timestamp, obj.ChangesetID, obj.User, obj.UserID),
 			}
 
-			// see if this object is already imported
-			_, err = importer.GetWayByID(wayID)
-			if err != nil && errorsx.Cause(err) != errorsx.ObjectNotFound {
-				// unexpected error
+			err = importer.ImportWay(ctx, way)
+			if err != nil {
 				return errorsx.Wrap(err)
 			}
-			if err == nil {
-				// object already imported
-				return nil
-			}
 
-			err = importer.ImportWay(way)
-			if err != nil {
-				return errorsx.Wrap(err)
+			importRun.Progress.AddImportedObjects(ownmap.ObjectTypeWay, 1)
+
+			if ownmap.IsCoastline(way.Tags) {
+				importRun.CoastlineWays = append(importRun.CoastlineWays, points)
 			}
-		case *osm.Relation:
-			logger.Debug("scanning relation ID: %d", obj.ID)
-			var err error
 
-			relation, err := ownmap.NewMapmakerRelationFromOSMRelation(obj)
+			err = importRun.relationMemberships.markWayInBounds(wayID)
 			if err != nil {
 				return errorsx.Wrap(err)
 			}
+		case *osm.Relation:
+			for _, member := range obj.Members {
+				if member.Type != osm.TypeNode {
+					continue
+				}
 
-			if importRun.Rescan.RelationRescanMap.IsItemRequestedForRescan(relation.ID) {
-				importRun.Rescan.RelationRescanMap.MarkIDAsScannedButNotInBounds(relation.ID)
-			}
-
-			isAtLeastOneMemberInBounds := false
-			haveAllSubElementsBeenScanned := true
-			for _, member := range relation.Members {
-				switch member.MemberType {
-				case ownmap.OSM_MEMBER_TYPE_NODE:
-					// get node from import run. If not available, skip, since it has already been deemed out of bounds
-					_, err = importer.GetNodeByID(member.ObjectID)
-					if err != nil {
-						if errorsx.Cause(err) != errorsx.ObjectNotFound {
-							return errorsx.Wrap(err)
-						}
-
-						continue
-					}
-
-					// since the node was found in the importer, it means at least some of the relation is in bounds
-					isAtLeastOneMemberInBounds = true
-				case ownmap.OSM_MEMBER_TYPE_WAY:
-					_, err = importer.GetWayByID(member.ObjectID)
-					if err != nil {
-						if errorsx.Cause(err) != errorsx.ObjectNotFound {
-							return errorsx.Wrap(err)
-						}
-
-						rescanResult := importRun.Rescan.WayRescanMap.GetValueOfWayMarkedForRescan(member.ObjectID)
-						if rescanResult.KnownToBeOutOfBounds {
-							// if it was found in the map, but not imported, it means it was out of bounds
-							continue
-						}
-						// debug
-						logger.Debug("way marking for rescan. Relation ID: %d, Way ID: %d", obj.ID, member.ObjectID)
-
-						// we're interested in this way. So mark it as required and don't import this relation
-						importRun.Rescan.WayRescanMap.RequestIDToBeRescanned(member.ObjectID)
-						haveAllSubElementsBeenScanned = false
-						continue
+				// get node from the importer. If not available, skip, since it has already been deemed out of bounds
+				_, err := importer.GetNodeLocation(member.Ref)
+				if err != nil {
+					if errorsx.Cause(err) != errorsx.ObjectNotFound {
+						return errorsx.Wrap(err)
 					}
 
-					// the way has been imported, so it's in bounds
-					isAtLeastOneMemberInBounds = true
-				case ownmap.OSM_MEMBER_TYPE_RELATION:
-					_, err = importer.GetRelationByID(member.ObjectID)
-					if err != nil {
-						if errorsx.Cause(err) != errorsx.ObjectNotFound {
-							return errorsx.Wrap(err)
-						}
-
-						rescanResult := importRun.Rescan.RelationRescanMap.GetValueOfWayMarkedForRescan(member.ObjectID)
-						if rescanResult.KnownToBeOutOfBounds {
-							// if it was found in the map, but not imported, it means it was out of bounds
-							continue
-						}
-
-						// debug
-						logger.Debug("subrelation marking for rescan. Relation ID: %d, SubRelation ID: %d", obj.ID, member.ObjectID)
-
-						// we're interested in this subrelation. So mark it as required and don't import this relation
-						importRun.Rescan.RelationRescanMap.RequestIDToBeRescanned(member.ObjectID)
-						haveAllSubElementsBeenScanned = false
-
-						continue
-					}
+					continue
+				}
 
-					isAtLeastOneMemberInBounds = true
+				// since the node was found in the importer, it means at least some of the relation is in bounds
+				markErr := importRun.relationMemberships.markRelationInBounds(int64(obj.ID))
+				if markErr != nil {
+					return errorsx.Wrap(markErr)
 				}
-			}
 
-			if !haveAllSubElementsBeenScanned {
-				logger.Debug("waiting for all subelements to be rescanned. Relation ID: %d", obj.ID)
-				// wait to rescan this
 				return nil
 			}
+		default:
+			// not interesting to us. do nothing
+		}
+		return nil
+	}
+}
 
-			if !isAtLeastOneMemberInBounds {
-				logger.Debug("declaring out of bounds. Relation ID: %d", obj.ID)
-				// out of bounds
-				return nil
-			}
+// createScanRelationPassFunc imports the relations marked as in bounds in the relation membership index
+func createScanRelationPassFunc(
+	logger *logpkg.Logger,
+	importRun *ImportRunType,
+	importer Importer,
+) scanObjectFunc {
+	return func(ctx context.Context, obj osm.Object) errorsx.Error {
+		relationObj, ok := obj.(*osm.Relation)
+		if !ok {
+			// not interesting to us. do nothing
+			return nil
+		}
+
+		isInBounds, err := importRun.relationMemberships.isRelationInBounds(int64(relationObj.ID))
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		if !isInBounds {
+			logger.Debug("declaring out of bounds. Relation ID: %d", relationObj.ID)
+			return nil
+		}
 
-			// see if this object is already imported
-			_, err = importer.GetRelationByID(relation.ID)
-			if err != nil && errorsx.Cause(err) != errorsx.ObjectNotFound {
-				// unexpected error
+		if importRun.DeduplicateByID {
+			alreadyImported, err := isAlreadyImported(importer.GetRelationByID(int64(relationObj.ID)))
+			if err != nil {
 				return errorsx.Wrap(err)
 			}
-			if err == nil {
-				// object already imported
+			if alreadyImported {
 				return nil
 			}
+		}
 
-			logger.Debug("importing relation. ID: %d", obj.ID)
-			err = importer.ImportRelation(relation)
-			if err != nil {
-				return errorsx.Wrap(err)
-			}
-		default:
-			// not interesting to us. do nothing
+		relation, relationErr := ownmap.NewMapmakerRelationFromOSMRelation(relationObj)
+		if relationErr != nil {
+			return errorsx.Wrap(relationErr)
 		}
+
+		err = SetRelationAreaGeometry(importer, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		if ownmap.IsAreaRelation(relation.Tags) && relation.Area == nil {
+			importRun.UnassembledAreaRelationIDs = append(importRun.UnassembledAreaRelationIDs, relation.ID)
+		}
+
+		logger.Debug("importing relation. ID: %d", relation.ID)
+		err = importer.ImportRelation(ctx, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		importRun.Progress.AddImportedObjects(ownmap.ObjectTypeRelation, 1)
+
 		return nil
 	}
 }
 
+// maxUnassembledAreaRelationIDsInWarning is how many of the relations that couldn't be assembled into areas are listed in the import warning
+const maxUnassembledAreaRelationIDsInWarning = 10
+
+// getUnassembledAreaRelationsWarning describes the multipolygon and boundary relations that were imported without an area.
+// This is usually because some of their member ways are outside of the import bounds, or the relation is broken in the raw data.
+func getUnassembledAreaRelationsWarning(relationIDs []int64) string {
+	if len(relationIDs) <= maxUnassembledAreaRelationIDsInWarning {
+		return fmt.Sprintf("%d multipolygon or boundary relations couldn't be assembled into areas, and were imported without them. Relation IDs: %v", len(relationIDs), relationIDs)
+	}
+
+	return fmt.Sprintf(
+		"%d multipolygon or boundary relations couldn't be assembled into areas, and were imported without them. Relation IDs: %v and %d more",
+		len(relationIDs),
+		relationIDs[:maxUnassembledAreaRelationIDsInWarning],
+		len(relationIDs)-maxUnassembledAreaRelationIDsInWarning,
+	)
+}
+
+// Import imports the raw data into the importer. If the context is cancelled, the import is stopped and rolled back.
+// The relation membership index is kept in workDir while the import runs.
 func Import(
+	ctx context.Context,
 	logger *logpkg.Logger,
 	pbfReader PBFReader,
 	fs gofs.Fs,
+	workDir string,
 	importer Importer,
 	bounds osm.Bounds,
+	progressTracker *ImportProgressTracker,
 ) (DataSourceConn, errorsx.Error) {
 	var successful bool
 	defer func() {
@@ -255,90 +298,144 @@ func Import(
 		}
 	}()
 
-	importRun := &ImportRunType{
-		Rescan:           NewRescan(),
-		MaxItemsPerBatch: 10 * 1000,
-		Bounds:           bounds,
+	// read the header before scanning; the XML reader can only see the <bounds> element before any data
+	pbfHeader, headerErr := pbfReader.Header()
+	if headerErr != nil {
+		return nil, errorsx.Wrap(headerErr)
 	}
 
-	err := importRun.Validate()
+	relationMemberships, err := newRelationMembershipIndex(fs, filepath.Join(workDir, "relation_membership_index"))
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
-	scanFirstPass := createScanFirstPassFunc(importRun, importer)
-	rescanImportRunFunc := createRescanRunFunc(logger, importRun, importer)
-
-	batchNumber := 0
-	for {
-		batchNumber++
-
-		logger.Info(
-			"running import batch %d. %.02f%% read.",
-			batchNumber,
-			float64(pbfReader.FullyScannedBytes())*100/float64(pbfReader.TotalSize()),
-		)
-
-		shouldContinue, err := scanBatch(
-			pbfReader,
-			importRun,
-			scanFirstPass,
-		)
+	defer func() {
+		err := relationMemberships.close()
 		if err != nil {
-			return nil, errorsx.Wrap(err)
-		}
-
-		if !shouldContinue {
-			break
+			logger.Error("error removing the relation membership index: %q\nStack:\n%s\n", err.Error(), err.Stack())
 		}
-	}
+	}()
 
-	logger.Info("First scan finished. Now re-scanning unscanned relations.")
+	// objects are only looked up before importing when reading several files, since a single file doesn't have duplicates
+	_, isMultiFile := pbfReader.(*MultiFilePBFReader)
 
-	const MaxRescans = 10000
-	for i := 0; i < MaxRescans; i++ {
-		logger.Info("running rescan %d", i)
-		err := pbfReader.Reset()
-		if err != nil {
-			return nil, errorsx.Wrap(err)
-		}
+	importRun := &ImportRunType{
+		MaxItemsPerBatch:    10 * 1000,
+		Bounds:              bounds,
+		Progress:            progressTracker,
+		DeduplicateByID:     isMultiFile,
+		relationMemberships: relationMemberships,
+	}
 
-		importRun.Rescan.MarkNewIteration()
+	err = importRun.Validate()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
 
-		batchNumber := 0
-		for {
-			batchNumber++
+	// The raw data is read 3 times, whatever the order of the objects in it and however deeply the relations are nested:
+	// 1. import the nodes in bounds, and index the members of each relation
+	// 2. import the ways with a node in bounds, and mark the relations containing them (or containing a node in bounds) as in bounds
+	// 3. import the relations marked as in bounds
+	passes := []struct {
+		phase      ImportPhase
+		scanObject scanObjectFunc
+	}{
+		{ImportPhaseFirstPass, createScanFirstPassFunc(importRun, importer)},
+		{ImportPhaseWayPass, createScanWayPassFunc(logger, importRun, importer)},
+		{ImportPhaseRelationPass, createScanRelationPassFunc(logger, importRun, importer)},
+	}
 
-			logger.Info(
-				"running import batch %d of rescan #%d. %.02f%% read.",
-				batchNumber,
-				i+1,
-				float64(pbfReader.FullyScannedBytes())*100/float64(pbfReader.TotalSize()),
-			)
+	for i, pass := range passes {
+		progressTracker.SetPhase(pass.phase, "")
 
-			shouldContinue, err := scanBatch(pbfReader, importRun, rescanImportRunFunc)
+		if i != 0 {
+			err = pbfReader.Reset()
 			if err != nil {
 				return nil, errorsx.Wrap(err)
 			}
-
-			if !shouldContinue {
-				break
-			}
 		}
 
-		rescanObjectsCount := importRun.Rescan.GetRescanItemRequestsThisIteration()
-		logger.Info("End of iteration. Objects requested for rescan: %d", rescanObjectsCount)
-		if rescanObjectsCount == 0 {
-			// if there have been no requests for any item rescans, we are all done here
-			break
+		err = scanPass(ctx, logger, pbfReader, importRun, pass.scanObject)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "phase", pass.phase)
 		}
+
+		logger.Info("finished the %s pass", progressTracker.Progress().PhaseDescription())
+	}
+
+	if len(importRun.UnassembledAreaRelationIDs) != 0 {
+		progressTracker.AddWarning(getUnassembledAreaRelationsWarning(importRun.UnassembledAreaRelationIDs))
+	}
+
+	datasetBounds := ChooseDatasetBounds(importRun.DataExtent, pbfHeader.Bounds, bounds)
+	logger.Info("dataset bounds: %v (from %s)", datasetBounds.Bounds, datasetBounds.Source)
+
+	// the sea only goes as far as the dataset bounds. The requested bounds are often the whole world, which would surround a regional extract with sea.
+	logger.Info("building sea areas from %d coastline ways", len(importRun.CoastlineWays))
+	err = importCoastlineWaterAreas(ctx, importRun, importer, datasetBounds.Bounds)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
 	}
 
-	dataSourceConn, err := importer.Commit()
+	progressTracker.SetPhase(ImportPhaseWriting, "")
+
+	dataSourceConn, err := importer.Commit(ctx, datasetBounds)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
+	progressTracker.SetPhase(ImportPhaseDone, "")
+
 	successful = true
 
 	return dataSourceConn, nil
 }
+
+// scanPass scans through the whole of the raw data file once
+func scanPass(ctx context.Context, logger *logpkg.Logger, pbfReader PBFReader, importRun *ImportRunType, scanObject scanObjectFunc) errorsx.Error {
+	batchNumber := 0
+	for {
+		batchNumber++
+
+		logger.Debug("running import batch %d", batchNumber)
+
+		shouldContinue, err := scanBatch(ctx, pbfReader, importRun, scanObject)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		importRun.Progress.SetScannedBytes(pbfReader.FullyScannedBytes())
+
+		if !shouldContinue {
+			return nil
+		}
+	}
+}
+
+// coastlineWaterCellSize is the size (in degrees) of the grid cells that the sea is split up into.
+// The sea can cover most of a dataset, so this stops the whole of it being fetched and drawn for every tile.
+const coastlineWaterCellSize = 0.1
+
+// importCoastlineWaterAreas builds the sea within the bounds from the coastlines collected during the import, and imports it as generated relations with an area but no members
+func importCoastlineWaterAreas(ctx context.Context, importRun *ImportRunType, importer Importer, bounds osm.Bounds) errorsx.Error {
+	waterArea := ownmap.BuildWaterAreaFromCoastlines(importRun.CoastlineWays, bounds)
+	if waterArea == nil {
+		return nil
+	}
+
+	for i, cellArea := range ownmap.SplitAreaIntoCells(waterArea, bounds, coastlineWaterCellSize) {
+		relation := &ownmap.OSMRelation{
+			ID:   ownmap.GeneratedRelationIDStart + int64(i),
+			Tags: ownmap.NewCoastlineWaterTags(),
+			Area: cellArea,
+		}
+
+		err := importer.ImportRelation(ctx, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		importRun.Progress.AddImportedObjects(ownmap.ObjectTypeRelation, 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,457 @@
+package ownmapdal
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeImporter struct {
+	onImportNode      func()
+	nodesImported     int
+	waysImported      int
+	relationsImported int
+	nodes             map[int64]*ownmap.OSMNode
+	ways              map[int64]*ownmap.OSMWay
+	relations         map[int64]*ownmap.OSMRelation
+	committed         bool
+	datasetBounds     DatasetBounds
+	rolledBack        bool
+}
+
+func newFakeImporter() *fakeImporter {
+	return &fakeImporter{
+		nodes:     make(map[int64]*ownmap.OSMNode),
+		ways:      make(map[int64]*ownmap.OSMWay),
+		relations: make(map[int64]*ownmap.OSMRelation),
+	}
+}
+
+func (i *fakeImporter) GetNodeByID(id int64) (*ownmap.OSMNode, error) {
+	node, ok := i.nodes[id]
+	if !ok {
+		return nil, errorsx.ObjectNotFound
+	}
+	return node, nil
+}
+func (i *fakeImporter) GetNodeLocation(id int64) (*ownmap.Location, error) {
+	node, ok := i.nodes[id]
+	if !ok {
+		return nil, errorsx.ObjectNotFound
+	}
+	return &ownmap.Location{Lat: node.Lat, Lon: node.Lon}, nil
+}
+func (i *fakeImporter) GetWayByID(id int64) (*ownmap.OSMWay, error) {
+	way, ok := i.ways[id]
+	if !ok {
+		return nil, errorsx.ObjectNotFound
+	}
+	return way, nil
+}
+func (i *fakeImporter) GetRelationByID(id int64) (*ownmap.OSMRelation, error) {
+	relation, ok := i.relations[id]
+	if !ok {
+		return nil, errorsx.ObjectNotFound
+	}
+	return relation, nil
+}
+func (i *fakeImporter) ImportNode(ctx context.Context, obj *ownmap.OSMNode) errorsx.Error {
+	i.nodesImported++
+	i.nodes[obj.ID] = obj
+	if i.onImportNode != nil {
+		i.onImportNode()
+	}
+	return nil
+}
+func (i *fakeImporter) ImportWay(ctx context.Context, obj *ownmap.OSMWay) errorsx.Error {
+	i.waysImported++
+	i.ways[obj.ID] = obj
+	return nil
+}
+func (i *fakeImporter) ImportRelation(ctx context.Context, obj *ownmap.OSMRelation) errorsx.Error {
+	i.relationsImported++
+	i.relations[obj.ID] = obj
+	return nil
+}
+func (i *fakeImporter) Commit(ctx context.Context, datasetBounds DatasetBounds) (DataSourceConn, errorsx.Error) {
+	i.committed = true
+	i.datasetBounds = datasetBounds
+	return nil, nil
+}
+func (i *fakeImporter) Rollback() errorsx.Error {
+	i.rolledBack = true
+	return nil
+}
+
+func TestImport_cancelled(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	tests := []struct {
+		name              string
+		cancelBeforeStart bool
+		wantNodesImported int
+	}{
+		{"cancelled before start", true, 0},
+		// the file fits in one batch, so both nodes are imported before the context is checked again
+		{"cancelled during import", false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := mockfs.NewMockFs()
+			err := fs.WriteFile("test.osm", []byte(testOSMXML), 0600)
+			require.NoError(t, err)
+
+			file, err := fs.Open("test.osm")
+			require.NoError(t, err)
+			defer file.Close()
+
+			reader, err := NewPBFReaderForFile(file, "test.osm")
+			require.NoError(t, err)
+			defer reader.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			importer := newFakeImporter()
+			if tt.cancelBeforeStart {
+				cancel()
+			} else {
+				importer.onImportNode = cancel
+			}
+
+			progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+			_, err = Import(ctx, logger, reader, fs, "work", importer, ownmap.GetWholeWorldBounds(), progressTracker)
+			require.Error(t, err)
+			assert.Equal(t, context.Canceled, errorsx.Cause(err))
+
+			assert.True(t, importer.rolledBack)
+			assert.False(t, importer.committed)
+			assert.Equal(t, tt.wantNodesImported, importer.nodesImported)
+		})
+	}
+}
+
+func TestImport_datasetBounds(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	const osmXMLWithoutHeaderBounds = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<node id="1" lat="51.15" lon="-0.15"/>
+	<node id="2" lat="51.16" lon="-0.16"/>
+	<node id="3" lat="53" lon="1"/>
+</osm>`
+
+	tests := []struct {
+		name            string
+		osmXML          string
+		requestedBounds osm.Bounds
+		want            DatasetBounds
+	}{
+		{
+			"no header bounds",
+			osmXMLWithoutHeaderBounds,
+			ownmap.GetWholeWorldBounds(),
+			DatasetBounds{
+				Bounds:       osm.Bounds{MinLat: 51.15, MaxLat: 53, MinLon: -0.16, MaxLon: 1},
+				Source:       ownmap.BOUNDS_SOURCE_DATA_EXTENT,
+				ImportBounds: ownmap.GetWholeWorldBounds(),
+			},
+		}, {
+			"requested bounds cut off some of the nodes",
+			osmXMLWithoutHeaderBounds,
+			osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 0},
+			DatasetBounds{
+				Bounds:       osm.Bounds{MinLat: 51.15, MaxLat: 51.16, MinLon: -0.16, MaxLon: -0.15},
+				Source:       ownmap.BOUNDS_SOURCE_DATA_EXTENT,
+				ImportBounds: osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 0},
+			},
+		}, {
+			"no nodes in requested bounds",
+			osmXMLWithoutHeaderBounds,
+			osm.Bounds{MinLat: 10, MaxLat: 11, MinLon: 10, MaxLon: 11},
+			DatasetBounds{
+				Bounds:       osm.Bounds{MinLat: 10, MaxLat: 11, MinLon: 10, MaxLon: 11},
+				Source:       ownmap.BOUNDS_SOURCE_REQUESTED,
+				ImportBounds: osm.Bounds{MinLat: 10, MaxLat: 11, MinLon: 10, MaxLon: 11},
+			},
+		}, {
+			"no nodes, header bounds smaller than requested",
+			`<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<bounds minlat="51.1" minlon="-0.2" maxlat="51.2" maxlon="-0.1"/>
+</osm>`,
+			ownmap.GetWholeWorldBounds(),
+			DatasetBounds{
+				Bounds:       osm.Bounds{MinLat: 51.1, MaxLat: 51.2, MinLon: -0.2, MaxLon: -0.1},
+				Source:       ownmap.BOUNDS_SOURCE_HEADER,
+				ImportBounds: osm.Bounds{MinLat: 51.1, MaxLat: 51.2, MinLon: -0.2, MaxLon: -0.1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := mockfs.NewMockFs()
+			err := fs.WriteFile("test.osm", []byte(tt.osmXML), 0600)
+			require.NoError(t, err)
+
+			file, err := fs.Open("test.osm")
+			require.NoError(t, err)
+			defer file.Close()
+
+			reader, err := NewPBFReaderForFile(file, "test.osm")
+			require.NoError(t, err)
+			defer reader.Close()
+
+			importer := newFakeImporter()
+			progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+			_, err = Import(context.Background(), logger, reader, fs, "work", importer, tt.requestedBounds, progressTracker)
+			require.NoError(t, err)
+
+			require.True(t, importer.committed)
+			assert.Equal(t, tt.want, importer.datasetBounds)
+		})
+	}
+}
+
+// resetCountingPBFReader counts how many times the raw data is read again from the start
+type resetCountingPBFReader struct {
+	PBFReader
+	resets int
+}
+
+func (r *resetCountingPBFReader) Reset() errorsx.Error {
+	r.resets++
+	return r.PBFReader.Reset()
+}
+
+func TestImport_nestedRelations(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// the relations come before their members, and are nested in each other, so the old importer would have needed a rescan for every level
+	const osmXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<relation id="300">
+		<member type="relation" ref="200" role=""/>
+		<tag k="type" v="route_master"/>
+	</relation>
+	<relation id="200">
+		<member type="relation" ref="100" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+	<relation id="100">
+		<member type="way" ref="10" role=""/>
+		<member type="way" ref="11" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+	<relation id="400">
+		<member type="way" ref="11" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+	<relation id="500">
+		<member type="node" ref="1" role=""/>
+		<member type="relation" ref="600" role=""/>
+		<tag k="type" v="site"/>
+	</relation>
+	<relation id="600">
+		<member type="relation" ref="601" role=""/>
+		<member type="way" ref="11" role=""/>
+		<tag k="type" v="site"/>
+	</relation>
+	<relation id="601">
+		<member type="relation" ref="600" role=""/>
+		<tag k="type" v="site"/>
+	</relation>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<way id="11">
+		<nd ref="3"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<node id="1" lat="51.5" lon="0"/>
+	<node id="2" lat="51.6" lon="0.1"/>
+	<node id="3" lat="10" lon="10"/>
+</osm>`
+
+	fs := mockfs.NewMockFs()
+	err := fs.WriteFile("test.osm", []byte(osmXML), 0600)
+	require.NoError(t, err)
+
+	file, err := fs.Open("test.osm")
+	require.NoError(t, err)
+	defer file.Close()
+
+	xmlReader, err := NewPBFReaderForFile(file, "test.osm")
+	require.NoError(t, err)
+	defer xmlReader.Close()
+
+	reader := &resetCountingPBFReader{PBFReader: xmlReader}
+
+	importer := newFakeImporter()
+	progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+	_, err = Import(context.Background(), logger, reader, fs, "work", importer, osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1}, progressTracker)
+	require.NoError(t, err)
+
+	// read 3 times in total
+	assert.Equal(t, 2, reader.resets)
+
+	assert.Len(t, importer.nodes, 2)
+	assert.Len(t, importer.ways, 1)
+
+	var relationIDs []int64
+	for id := range importer.relations {
+		relationIDs = append(relationIDs, id)
+	}
+	// 400 only has a way out of bounds. 600 and 601 contain each other, but nothing in bounds
+	assert.ElementsMatch(t, []int64{100, 200, 300, 500}, relationIDs)
+
+	// the relation membership index is removed afterwards
+	_, err = fs.Stat("work/relation_membership_index")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestImport_coastlineWaterAreas(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// a coastline going east across the middle of the data, with the sea to the south of it
+	const osmXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<node id="1" lat="51.1" lon="0"/>
+	<node id="2" lat="51.1" lon="0.2"/>
+	<node id="3" lat="51" lon="0"/>
+	<node id="4" lat="51.2" lon="0.2"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="natural" v="coastline"/>
+	</way>
+</osm>`
+
+	fs := mockfs.NewMockFs()
+	err := fs.WriteFile("test.osm", []byte(osmXML), 0600)
+	require.NoError(t, err)
+
+	file, err := fs.Open("test.osm")
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := NewPBFReaderForFile(file, "test.osm")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	importer := newFakeImporter()
+	progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+	// the whole world is requested, but the sea only goes as far as the data
+	_, err = Import(context.Background(), logger, reader, fs, "work", importer, ownmap.GetWholeWorldBounds(), progressTracker)
+	require.NoError(t, err)
+
+	dataExtent := osm.Bounds{MinLat: 51, MaxLat: 51.2, MinLon: 0, MaxLon: 0.2}
+	assert.Equal(t, dataExtent, importer.datasetBounds.Bounds)
+
+	var waterRelations []*ownmap.OSMRelation
+	for id, relation := range importer.relations {
+		if id >= ownmap.GeneratedRelationIDStart {
+			waterRelations = append(waterRelations, relation)
+		}
+	}
+
+	// the sea is between 51 and 51.1, so it is split into a cell for each 0.1 degrees of longitude
+	require.NotEmpty(t, waterRelations)
+	assert.LessOrEqual(t, len(waterRelations), 4)
+
+	for _, relation := range waterRelations {
+		for _, polygon := range relation.Area.Polygons {
+			ringBounds := ownmap.GetRingBounds(polygon.Outer.Points)
+			assert.GreaterOrEqual(t, ringBounds.MinLat, dataExtent.MinLat)
+			assert.LessOrEqual(t, ringBounds.MaxLat, 51.1)
+			assert.GreaterOrEqual(t, ringBounds.MinLon, dataExtent.MinLon)
+			assert.LessOrEqual(t, ringBounds.MaxLon, dataExtent.MaxLon)
+		}
+	}
+}
+
+func TestImport_unassembledAreaRelationsWarning(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// relation 100's outer way is a closed ring, relation 101's isn't
+	const osmXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<node id="1" lat="51.1" lon="0.1"/>
+	<node id="2" lat="51.1" lon="0.2"/>
+	<node id="3" lat="51.2" lon="0.2"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<nd ref="3"/>
+		<nd ref="1"/>
+	</way>
+	<way id="11">
+		<nd ref="1"/>
+		<nd ref="2"/>
+	</way>
+	<relation id="100">
+		<member type="way" ref="10" role="outer"/>
+		<tag k="type" v="multipolygon"/>
+		<tag k="natural" v="wood"/>
+	</relation>
+	<relation id="101">
+		<member type="way" ref="11" role="outer"/>
+		<tag k="type" v="multipolygon"/>
+		<tag k="natural" v="water"/>
+	</relation>
+</osm>`
+
+	fs := mockfs.NewMockFs()
+	err := fs.WriteFile("test.osm", []byte(osmXML), 0600)
+	require.NoError(t, err)
+
+	file, err := fs.Open("test.osm")
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := NewPBFReaderForFile(file, "test.osm")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	importer := newFakeImporter()
+	progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+	_, err = Import(context.Background(), logger, reader, fs, "work", importer, osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1}, progressTracker)
+	require.NoError(t, err)
+
+	// both relations are imported, but only relation 100 has an area
+	require.Contains(t, importer.relations, int64(100))
+	require.Contains(t, importer.relations, int64(101))
+	assert.NotNil(t, importer.relations[100].Area)
+	assert.Nil(t, importer.relations[101].Area)
+
+	assert.Equal(t, []string{
+		"1 multipolygon or boundary relations couldn't be assembled into areas, and were imported without them. Relation IDs: [101]",
+	}, progressTracker.Progress().Warnings)
+
+	// long lists of relations are cut short
+	var relationIDs []int64
+	for id := int64(1); id <= 12; id++ {
+		relationIDs = append(relationIDs, id)
+	}
+	assert.Equal(t,
+		"12 multipolygon or boundary relations couldn't be assembled into areas, and were imported without them. Relation IDs: [1 2 3 4 5 6 7 8 9 10] and 2 more",
+		getUnassembledAreaRelationsWarning(relationIDs),
+	)
+}
@@ -0,0 +1,211 @@
+package ownmapdal
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// datasetRank orders the datasets an object can come from: the highest priority wins, then the most recently updated dataset, and then the dataset whose name sorts first, so that the choice doesn't depend on which dataset answered first
+type datasetRank struct {
+	priority          int
+	replicationTimeMs uint64
+	name              string
+}
+
+func (r datasetRank) isHigherThan(other datasetRank) bool {
+	if r.priority != other.priority {
+		return r.priority > other.priority
+	}
+
+	if r.replicationTimeMs != other.replicationTimeMs {
+		return r.replicationTimeMs > other.replicationTimeMs
+	}
+
+	return r.name < other.name
+}
+
+type mergedObject struct {
+	source DataSourceConn
+	rank   datasetRank
+	// objectsByTagKey can have more than one object with the same ID from the same dataset, for example a long way split into several lines by osm2pgsql
+	objectsByTagKey map[ownmap.TagKey][]ownmap.OSMObject
+	// tagKeys are in the order they were added
+	tagKeys []ownmap.TagKey
+}
+
+// mergedObjectKey identifies the same object in different datasets.
+// Objects generated at import time (e.g. water polygons from coastlines) are numbered from ownmap.GeneratedRelationIDStart in every dataset, so the same ID in two datasets is two different objects. They are keyed by dataset as well, so that they are never deduplicated.
+type mergedObjectKey struct {
+	id     int64
+	source DataSourceConn
+}
+
+func newMergedObjectKey(source DataSourceConn, id int64) mergedObjectKey {
+	if id >= ownmap.GeneratedRelationIDStart {
+		return mergedObjectKey{id: id, source: source}
+	}
+
+	return mergedObjectKey{id: id}
+}
+
+// mergedObjects are the objects of one type, by ID
+type mergedObjects struct {
+	byKey map[mergedObjectKey]*mergedObject
+	// keys are in the order they were first added, so that the results are in a stable order
+	keys []mergedObjectKey
+}
+
+func newMergedObjects() *mergedObjects {
+	return &mergedObjects{byKey: make(map[mergedObjectKey]*mergedObject)}
+}
+
+func (m *mergedObjects) add(source DataSourceConn, rank datasetRank, tagKey ownmap.TagKey, object ownmap.OSMObject) {
+	key := newMergedObjectKey(source, object.OSMObjectID())
+
+	merged, ok := m.byKey[key]
+	if !ok {
+		m.keys = append(m.keys, key)
+	}
+
+	if !ok || (merged.source != source && rank.isHigherThan(merged.rank)) {
+		merged = &mergedObject{
+			source:          source,
+			rank:            rank,
+			objectsByTagKey: make(map[ownmap.TagKey][]ownmap.OSMObject),
+		}
+		m.byKey[key] = merged
+	}
+
+	if merged.source != source {
+		// the version from a lower ranked dataset
+		return
+	}
+
+	objects, ok := merged.objectsByTagKey[tagKey]
+	if !ok {
+		merged.tagKeys = append(merged.tagKeys, tagKey)
+	}
+
+	for _, existingObject := range objects {
+		if existingObject == object {
+			return
+		}
+	}
+
+	merged.objectsByTagKey[tagKey] = append(objects, object)
+}
+
+func (m *mergedObjects) forEach(fn func(tagKey ownmap.TagKey, object ownmap.OSMObject)) {
+	for _, key := range m.keys {
+		merged := m.byKey[key]
+		for _, tagKey := range merged.tagKeys {
+			for _, object := range merged.objectsByTagKey[tagKey] {
+				fn(tagKey, object)
+			}
+		}
+	}
+}
+
+// Merger combines the objects from several datasets. An object that is in more than one of them (where extracts overlap) is only returned once, by type and ID.
+// The version from the dataset with the highest priority (from the dataset configs) is used, or if they have the same priority, the version from the most recently updated dataset (by replication time).
+// Add can be called from several goroutines.
+type Merger struct {
+	dbConnSet *DBConnSet
+	mu        sync.Mutex
+	nodes     *mergedObjects
+	ways      *mergedObjects
+	relations *mergedObjects
+}
+
+func NewMerger(dbConnSet *DBConnSet) *Merger {
+	return &Merger{
+		dbConnSet: dbConnSet,
+		nodes:     newMergedObjects(),
+		ways:      newMergedObjects(),
+		relations: newMergedObjects(),
+	}
+}
+
+func sortedTagKeys(tagKeys []ownmap.TagKey) []ownmap.TagKey {
+	sort.Slice(tagKeys, func(i, j int) bool {
+		return tagKeys[i] < tagKeys[j]
+	})
+
+	return tagKeys
+}
+
+// Add adds the objects fetched from a dataset. Any of the maps can be nil.
+func (m *Merger) Add(conn DataSourceConn, nodeMap TagNodeMap, wayMap TagWayMap, relationMap TagRelationMap) errorsx.Error {
+	datasetInfo, err := conn.DatasetInfo()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	rank := datasetRank{
+		priority:          m.dbConnSet.GetDatasetConfig(conn.Name()).Priority,
+		replicationTimeMs: datasetInfo.ReplicationTimeMs,
+		name:              conn.Name(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the maps are gone through in order of tag key, so that the objects are in the same order each time
+	var tagKeys []ownmap.TagKey
+	for tagKey := range nodeMap {
+		tagKeys = append(tagKeys, tagKey)
+	}
+	for _, tagKey := range sortedTagKeys(tagKeys) {
+		for _, node := range nodeMap[tagKey] {
+			m.nodes.add(conn, rank, tagKey, node)
+		}
+	}
+
+	tagKeys = nil
+	for tagKey := range wayMap {
+		tagKeys = append(tagKeys, tagKey)
+	}
+	for _, tagKey := range sortedTagKeys(tagKeys) {
+		for _, way := range wayMap[tagKey] {
+			m.ways.add(conn, rank, tagKey, way)
+		}
+	}
+
+	tagKeys = nil
+	for tagKey := range relationMap {
+		tagKeys = append(tagKeys, tagKey)
+	}
+	for _, tagKey := range sortedTagKeys(tagKeys) {
+		for _, relation := range relationMap[tagKey] {
+			m.relations.add(conn, rank, tagKey, relation)
+		}
+	}
+
+	return nil
+}
+
+// Result returns the merged objects
+func (m *Merger) Result() (TagNodeMap, TagWayMap, TagRelationMap) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nodeMap := make(TagNodeMap)
+	m.nodes.forEach(func(tagKey ownmap.TagKey, object ownmap.OSMObject) {
+		nodeMap[tagKey] = append(nodeMap[tagKey], object.(*ownmap.OSMNode))
+	})
+
+	wayMap := make(TagWayMap)
+	m.ways.forEach(func(tagKey ownmap.TagKey, object ownmap.OSMObject) {
+		wayMap[tagKey] = append(wayMap[tagKey], object.(*ownmap.OSMWay))
+	})
+
+	relationMap := make(TagRelationMap)
+	m.relations.forEach(func(tagKey ownmap.TagKey, object ownmap.OSMObject) {
+		relationMap[tagKey] = append(relationMap[tagKey], object.(*ownmap.RelationData))
+	})
+
+	return nodeMap, wayMap, relationMap
+}
@@ -0,0 +1,130 @@
+package ownmapdal
+
+import (
+	"testing"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerger(t *testing.T) {
+	node := func(id int64, name string) *ownmap.OSMNode {
+		return &ownmap.OSMNode{ID: id, Tags: []*ownmap.OSMTag{{Key: "place", Value: "town"}, {Key: "name", Value: name}}}
+	}
+
+	type datasetResult struct {
+		conn    *fakeDataSourceConn
+		nodeMap TagNodeMap
+	}
+
+	olderConn := &fakeDataSourceConn{"older.ownmapdb", &ownmap.DatasetInfo{ReplicationTimeMs: 1000}}
+	newerConn := &fakeDataSourceConn{"newer.ownmapdb", &ownmap.DatasetInfo{ReplicationTimeMs: 2000}}
+
+	tests := []struct {
+		name           string
+		datasetConfigs DatasetConfigs
+		results        []datasetResult
+		want           []*ownmap.OSMNode
+	}{
+		{
+			"no overlap",
+			nil,
+			[]datasetResult{
+				{olderConn, TagNodeMap{"place": {node(1, "a")}}},
+				{newerConn, TagNodeMap{"place": {node(2, "b")}}},
+			},
+			[]*ownmap.OSMNode{node(1, "a"), node(2, "b")},
+		}, {
+			"newest dataset wins",
+			nil,
+			[]datasetResult{
+				{olderConn, TagNodeMap{"place": {node(1, "old name"), node(2, "b")}}},
+				{newerConn, TagNodeMap{"place": {node(1, "new name")}}},
+			},
+			[]*ownmap.OSMNode{node(1, "new name"), node(2, "b")},
+		}, {
+			"newest dataset wins, whichever is added first",
+			nil,
+			[]datasetResult{
+				{newerConn, TagNodeMap{"place": {node(1, "new name")}}},
+				{olderConn, TagNodeMap{"place": {node(1, "old name")}}},
+			},
+			[]*ownmap.OSMNode{node(1, "new name")},
+		}, {
+			"priority beats replication time",
+			DatasetConfigs{"older.ownmapdb": {Priority: 1}},
+			[]datasetResult{
+				{olderConn, TagNodeMap{"place": {node(1, "old name")}}},
+				{newerConn, TagNodeMap{"place": {node(1, "new name")}}},
+			},
+			[]*ownmap.OSMNode{node(1, "old name")},
+		}, {
+			"objects with the same ID from the same dataset are kept",
+			nil,
+			[]datasetResult{
+				{olderConn, TagNodeMap{"place": {node(1, "part 1"), node(1, "part 2")}}},
+			},
+			[]*ownmap.OSMNode{node(1, "part 1"), node(1, "part 2")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbConnSet := NewDBConnSet(nil)
+			if tt.datasetConfigs != nil {
+				dbConnSet.SetDatasetConfigs(tt.datasetConfigs)
+			}
+
+			merger := NewMerger(dbConnSet)
+			for _, result := range tt.results {
+				err := merger.Add(result.conn, result.nodeMap, nil, nil)
+				require.NoError(t, err)
+			}
+
+			nodeMap, wayMap, relationMap := merger.Result()
+			assert.Equal(t, tt.want, nodeMap["place"])
+			assert.Empty(t, wayMap)
+			assert.Empty(t, relationMap)
+		})
+	}
+}
+
+func TestMerger_tagKeys(t *testing.T) {
+	olderConn := &fakeDataSourceConn{"older.ownmapdb", &ownmap.DatasetInfo{ReplicationTimeMs: 1000}}
+	newerConn := &fakeDataSourceConn{"newer.ownmapdb", &ownmap.DatasetInfo{ReplicationTimeMs: 2000}}
+
+	// the way was a road in the older dataset, but is only a footpath in the newer one
+	oldWay := &ownmap.OSMWay{ID: 1, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "residential"}, {Key: "name", Value: "High Street"}}}
+	newWay := &ownmap.OSMWay{ID: 1, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "footway"}}}
+	relation := &ownmap.RelationData{RelationID: 5, Tags: []*ownmap.OSMTag{{Key: "boundary", Value: "administrative"}}}
+
+	merger := NewMerger(NewDBConnSet(nil))
+	err := merger.Add(olderConn, nil, TagWayMap{"highway": {oldWay}, "name": {oldWay}}, TagRelationMap{"boundary": {relation}})
+	require.NoError(t, err)
+	err = merger.Add(newerConn, nil, TagWayMap{"highway": {newWay}}, TagRelationMap{"boundary": {relation}})
+	require.NoError(t, err)
+
+	_, wayMap, relationMap := merger.Result()
+	assert.Equal(t, TagWayMap{"highway": {newWay}}, wayMap)
+	assert.Equal(t, TagRelationMap{"boundary": {relation}}, relationMap)
+}
+
+func TestMerger_generatedRelations(t *testing.T) {
+	englandConn := &fakeDataSourceConn{"england.ownmapdb", &ownmap.DatasetInfo{ReplicationTimeMs: 1000}}
+	franceConn := &fakeDataSourceConn{"france.ownmapdb", &ownmap.DatasetInfo{ReplicationTimeMs: 2000}}
+
+	// both datasets number their generated sea polygons from the same ID, but they are different polygons
+	englandSea := &ownmap.RelationData{RelationID: ownmap.GeneratedRelationIDStart, Tags: []*ownmap.OSMTag{{Key: "natural", Value: "water"}, {Key: "name", Value: "english sea"}}}
+	franceSea := &ownmap.RelationData{RelationID: ownmap.GeneratedRelationIDStart, Tags: []*ownmap.OSMTag{{Key: "natural", Value: "water"}, {Key: "name", Value: "french sea"}}}
+	boundary := &ownmap.RelationData{RelationID: 5, Tags: []*ownmap.OSMTag{{Key: "natural", Value: "water"}}}
+
+	merger := NewMerger(NewDBConnSet(nil))
+	err := merger.Add(englandConn, nil, nil, TagRelationMap{"natural": {englandSea, boundary}})
+	require.NoError(t, err)
+	err = merger.Add(franceConn, nil, nil, TagRelationMap{"natural": {franceSea, boundary}})
+	require.NoError(t, err)
+
+	_, _, relationMap := merger.Result()
+	assert.Equal(t, TagRelationMap{"natural": {englandSea, boundary, franceSea}}, relationMap)
+}
@@ -0,0 +1,173 @@
+package ownmapdal
+
+import (
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// MultiFilePBFReader reads several raw data files, one after the other, as if they were one file.
+// Files for neighbouring regions usually overlap at the borders, so the same objects can be read more than once; the import skips objects it has already imported.
+type MultiFilePBFReader struct {
+	readers      []PBFReader
+	currentIndex int
+	header       *osmpbf.Header
+}
+
+func NewMultiFilePBFReader(readers []PBFReader) (*MultiFilePBFReader, errorsx.Error) {
+	if len(readers) == 0 {
+		return nil, errorsx.Errorf("no readers given")
+	}
+
+	return &MultiFilePBFReader{readers: readers}, nil
+}
+
+// Header is the header of the first file, with the bounds being the union of the bounds of all the files.
+// If any of the files doesn't have bounds, the bounds are left out, since the extent of that file isn't known.
+// The replication state (sequence number, timestamp and base URL) is only kept if it is the same in all the files. Otherwise there isn't one diff stream that keeps the whole dataset up to date, so it is left out.
+func (r *MultiFilePBFReader) Header() (*osmpbf.Header, error) {
+	if r.header != nil {
+		return r.header, nil
+	}
+
+	var header osmpbf.Header
+	var bounds *osm.Bounds
+	allHaveBounds := true
+	allHaveSameReplicationState := true
+	for i, reader := range r.readers {
+		readerHeader, err := reader.Header()
+		if err != nil {
+			return nil, errorsx.Wrap(err, "file index", i)
+		}
+
+		if i == 0 && readerHeader != nil {
+			header = *readerHeader
+		}
+
+		if i != 0 && !hasSameReplicationState(&header, readerHeader) {
+			allHaveSameReplicationState = false
+		}
+
+		if readerHeader == nil || readerHeader.Bounds == nil {
+			allHaveBounds = false
+			continue
+		}
+
+		bounds = unionBounds(bounds, readerHeader.Bounds)
+	}
+
+	header.Bounds = nil
+	if allHaveBounds {
+		header.Bounds = bounds
+	}
+
+	if !allHaveSameReplicationState {
+		header.ReplicationSeqNum = 0
+		header.ReplicationTimestamp = time.Time{}
+		header.ReplicationBaseURL = ""
+	}
+
+	r.header = &header
+
+	return r.header, nil
+}
+
+func hasSameReplicationState(header, other *osmpbf.Header) bool {
+	if other == nil {
+		other = &osmpbf.Header{}
+	}
+
+	return header.ReplicationSeqNum == other.ReplicationSeqNum &&
+		header.ReplicationTimestamp.Equal(other.ReplicationTimestamp) &&
+		header.ReplicationBaseURL == other.ReplicationBaseURL
+}
+
+func unionBounds(bounds, other *osm.Bounds) *osm.Bounds {
+	if bounds == nil {
+		otherCopy := *other
+		return &otherCopy
+	}
+
+	bounds = extendBounds(bounds, other.MinLat, other.MinLon)
+	bounds = extendBounds(bounds, other.MaxLat, other.MaxLon)
+
+	return bounds
+}
+
+// Scan advances to the next object, moving on to the next file when the current one has been fully read
+func (r *MultiFilePBFReader) Scan() bool {
+	for r.currentIndex < len(r.readers) {
+		reader := r.readers[r.currentIndex]
+		if reader.Scan() {
+			return true
+		}
+
+		if reader.Err() != nil {
+			return false
+		}
+
+		if r.currentIndex == len(r.readers)-1 {
+			return false
+		}
+
+		r.currentIndex++
+	}
+
+	return false
+}
+
+func (r *MultiFilePBFReader) Object() osm.Object {
+	return r.readers[r.currentIndex].Object()
+}
+
+func (r *MultiFilePBFReader) Err() error {
+	return r.readers[r.currentIndex].Err()
+}
+
+// Reset resets all the files, and starts again from the first one
+func (r *MultiFilePBFReader) Reset() errorsx.Error {
+	for i, reader := range r.readers {
+		err := reader.Reset()
+		if err != nil {
+			return errorsx.Wrap(err, "file index", i)
+		}
+	}
+
+	r.currentIndex = 0
+
+	return nil
+}
+
+// FullyScannedBytes is the size of the files already read, plus the bytes scanned of the current file
+func (r *MultiFilePBFReader) FullyScannedBytes() int64 {
+	var scannedBytes int64
+	for _, reader := range r.readers[:r.currentIndex] {
+		scannedBytes += reader.TotalSize()
+	}
+
+	return scannedBytes + r.readers[r.currentIndex].FullyScannedBytes()
+}
+
+func (r *MultiFilePBFReader) TotalSize() int64 {
+	var totalSize int64
+	for _, reader := range r.readers {
+		totalSize += reader.TotalSize()
+	}
+
+	return totalSize
+}
+
+// Close closes all the readers. It returns the first error encountered, after trying to close all of them.
+func (r *MultiFilePBFReader) Close() error {
+	var firstErr error
+	for _, reader := range r.readers {
+		err := reader.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
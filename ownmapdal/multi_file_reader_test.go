@@ -0,0 +1,247 @@
+package ownmapdal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the two regions overlap at their border: node 2, way 10 and relation 100 are in both files
+const (
+	testOSMXMLRegionA = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<bounds minlat="51.1" minlon="-0.2" maxlat="51.2" maxlon="-0.1"/>
+	<node id="1" lat="51.15" lon="-0.15"/>
+	<node id="2" lat="51.2" lon="-0.1"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<relation id="100">
+		<member type="way" ref="10" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+</osm>`
+	testOSMXMLRegionB = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<bounds minlat="51.2" minlon="-0.1" maxlat="51.3" maxlon="0"/>
+	<node id="2" lat="51.2" lon="-0.1"/>
+	<node id="3" lat="51.25" lon="-0.05"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<way id="11">
+		<nd ref="2"/>
+		<nd ref="3"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<relation id="100">
+		<member type="way" ref="10" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+	<relation id="101">
+		<member type="way" ref="11" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+</osm>`
+)
+
+func newTestPBFReader(t *testing.T, fileName, contents string) PBFReader {
+	fs := mockfs.NewMockFs()
+	err := fs.WriteFile(fileName, []byte(contents), 0600)
+	require.NoError(t, err)
+
+	file, err := fs.Open(fileName)
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	reader, err := NewPBFReaderForFile(file, fileName)
+	require.NoError(t, err)
+
+	return reader
+}
+
+func newTestMultiFilePBFReader(t *testing.T, fileContents ...string) *MultiFilePBFReader {
+	var readers []PBFReader
+	for i, contents := range fileContents {
+		readers = append(readers, newTestPBFReader(t, string(rune('a'+i))+".osm", contents))
+	}
+
+	reader, err := NewMultiFilePBFReader(readers)
+	require.NoError(t, err)
+	t.Cleanup(func() { reader.Close() })
+
+	return reader
+}
+
+func TestMultiFilePBFReader(t *testing.T) {
+	reader := newTestMultiFilePBFReader(t, testOSMXMLRegionA, testOSMXMLRegionB)
+
+	header, err := reader.Header()
+	require.NoError(t, err)
+	assert.Equal(t, &osm.Bounds{MinLat: 51.1, MaxLat: 51.3, MinLon: -0.2, MaxLon: 0}, header.Bounds)
+
+	assert.Equal(t, int64(len(testOSMXMLRegionA)+len(testOSMXMLRegionB)), reader.TotalSize())
+
+	expectedIDs := []osm.ObjectID{
+		osm.NodeID(1).ObjectID(0),
+		osm.NodeID(2).ObjectID(0),
+		osm.WayID(10).ObjectID(0),
+		osm.RelationID(100).ObjectID(0),
+		osm.NodeID(2).ObjectID(0),
+		osm.NodeID(3).ObjectID(0),
+		osm.WayID(10).ObjectID(0),
+		osm.WayID(11).ObjectID(0),
+		osm.RelationID(100).ObjectID(0),
+		osm.RelationID(101).ObjectID(0),
+	}
+
+	assert.Equal(t, expectedIDs, scanAllIDs(t, reader))
+	assert.Equal(t, reader.TotalSize(), reader.FullyScannedBytes())
+
+	// scanning again after a reset starts from the first file
+	err = reader.Reset()
+	require.NoError(t, err)
+
+	assert.Equal(t, expectedIDs, scanAllIDs(t, reader))
+}
+
+func TestMultiFilePBFReader_Header_fileWithoutBounds(t *testing.T) {
+	reader := newTestMultiFilePBFReader(t, testOSMXMLRegionA, `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<node id="5" lat="10" lon="10"/>
+</osm>`)
+
+	header, err := reader.Header()
+	require.NoError(t, err)
+
+	// the extent of the second file isn't known
+	assert.Nil(t, header.Bounds)
+
+	// the object peeked while reading the header is still scanned
+	assert.Contains(t, scanAllIDs(t, reader), osm.NodeID(5).ObjectID(0))
+}
+
+// fakePBFReader returns a header and then fails reading, if an error is given
+type fakePBFReader struct {
+	header *osmpbf.Header
+	err    error
+}
+
+func (r *fakePBFReader) Header() (*osmpbf.Header, error) { return r.header, nil }
+func (r *fakePBFReader) Scan() bool                      { return false }
+func (r *fakePBFReader) Object() osm.Object              { return nil }
+func (r *fakePBFReader) Err() error                      { return r.err }
+func (r *fakePBFReader) Reset() errorsx.Error            { return nil }
+func (r *fakePBFReader) FullyScannedBytes() int64        { return 0 }
+func (r *fakePBFReader) TotalSize() int64                { return 0 }
+func (r *fakePBFReader) Close() error                    { return nil }
+
+func TestMultiFilePBFReader_Header_replicationState(t *testing.T) {
+	replicationTime := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newHeader := func(seqNum uint64, timestamp time.Time, baseURL string) *osmpbf.Header {
+		return &osmpbf.Header{
+			ReplicationSeqNum:    seqNum,
+			ReplicationTimestamp: timestamp,
+			ReplicationBaseURL:   baseURL,
+		}
+	}
+
+	regionA := newHeader(100, replicationTime, "https://download.geofabrik.de/europe/region-a-updates")
+	regionB := newHeader(200, replicationTime.Add(time.Hour), "https://download.geofabrik.de/europe/region-b-updates")
+
+	tests := []struct {
+		name    string
+		headers []*osmpbf.Header
+		want    *osmpbf.Header
+	}{
+		{"same replication state", []*osmpbf.Header{regionA, newHeader(100, replicationTime, regionA.ReplicationBaseURL)}, regionA},
+		{"different replication states", []*osmpbf.Header{regionA, regionB}, &osmpbf.Header{}},
+		{"file without replication state", []*osmpbf.Header{regionA, {}}, &osmpbf.Header{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var readers []PBFReader
+			for _, header := range tt.headers {
+				readers = append(readers, &fakePBFReader{header: header})
+			}
+
+			reader, multiFileErr := NewMultiFilePBFReader(readers)
+			require.NoError(t, multiFileErr)
+
+			header, err := reader.Header()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.ReplicationSeqNum, header.ReplicationSeqNum)
+			assert.True(t, tt.want.ReplicationTimestamp.Equal(header.ReplicationTimestamp))
+			assert.Equal(t, tt.want.ReplicationBaseURL, header.ReplicationBaseURL)
+		})
+	}
+}
+
+func TestImport_multipleFiles(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	reader := newTestMultiFilePBFReader(t, testOSMXMLRegionA, testOSMXMLRegionB)
+
+	importer := newFakeImporter()
+	progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+	_, err := Import(context.Background(), logger, reader, mockfs.NewMockFs(), "work", importer, ownmap.GetWholeWorldBounds(), progressTracker)
+	require.NoError(t, err)
+
+	// the objects in both files are only imported once
+	assert.Equal(t, 3, importer.nodesImported)
+	assert.Equal(t, 2, importer.waysImported)
+	assert.Equal(t, 2, importer.relationsImported)
+
+	// way 11 is only in the second file, and has a node that was imported from the first file
+	require.Contains(t, importer.ways, int64(11))
+	assert.Len(t, importer.ways[11].WayPoints, 2)
+
+	assert.Equal(t, DatasetBounds{
+		Bounds: osm.Bounds{MinLat: 51.15, MaxLat: 51.25, MinLon: -0.15, MaxLon: -0.05},
+		Source: ownmap.BOUNDS_SOURCE_DATA_EXTENT,
+		// the union of the header bounds of the files
+		ImportBounds: osm.Bounds{MinLat: 51.1, MaxLat: 51.3, MinLon: -0.2, MaxLon: 0},
+	}, importer.datasetBounds)
+}
+
+func TestImport_multipleFiles_fileFails(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// reading the first file fails part way through, e.g. because it is truncated
+	readers := []PBFReader{
+		&fakePBFReader{header: &osmpbf.Header{}, err: errors.New("unexpected EOF")},
+		newTestPBFReader(t, "b.osm", testOSMXMLRegionB),
+	}
+
+	reader, err := NewMultiFilePBFReader(readers)
+	require.NoError(t, err)
+
+	importer := newFakeImporter()
+	progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+	_, err = Import(context.Background(), logger, reader, mockfs.NewMockFs(), "work", importer, ownmap.GetWholeWorldBounds(), progressTracker)
+	require.Error(t, err)
+
+	// the import doesn't carry on with only some of the files
+	assert.True(t, importer.rolledBack)
+	assert.False(t, importer.committed)
+	assert.Equal(t, 0, importer.nodesImported)
+}
@@ -0,0 +1,203 @@
+package ownmapdal
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"io"
+	"sync/atomic"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/paulmach/osm/osmxml"
+)
+
+type CompressionType int
+
+const (
+	CompressionTypeNone  CompressionType = 0
+	CompressionTypeGzip  CompressionType = 1
+	CompressionTypeBzip2 CompressionType = 2
+)
+
+// countingReader keeps track of how many bytes have been read from the underlying (compressed) file.
+// The count is read from other goroutines (progress reporting), so it is accessed atomically.
+type countingReader struct {
+	reader    io.Reader
+	bytesRead int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	atomic.AddInt64(&r.bytesRead, int64(n))
+	return n, err
+}
+
+func (r *countingReader) BytesRead() int64 {
+	return atomic.LoadInt64(&r.bytesRead)
+}
+
+// OSMXMLReader is a PBFReader for OSM XML files, such as those exported from JOSM or the OSM API.
+// The file can optionally be compressed with gzip or bzip2.
+type OSMXMLReader struct {
+	file         gofs.File
+	compression  CompressionType
+	totalSize    int64
+	counter      *countingReader
+	decompressor io.Closer
+	scanner      *osmxml.Scanner
+	header       *osmpbf.Header
+	// peekedObject is an object that was read while looking for the header, but has not been returned from Scan yet
+	peekedObject osm.Object
+}
+
+func NewOSMXMLReader(file gofs.File, compression CompressionType) (*OSMXMLReader, errorsx.Error) {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	r := &OSMXMLReader{
+		file:        file,
+		compression: compression,
+		totalSize:   fileInfo.Size(),
+	}
+
+	err = r.openScanner()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return r, nil
+}
+
+func (r *OSMXMLReader) openScanner() errorsx.Error {
+	r.counter = &countingReader{reader: r.file}
+	r.decompressor = nil
+
+	var reader io.Reader
+	switch r.compression {
+	case CompressionTypeNone:
+		reader = r.counter
+	case CompressionTypeGzip:
+		gzipReader, err := gzip.NewReader(r.counter)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+		r.decompressor = gzipReader
+		reader = gzipReader
+	case CompressionTypeBzip2:
+		reader = bzip2.NewReader(r.counter)
+	default:
+		return errorsx.Errorf("unknown compression type: %d", r.compression)
+	}
+
+	r.scanner = osmxml.New(context.Background(), reader)
+	return nil
+}
+
+// Header builds a header from the <bounds> element, if the file has one.
+// OSM XML files don't have the other information that a PBF header has.
+func (r *OSMXMLReader) Header() (*osmpbf.Header, error) {
+	if r.header != nil {
+		return r.header, nil
+	}
+
+	r.header = new(osmpbf.Header)
+
+	// the <bounds> element, if present, comes before any of the data elements
+	if !r.scanner.Scan() {
+		return r.header, r.scanner.Err()
+	}
+
+	switch obj := r.scanner.Object().(type) {
+	case *osm.Bounds:
+		r.header.Bounds = obj
+	default:
+		r.peekedObject = obj
+	}
+
+	return r.header, nil
+}
+
+// Scan advances to the next node, way or relation. Other elements (bounds, changesets, notes, users) are skipped over.
+func (r *OSMXMLReader) Scan() bool {
+	if r.peekedObject != nil {
+		if isDataObject(r.peekedObject) {
+			return true
+		}
+		r.peekedObject = nil
+	}
+
+	for r.scanner.Scan() {
+		if isDataObject(r.scanner.Object()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *OSMXMLReader) Object() osm.Object {
+	if r.peekedObject != nil {
+		obj := r.peekedObject
+		r.peekedObject = nil
+		return obj
+	}
+
+	return r.scanner.Object()
+}
+
+func (r *OSMXMLReader) Err() error {
+	return r.scanner.Err()
+}
+
+func (r *OSMXMLReader) Reset() errorsx.Error {
+	err := r.Close()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = r.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	r.peekedObject = nil
+
+	return r.openScanner()
+}
+
+// FullyScannedBytes returns the amount of bytes read from the file so far. For compressed files, this is the amount of compressed bytes read.
+func (r *OSMXMLReader) FullyScannedBytes() int64 {
+	return r.counter.BytesRead()
+}
+
+func (r *OSMXMLReader) TotalSize() int64 {
+	return r.totalSize
+}
+
+// Close closes the scanner and any decompressor. It does not close the underlying file.
+func (r *OSMXMLReader) Close() error {
+	err := r.scanner.Close()
+	if err != nil {
+		return err
+	}
+
+	if r.decompressor != nil {
+		return r.decompressor.Close()
+	}
+
+	return nil
+}
+
+func isDataObject(obj osm.Object) bool {
+	switch obj.(type) {
+	case *osm.Node, *osm.Way, *osm.Relation:
+		return true
+	default:
+		return false
+	}
+}
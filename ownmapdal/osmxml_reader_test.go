@@ -0,0 +1,179 @@
+package ownmapdal
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOSMXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<bounds minlat="51.1" minlon="-0.2" maxlat="51.2" maxlon="-0.1"/>
+	<node id="1" lat="51.15" lon="-0.15">
+		<tag k="place" v="village"/>
+	</node>
+	<node id="2" lat="51.16" lon="-0.16"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<relation id="100">
+		<member type="way" ref="10" role="outer"/>
+		<tag k="type" v="multipolygon"/>
+	</relation>
+</osm>`
+
+func scanAllIDs(t *testing.T, reader PBFReader) []osm.ObjectID {
+	var ids []osm.ObjectID
+	for reader.Scan() {
+		ids = append(ids, reader.Object().ObjectID())
+	}
+	require.NoError(t, reader.Err())
+	return ids
+}
+
+func TestOSMXMLReader(t *testing.T) {
+	gzipBuffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(gzipBuffer)
+	_, err := gzipWriter.Write([]byte(testOSMXML))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	// the standard library can't write bzip2, so the bzip2 file is testOSMXML compressed beforehand
+	bzip2Data, err := ioutil.ReadFile("testdata/test.osm.bz2")
+	require.NoError(t, err)
+	bzip2Contents, err := ioutil.ReadAll(bzip2.NewReader(bytes.NewReader(bzip2Data)))
+	require.NoError(t, err)
+	require.Equal(t, testOSMXML, string(bzip2Contents))
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+	}{
+		{"uncompressed", "test.osm", []byte(testOSMXML)},
+		{"gzip", "test.osm.gz", gzipBuffer.Bytes()},
+		{"bzip2", "test.osm.bz2", bzip2Data},
+	}
+
+	expectedIDs := []osm.ObjectID{
+		osm.NodeID(1).ObjectID(0),
+		osm.NodeID(2).ObjectID(0),
+		osm.WayID(10).ObjectID(0),
+		osm.RelationID(100).ObjectID(0),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := mockfs.NewMockFs()
+			err := fs.WriteFile(tt.fileName, tt.data, 0600)
+			require.NoError(t, err)
+
+			file, err := fs.Open(tt.fileName)
+			require.NoError(t, err)
+			defer file.Close()
+
+			reader, err := NewPBFReaderForFile(file, tt.fileName)
+			require.NoError(t, err)
+			defer reader.Close()
+
+			header, err := reader.Header()
+			require.NoError(t, err)
+			require.NotNil(t, header.Bounds)
+			assert.Equal(t, 51.1, header.Bounds.MinLat)
+			assert.Equal(t, -0.1, header.Bounds.MaxLon)
+
+			assert.Equal(t, expectedIDs, scanAllIDs(t, reader))
+			assert.Equal(t, int64(len(tt.data)), reader.FullyScannedBytes())
+			assert.Equal(t, int64(len(tt.data)), reader.TotalSize())
+
+			// rescan
+			err = reader.Reset()
+			require.NoError(t, err)
+
+			assert.Equal(t, expectedIDs, scanAllIDs(t, reader))
+		})
+	}
+}
+
+func TestSplitRawDataFileSuffix(t *testing.T) {
+	tests := []struct {
+		fileName       string
+		wantName       string
+		wantSuffix     string
+		wantErrNotNull bool
+	}{
+		{"region-latest.osm.pbf", "region-latest.osm", ".pbf", false},
+		{"export.osm", "export", ".osm", false},
+		{"export.osm.bz2", "export", ".osm.bz2", false},
+		{"export.osm.gz", "export", ".osm.gz", false},
+		{"export.txt", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			name, suffix, err := SplitRawDataFileSuffix(tt.fileName)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantSuffix, suffix)
+			assert.Equal(t, tt.wantErrNotNull, err != nil)
+		})
+	}
+}
+
+func TestImport_truncatedFile(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// enough nodes that the file is cut off part way through them, after the header has been read
+	osmXML := bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<bounds minlat="51.1" minlon="-0.2" maxlat="51.2" maxlon="-0.1"/>
+`)
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(osmXML, "\t<node id=\"%d\" lat=\"51.15\" lon=\"-0.15\"/>\n", i)
+	}
+	osmXML.WriteString("</osm>")
+
+	gzipBuffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(gzipBuffer)
+	_, err := gzipWriter.Write(osmXML.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	truncatedData := gzipBuffer.Bytes()[:gzipBuffer.Len()*3/4]
+
+	fs := mockfs.NewMockFs()
+	err = fs.WriteFile("test.osm.gz", truncatedData, 0600)
+	require.NoError(t, err)
+
+	file, err := fs.Open("test.osm.gz")
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := NewPBFReaderForFile(file, "test.osm.gz")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	importer := newFakeImporter()
+	progressTracker := NewImportProgressTracker(reader.TotalSize())
+
+	_, err = Import(context.Background(), logger, reader, fs, "work", importer, ownmap.GetWholeWorldBounds(), progressTracker)
+	require.Error(t, err)
+
+	assert.True(t, importer.rolledBack)
+	assert.False(t, importer.committed)
+
+	// the error is caught in the pass that read the file, rather than only when the file is reopened for the next pass
+	assert.Equal(t, ImportPhaseFirstPass, progressTracker.Progress().Phase)
+}
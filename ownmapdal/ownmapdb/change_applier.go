@@ -0,0 +1,224 @@
+package ownmapdb
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+var _ ownmapdal.ChangeApplier = &ChangeApplier{}
+
+// ChangeApplier applies changes to an ownmapdb file.
+// Since ownmapdb files are sorted and indexed, the file is rewritten: the unchanged objects are copied over from the existing file,
+// and the changed objects are taken from the change. Ways are rebuilt from the (possibly moved) node locations while copying,
+// so the way geometry and tag index stay in sync with the nodes.
+type ChangeApplier struct {
+	logger                   *logpkg.Logger
+	fs                       gofs.Fs
+	workDir, dbFilePath      string
+	ownmapDBFileHandlerLimit uint
+	options                  ImportOptions
+}
+
+func NewChangeApplier(logger *logpkg.Logger, fs gofs.Fs, workDir, dbFilePath string, ownmapDBFileHandlerLimit uint, options ImportOptions) *ChangeApplier {
+	return &ChangeApplier{logger, fs, workDir, dbFilePath, ownmapDBFileHandlerLimit, options}
+}
+
+func (a *ChangeApplier) ApplyChanges(ctx context.Context, changeIndex *ownmapdal.ChangeIndex) (ownmapdal.DataSourceConn, errorsx.Error) {
+	openFileFunc := func() (gofs.File, errorsx.Error) {
+		file, err := a.fs.Open(a.dbFilePath)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		return file, nil
+	}
+
+	existingConn, err := NewMapmakerDBConn(openFileFunc, filepath.Base(a.dbFilePath), 1)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer existingConn.Close()
+
+	datasetInfo, err := existingConn.DatasetInfo()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	bounds := datasetInfo.Bounds.ToOSMBounds()
+	// new nodes are filtered with the bounds the dataset was imported with, not the (usually tighter) extent of the data imported so far,
+	// so that a node added just outside of the existing data is still added
+	boundsForNewObjects := datasetInfo.GetBoundsForNewObjects()
+
+	header := &osmpbf.Header{
+		Bounds:               &boundsForNewObjects,
+		ReplicationTimestamp: changeIndex.ReplicationTimeAfterChange(datasetInfo),
+		ReplicationSeqNum:    changeIndex.ReplicationSequenceNumberAfterChange(datasetInfo),
+		ReplicationBaseURL:   datasetInfo.ReplicationBaseURL,
+	}
+
+	// keep the metadata if the dataset has it, and don't add it if it doesn't
+	options := a.options
+	options.KeepMetadata = datasetInfo.HasMetadata
+
+	importer, err := NewImporter(a.logger, a.fs, a.workDir, a.dbFilePath, a.ownmapDBFileHandlerLimit, header, options)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	var successful bool
+	defer func() {
+		if successful {
+			return
+		}
+
+		err := importer.Rollback()
+		if err != nil {
+			a.logger.Error("error rolling back change import: %q\nStack:\n%s\n", err.Error(), err.Stack())
+		}
+	}()
+
+	err = a.copyWithChanges(ctx, existingConn, importer, changeIndex, boundsForNewObjects)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	// keep the bounds of the existing dataset, and the bounds it was imported with, so that the next changes are filtered in the same way
+	dbConn, err := importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: bounds, Source: datasetInfo.BoundsSource, ImportBounds: boundsForNewObjects})
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	successful = true
+
+	return dbConn, nil
+}
+
+func (a *ChangeApplier) copyWithChanges(ctx context.Context, existingConn *MapmakerDBConn, importer *Importer, changeIndex *ownmapdal.ChangeIndex, boundsForNewObjects osm.Bounds) errorsx.Error {
+	file, err := existingConn.fileHandlerPool.Get()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+	defer existingConn.fileHandlerPool.Release(file)
+
+	// nodes
+	err = existingConn.forEachNode(file, func(node *ownmap.OSMNode) errorsx.Error {
+		if changeIndex.IsNodeChanged(node.ID) && !changeIndex.DropChangeIfNotNewer(osm.NodeID(node.ID).FeatureID(), node.Metadata) {
+			return nil
+		}
+
+		return importer.ImportNode(ctx, node)
+	})
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, obj := range changeIndex.UpsertedNodes {
+		if !boundsForNewObjects.ContainsNode(obj) {
+			// out of bounds (or moved out of bounds), so not part of this dataset
+			continue
+		}
+
+		err = importer.ImportNode(ctx, ownmapdal.NewOSMNodeFromChangeNode(obj))
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	// ways
+	err = existingConn.forEachWay(file, func(way *ownmap.OSMWay) errorsx.Error {
+		if changeIndex.IsWayChanged(way.ID) && !changeIndex.DropChangeIfNotNewer(osm.WayID(way.ID).FeatureID(), way.Metadata) {
+			return nil
+		}
+
+		var nodeIDs []int64
+		for _, wayPoint := range way.WayPoints {
+			nodeIDs = append(nodeIDs, wayPoint.NodeID)
+		}
+
+		return a.importWay(ctx, importer, way.ID, way.Tags, way.Metadata, nodeIDs)
+	})
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, obj := range changeIndex.UpsertedWays {
+		metadata := ownmap.NewOSMObjectMetadata(obj.Version, obj.Timestamp, obj.ChangesetID, obj.User, obj.UserID)
+		err = a.importWay(ctx, importer, int64(obj.ID), ownmap.NewMapmakerTagsFromOSMTags(obj.Tags), metadata, ownmapdal.WayNodeIDs(obj))
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	// relations
+	err = existingConn.forEachRelation(file, func(relation *ownmap.OSMRelation) errorsx.Error {
+		if changeIndex.IsRelationChanged(relation.ID) && !changeIndex.DropChangeIfNotNewer(osm.RelationID(relation.ID).FeatureID(), relation.Metadata) {
+			return nil
+		}
+
+		// member ways may have changed, so re-assemble the area
+		err := ownmapdal.SetRelationAreaGeometry(importer, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		return importer.ImportRelation(ctx, relation)
+	})
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, obj := range changeIndex.UpsertedRelations {
+		relation, err := ownmap.NewMapmakerRelationFromOSMRelation(obj)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		isInDataset, err := ownmapdal.IsAnyRelationMemberInDataset(importer, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		if !isInDataset {
+			continue
+		}
+
+		err = ownmapdal.SetRelationAreaGeometry(importer, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		err = importer.ImportRelation(ctx, relation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+// importWay imports a way with the current locations of its nodes. If none of its nodes are in the dataset any more, it is dropped.
+func (a *ChangeApplier) importWay(ctx context.Context, importer *Importer, id int64, tags []*ownmap.OSMTag, metadata *ownmap.OSMObjectMetadata, nodeIDs []int64) errorsx.Error {
+	wayPoints, err := ownmapdal.ResolveWayPoints(importer, nodeIDs)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	if len(wayPoints) == 0 {
+		a.logger.Debug("dropping way with no nodes in the dataset. ID: %d", id)
+		return nil
+	}
+
+	return importer.ImportWay(ctx, &ownmap.OSMWay{
+		ID:        id,
+		Tags:      tags,
+		WayPoints: wayPoints,
+		Metadata:  metadata,
+	})
+}
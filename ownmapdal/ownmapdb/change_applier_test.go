@@ -0,0 +1,181 @@
+package ownmapdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeApplier_ApplyChanges(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+	fs := mockfs.NewMockFs()
+	const dbFilePath = "/data/test.ownmapdb"
+
+	bounds := &osm.Bounds{MinLat: 50, MaxLat: 52, MinLon: -1, MaxLon: 1}
+
+	// create the initial dataset
+	importer, err := NewImporter(logger, fs, "/import_workdir", dbFilePath, 1, &osmpbf.Header{Bounds: bounds}, ImportOptions{})
+	require.NoError(t, err)
+
+	for _, node := range []*ownmap.OSMNode{
+		{ID: 1, Lat: 51.1, Lon: 0.1, Tags: []*ownmap.OSMTag{{Key: "place", Value: "village"}}},
+		{ID: 2, Lat: 51.2, Lon: 0.2},
+		{ID: 3, Lat: 51.3, Lon: 0.3},
+	} {
+		require.NoError(t, importer.ImportNode(context.Background(), node))
+	}
+
+	for _, way := range []*ownmap.OSMWay{
+		{ID: 10, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "residential"}}, WayPoints: []*ownmap.WayPoint{
+			{NodeID: 1, Point: &ownmap.Location{Lat: 51.1, Lon: 0.1}},
+			{NodeID: 2, Point: &ownmap.Location{Lat: 51.2, Lon: 0.2}},
+		}},
+		{ID: 11, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "track"}}, WayPoints: []*ownmap.WayPoint{
+			{NodeID: 2, Point: &ownmap.Location{Lat: 51.2, Lon: 0.2}},
+			{NodeID: 3, Point: &ownmap.Location{Lat: 51.3, Lon: 0.3}},
+		}},
+	} {
+		require.NoError(t, importer.ImportWay(context.Background(), way))
+	}
+
+	// the dataset bounds are the extent of the data, which is tighter than the bounds it was imported with
+	dataExtent := osm.Bounds{MinLat: 51.1, MaxLat: 51.3, MinLon: 0.1, MaxLon: 0.3}
+	dbConn, err := importer.Commit(context.Background(), ownmapdal.DatasetBounds{Bounds: dataExtent, Source: ownmap.BOUNDS_SOURCE_DATA_EXTENT, ImportBounds: *bounds})
+	require.NoError(t, err)
+	require.NoError(t, dbConn.(*MapmakerDBConn).Close())
+
+	// apply a change: move node 2, delete way 11 and node 3, create a new node outside the data extent but inside the import bounds,
+	// and a new node outside the import bounds
+	changeTime := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+	change := &osm.Change{
+		Modify: &osm.OSM{
+			Nodes: osm.Nodes{
+				{ID: 2, Version: 2, Timestamp: changeTime, Lat: 51.25, Lon: 0.25},
+			},
+		},
+		Create: &osm.OSM{
+			Nodes: osm.Nodes{
+				{ID: 4, Version: 1, Timestamp: changeTime, Lat: 10, Lon: 10, Tags: osm.Tags{{Key: "place", Value: "city"}}},
+				{ID: 5, Version: 1, Timestamp: changeTime, Lat: 50.5, Lon: -0.5, Tags: osm.Tags{{Key: "place", Value: "town"}}},
+			},
+		},
+		Delete: &osm.OSM{
+			Nodes: osm.Nodes{{ID: 3, Version: 2, Timestamp: changeTime}},
+			Ways:  osm.Ways{{ID: 11, Version: 2, Timestamp: changeTime}},
+		},
+	}
+
+	changeApplier := NewChangeApplier(logger, fs, "/change_workdir", dbFilePath, 1, ImportOptions{})
+	newDBConn, err := changeApplier.ApplyChanges(context.Background(), ownmapdal.NewChangeIndex(change))
+	require.NoError(t, err)
+
+	datasetInfo, err := newDBConn.DatasetInfo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(changeTime.UnixNano()/int64(time.Millisecond)), datasetInfo.ReplicationTimeMs)
+	assert.Equal(t, dataExtent, datasetInfo.Bounds.ToOSMBounds())
+	assert.Equal(t, ownmap.BOUNDS_SOURCE_DATA_EXTENT, datasetInfo.BoundsSource)
+	assert.Equal(t, *bounds, datasetInfo.ImportBounds.ToOSMBounds())
+
+	filter := &ownmapdal.GetInBoundsFilter{
+		Objects: []*ownmapdal.TagKeyWithType{
+			{ObjectType: ownmap.ObjectTypeNode, TagKey: "place"},
+			{ObjectType: ownmap.ObjectTypeWay, TagKey: "highway"},
+		},
+	}
+
+	queryBounds := osm.Bounds{MinLat: 51.05, MaxLat: 51.2, MinLon: 0.05, MaxLon: 0.2}
+	nodeMap, wayMap, _, err := newDBConn.GetInBounds(context.Background(), queryBounds, filter)
+	require.NoError(t, err)
+
+	// node 4 is outside of the import bounds, so should not have been added
+	require.Len(t, nodeMap["place"], 1)
+	assert.Equal(t, int64(1), nodeMap["place"][0].ID)
+
+	// node 5 is outside of the data extent, but inside the import bounds, so should have been added
+	nodeMap, _, _, err = newDBConn.GetInBounds(context.Background(), osm.Bounds{MinLat: 50.5, MaxLat: 50.5, MinLon: -0.5, MaxLon: -0.5}, filter)
+	require.NoError(t, err)
+	require.Len(t, nodeMap["place"], 1)
+	assert.Equal(t, int64(5), nodeMap["place"][0].ID)
+
+	require.Len(t, wayMap["highway"], 1)
+	way := wayMap["highway"][0]
+	assert.Equal(t, int64(10), way.ID)
+	require.Len(t, way.WayPoints, 2)
+	// the way should have picked up the new location of node 2
+	assert.Equal(t, &ownmap.Location{Lat: 51.25, Lon: 0.25}, way.WayPoints[1].Point)
+}
+
+func TestChangeApplier_ApplyChanges_withMetadata(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+	fs := mockfs.NewMockFs()
+	const dbFilePath = "/data/test.ownmapdb"
+
+	bounds := &osm.Bounds{MinLat: 50, MaxLat: 52, MinLon: -1, MaxLon: 1}
+	importTime := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	importer, err := NewImporter(logger, fs, "/import_workdir", dbFilePath, 1, &osmpbf.Header{Bounds: bounds}, ImportOptions{KeepMetadata: true})
+	require.NoError(t, err)
+
+	for _, node := range []*ownmap.OSMNode{
+		{ID: 1, Lat: 51.1, Lon: 0.1, Tags: []*ownmap.OSMTag{{Key: "place", Value: "village"}}, Metadata: ownmap.NewOSMObjectMetadata(3, importTime, 100, "mapper", 1)},
+		{ID: 2, Lat: 51.2, Lon: 0.2, Tags: []*ownmap.OSMTag{{Key: "place", Value: "hamlet"}}, Metadata: ownmap.NewOSMObjectMetadata(3, importTime, 100, "mapper", 1)},
+	} {
+		require.NoError(t, importer.ImportNode(context.Background(), node))
+	}
+
+	dbConn, err := importer.Commit(context.Background(), ownmapdal.DatasetBounds{Bounds: *bounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: *bounds})
+	require.NoError(t, err)
+	require.NoError(t, dbConn.(*MapmakerDBConn).Close())
+
+	// node 1's change is older than what is in the dataset already (e.g. from an overlapping change file), so should be skipped. Node 2's change is newer.
+	changeTime := time.Date(2021, 1, 3, 12, 0, 0, 0, time.UTC)
+	change := &osm.Change{
+		Modify: &osm.OSM{
+			Nodes: osm.Nodes{
+				{ID: 1, Version: 2, Timestamp: importTime.Add(-time.Hour), Lat: 51.15, Lon: 0.15, Tags: osm.Tags{{Key: "place", Value: "village"}}},
+				{ID: 2, Version: 4, Timestamp: changeTime, ChangesetID: 200, User: "other_mapper", UserID: 2, Lat: 51.25, Lon: 0.25, Tags: osm.Tags{{Key: "place", Value: "hamlet"}}},
+			},
+		},
+	}
+
+	// the dataset decides whether the metadata is kept, not the options
+	changeApplier := NewChangeApplier(logger, fs, "/change_workdir", dbFilePath, 1, ImportOptions{})
+	newDBConn, err := changeApplier.ApplyChanges(context.Background(), ownmapdal.NewChangeIndex(change))
+	require.NoError(t, err)
+
+	datasetInfo, err := newDBConn.DatasetInfo()
+	require.NoError(t, err)
+	assert.True(t, datasetInfo.HasMetadata)
+
+	filter := &ownmapdal.GetInBoundsFilter{
+		Objects: []*ownmapdal.TagKeyWithType{
+			{ObjectType: ownmap.ObjectTypeNode, TagKey: "place"},
+		},
+	}
+
+	getNode := func(queryBounds osm.Bounds) *ownmap.OSMNode {
+		nodeMap, _, _, err := newDBConn.GetInBounds(context.Background(), queryBounds, filter)
+		require.NoError(t, err)
+		require.Len(t, nodeMap["place"], 1)
+		return nodeMap["place"][0]
+	}
+
+	node1 := getNode(osm.Bounds{MinLat: 51.09, MaxLat: 51.16, MinLon: 0.09, MaxLon: 0.16})
+	assert.Equal(t, int64(1), node1.ID)
+	assert.Equal(t, 51.1, node1.Lat)
+	assert.Equal(t, int64(3), node1.Metadata.Version)
+
+	node2 := getNode(osm.Bounds{MinLat: 51.24, MaxLat: 51.25, MinLon: 0.24, MaxLon: 0.25})
+	assert.Equal(t, int64(2), node2.ID)
+	assert.Equal(t, ownmap.NewOSMObjectMetadata(4, changeTime, 200, "other_mapper", 2), node2.Metadata)
+}
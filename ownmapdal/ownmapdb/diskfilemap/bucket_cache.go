@@ -0,0 +1,102 @@
+package diskfilemap
+
+import (
+	"container/list"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	ownmap "github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// kvPairOverheadBytes is a rough guess of the memory used by a key-value pair, on top of the key and value bytes (the pointer to it, the struct and the slice headers)
+const kvPairOverheadBytes = 80
+
+type cacheEntryType struct {
+	Path   string
+	Sorted bool
+	// Dirty is true if the bucket has been changed since it was read from (or written to) disk
+	Dirty bool
+	// Size is the estimated memory used by the bucket data, in bytes
+	Size int64
+	Data *BucketData
+}
+
+type writeBucketFuncType func(entry *cacheEntryType) errorsx.Error
+
+// bucketCache keeps the most recently used buckets in memory, up to a maximum estimated size in bytes.
+// When it is over the maximum, the least recently used buckets are written to disk (if they have changed) and dropped from the cache.
+// The most recently used bucket is always kept, even if it alone is over the maximum.
+type bucketCache struct {
+	maxBytes    int64
+	totalBytes  int64
+	entries     map[string]*list.Element
+	lru         *list.List // front is the most recently used
+	writeBucket writeBucketFuncType
+}
+
+func newBucketCache(maxBytes int64, writeBucket writeBucketFuncType) *bucketCache {
+	return &bucketCache{
+		maxBytes:    maxBytes,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		writeBucket: writeBucket,
+	}
+}
+
+// get returns the cached bucket and marks it as the most recently used, or nil if the bucket isn't in the cache
+func (c *bucketCache) get(path string) *cacheEntryType {
+	element, ok := c.entries[path]
+	if !ok {
+		return nil
+	}
+
+	c.lru.MoveToFront(element)
+	return element.Value.(*cacheEntryType)
+}
+
+// add puts the bucket into the cache as the most recently used, and evicts other buckets if the cache is too big
+func (c *bucketCache) add(entry *cacheEntryType) errorsx.Error {
+	c.entries[entry.Path] = c.lru.PushFront(entry)
+	c.totalBytes += entry.Size
+
+	return c.evict()
+}
+
+// addSize changes the size of a bucket in the cache (after items have been added or changed), and evicts other buckets if the cache is too big
+func (c *bucketCache) addSize(entry *cacheEntryType, sizeDelta int64) errorsx.Error {
+	entry.Size += sizeDelta
+	c.totalBytes += sizeDelta
+
+	return c.evict()
+}
+
+func (c *bucketCache) evict() errorsx.Error {
+	for c.totalBytes > c.maxBytes && c.lru.Len() > 1 {
+		element := c.lru.Back()
+		entry := element.Value.(*cacheEntryType)
+
+		if entry.Dirty {
+			err := c.writeBucket(entry)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		}
+
+		c.lru.Remove(element)
+		delete(c.entries, entry.Path)
+		c.totalBytes -= entry.Size
+	}
+
+	return nil
+}
+
+func estimateKVPairSize(kvPair *ownmap.KVPair) int64 {
+	return int64(len(kvPair.Key) + len(kvPair.Value) + kvPairOverheadBytes)
+}
+
+func estimateBucketDataSize(bucketData *BucketData) int64 {
+	var size int64
+	for _, kvPair := range bucketData.Items {
+		size += estimateKVPairSize(kvPair)
+	}
+	return size
+}
@@ -0,0 +1,48 @@
+package diskfilemap
+
+import (
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bucketCache(t *testing.T) {
+	var written []string
+	cache := newBucketCache(100, func(entry *cacheEntryType) errorsx.Error {
+		written = append(written, entry.Path)
+		return nil
+	})
+
+	err := cache.add(&cacheEntryType{Path: "a", Size: 40, Dirty: true})
+	require.NoError(t, err)
+	err = cache.add(&cacheEntryType{Path: "b", Size: 40})
+	require.NoError(t, err)
+
+	// use "a", so that "b" is the least recently used
+	require.NotNil(t, cache.get("a"))
+
+	err = cache.add(&cacheEntryType{Path: "c", Size: 40})
+	require.NoError(t, err)
+
+	// "b" is evicted. It wasn't changed, so it isn't written
+	assert.Nil(t, cache.get("b"))
+	assert.Empty(t, written)
+	assert.Equal(t, int64(80), cache.totalBytes)
+
+	// growing "c" evicts "a", which has been changed, so it is written to disk
+	entryC := cache.get("c")
+	require.NotNil(t, entryC)
+	err = cache.addSize(entryC, 30)
+	require.NoError(t, err)
+
+	assert.Nil(t, cache.get("a"))
+	assert.Equal(t, []string{"a"}, written)
+	assert.Equal(t, int64(70), cache.totalBytes)
+
+	// the most recently used bucket is kept, even when it's bigger than the whole cache
+	err = cache.addSize(entryC, 1000)
+	require.NoError(t, err)
+	assert.NotNil(t, cache.get("c"))
+}
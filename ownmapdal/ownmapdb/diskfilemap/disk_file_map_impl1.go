@@ -21,39 +21,31 @@ var (
 )
 
 type DiskCollection struct {
-	fs                               gofs.Fs
-	basePath                         string
-	bucketFunc                       BucketPolicyFunc
-	bucketSortIsKey1Larger           IsKey1LargerThanKey2Func
-	bucketNames                      []BucketName
-	cachedDiskFiles                  map[string]*cacheEntryType
-	cacheFlushAfterXWrites           int
-	cacheCurrentWritesSinceLastFlush int
+	fs                     gofs.Fs
+	basePath               string
+	bucketFunc             BucketPolicyFunc
+	bucketSortIsKey1Larger IsKey1LargerThanKey2Func
+	bucketNames            []BucketName
+	cache                  *bucketCache
 }
 
-const defaultCacheFlushAfterXWrites = 1000
-
-type cacheEntryType struct {
-	Sorted bool
-	Data   *BucketData
-}
-
-func NewDiskCollection(fs gofs.Fs, basePath string, bucketFunc BucketPolicyFunc, bucketSortIsKey1Larger IsKey1LargerThanKey2Func) (*DiskCollection, errorsx.Error) {
+// NewDiskCollection creates a collection that keeps its buckets in files in basePath.
+// Up to maxCacheBytes (estimated) of the most recently used buckets are kept in memory, for both reads and writes.
+func NewDiskCollection(fs gofs.Fs, basePath string, bucketFunc BucketPolicyFunc, bucketSortIsKey1Larger IsKey1LargerThanKey2Func, maxCacheBytes int64) (*DiskCollection, errorsx.Error) {
 	err := fs.MkdirAll(basePath, 0700)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	return &DiskCollection{
-		fs,
-		basePath,
-		bucketFunc,
-		bucketSortIsKey1Larger,
-		nil,
-		make(map[string]*cacheEntryType),
-		defaultCacheFlushAfterXWrites,
-		0,
-	}, nil
+	dc := &DiskCollection{
+		fs:                     fs,
+		basePath:               basePath,
+		bucketFunc:             bucketFunc,
+		bucketSortIsKey1Larger: bucketSortIsKey1Larger,
+	}
+	dc.cache = newBucketCache(maxCacheBytes, dc.writeBucket)
+
+	return dc, nil
 }
 
 func (dc *DiskCollection) getIndexOfKey(bucketData *BucketData, key []byte) (int, error) {
@@ -101,7 +93,7 @@ func (dc *DiskCollection) Get(key []byte) ([]byte, error) {
 		return nil, errorsx.Wrap(err)
 	}
 
-	bucketData, _, err := dc.getBucketData(bucketName)
+	cacheEntry, err := dc.getSortedCacheEntry(bucketName)
 	if err != nil {
 		if errorsx.Cause(err) == ErrBucketNotFound {
 			return nil, errorsx.ObjectNotFound
@@ -109,12 +101,12 @@ func (dc *DiskCollection) Get(key []byte) ([]byte, error) {
 		return nil, errorsx.Wrap(err)
 	}
 
-	idx, err := dc.getIndexOfKey(bucketData, key)
+	idx, err := dc.getIndexOfKey(cacheEntry.Data, key)
 	if err != nil {
 		return nil, err
 	}
 
-	return bucketData.Items[idx].Value, nil
+	return cacheEntry.Data.Items[idx].Value, nil
 }
 
 func (dc *DiskCollection) Set(key, value []byte) errorsx.Error {
@@ -125,16 +117,34 @@ func (dc *DiskCollection) Set(key, value []byte) errorsx.Error {
 		return errorsx.Wrap(err)
 	}
 
-	bucketData, bucketFromCache, err := dc.getBucketData(bucketName)
+	cacheEntry, err := dc.getSortedCacheEntry(bucketName)
 	if err != nil {
 		if errorsx.Cause(err) != ErrBucketNotFound {
-			return errorsx.Wrap(err, "bucketFromCache", bucketFromCache)
+			return errorsx.Wrap(err)
 		}
+
 		// create a new bucket
+		pathToThisDataFile, err := dc.getPathToBucketFile(bucketName)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
 		dc.bucketNames = append(dc.bucketNames, bucketName)
-		bucketData = new(BucketData)
+		cacheEntry = &cacheEntryType{
+			Path:   pathToThisDataFile,
+			Sorted: true,
+			Data:   new(BucketData),
+		}
+
+		err = dc.cache.add(cacheEntry)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
 	}
 
+	bucketData := cacheEntry.Data
+	cacheEntry.Dirty = true
+
 	// see if there is an existing one
 	idx, err := dc.getIndexOfKey(bucketData, key)
 	if err != nil {
@@ -144,63 +154,45 @@ func (dc *DiskCollection) Set(key, value []byte) errorsx.Error {
 		}
 
 		// value didn't exist before, so append to the existing Items
-		bucketData.Items = append(bucketData.Items, &ownmap.KVPair{
+		kvPair := &ownmap.KVPair{
 			Key:   key,
 			Value: value,
-		})
-	} else {
-		// value already exists, so go through the list and change the one that previously existed
-		bucketData.Items[idx].Value = value
-	}
-
-	dc.cacheCurrentWritesSinceLastFlush++
+		}
+		bucketData.Items = append(bucketData.Items, kvPair)
+		cacheEntry.Sorted = false
 
-	pathToThisDataFile, err := dc.getPathToBucketFile(bucketName)
-	if err != nil {
-		return errorsx.Wrap(err)
+		return dc.cache.addSize(cacheEntry, estimateKVPairSize(kvPair))
 	}
 
-	if dc.cacheCurrentWritesSinceLastFlush >= dc.cacheFlushAfterXWrites {
-		// flush all other items to disk from the cache, onto disk
-		for pathToDataFile, cacheEntry := range dc.cachedDiskFiles {
-			if pathToDataFile == pathToThisDataFile {
-				// the one we are operating on - ignore.
-				// quite often the same bucket is inserted into a number of times in a row.
-				// So keep this one in memory.
-				continue
-			}
-
-			if !cacheEntry.Sorted {
-				err = dc.sortBucketData(cacheEntry.Data)
-				if err != nil {
-					return errorsx.Wrap(err)
-				}
-			}
+	// value already exists, so go through the list and change the one that previously existed
+	sizeDelta := int64(len(value) - len(bucketData.Items[idx].Value))
+	bucketData.Items[idx].Value = value
 
-			b, err := proto.Marshal(cacheEntry.Data)
-			if err != nil {
-				return errorsx.Wrap(err)
-			}
-
-			err = dc.fs.WriteFile(pathToDataFile, b, 0600)
-			if err != nil {
-				return errorsx.Wrap(err)
-			}
+	return dc.cache.addSize(cacheEntry, sizeDelta)
+}
 
-			// remove item from cache
-			delete(dc.cachedDiskFiles, pathToDataFile)
+// writeBucket writes the bucket to its file on disk
+func (dc *DiskCollection) writeBucket(cacheEntry *cacheEntryType) errorsx.Error {
+	if !cacheEntry.Sorted {
+		err := dc.sortBucketData(cacheEntry.Data)
+		if err != nil {
+			return errorsx.Wrap(err)
 		}
-
-		dc.cacheCurrentWritesSinceLastFlush = 0
+		cacheEntry.Sorted = true
 	}
 
-	// now add this item to the cache
+	b, err := proto.Marshal(cacheEntry.Data)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
 
-	dc.cachedDiskFiles[pathToThisDataFile] = &cacheEntryType{
-		Sorted: false,
-		Data:   bucketData,
+	err = dc.fs.WriteFile(cacheEntry.Path, b, 0600)
+	if err != nil {
+		return errorsx.Wrap(err)
 	}
 
+	cacheEntry.Dirty = false
+
 	return nil
 }
 
@@ -276,32 +268,12 @@ func (dci *DiskCollectionIterator) NextBucket() bool {
 func (dci *DiskCollectionIterator) GetAllFromCurrentBucketAscending() ([]*ownmap.KVPair, errorsx.Error) {
 	bucketName := dci.sortedBucketNames[dci.currentBucketIndex]
 
-	bucketData, _, err := dci.diskCollection.getBucketData(bucketName)
+	cacheEntry, err := dci.diskCollection.getSortedCacheEntry(bucketName)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	kvPairs := bucketData.Items
-
-	var sortErr errorsx.Error
-	sort.Slice(kvPairs, func(i, j int) bool {
-		isKey1Larger, err := dci.diskCollection.bucketSortIsKey1Larger(kvPairs[i].Key, kvPairs[j].Key)
-		if err != nil {
-			sortErr = err
-			return false
-		}
-
-		if isKey1Larger {
-			return false
-		}
-
-		return true
-	})
-	if sortErr != nil {
-		return nil, sortErr
-	}
-
-	return kvPairs, nil
+	return cacheEntry.Data.Items, nil
 }
 
 func (dc *DiskCollection) getPathToBucketFile(bucketName BucketName) (string, errorsx.Error) {
@@ -320,46 +292,60 @@ func (dc *DiskCollection) getPathToBucketFile(bucketName BucketName) (string, er
 	return filepath.Join(dc.basePath, bb.String()), nil
 }
 
-// data, from cache, err
-func (dc *DiskCollection) getBucketData(bucketName BucketName) (*BucketData, bool, error) {
+// getSortedCacheEntry gets the bucket from the cache, or reads it from disk into the cache. The items in the bucket are sorted.
+// Returns ErrBucketNotFound if the bucket doesn't exist yet.
+func (dc *DiskCollection) getSortedCacheEntry(bucketName BucketName) (*cacheEntryType, error) {
 	var err error
 
 	bucketFilePath, err := dc.getPathToBucketFile(bucketName)
 	if err != nil {
-		return nil, false, errorsx.Wrap(err)
+		return nil, errorsx.Wrap(err)
 	}
 
 	// first, try from cache
-	cacheEntry, ok := dc.cachedDiskFiles[bucketFilePath]
-	if ok {
+	cacheEntry := dc.cache.get(bucketFilePath)
+	if cacheEntry != nil {
 		// cache hit! Now sort items if necessary and return data
 		if !cacheEntry.Sorted {
 			err = dc.sortBucketData(cacheEntry.Data)
 			if err != nil {
-				return nil, false, errorsx.Wrap(err)
+				return nil, errorsx.Wrap(err)
 			}
 			cacheEntry.Sorted = true
 		}
 
-		return cacheEntry.Data, true, nil
+		return cacheEntry, nil
 	}
 
 	bucketFile, err := dc.fs.Open(bucketFilePath)
 	if err != nil {
-		return nil, false, ErrBucketNotFound
+		return nil, ErrBucketNotFound
 	}
 	defer bucketFile.Close()
 
 	b, err := ioutil.ReadAll(bucketFile)
 	if err != nil {
-		return nil, false, errorsx.Wrap(err)
+		return nil, errorsx.Wrap(err)
 	}
 
 	bucketData := new(BucketData)
 	err = proto.Unmarshal(b, bucketData)
 	if err != nil {
-		return nil, false, errorsx.Wrap(err)
+		return nil, errorsx.Wrap(err)
+	}
+
+	// buckets are sorted before they are written to disk
+	cacheEntry = &cacheEntryType{
+		Path:   bucketFilePath,
+		Sorted: true,
+		Size:   estimateBucketDataSize(bucketData),
+		Data:   bucketData,
+	}
+
+	err = dc.cache.add(cacheEntry)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
 	}
 
-	return bucketData, false, nil
+	return cacheEntry, nil
 }
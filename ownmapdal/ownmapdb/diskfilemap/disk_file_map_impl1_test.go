@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+const testMaxCacheBytes = 1024 * 1024
+
 func TestDiskCollection_Get(t *testing.T) {
 	bucketFunc := func(key []byte) (BucketName, errorsx.Error) {
 		keyAsUint := binary.LittleEndian.Uint64(key)
@@ -29,7 +31,7 @@ func TestDiskCollection_Get(t *testing.T) {
 	t.Run("1 item test", func(t *testing.T) {
 		var err error
 
-		collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc)
+		collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc, testMaxCacheBytes)
 		require.NoError(t, err)
 
 		// put in key1
@@ -49,7 +51,7 @@ func TestDiskCollection_Get(t *testing.T) {
 	t.Run("2 items in different buckets", func(t *testing.T) {
 		var err error
 
-		collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc)
+		collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc, testMaxCacheBytes)
 		require.NoError(t, err)
 
 		// key 1
@@ -96,7 +98,7 @@ func Test_Set(t *testing.T) {
 	t.Run("overwrite", func(t *testing.T) {
 		var err error
 
-		collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc)
+		collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc, testMaxCacheBytes)
 		require.NoError(t, err)
 
 		// key 1
@@ -119,3 +121,78 @@ func Test_Set(t *testing.T) {
 		assert.Equal(t, val2, fetched)
 	})
 }
+
+func TestDiskCollection_cacheEviction(t *testing.T) {
+	bucketFunc := func(key []byte) (BucketName, errorsx.Error) {
+		keyAsUint := binary.LittleEndian.Uint64(key)
+
+		// bucket per 10 items
+		bucketVal := (keyAsUint / 10) * 10
+		bucketName := make([]byte, 8)
+		binary.LittleEndian.PutUint64(bucketName, bucketVal)
+		return bucketName, nil
+	}
+	isKey1LargerFunc := func(key1, key2 []byte) (bool, errorsx.Error) {
+		val1 := binary.LittleEndian.Uint64(key1)
+		val2 := binary.LittleEndian.Uint64(key2)
+		return val1 > val2, nil
+	}
+
+	makeKey := func(i uint64) []byte {
+		key := make([]byte, 8)
+		binary.LittleEndian.PutUint64(key, i)
+		return key
+	}
+
+	var err error
+
+	// room for roughly one bucket, so buckets are written to disk and read back in all the time
+	const maxCacheBytes = 10 * (8 + 8 + kvPairOverheadBytes)
+
+	collection, err := NewDiskCollection(mockfs.NewMockFs(), "/tmp/", bucketFunc, isKey1LargerFunc, maxCacheBytes)
+	require.NoError(t, err)
+
+	// insert out of order, across buckets
+	const itemCount = 100
+	for i := uint64(0); i < itemCount; i++ {
+		id := (i * 37) % itemCount
+		err = collection.Set(makeKey(id), makeKey(id*2))
+		require.NoError(t, err)
+
+		assert.LessOrEqual(t, collection.cache.totalBytes, int64(2*maxCacheBytes))
+	}
+
+	// overwrite a value in a bucket that has been written to disk
+	err = collection.Set(makeKey(5), []byte("overwritten"))
+	require.NoError(t, err)
+
+	for i := uint64(0); i < itemCount; i++ {
+		value, err := collection.Get(makeKey(i))
+		require.NoError(t, err)
+
+		if i == 5 {
+			assert.Equal(t, []byte("overwritten"), value)
+			continue
+		}
+		assert.Equal(t, makeKey(i*2), value)
+	}
+
+	_, err = collection.Get(makeKey(itemCount + 50))
+	assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+
+	// iterating goes through the buckets in order, with the items in each bucket in order
+	iterator, err := collection.Iterator()
+	require.NoError(t, err)
+
+	var next uint64
+	for iterator.NextBucket() {
+		kvPairs, err := iterator.GetAllFromCurrentBucketAscending()
+		require.NoError(t, err)
+
+		for _, kvPair := range kvPairs {
+			assert.Equal(t, next, binary.LittleEndian.Uint64(kvPair.Key))
+			next++
+		}
+	}
+	assert.Equal(t, uint64(itemCount), next)
+}
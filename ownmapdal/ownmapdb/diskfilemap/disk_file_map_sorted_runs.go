@@ -0,0 +1,621 @@
+package diskfilemap
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	ownmap "github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+const (
+	// sortedRunsMergeFactor is how many runs of the same level there can be, before they are merged into one run of the next level
+	sortedRunsMergeFactor = 8
+	// sortedRunIndexIntervalBytes is (roughly) how far apart the keys in the in-memory index of each run are. A lookup reads at most this much of a run.
+	sortedRunIndexIntervalBytes = 64 * 1024
+	// sortedRunsIteratorBucketSize is how many items the iterator returns at a time
+	sortedRunsIteratorBucketSize = 1024
+)
+
+// AppendableCollection is an OnDiskCollection that can add to a value without reading it first.
+// The appended bytes are concatenated to the existing value, so this only makes sense for values where that gives a valid value,
+// such as protobuf messages where repeated fields are being added to (and other fields are the same every time).
+type AppendableCollection interface {
+	OnDiskCollection
+	Append(key, value []byte) errorsx.Error
+}
+
+type sortedRunRecordKind byte
+
+const (
+	// sortedRunRecordKindSet replaces any older value
+	sortedRunRecordKindSet sortedRunRecordKind = 1
+	// sortedRunRecordKindAppend is added to the end of the older value, if there is one
+	sortedRunRecordKindAppend sortedRunRecordKind = 2
+)
+
+type sortedRunRecord struct {
+	Kind       sortedRunRecordKind
+	Key, Value []byte
+}
+
+// combineSortedRunRecords combines an older and a newer record for the same key
+func combineSortedRunRecords(older, newer *sortedRunRecord) *sortedRunRecord {
+	if newer.Kind == sortedRunRecordKindSet {
+		return newer
+	}
+
+	value := make([]byte, 0, len(older.Value)+len(newer.Value))
+	value = append(value, older.Value...)
+	value = append(value, newer.Value...)
+
+	return &sortedRunRecord{older.Kind, older.Key, value}
+}
+
+type sortedRunIndexEntry struct {
+	Key    []byte
+	Offset int64
+}
+
+// sortedRun is a file of records, sorted by key. Once written, it is only read (and eventually merged into a bigger run and removed).
+type sortedRun struct {
+	Path  string
+	Level int
+	// Index has the key and offset of a record every sortedRunIndexIntervalBytes or so, starting with the first record
+	Index []sortedRunIndexEntry
+}
+
+// SortedRunsCollection is an OnDiskCollection that writes sorted runs of items to disk, and merges them together (an external merge sort) when iterating over them.
+// Writes are only ever appended to the in-memory buffer, so they stay fast however big a bucket of DiskCollection would have become.
+// Lookups are slower than in DiskCollection, since they might have to look in several runs.
+type SortedRunsCollection struct {
+	fs             gofs.Fs
+	basePath       string
+	isKey1Larger   IsKey1LargerThanKey2Func
+	maxBufferBytes int64
+
+	buffer      map[string]*sortedRunRecord
+	bufferBytes int64
+	// runs are in order of when they were written, oldest first. Newer runs have newer values for the same key.
+	runs      []*sortedRun
+	nextRunID int
+}
+
+// NewSortedRunsCollection creates a collection that keeps its sorted runs in basePath.
+// Up to maxBufferBytes (estimated) of items are kept in memory before being written to disk as a new run.
+func NewSortedRunsCollection(fs gofs.Fs, basePath string, isKey1Larger IsKey1LargerThanKey2Func, maxBufferBytes int64) (*SortedRunsCollection, errorsx.Error) {
+	err := fs.MkdirAll(basePath, 0700)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return &SortedRunsCollection{
+		fs:             fs,
+		basePath:       basePath,
+		isKey1Larger:   isKey1Larger,
+		maxBufferBytes: maxBufferBytes,
+		buffer:         make(map[string]*sortedRunRecord),
+	}, nil
+}
+
+func (c *SortedRunsCollection) Set(key, value []byte) errorsx.Error {
+	return c.addToBuffer(sortedRunRecordKindSet, key, value)
+}
+
+// Append adds the value to the end of the existing value for the key, or sets it if there isn't one
+func (c *SortedRunsCollection) Append(key, value []byte) errorsx.Error {
+	return c.addToBuffer(sortedRunRecordKindAppend, key, value)
+}
+
+func (c *SortedRunsCollection) addToBuffer(kind sortedRunRecordKind, key, value []byte) errorsx.Error {
+	record := &sortedRunRecord{kind, copyBytes(key), copyBytes(value)}
+
+	existing, ok := c.buffer[string(key)]
+	if ok {
+		c.bufferBytes -= estimateSortedRunRecordSize(existing)
+		record = combineSortedRunRecords(existing, record)
+	}
+
+	c.buffer[string(key)] = record
+	c.bufferBytes += estimateSortedRunRecordSize(record)
+
+	if c.bufferBytes < c.maxBufferBytes {
+		return nil
+	}
+
+	return c.flushBuffer()
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+func estimateSortedRunRecordSize(record *sortedRunRecord) int64 {
+	return int64(len(record.Key)+len(record.Value)) + kvPairOverheadBytes
+}
+
+func (c *SortedRunsCollection) sortRecords(records []*sortedRunRecord) errorsx.Error {
+	var sortErr errorsx.Error
+
+	sort.Slice(records, func(i, j int) bool {
+		isKey1Larger, err := c.isKey1Larger(records[i].Key, records[j].Key)
+		if err != nil {
+			sortErr = err
+			return false
+		}
+
+		return !isKey1Larger
+	})
+
+	return sortErr
+}
+
+// flushBuffer writes the buffer out as a new run, and merges runs if there are enough of the same level
+func (c *SortedRunsCollection) flushBuffer() errorsx.Error {
+	if len(c.buffer) == 0 {
+		return nil
+	}
+
+	records := make([]*sortedRunRecord, 0, len(c.buffer))
+	for _, record := range c.buffer {
+		records = append(records, record)
+	}
+
+	err := c.sortRecords(records)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	i := 0
+	run, err := c.writeRun(0, func() (*sortedRunRecord, errorsx.Error) {
+		if i == len(records) {
+			return nil, nil
+		}
+		i++
+		return records[i-1], nil
+	})
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	c.runs = append(c.runs, run)
+	c.buffer = make(map[string]*sortedRunRecord)
+	c.bufferBytes = 0
+
+	return c.mergeRunsIfNeeded()
+}
+
+// mergeRunsIfNeeded merges the newest runs together, while there are sortedRunsMergeFactor of them at the same level.
+// Only the newest runs are merged, so the runs stay in the order they were written.
+func (c *SortedRunsCollection) mergeRunsIfNeeded() errorsx.Error {
+	for {
+		level := c.runs[len(c.runs)-1].Level
+
+		sameLevelCount := 0
+		for i := len(c.runs) - 1; i >= 0 && c.runs[i].Level == level; i-- {
+			sameLevelCount++
+		}
+
+		if sameLevelCount < sortedRunsMergeFactor {
+			return nil
+		}
+
+		firstMergedIdx := len(c.runs) - sameLevelCount
+		runsToMerge := c.runs[firstMergedIdx:]
+
+		merger, err := newSortedRunsMerger(c.fs, runsToMerge, c.isKey1Larger)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		mergedRun, err := c.writeRun(level+1, merger.next)
+		closeErr := merger.close()
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+		if closeErr != nil {
+			return errorsx.Wrap(closeErr)
+		}
+
+		for _, run := range runsToMerge {
+			removeErr := c.fs.Remove(run.Path)
+			if removeErr != nil {
+				return errorsx.Wrap(removeErr)
+			}
+		}
+
+		c.runs = append(c.runs[:firstMergedIdx], mergedRun)
+	}
+}
+
+// writeRun writes the records (which must come in sorted order) to a new run file
+func (c *SortedRunsCollection) writeRun(level int, nextRecord func() (*sortedRunRecord, errorsx.Error)) (*sortedRun, errorsx.Error) {
+	var err error
+
+	run := &sortedRun{
+		Path:  filepath.Join(c.basePath, fmt.Sprintf("run_%d", c.nextRunID)),
+		Level: level,
+	}
+	c.nextRunID++
+
+	file, err := c.fs.Create(run.Path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	var offset int64
+	lastIndexedOffset := int64(-sortedRunIndexIntervalBytes)
+	for {
+		record, err := nextRecord()
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		if record == nil {
+			break
+		}
+
+		if offset-lastIndexedOffset >= sortedRunIndexIntervalBytes {
+			run.Index = append(run.Index, sortedRunIndexEntry{record.Key, offset})
+			lastIndexedOffset = offset
+		}
+
+		n, err := writeSortedRunRecord(writer, record)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		offset += n
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return run, nil
+}
+
+// records are written as: kind (1 byte), key length (uvarint), key, value length (uvarint), value
+func writeSortedRunRecord(writer io.Writer, record *sortedRunRecord) (int64, errorsx.Error) {
+	header := make([]byte, 1+binary.MaxVarintLen64)
+	header[0] = byte(record.Kind)
+	n := 1 + binary.PutUvarint(header[1:], uint64(len(record.Key)))
+
+	valueLen := make([]byte, binary.MaxVarintLen64)
+	valueLenSize := binary.PutUvarint(valueLen, uint64(len(record.Value)))
+
+	for _, b := range [][]byte{header[:n], record.Key, valueLen[:valueLenSize], record.Value} {
+		_, err := writer.Write(b)
+		if err != nil {
+			return 0, errorsx.Wrap(err)
+		}
+	}
+
+	return int64(n + len(record.Key) + valueLenSize + len(record.Value)), nil
+}
+
+// readSortedRunRecord reads the next record. It returns nil (and no error) at the end of the run.
+func readSortedRunRecord(reader *bufio.Reader) (*sortedRunRecord, errorsx.Error) {
+	kind, err := reader.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, errorsx.Wrap(err)
+	}
+
+	record := &sortedRunRecord{Kind: sortedRunRecordKind(kind)}
+	for _, field := range []*[]byte{&record.Key, &record.Value} {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		*field = make([]byte, length)
+		_, err = io.ReadFull(reader, *field)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	return record, nil
+}
+
+func (c *SortedRunsCollection) Get(key []byte) ([]byte, error) {
+	// collect the records for the key, from the newest to the oldest, until one that sets the value
+	var records []*sortedRunRecord
+
+	record, ok := c.buffer[string(key)]
+	if ok {
+		records = append(records, record)
+	}
+
+	for i := len(c.runs) - 1; i >= 0; i-- {
+		if len(records) != 0 && records[len(records)-1].Kind == sortedRunRecordKindSet {
+			break
+		}
+
+		record, err := c.getFromRun(c.runs[i], key)
+		if err != nil {
+			if errorsx.Cause(err) == errorsx.ObjectNotFound {
+				continue
+			}
+			return nil, errorsx.Wrap(err)
+		}
+
+		records = append(records, record)
+	}
+
+	if len(records) == 0 {
+		return nil, errorsx.ObjectNotFound
+	}
+
+	combined := records[len(records)-1]
+	for i := len(records) - 2; i >= 0; i-- {
+		combined = combineSortedRunRecords(combined, records[i])
+	}
+
+	return combined.Value, nil
+}
+
+func (c *SortedRunsCollection) getFromRun(run *sortedRun, key []byte) (*sortedRunRecord, error) {
+	// find the last index entry with a key not larger than the key
+	var searchErr errorsx.Error
+	indexIdx := sort.Search(len(run.Index), func(i int) bool {
+		isLarger, err := c.isKey1Larger(run.Index[i].Key, key)
+		if err != nil {
+			searchErr = err
+		}
+		return isLarger
+	}) - 1
+	if searchErr != nil {
+		return nil, errorsx.Wrap(searchErr)
+	}
+	if indexIdx < 0 {
+		return nil, errorsx.ObjectNotFound
+	}
+
+	file, err := c.fs.Open(run.Path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer file.Close()
+
+	// the key can only be between this index entry and the next one
+	sectionEnd := int64(1<<63 - 1)
+	if indexIdx+1 < len(run.Index) {
+		sectionEnd = run.Index[indexIdx+1].Offset
+	}
+	startOffset := run.Index[indexIdx].Offset
+	reader := bufio.NewReader(io.NewSectionReader(file, startOffset, sectionEnd-startOffset))
+
+	for {
+		record, err := readSortedRunRecord(reader)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		if record == nil {
+			return nil, errorsx.ObjectNotFound
+		}
+
+		if bytes.Equal(record.Key, key) {
+			return record, nil
+		}
+
+		isLarger, err := c.isKey1Larger(record.Key, key)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		if isLarger {
+			return nil, errorsx.ObjectNotFound
+		}
+	}
+}
+
+// Iterator writes out the buffer, and then merges all the runs together, in sorted order
+func (c *SortedRunsCollection) Iterator() (Iterator, errorsx.Error) {
+	err := c.flushBuffer()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	merger, err := newSortedRunsMerger(c.fs, c.runs, c.isKey1Larger)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return &SortedRunsIterator{merger: merger}, nil
+}
+
+type SortedRunsIterator struct {
+	merger        *sortedRunsMerger
+	currentBucket []*ownmap.KVPair
+	err           errorsx.Error
+}
+
+// NextBucket reads the next items from the merged runs. If there is an error, it returns true, so that the error is returned from GetAllFromCurrentBucketAscending.
+func (it *SortedRunsIterator) NextBucket() bool {
+	if it.merger == nil {
+		return false
+	}
+
+	it.currentBucket = nil
+	for len(it.currentBucket) < sortedRunsIteratorBucketSize {
+		record, err := it.merger.next()
+		if err != nil {
+			it.err = err
+			it.close()
+			return true
+		}
+		if record == nil {
+			it.close()
+			break
+		}
+
+		it.currentBucket = append(it.currentBucket, &ownmap.KVPair{
+			Key:   record.Key,
+			Value: record.Value,
+		})
+	}
+
+	return len(it.currentBucket) != 0
+}
+
+func (it *SortedRunsIterator) close() {
+	err := it.merger.close()
+	if err != nil && it.err == nil {
+		it.err = err
+	}
+	it.merger = nil
+}
+
+func (it *SortedRunsIterator) GetAllFromCurrentBucketAscending() ([]*ownmap.KVPair, errorsx.Error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+
+	return it.currentBucket, nil
+}
+
+// sortedRunsMerger does a k-way merge of runs. Records for the same key in more than one run are combined, with the later runs being newer.
+type sortedRunsMerger struct {
+	files   []gofs.File
+	readers []*bufio.Reader
+	heap    *sortedRunsMergeHeap
+}
+
+func newSortedRunsMerger(fs gofs.Fs, runs []*sortedRun, isKey1Larger IsKey1LargerThanKey2Func) (*sortedRunsMerger, errorsx.Error) {
+	merger := &sortedRunsMerger{
+		heap: &sortedRunsMergeHeap{isKey1Larger: isKey1Larger},
+	}
+
+	for i, run := range runs {
+		file, err := fs.Open(run.Path)
+		if err != nil {
+			merger.close()
+			return nil, errorsx.Wrap(err)
+		}
+		merger.files = append(merger.files, file)
+		merger.readers = append(merger.readers, bufio.NewReader(file))
+
+		err = merger.pushNextFromRun(i)
+		if err != nil {
+			merger.close()
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	return merger, nil
+}
+
+func (m *sortedRunsMerger) pushNextFromRun(runIdx int) errorsx.Error {
+	record, err := readSortedRunRecord(m.readers[runIdx])
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+	if record == nil {
+		return nil
+	}
+
+	heap.Push(m.heap, sortedRunsMergeHeapItem{record, runIdx})
+	if m.heap.err != nil {
+		return m.heap.err
+	}
+
+	return nil
+}
+
+// next returns the next record in key order, or nil (and no error) when all the runs have been read
+func (m *sortedRunsMerger) next() (*sortedRunRecord, errorsx.Error) {
+	if m.heap.Len() == 0 {
+		return nil, nil
+	}
+
+	// items for the same key come out of the heap in run order (oldest first)
+	item := heap.Pop(m.heap).(sortedRunsMergeHeapItem)
+	combined := item.record
+
+	err := m.pushNextFromRun(item.runIdx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	for m.heap.Len() != 0 && bytes.Equal(m.heap.items[0].record.Key, combined.Key) {
+		item := heap.Pop(m.heap).(sortedRunsMergeHeapItem)
+		combined = combineSortedRunRecords(combined, item.record)
+
+		err = m.pushNextFromRun(item.runIdx)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	if m.heap.err != nil {
+		return nil, m.heap.err
+	}
+
+	return combined, nil
+}
+
+func (m *sortedRunsMerger) close() errorsx.Error {
+	var firstErr errorsx.Error
+	for _, file := range m.files {
+		err := file.Close()
+		if err != nil && firstErr == nil {
+			firstErr = errorsx.Wrap(err)
+		}
+	}
+	m.files = nil
+
+	return firstErr
+}
+
+type sortedRunsMergeHeapItem struct {
+	record *sortedRunRecord
+	runIdx int
+}
+
+// sortedRunsMergeHeap is a min-heap of the next record from each run, by key and then by run
+type sortedRunsMergeHeap struct {
+	items        []sortedRunsMergeHeapItem
+	isKey1Larger IsKey1LargerThanKey2Func
+	// err is the first error from comparing keys. container/heap can't return errors, so it is checked after each heap operation.
+	err errorsx.Error
+}
+
+func (h *sortedRunsMergeHeap) Len() int { return len(h.items) }
+
+func (h *sortedRunsMergeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if bytes.Equal(a.record.Key, b.record.Key) {
+		return a.runIdx < b.runIdx
+	}
+
+	isLarger, err := h.isKey1Larger(a.record.Key, b.record.Key)
+	if err != nil && h.err == nil {
+		h.err = err
+	}
+
+	return !isLarger
+}
+
+func (h *sortedRunsMergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *sortedRunsMergeHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(sortedRunsMergeHeapItem))
+}
+
+func (h *sortedRunsMergeHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
@@ -0,0 +1,119 @@
+package diskfilemap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isUint64Key1Larger(key1, key2 []byte) (bool, errorsx.Error) {
+	return binary.LittleEndian.Uint64(key1) > binary.LittleEndian.Uint64(key2), nil
+}
+
+func uint64Key(val uint64) []byte {
+	key := make([]byte, 8)
+	binary.LittleEndian.PutUint64(key, val)
+	return key
+}
+
+func TestSortedRunsCollection(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxBufferBytes int64
+	}{
+		{"everything in the buffer", testMaxCacheBytes * 10},
+		// small enough that runs are written and merged, and that the merged runs have more than one index entry
+		{"many runs", 4 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+
+			fs := mockfs.NewMockFs()
+			collection, err := NewSortedRunsCollection(fs, "/tmp/collection", isUint64Key1Larger, tt.maxBufferBytes)
+			require.NoError(t, err)
+
+			// the collection should end up with the same as a map
+			expected := make(map[uint64][]byte)
+
+			random := rand.New(rand.NewSource(1))
+			for i := 0; i < 5000; i++ {
+				keyVal := uint64(random.Intn(2000))
+				value := []byte(fmt.Sprintf("value %d %s;", i, bytes.Repeat([]byte("x"), random.Intn(100))))
+
+				if random.Intn(3) == 0 {
+					err = collection.Set(uint64Key(keyVal), value)
+					require.NoError(t, err)
+					expected[keyVal] = value
+				} else {
+					err = collection.Append(uint64Key(keyVal), value)
+					require.NoError(t, err)
+					expected[keyVal] = append(append([]byte(nil), expected[keyVal]...), value...)
+				}
+			}
+
+			for keyVal, value := range expected {
+				fetched, err := collection.Get(uint64Key(keyVal))
+				require.NoError(t, err)
+				require.Equal(t, value, fetched, "key: %d", keyVal)
+			}
+
+			_, err = collection.Get(uint64Key(2001))
+			assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+
+			var expectedKeys []uint64
+			for keyVal := range expected {
+				expectedKeys = append(expectedKeys, keyVal)
+			}
+			sort.Slice(expectedKeys, func(i, j int) bool {
+				return expectedKeys[i] < expectedKeys[j]
+			})
+
+			iterator, err := collection.Iterator()
+			require.NoError(t, err)
+
+			var iteratedKeys []uint64
+			for iterator.NextBucket() {
+				kvPairs, err := iterator.GetAllFromCurrentBucketAscending()
+				require.NoError(t, err)
+
+				for _, kvPair := range kvPairs {
+					keyVal := binary.LittleEndian.Uint64(kvPair.Key)
+					iteratedKeys = append(iteratedKeys, keyVal)
+					require.Equal(t, expected[keyVal], kvPair.Value, "key: %d", keyVal)
+				}
+			}
+
+			assert.Equal(t, expectedKeys, iteratedKeys)
+
+			// runs that have been merged are removed
+			if tt.maxBufferBytes < testMaxCacheBytes {
+				assert.Less(t, len(collection.runs), sortedRunsMergeFactor*3)
+			}
+		})
+	}
+}
+
+func TestSortedRunsCollection_empty(t *testing.T) {
+	var err error
+
+	collection, err := NewSortedRunsCollection(mockfs.NewMockFs(), "/tmp/collection", isUint64Key1Larger, testMaxCacheBytes)
+	require.NoError(t, err)
+
+	_, err = collection.Get(uint64Key(1))
+	assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+
+	iterator, err := collection.Iterator()
+	require.NoError(t, err)
+
+	assert.False(t, iterator.NextBucket())
+}
@@ -2,6 +2,7 @@ package ownmapdb
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"path/filepath"
 
@@ -11,7 +12,8 @@ import (
 	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/diskfilemap"
 )
 
-func createSectionFromDisk(fs gofs.Fs, diskFileMap diskfilemap.OnDiskCollection, blockData BlockData, tempdirName, tempFilePrefix string, blockSize int64) (*SectionMetadata, io.ReadCloser, errorsx.Error) {
+// createSectionFromDisk writes the items in the collection into blocks in a section file. It stops with an error if the context is cancelled.
+func createSectionFromDisk(ctx context.Context, fs gofs.Fs, diskFileMap diskfilemap.OnDiskCollection, blockData BlockData, tempdirName, tempFilePrefix string, blockSize int64) (*SectionMetadata, io.ReadCloser, errorsx.Error) {
 	sectionHeader := new(SectionMetadata)
 	sectionDataFile, err := fs.Create(filepath.Join(tempdirName, tempFilePrefix))
 	if err != nil {
@@ -26,6 +28,10 @@ func createSectionFromDisk(fs gofs.Fs, diskFileMap diskfilemap.OnDiskCollection,
 	}
 
 	for iterator.NextBucket() {
+		if ctx.Err() != nil {
+			return nil, nil, errorsx.Wrap(ctx.Err())
+		}
+
 		kvPairs, err := iterator.GetAllFromCurrentBucketAscending()
 		if err != nil {
 			return nil, nil, errorsx.Wrap(err)
@@ -2,6 +2,7 @@ package ownmapdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"io"
 	"log"
@@ -17,6 +18,7 @@ import (
 	"github.com/jamesrr39/ownmap-app/ownmap"
 	"github.com/jamesrr39/ownmap-app/ownmapdal"
 	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/diskfilemap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/nodelocations"
 	"github.com/paulmach/osm/osmpbf"
 )
 
@@ -72,8 +74,44 @@ type Collections struct {
 	NodeCollection, WayCollection, RelationCollection, TagCollection diskfilemap.OnDiskCollection
 }
 
+// DefaultImportMemoryBytes is the memory used for the import caches if it isn't set in the import options
+const DefaultImportMemoryBytes = 256 * 1024 * 1024
+
 type ImportOptions struct {
 	KeepWorkDir bool
+	// ProgressTracker is optional. If set, the sections being built while committing are reported to it.
+	ProgressTracker *ownmapdal.ImportProgressTracker
+	// ImportMemoryBytes is roughly how much memory the import caches can use, shared between the node, way, relation and tag collections.
+	// Lower it for machines with little RAM; the import is slower, since more is read from and written to disk. 0 (or less) means DefaultImportMemoryBytes.
+	ImportMemoryBytes int64
+	// NodeLocationStoreType is the store used to look up node locations when building way geometries. Empty means the sparse store.
+	// The dense store is better for the planet or big extracts, but needs the work dir to be on the OS filesystem.
+	NodeLocationStoreType nodelocations.StoreType
+	// KeepMetadata keeps the version, timestamp, changeset and user of each object. It makes the DB bigger.
+	// With it, change files that overlap with the data already in the DB can be applied correctly, since changes older than the objects in the DB are skipped.
+	KeepMetadata bool
+	// CollectionType is how the nodes, ways, relations and tag index are kept on disk while importing. Empty means ImportCollectionTypeBuckets.
+	CollectionType ImportCollectionType
+}
+
+type ImportCollectionType string
+
+const (
+	// ImportCollectionTypeBuckets keeps the items in bucket files, which are read, changed and written again as items are added
+	ImportCollectionTypeBuckets ImportCollectionType = "buckets"
+	// ImportCollectionTypeSortedRuns writes the items out in sorted runs, and merges them when building the file (an external merge sort).
+	// Adding items stays fast when there are a lot of them in the same area, but looking them up during the import is slower.
+	ImportCollectionTypeSortedRuns ImportCollectionType = "sorted-runs"
+)
+
+var ImportCollectionTypes = []ImportCollectionType{ImportCollectionTypeBuckets, ImportCollectionTypeSortedRuns}
+
+func (o ImportOptions) getImportMemoryBytes() int64 {
+	if o.ImportMemoryBytes <= 0 {
+		return DefaultImportMemoryBytes
+	}
+
+	return o.ImportMemoryBytes
 }
 
 type Importer struct {
@@ -82,43 +120,87 @@ type Importer struct {
 	workDir, outFilePath     string
 	ownmapDBFileHandlerLimit uint
 	collections              *Collections
-	pbfHeader                *osmpbf.Header
-	options                  ImportOptions
+	// nodeLocations has the location of every imported node. The whole node (with its tags) is in the node collection, and only read from there when it's needed.
+	nodeLocations nodelocations.Store
+	pbfHeader     *osmpbf.Header
+	options       ImportOptions
+	// relationIDsWithSubRelations are the relations that are put in the tag index when committing, rather than when they are imported,
+	// since their sub-relations (and their sub-relations' members) can come after them in the file
+	relationIDsWithSubRelations []int64
 }
 
 func NewImporter(logger *logpkg.Logger, fs gofs.Fs, workDir, outFilePath string, ownmapDBFileHandlerLimit uint, pbfHeader *osmpbf.Header, options ImportOptions) (*Importer, errorsx.Error) {
-	collections, err := makeCollections(fs, workDir)
+	collections, err := makeCollections(fs, workDir, options.getImportMemoryBytes(), options.CollectionType)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
-	return &Importer{logger, fs, workDir, outFilePath, ownmapDBFileHandlerLimit, collections, pbfHeader, options}, nil
-}
-
-func makeCollections(fs gofs.Fs, workDir string) (*Collections, errorsx.Error) {
-	var err error
-	collections := new(Collections)
 
-	collections.NodeCollection, err = diskfilemap.NewDiskCollection(fs, filepath.Join(workDir, "node_collection.ownmap_import_cache"), makeInt64BucketNameFunc, isKey1GreaterThanKey2CompareInt64Func)
+	nodeLocations, err := nodelocations.NewStore(options.NodeLocationStoreType, filepath.Join(workDir, "node_locations.ownmap_import_cache"))
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
-	collections.WayCollection, err = diskfilemap.NewDiskCollection(fs, filepath.Join(workDir, "way_collection.ownmap_import_cache"), makeInt64BucketNameFunc, isKey1GreaterThanKey2CompareInt64Func)
-	if err != nil {
-		return nil, errorsx.Wrap(err)
-	}
-	collections.RelationCollection, err = diskfilemap.NewDiskCollection(fs, filepath.Join(workDir, "relation_collection.ownmap_import_cache"), makeInt64BucketNameFunc, isKey1GreaterThanKey2CompareInt64Func)
-	if err != nil {
-		return nil, errorsx.Wrap(err)
+
+	return &Importer{
+		logger:                   logger,
+		fs:                       fs,
+		workDir:                  workDir,
+		outFilePath:              outFilePath,
+		ownmapDBFileHandlerLimit: ownmapDBFileHandlerLimit,
+		collections:              collections,
+		nodeLocations:            nodeLocations,
+		pbfHeader:                pbfHeader,
+		options:                  options,
+	}, nil
+}
+
+func makeCollections(fs gofs.Fs, workDir string, importMemoryBytes int64, collectionType ImportCollectionType) (*Collections, errorsx.Error) {
+	collections := new(Collections)
+
+	// split the memory evenly between the collections
+	cacheBytesPerCollection := importMemoryBytes / 4
+
+	collectionDefs := []struct {
+		collection   *diskfilemap.OnDiskCollection
+		name         string
+		bucketFunc   diskfilemap.BucketPolicyFunc
+		isKey1Larger diskfilemap.IsKey1LargerThanKey2Func
+	}{
+		{&collections.NodeCollection, "node_collection.ownmap_import_cache", makeInt64BucketNameFunc, isKey1GreaterThanKey2CompareInt64Func},
+		{&collections.WayCollection, "way_collection.ownmap_import_cache", makeInt64BucketNameFunc, isKey1GreaterThanKey2CompareInt64Func},
+		{&collections.RelationCollection, "relation_collection.ownmap_import_cache", makeInt64BucketNameFunc, isKey1GreaterThanKey2CompareInt64Func},
+		{&collections.TagCollection, "tag_collection.ownmap_import_cache", makeTagIndexBucketNameFunc, isKey1GreaterThanKey2CompareTagsFunc},
 	}
-	collections.TagCollection, err = diskfilemap.NewDiskCollection(fs, filepath.Join(workDir, "tag_collection.ownmap_import_cache"), makeTagIndexBucketNameFunc, isKey1GreaterThanKey2CompareTagsFunc)
-	if err != nil {
-		return nil, errorsx.Wrap(err)
+
+	for _, def := range collectionDefs {
+		path := filepath.Join(workDir, def.name)
+
+		switch collectionType {
+		case ImportCollectionTypeBuckets, "":
+			collection, err := diskfilemap.NewDiskCollection(fs, path, def.bucketFunc, def.isKey1Larger, cacheBytesPerCollection)
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+			*def.collection = collection
+		case ImportCollectionTypeSortedRuns:
+			collection, err := diskfilemap.NewSortedRunsCollection(fs, path, def.isKey1Larger, cacheBytesPerCollection)
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+			*def.collection = collection
+		default:
+			return nil, errorsx.Errorf("unknown import collection type: %q", collectionType)
+		}
 	}
 
 	return collections, nil
 }
 
 func setTagsOnCollection(keys []*tagCollectionKeyType, collection diskfilemap.OnDiskCollection, itemID int64) errorsx.Error {
+	appendableCollection, ok := collection.(diskfilemap.AppendableCollection)
+	if ok {
+		return appendTagsOnCollection(keys, appendableCollection, itemID)
+	}
+
 	for _, tagsCollectionKey := range keys {
 		var err error
 
@@ -158,7 +240,36 @@ func setTagsOnCollection(keys []*tagCollectionKeyType, collection diskfilemap.On
 	return nil
 }
 
-func (importer *Importer) ImportNode(node *ownmap.OSMNode) errorsx.Error {
+// appendTagsOnCollection adds the item to the tag index records without reading them first.
+// Marshalled protobuf messages can be concatenated; when unmarshalled, the item IDs of each part are all kept (and the index key is the same in each part).
+func appendTagsOnCollection(keys []*tagCollectionKeyType, collection diskfilemap.AppendableCollection, itemID int64) errorsx.Error {
+	for _, tagsCollectionKey := range keys {
+		key := tagsCollectionKey.MarshalKey()
+
+		valBytes, err := proto.Marshal(&TagIndexRecord{
+			IndexKey: key,
+			ItemIDs:  []int64{itemID},
+		})
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		appendErr := collection.Append(key, valBytes)
+		if appendErr != nil {
+			return errorsx.Wrap(appendErr)
+		}
+	}
+
+	return nil
+}
+
+func (importer *Importer) ImportNode(ctx context.Context, node *ownmap.OSMNode) errorsx.Error {
+	if !importer.options.KeepMetadata && node.Metadata != nil {
+		nodeWithoutMetadata := *node
+		nodeWithoutMetadata.Metadata = nil
+		node = &nodeWithoutMetadata
+	}
+
 	bb := binaryx.LittleEndianPutUint64(uint64(node.ID))
 	ownmapNodeBytes, err := proto.Marshal(node)
 	if err != nil {
@@ -170,6 +281,11 @@ func (importer *Importer) ImportNode(node *ownmap.OSMNode) errorsx.Error {
 		return errorsx.Wrap(err)
 	}
 
+	err = importer.nodeLocations.Set(node.ID, node.Lat, node.Lon)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
 	var tagCollectionKeys []*tagCollectionKeyType
 	for _, tag := range node.Tags {
 		tagCollectionKeys = append(tagCollectionKeys, newTagCollectionKey(node.Lat, node.Lon, ownmap.ObjectTypeNode, tag.Key))
@@ -183,11 +299,13 @@ func (importer *Importer) ImportNode(node *ownmap.OSMNode) errorsx.Error {
 	return nil
 }
 
-func (importer *Importer) ImportWay(ownmapWay *ownmap.OSMWay) errorsx.Error {
+func (importer *Importer) ImportWay(ctx context.Context, ownmapWay *ownmap.OSMWay) errorsx.Error {
 	var err error
-	nodes, err := importer.getNodesInWay(ownmapWay)
-	if err != nil {
-		return errorsx.Wrap(err)
+
+	if !importer.options.KeepMetadata && ownmapWay.Metadata != nil {
+		wayWithoutMetadata := *ownmapWay
+		wayWithoutMetadata.Metadata = nil
+		ownmapWay = &wayWithoutMetadata
 	}
 
 	bb := binaryx.LittleEndianPutUint64(uint64(ownmapWay.ID))
@@ -200,7 +318,13 @@ func (importer *Importer) ImportWay(ownmapWay *ownmap.OSMWay) errorsx.Error {
 		return errorsx.Wrap(err)
 	}
 
-	tagCollectionKeys := buildTagIndexesForObject(ownmapWay.Tags, ownmap.GetPointsFromNodes(nodes), ownmap.ObjectTypeWay)
+	// the way points only have the nodes that have been imported, and already have their locations
+	var points []*ownmap.Location
+	for _, wayPoint := range ownmapWay.WayPoints {
+		points = append(points, wayPoint.Point)
+	}
+
+	tagCollectionKeys := buildTagIndexesForObject(ownmapWay.Tags, points, ownmap.ObjectTypeWay)
 
 	err = setTagsOnCollection(tagCollectionKeys, importer.collections.TagCollection, ownmapWay.ID)
 	if err != nil {
@@ -226,14 +350,25 @@ func (importer *Importer) getObjectByID(id int64, collection diskfilemap.OnDiskC
 	return nil
 }
 func (importer *Importer) GetNodeByID(id int64) (*ownmap.OSMNode, error) {
+	// look in the node location store first, so that nodes that haven't been imported (e.g. out of bounds) are found to be missing without reading a bucket from disk
+	_, err := importer.nodeLocations.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
 	node := new(ownmap.OSMNode)
-	err := importer.getObjectByID(id, importer.collections.NodeCollection, node)
+	err = importer.getObjectByID(id, importer.collections.NodeCollection, node)
 	if err != nil {
 		return nil, err
 	}
 
 	return node, nil
 }
+
+// GetNodeLocation gets the location of an imported node from the node location store, without reading the whole node
+func (importer *Importer) GetNodeLocation(id int64) (*ownmap.Location, error) {
+	return importer.nodeLocations.Get(id)
+}
 func (importer *Importer) GetWayByID(id int64) (*ownmap.OSMWay, error) {
 	way := new(ownmap.OSMWay)
 	err := importer.getObjectByID(id, importer.collections.WayCollection, way)
@@ -255,13 +390,34 @@ func (importer *Importer) GetRelationByID(id int64) (*ownmap.OSMRelation, error)
 
 type tagCollectionKeySet map[tagCollectionKeyType]struct{}
 
-func (importer *Importer) getTagIndexesForRelation(relation *ownmap.OSMRelation) (tagCollectionKeySet, errorsx.Error) {
+// getTagIndexesForRelation builds the tag indexes of a relation from its members, and the members of its sub-relations.
+// visitedRelationIDs are the relations already gone through, so that relations that are members of each other (directly or further down) don't recurse forever.
+func (importer *Importer) getTagIndexesForRelation(relation *ownmap.OSMRelation, visitedRelationIDs map[int64]bool) (tagCollectionKeySet, errorsx.Error) {
 	set := make(tagCollectionKeySet)
 
+	visitedRelationIDs[relation.ID] = true
+
+	if len(relation.Members) == 0 && relation.Area != nil {
+		// generated areas (e.g. the sea from coastlines) have no members to index them by, so index them by the area they cover
+		for _, polygon := range relation.Area.Polygons {
+			ringBounds := ownmap.GetRingBounds(polygon.Outer.Points)
+			// the tag indexes cover the rectangle between consecutive points, so the corners cover the whole bounding box of the ring
+			corners := []*ownmap.Location{
+				{Lat: ringBounds.MinLat, Lon: ringBounds.MinLon},
+				{Lat: ringBounds.MaxLat, Lon: ringBounds.MaxLon},
+			}
+
+			tagIndexes := buildTagIndexesForObject(relation.Tags, corners, ownmap.ObjectTypeRelation)
+			for _, tagIndex := range tagIndexes {
+				set[*tagIndex] = struct{}{}
+			}
+		}
+	}
+
 	for _, member := range relation.Members {
 		switch member.MemberType {
 		case ownmap.OSM_MEMBER_TYPE_NODE:
-			node, err := importer.GetNodeByID(member.ObjectID)
+			location, err := importer.GetNodeLocation(member.ObjectID)
 			if err != nil {
 				if errorsx.Cause(err) != errorsx.ObjectNotFound {
 					return nil, errorsx.Wrap(err)
@@ -271,7 +427,7 @@ func (importer *Importer) getTagIndexesForRelation(relation *ownmap.OSMRelation)
 				continue
 			}
 
-			tagIndexes := buildTagIndexesForObject(relation.Tags, []*ownmap.Location{{Lat: node.Lat, Lon: node.Lon}}, ownmap.ObjectTypeRelation)
+			tagIndexes := buildTagIndexesForObject(relation.Tags, []*ownmap.Location{location}, ownmap.ObjectTypeRelation)
 			for _, tagIndex := range tagIndexes {
 				set[*tagIndex] = struct{}{}
 			}
@@ -296,17 +452,21 @@ func (importer *Importer) getTagIndexesForRelation(relation *ownmap.OSMRelation)
 				set[*tagIndex] = struct{}{}
 			}
 		case ownmap.OSM_MEMBER_TYPE_RELATION:
+			if visitedRelationIDs[member.ObjectID] {
+				continue
+			}
+
 			subRelation, err := importer.GetRelationByID(member.ObjectID)
 			if err != nil {
 				if errorsx.Cause(err) != errorsx.ObjectNotFound {
 					return nil, errorsx.Wrap(err)
 				}
 
-				// way not found. Ignore.
+				// relation not found. Ignore.
 				continue
 			}
 
-			subRelationTagIndexesSet, err := importer.getTagIndexesForRelation(subRelation)
+			subRelationTagIndexesSet, err := importer.getTagIndexesForRelation(subRelation, visitedRelationIDs)
 			if err != nil {
 				return nil, errorsx.Wrap(err)
 			}
@@ -320,25 +480,13 @@ func (importer *Importer) getTagIndexesForRelation(relation *ownmap.OSMRelation)
 	return set, nil
 }
 
-func (importer *Importer) getNodesInWay(way *ownmap.OSMWay) ([]*ownmap.OSMNode, errorsx.Error) {
-	var nodes []*ownmap.OSMNode
-	for _, point := range way.WayPoints {
-		node, err := importer.GetNodeByID(point.NodeID)
-		if err != nil {
-			if errorsx.Cause(err) != errorsx.ObjectNotFound {
-				return nil, errorsx.Wrap(err)
-			}
-
-			// nodeID not imported (out of bounds)
-			continue
-		}
-
-		nodes = append(nodes, node)
+func (importer *Importer) ImportRelation(ctx context.Context, relation *ownmap.OSMRelation) errorsx.Error {
+	if !importer.options.KeepMetadata && relation.Metadata != nil {
+		relationWithoutMetadata := *relation
+		relationWithoutMetadata.Metadata = nil
+		relation = &relationWithoutMetadata
 	}
-	return nodes, nil
-}
 
-func (importer *Importer) ImportRelation(relation *ownmap.OSMRelation) errorsx.Error {
 	bb := binaryx.LittleEndianPutUint64(uint64(relation.ID))
 	relationBytes, err := proto.Marshal(relation)
 	if err != nil {
@@ -351,7 +499,27 @@ func (importer *Importer) ImportRelation(relation *ownmap.OSMRelation) errorsx.E
 		return errorsx.Wrap(err)
 	}
 	importer.logger.Debug("finished setting relation in collection. ID: %d", relation.ID)
-	tagIndexSet, err := importer.getTagIndexesForRelation(relation)
+
+	if hasRelationMember(relation) {
+		importer.relationIDsWithSubRelations = append(importer.relationIDsWithSubRelations, relation.ID)
+		return nil
+	}
+
+	return importer.setRelationTagIndexes(relation)
+}
+
+func hasRelationMember(relation *ownmap.OSMRelation) bool {
+	for _, member := range relation.Members {
+		if member.MemberType == ownmap.OSM_MEMBER_TYPE_RELATION {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (importer *Importer) setRelationTagIndexes(relation *ownmap.OSMRelation) errorsx.Error {
+	tagIndexSet, err := importer.getTagIndexesForRelation(relation, make(map[int64]bool))
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
@@ -379,11 +547,18 @@ type sectionMetadataImportType struct {
 }
 
 func (importer *Importer) Rollback() errorsx.Error {
+	var err error
+
+	err = importer.nodeLocations.Close()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
 	if importer.options.KeepWorkDir {
 		return nil
 	}
 
-	err := importer.fs.RemoveAll(importer.workDir)
+	err = importer.fs.RemoveAll(importer.workDir)
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
@@ -391,42 +566,86 @@ func (importer *Importer) Rollback() errorsx.Error {
 	return nil
 }
 
-func (importer *Importer) Commit() (ownmapdal.DataSourceConn, errorsx.Error) {
+func (importer *Importer) setProgressPhase(phase ownmapdal.ImportPhase, sectionName string) {
+	if importer.options.ProgressTracker == nil {
+		return
+	}
+
+	importer.options.ProgressTracker.SetPhase(phase, sectionName)
+}
+
+// setTagIndexesForRelationsWithSubRelations puts the relations with sub-relations in the tag index, now that all the relations have been imported
+func (importer *Importer) setTagIndexesForRelationsWithSubRelations(ctx context.Context) errorsx.Error {
+	for _, relationID := range importer.relationIDsWithSubRelations {
+		if ctx.Err() != nil {
+			return errorsx.Wrap(ctx.Err())
+		}
+
+		relation, err := importer.GetRelationByID(relationID)
+		if err != nil {
+			return errorsx.Wrap(err, "relation ID", relationID)
+		}
+
+		setErr := importer.setRelationTagIndexes(relation)
+		if setErr != nil {
+			return errorsx.Wrap(setErr, "relation ID", relationID)
+		}
+	}
+
+	importer.relationIDsWithSubRelations = nil
+
+	return nil
+}
+
+func (importer *Importer) Commit(ctx context.Context, datasetBounds ownmapdal.DatasetBounds) (ownmapdal.DataSourceConn, errorsx.Error) {
 
 	err := importer.fs.MkdirAll(importer.workDir, 0700)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
+	err = importer.setTagIndexesForRelationsWithSubRelations(ctx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	datasetInfo := &ownmap.DatasetInfo{
-		Bounds: &ownmap.DatasetInfo_Bounds{
-			MaxLat: importer.pbfHeader.Bounds.MaxLat,
-			MaxLon: importer.pbfHeader.Bounds.MaxLon,
-			MinLat: importer.pbfHeader.Bounds.MinLat,
-			MinLon: importer.pbfHeader.Bounds.MinLon,
-		},
+		Bounds:                    datasetBounds.ToDatasetInfoBounds(),
+		BoundsSource:              datasetBounds.Source,
+		ImportBounds:              datasetBounds.ImportBoundsToDatasetInfoBounds(),
+		HasMetadata:               importer.options.KeepMetadata,
+		ReplicationSequenceNumber: importer.pbfHeader.ReplicationSeqNum,
+		ReplicationBaseURL:        importer.pbfHeader.ReplicationBaseURL,
+	}
+
+	if !importer.pbfHeader.ReplicationTimestamp.IsZero() {
+		datasetInfo.ReplicationTimeMs = uint64(importer.pbfHeader.ReplicationTimestamp.UnixNano() / (1000 * 1000))
 	}
 
 	// save data to files
-	nodesSectionMetadata, nodesFile, err := createSectionFromDisk(importer.fs, importer.collections.NodeCollection, NewNodesFromDiskBlockData(), importer.workDir, "nodes_workdir", blockSize)
+	importer.setProgressPhase(ownmapdal.ImportPhaseBuildingSection, "nodes")
+	nodesSectionMetadata, nodesFile, err := createSectionFromDisk(ctx, importer.fs, importer.collections.NodeCollection, NewNodesFromDiskBlockData(), importer.workDir, "nodes_workdir", blockSize)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 	defer nodesFile.Close()
 
-	waysSectionMetadata, waysFile, err := createSectionFromDisk(importer.fs, importer.collections.WayCollection, NewWaysFromDiskBlockData(), importer.workDir, "ways_workdir", blockSize)
+	importer.setProgressPhase(ownmapdal.ImportPhaseBuildingSection, "ways")
+	waysSectionMetadata, waysFile, err := createSectionFromDisk(ctx, importer.fs, importer.collections.WayCollection, NewWaysFromDiskBlockData(), importer.workDir, "ways_workdir", blockSize)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 	defer waysFile.Close()
 
-	relationsSectionMetadata, relationsFile, err := createSectionFromDisk(importer.fs, importer.collections.RelationCollection, NewRelationsFromDiskBlockData(), importer.workDir, "relations_workdir", blockSize)
+	importer.setProgressPhase(ownmapdal.ImportPhaseBuildingSection, "relations")
+	relationsSectionMetadata, relationsFile, err := createSectionFromDisk(ctx, importer.fs, importer.collections.RelationCollection, NewRelationsFromDiskBlockData(), importer.workDir, "relations_workdir", blockSize)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 	defer relationsFile.Close()
 
-	tagIndexSectionMetadata, tagIndexFile, err := createSectionFromDisk(importer.fs, importer.collections.TagCollection, NewTagsFromDiskBlockData(), importer.workDir, "tags_workdir", blockSize)
+	importer.setProgressPhase(ownmapdal.ImportPhaseBuildingSection, "tags")
+	tagIndexSectionMetadata, tagIndexFile, err := createSectionFromDisk(ctx, importer.fs, importer.collections.TagCollection, NewTagsFromDiskBlockData(), importer.workDir, "tags_workdir", blockSize)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
@@ -450,6 +669,8 @@ func (importer *Importer) Commit() (ownmapdal.DataSourceConn, errorsx.Error) {
 	headerSizeContainer := make([]byte, HeaderSizeContainerSize)
 	binary.LittleEndian.PutUint32(headerSizeContainer, uint32(len(headerBytes)))
 
+	importer.setProgressPhase(ownmapdal.ImportPhaseWriting, "")
+
 	finalBuildFilePath := filepath.Join(importer.workDir, "final_build.ownmapdb")
 	finalBuildFile, err := importer.fs.Create(finalBuildFilePath)
 	if err != nil {
@@ -475,6 +696,10 @@ func (importer *Importer) Commit() (ownmapdal.DataSourceConn, errorsx.Error) {
 	}
 
 	for _, bb := range readers {
+		if ctx.Err() != nil {
+			return nil, errorsx.Wrap(ctx.Err())
+		}
+
 		bytesWritten, err := io.Copy(finalBuildFile, bb)
 		if err != nil {
 			return nil, errorsx.Wrap(err)
@@ -501,6 +726,11 @@ func (importer *Importer) Commit() (ownmapdal.DataSourceConn, errorsx.Error) {
 		return outFile, nil
 	}
 
+	err = importer.nodeLocations.Close()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	if !importer.options.KeepWorkDir {
 		err = importer.fs.RemoveAll(importer.workDir)
 		if err != nil {
@@ -1,17 +1,24 @@
 package ownmapdb
 
 import (
+	"context"
 	"encoding/binary"
+	"os"
 	"reflect"
 	"testing"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/goutil/gofs/mockfs"
 	"github.com/jamesrr39/goutil/logpkg"
 	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
 	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/diskfilemap"
+	"github.com/paulmach/osm"
 	"github.com/paulmach/osm/osmpbf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_getTagIndexesForWay(t *testing.T) {
@@ -247,6 +254,29 @@ func TestImporter_getTagIndexesForRelation(t *testing.T) {
 					TagKey:     "place",
 				}: struct{}{},
 			},
+		}, {
+			name: "generated area with no members",
+			fields: fields{
+				collections: c,
+			},
+			args: args{
+				relation: &ownmap.OSMRelation{
+					ID:   ownmap.GeneratedRelationIDStart,
+					Tags: []*ownmap.OSMTag{{Key: "natural", Value: "water"}},
+					Area: &ownmap.AreaGeometry{Polygons: []*ownmap.AreaPolygon{{
+						Outer: &ownmap.AreaRing{Points: []*ownmap.Location{
+							{Lat: 1, Lon: 1},
+							{Lat: 1, Lon: 1.015},
+							{Lat: 1.005, Lon: 1},
+							{Lat: 1, Lon: 1},
+						}},
+					}}},
+				},
+			},
+			want: tagCollectionKeySet{
+				tagCollectionKeyType{LatBucket: 100, LonBucket: 100, ObjectType: ownmap.ObjectTypeRelation, TagKey: "natural"}: struct{}{},
+				tagCollectionKeyType{LatBucket: 100, LonBucket: 101, ObjectType: ownmap.ObjectTypeRelation, TagKey: "natural"}: struct{}{},
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -261,7 +291,7 @@ func TestImporter_getTagIndexesForRelation(t *testing.T) {
 				pbfHeader:                tt.fields.pbfHeader,
 				options:                  tt.fields.options,
 			}
-			got, got1 := importer.getTagIndexesForRelation(tt.args.relation)
+			got, got1 := importer.getTagIndexesForRelation(tt.args.relation, make(map[int64]bool))
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Importer.getTagIndexesForRelation() got = %v, want %v", got, tt.want)
 			}
@@ -271,3 +301,186 @@ func TestImporter_getTagIndexesForRelation(t *testing.T) {
 		})
 	}
 }
+
+func TestImporter_collectionTypes(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+	bounds := &osm.Bounds{MinLat: 50, MaxLat: 52, MinLon: -1, MaxLon: 1}
+
+	for _, collectionType := range ImportCollectionTypes {
+		t.Run(string(collectionType), func(t *testing.T) {
+			var err error
+
+			fs := mockfs.NewMockFs()
+
+			// very little memory, so that the collections have to write to disk all the time
+			options := ImportOptions{CollectionType: collectionType, ImportMemoryBytes: 1024}
+			importer, err := NewImporter(logger, fs, "/import_workdir", "/data/test.ownmapdb", 1, &osmpbf.Header{Bounds: bounds}, options)
+			require.NoError(t, err)
+
+			// the nodes are all in the same tag index record, so it is added to several times
+			var expectedNodeIDs []int64
+			for i := int64(1); i <= 50; i++ {
+				node := &ownmap.OSMNode{ID: i, Lat: 51.1 + float64(i)/10000, Lon: 0.1, Tags: []*ownmap.OSMTag{{Key: "place", Value: "village"}}}
+				require.NoError(t, importer.ImportNode(context.Background(), node))
+				expectedNodeIDs = append(expectedNodeIDs, i)
+			}
+
+			way := &ownmap.OSMWay{ID: 10, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "residential"}}, WayPoints: []*ownmap.WayPoint{
+				{NodeID: 1, Point: &ownmap.Location{Lat: 51.1001, Lon: 0.1}},
+				{NodeID: 2, Point: &ownmap.Location{Lat: 51.1002, Lon: 0.1}},
+			}}
+			require.NoError(t, importer.ImportWay(context.Background(), way))
+
+			// objects can be looked up while importing
+			fetchedNode, err := importer.GetNodeByID(5)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), fetchedNode.ID)
+
+			fetchedWay, err := importer.GetWayByID(10)
+			require.NoError(t, err)
+			assert.Len(t, fetchedWay.WayPoints, 2)
+
+			dbConn, err := importer.Commit(context.Background(), ownmapdal.DatasetBounds{Bounds: *bounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: *bounds})
+			require.NoError(t, err)
+			defer dbConn.(*MapmakerDBConn).Close()
+
+			filter := &ownmapdal.GetInBoundsFilter{
+				Objects: []*ownmapdal.TagKeyWithType{
+					{ObjectType: ownmap.ObjectTypeNode, TagKey: "place"},
+					{ObjectType: ownmap.ObjectTypeWay, TagKey: "highway"},
+				},
+			}
+
+			queryBounds := osm.Bounds{MinLat: 51.1001, MaxLat: 51.105, MinLon: 0.1, MaxLon: 0.1}
+			nodeMap, wayMap, _, err := dbConn.GetInBounds(context.Background(), queryBounds, filter)
+			require.NoError(t, err)
+
+			var nodeIDs []int64
+			for _, node := range nodeMap["place"] {
+				nodeIDs = append(nodeIDs, node.ID)
+			}
+			assert.ElementsMatch(t, expectedNodeIDs, nodeIDs)
+
+			require.Len(t, wayMap["highway"], 1)
+			assert.Equal(t, int64(10), wayMap["highway"][0].ID)
+		})
+	}
+}
+
+func TestImporter_nestedRelations(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// the relations come before their members
+	const osmXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<relation id="300">
+		<member type="relation" ref="200" role=""/>
+		<tag k="route_master" v="bus"/>
+	</relation>
+	<relation id="200">
+		<member type="relation" ref="100" role=""/>
+		<tag k="route" v="bus"/>
+	</relation>
+	<relation id="100">
+		<member type="way" ref="10" role=""/>
+		<tag k="route" v="bus"/>
+	</relation>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<node id="1" lat="51.5" lon="0"/>
+	<node id="2" lat="51.6" lon="0.1"/>
+</osm>`
+
+	dbConn := importTestOSMXML(t, logger, osmXML)
+	defer dbConn.Close()
+
+	relationMap := getTestRelationsAroundNode1(t, dbConn, "route_master", "route")
+
+	// the relations are found by the members of their sub-relations, even though the sub-relations came after them
+	assert.ElementsMatch(t, []int64{300}, relationIDsForTagKey(relationMap, "route_master"))
+	assert.ElementsMatch(t, []int64{100, 200}, relationIDsForTagKey(relationMap, "route"))
+}
+
+func TestImporter_cyclicRelations(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+
+	// 600 and 601 contain each other, and 600 has a node in bounds
+	const osmXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<relation id="600">
+		<member type="node" ref="1" role=""/>
+		<member type="relation" ref="601" role=""/>
+		<tag k="site" v="school"/>
+	</relation>
+	<relation id="601">
+		<member type="relation" ref="600" role=""/>
+		<tag k="site" v="school"/>
+	</relation>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<node id="1" lat="51.5" lon="0"/>
+	<node id="2" lat="51.6" lon="0.1"/>
+</osm>`
+
+	dbConn := importTestOSMXML(t, logger, osmXML)
+	defer dbConn.Close()
+
+	relationMap := getTestRelationsAroundNode1(t, dbConn, "site")
+
+	// 601 is only in the area through 600, which contains it
+	assert.ElementsMatch(t, []int64{600, 601}, relationIDsForTagKey(relationMap, "site"))
+}
+
+// importTestOSMXML imports the OSM XML with the whole import process, in bounds around node 1 (51.5, 0) and node 2 (51.6, 0.1) of the tests
+func importTestOSMXML(t *testing.T, logger *logpkg.Logger, osmXML string) *MapmakerDBConn {
+	bounds := osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1}
+
+	fs := mockfs.NewMockFs()
+	err := fs.WriteFile("test.osm", []byte(osmXML), 0600)
+	require.NoError(t, err)
+
+	file, err := fs.Open("test.osm")
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	reader, err := ownmapdal.NewPBFReaderForFile(file, "test.osm")
+	require.NoError(t, err)
+	t.Cleanup(func() { reader.Close() })
+
+	importer, err := NewImporter(logger, fs, "/import_workdir", "/data/test.ownmapdb", 1, &osmpbf.Header{Bounds: &bounds}, ImportOptions{})
+	require.NoError(t, err)
+
+	progressTracker := ownmapdal.NewImportProgressTracker(reader.TotalSize())
+
+	dbConn, err := ownmapdal.Import(context.Background(), logger, reader, fs, "/work", importer, bounds, progressTracker)
+	require.NoError(t, err)
+
+	return dbConn.(*MapmakerDBConn)
+}
+
+// getTestRelationsAroundNode1 gets the relations with the tag keys that are in the tag index around node 1 (51.5, 0)
+func getTestRelationsAroundNode1(t *testing.T, dbConn *MapmakerDBConn, tagKeys ...ownmap.TagKey) ownmapdal.TagRelationMap {
+	filter := new(ownmapdal.GetInBoundsFilter)
+	for _, tagKey := range tagKeys {
+		filter.Objects = append(filter.Objects, &ownmapdal.TagKeyWithType{ObjectType: ownmap.ObjectTypeRelation, TagKey: tagKey})
+	}
+
+	_, _, relationMap, err := dbConn.GetInBounds(context.Background(), osm.Bounds{MinLat: 51.5, MaxLat: 51.5, MinLon: 0, MaxLon: 0}, filter)
+	require.NoError(t, err)
+
+	return relationMap
+}
+
+func relationIDsForTagKey(relationMap ownmapdal.TagRelationMap, tagKey ownmap.TagKey) []int64 {
+	var ids []int64
+	for _, relation := range relationMap[tagKey] {
+		ids = append(ids, relation.RelationID)
+	}
+	return ids
+}
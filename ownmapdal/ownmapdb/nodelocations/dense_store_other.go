@@ -0,0 +1,28 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package nodelocations
+
+import (
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// DenseStore needs mmap, which isn't available on this platform. Use the sparse store instead.
+type DenseStore struct{}
+
+func NewDenseStore(filePath string) (*DenseStore, errorsx.Error) {
+	return nil, errorsx.Errorf("the dense node location store is not supported on this platform")
+}
+
+func (s *DenseStore) Set(id int64, lat, lon float64) errorsx.Error {
+	return errorsx.Errorf("the dense node location store is not supported on this platform")
+}
+
+func (s *DenseStore) Get(id int64) (*ownmap.Location, error) {
+	return nil, errorsx.Errorf("the dense node location store is not supported on this platform")
+}
+
+func (s *DenseStore) Close() errorsx.Error {
+	return nil
+}
@@ -0,0 +1,134 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package nodelocations
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+const (
+	denseStoreSlotSize = 8
+	// the file is grown (at least) this much at a time, so it doesn't have to be re-mapped too often
+	denseStoreGrowBytes = 64 * 1024 * 1024
+)
+
+// DenseStore is a flat array of locations, indexed by node ID, in a memory-mapped file.
+// The file is sparse on most filesystems, so the parts for IDs that aren't used don't take up disk space. The OS decides how much of it is kept in memory.
+type DenseStore struct {
+	file *os.File
+	data []byte
+}
+
+func NewDenseStore(filePath string) (*DenseStore, errorsx.Error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return &DenseStore{file: file}, nil
+}
+
+func (s *DenseStore) Set(id int64, lat, lon float64) errorsx.Error {
+	if id < 0 {
+		return errorsx.Errorf("the dense node location store can't store negative node IDs. ID: %d", id)
+	}
+
+	offset := id * denseStoreSlotSize
+	if offset+denseStoreSlotSize > int64(len(s.data)) {
+		err := s.grow(offset + denseStoreSlotSize)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	location := encodeLocation(lat, lon)
+	binary.LittleEndian.PutUint32(s.data[offset:], uint32(location.Lat))
+	binary.LittleEndian.PutUint32(s.data[offset+4:], location.Lon)
+
+	return nil
+}
+
+func (s *DenseStore) Get(id int64) (*ownmap.Location, error) {
+	offset := id * denseStoreSlotSize
+	if id < 0 || offset+denseStoreSlotSize > int64(len(s.data)) {
+		return nil, errorsx.ObjectNotFound
+	}
+
+	location := encodedLocation{
+		Lat: int32(binary.LittleEndian.Uint32(s.data[offset:])),
+		Lon: binary.LittleEndian.Uint32(s.data[offset+4:]),
+	}
+	if location.isEmpty() {
+		return nil, errorsx.ObjectNotFound
+	}
+
+	return location.toLocation(), nil
+}
+
+// grow makes the file at least minSize bytes, and maps all of it
+func (s *DenseStore) grow(minSize int64) errorsx.Error {
+	var err error
+
+	newSize := int64(len(s.data)) * 2
+	if newSize < minSize+denseStoreGrowBytes {
+		newSize = minSize + denseStoreGrowBytes
+	}
+
+	err = s.unmap()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	// the new part of the file is filled with zeros, i.e. no locations
+	err = s.file.Truncate(newSize)
+	if err != nil {
+		return errorsx.Wrap(err, "size", newSize)
+	}
+
+	s.data, err = syscall.Mmap(int(s.file.Fd()), 0, int(newSize), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return errorsx.Wrap(err, "size", newSize)
+	}
+
+	return nil
+}
+
+func (s *DenseStore) unmap() errorsx.Error {
+	if s.data == nil {
+		return nil
+	}
+
+	err := syscall.Munmap(s.data)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+	s.data = nil
+
+	return nil
+}
+
+// Close unmaps and closes the file. The file is left on disk.
+func (s *DenseStore) Close() errorsx.Error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.unmap()
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	closeErr := s.file.Close()
+	if closeErr != nil {
+		return errorsx.Wrap(closeErr)
+	}
+	s.file = nil
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package nodelocations
+
+import (
+	"math"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// Store keeps the location of every imported node, so that way geometries can be built without reading (and unmarshalling) the whole node
+type Store interface {
+	Set(id int64, lat, lon float64) errorsx.Error
+	Get(id int64) (*ownmap.Location, error) // errorsx.ObjectNotFound if not found
+	// Close releases the resources used by the store. It can be called more than once.
+	Close() errorsx.Error
+}
+
+type StoreType string
+
+const (
+	// StoreTypeSparse keeps a sorted table of node IDs and locations in memory. Good for extracts, where only some of the node IDs are used.
+	StoreTypeSparse StoreType = "sparse"
+	// StoreTypeDense keeps a flat array of locations, indexed by node ID, in a memory-mapped file. Good for the planet or big extracts, where most of the node IDs are used.
+	StoreTypeDense StoreType = "dense"
+)
+
+var StoreTypes = []StoreType{StoreTypeSparse, StoreTypeDense}
+
+// NewStore creates a store of the type. The dense store keeps its data in a file at filePath; it must be on the OS filesystem, since it is memory-mapped.
+func NewStore(storeType StoreType, filePath string) (Store, errorsx.Error) {
+	switch storeType {
+	case StoreTypeSparse, "":
+		return NewSparseStore(), nil
+	case StoreTypeDense:
+		store, err := NewDenseStore(filePath)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		return store, nil
+	default:
+		return nil, errorsx.Errorf("unknown node location store type: %q", storeType)
+	}
+}
+
+// locations are stored in the same way as in the OSM PBF format, as 100 nanodegree units in int32s
+const coordinateUnitsPerDegree = 1e7
+
+// encodedLocation is a location as stored. The longitude is stored with an offset (which makes it never 0 for a valid longitude), so that an all-zero location means "no location"
+type encodedLocation struct {
+	Lat int32
+	Lon uint32
+}
+
+const lonOffset = 1 << 31
+
+func encodeLocation(lat, lon float64) encodedLocation {
+	return encodedLocation{
+		Lat: int32(math.Round(lat * coordinateUnitsPerDegree)),
+		Lon: uint32(int64(math.Round(lon*coordinateUnitsPerDegree)) + lonOffset),
+	}
+}
+
+func (l encodedLocation) isEmpty() bool {
+	return l.Lat == 0 && l.Lon == 0
+}
+
+func (l encodedLocation) toLocation() *ownmap.Location {
+	return &ownmap.Location{
+		Lat: float64(l.Lat) / coordinateUnitsPerDegree,
+		Lon: float64(int64(l.Lon)-lonOffset) / coordinateUnitsPerDegree,
+	}
+}
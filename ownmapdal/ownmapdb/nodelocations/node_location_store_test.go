@@ -0,0 +1,61 @@
+package nodelocations
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	for _, storeType := range StoreTypes {
+		t.Run(string(storeType), func(t *testing.T) {
+			store, err := NewStore(storeType, filepath.Join(t.TempDir(), "node_locations"))
+			require.NoError(t, err)
+			defer store.Close()
+
+			type nodeType struct {
+				ID       int64
+				Location *ownmap.Location
+			}
+
+			nodes := []nodeType{
+				{5, &ownmap.Location{Lat: 51.1234567, Lon: -0.7654321}},
+				{6, &ownmap.Location{Lat: 0, Lon: 0}},
+				{7, &ownmap.Location{Lat: -90, Lon: -180}},
+				{8, &ownmap.Location{Lat: 90, Lon: 180}},
+				// out of order, and far enough on to make the dense store grow
+				{10 * 1000 * 1000, &ownmap.Location{Lat: 1, Lon: 2}},
+				{2, &ownmap.Location{Lat: 3, Lon: 4}},
+				// set again, with a new location
+				{5, &ownmap.Location{Lat: 51.5, Lon: -0.5}},
+			}
+
+			for _, node := range nodes {
+				err = store.Set(node.ID, node.Location.Lat, node.Location.Lon)
+				require.NoError(t, err)
+			}
+
+			for _, node := range nodes[1:] {
+				location, err := store.Get(node.ID)
+				require.NoError(t, err)
+				assert.Equal(t, node.Location, location)
+			}
+
+			for _, id := range []int64{1, 4, 9, 20 * 1000 * 1000} {
+				_, err := store.Get(id)
+				assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+			}
+
+			err = store.Close()
+			require.NoError(t, err)
+
+			// closing twice is fine
+			err = store.Close()
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package nodelocations
+
+import (
+	"sort"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+type sparseEntry struct {
+	ID       int64
+	Location encodedLocation
+}
+
+// SparseStore is a table of node IDs and locations, sorted by ID.
+// Nodes are sorted by ID in most files, so they are appended to the end of the table; if they come out of order, the table is sorted again before the next read.
+type SparseStore struct {
+	entries []sparseEntry
+	sorted  bool
+}
+
+func NewSparseStore() *SparseStore {
+	return &SparseStore{sorted: true}
+}
+
+func (s *SparseStore) Set(id int64, lat, lon float64) errorsx.Error {
+	if len(s.entries) != 0 && id <= s.entries[len(s.entries)-1].ID {
+		s.sorted = false
+	}
+
+	s.entries = append(s.entries, sparseEntry{id, encodeLocation(lat, lon)})
+
+	return nil
+}
+
+func (s *SparseStore) Get(id int64) (*ownmap.Location, error) {
+	if !s.sorted {
+		s.sort()
+	}
+
+	idx := sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].ID >= id
+	})
+	if idx == len(s.entries) || s.entries[idx].ID != id {
+		return nil, errorsx.ObjectNotFound
+	}
+
+	return s.entries[idx].Location.toLocation(), nil
+}
+
+// sort sorts the entries by ID. If a node has been set more than once, the last location set is kept.
+func (s *SparseStore) sort() {
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		return s.entries[i].ID < s.entries[j].ID
+	})
+
+	lastIdx := 0
+	for i := 1; i < len(s.entries); i++ {
+		if s.entries[i].ID == s.entries[lastIdx].ID {
+			s.entries[lastIdx] = s.entries[i]
+			continue
+		}
+
+		lastIdx++
+		s.entries[lastIdx] = s.entries[i]
+	}
+	if len(s.entries) != 0 {
+		s.entries = s.entries[:lastIdx+1]
+	}
+
+	s.sorted = true
+}
+
+func (s *SparseStore) Close() errorsx.Error {
+	s.entries = nil
+	s.sorted = true
+
+	return nil
+}
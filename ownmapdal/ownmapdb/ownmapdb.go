@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sync"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/jamesrr39/go-tracing"
@@ -28,6 +29,8 @@ type OpenFileFunc func() (gofs.File, errorsx.Error)
 
 type FileHandlerPool struct {
 	freeHandlers chan gofs.File
+	closeOnce    sync.Once
+	closeErr     errorsx.Error
 }
 
 func NewFileHandlerPool(openFileFunc OpenFileFunc, limit uint) (*FileHandlerPool, errorsx.Error) {
@@ -39,17 +42,51 @@ func NewFileHandlerPool(openFileFunc OpenFileFunc, limit uint) (*FileHandlerPool
 		}
 		freeHandlersChan <- handler
 	}
-	return &FileHandlerPool{freeHandlersChan}, nil
+	return &FileHandlerPool{freeHandlers: freeHandlersChan}, nil
 }
 
-func (p *FileHandlerPool) Get() gofs.File {
-	return <-p.freeHandlers
+// Get waits for a free file handler. It returns an error if the pool has been closed.
+func (p *FileHandlerPool) Get() (gofs.File, errorsx.Error) {
+	handler, ok := <-p.freeHandlers
+	if !ok {
+		return nil, errorsx.Errorf("file handler pool has been closed")
+	}
+
+	return handler, nil
 }
 
 func (p *FileHandlerPool) Release(handler gofs.File) {
 	p.freeHandlers <- handler
 }
 
+// Close waits for all the file handlers to be released, and then closes them. All of them are closed, even if closing one fails; the first error is returned.
+// Calls to Get that are waiting (or made afterwards) return an error. Calling Close again does nothing, and returns the same error.
+func (p *FileHandlerPool) Close() errorsx.Error {
+	p.closeOnce.Do(func() {
+		p.closeErr = p.closeHandlers()
+	})
+
+	return p.closeErr
+}
+
+func (p *FileHandlerPool) closeHandlers() errorsx.Error {
+	var handlers []gofs.File
+	for i := 0; i < cap(p.freeHandlers); i++ {
+		handlers = append(handlers, <-p.freeHandlers)
+	}
+	close(p.freeHandlers)
+
+	var firstErr errorsx.Error
+	for _, handler := range handlers {
+		err := handler.Close()
+		if err != nil && firstErr == nil {
+			firstErr = errorsx.Wrap(err)
+		}
+	}
+
+	return firstErr
+}
+
 type MapmakerDBConn struct {
 	name            string
 	header          *Header
@@ -62,7 +99,10 @@ func NewMapmakerDBConn(openFileFunc OpenFileFunc, name string, fileHandlerLimit
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
-	file := fileHandlerPool.Get()
+	file, err := fileHandlerPool.Get()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
 	defer fileHandlerPool.Release(file)
 
 	headerSizeBuffer := make([]byte, HeaderSizeContainerSize)
@@ -95,6 +135,11 @@ func (db *MapmakerDBConn) DatasetInfo() (*ownmap.DatasetInfo, errorsx.Error) {
 	return db.header.DatasetInfo, nil
 }
 
+// Close closes the file handlers, once all in-flight reads are finished
+func (db *MapmakerDBConn) Close() errorsx.Error {
+	return db.fileHandlerPool.Close()
+}
+
 func (db *MapmakerDBConn) offsetOfNodeSectionFromStartOfFile() int64 {
 	return int64(HeaderSizeContainerSize) + int64(db.header.Size())
 }
@@ -464,6 +509,80 @@ func (db *MapmakerDBConn) decodeBlock(
 	return nil
 }
 
+// forEachBlockInSection decodes every block in a section, in key order
+func (db *MapmakerDBConn) forEachBlockInSection(
+	file io.ReadSeeker,
+	sectionOffset int64,
+	sectionMetadata *SectionMetadata,
+	onFoundBlockDataFunc onFoundBlockDataFuncType,
+) errorsx.Error {
+	for _, blockMetadata := range sectionMetadata.BlockMetadatas {
+		err := db.decodeBlock(file, blockMetadata, sectionOffset, onFoundBlockDataFunc, nil)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (db *MapmakerDBConn) forEachNode(file io.ReadSeeker, onNodeFunc func(node *ownmap.OSMNode) errorsx.Error) errorsx.Error {
+	return db.forEachBlockInSection(file, db.offsetOfNodeSectionFromStartOfFile(), db.header.NodesSectionMetadata, func(blockDataBytes *bytes.Buffer, wantedKeys []KeyType) errorsx.Error {
+		blockData := new(NodesBlockData)
+		err := proto.Unmarshal(blockDataBytes.Bytes(), blockData)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		for _, node := range blockData.Nodes {
+			err = onNodeFunc(node)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *MapmakerDBConn) forEachWay(file io.ReadSeeker, onWayFunc func(way *ownmap.OSMWay) errorsx.Error) errorsx.Error {
+	return db.forEachBlockInSection(file, db.offsetOfWaySectionFromStartOfFile(), db.header.WaysSectionMetadata, func(blockDataBytes *bytes.Buffer, wantedKeys []KeyType) errorsx.Error {
+		blockData := new(WaysBlockData)
+		err := proto.Unmarshal(blockDataBytes.Bytes(), blockData)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		for _, way := range blockData.Ways {
+			err = onWayFunc(way)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *MapmakerDBConn) forEachRelation(file io.ReadSeeker, onRelationFunc func(relation *ownmap.OSMRelation) errorsx.Error) errorsx.Error {
+	return db.forEachBlockInSection(file, db.offsetOfRelationSectionFromStartOfFile(), db.header.RelationsSectionMetadata, func(blockDataBytes *bytes.Buffer, wantedKeys []KeyType) errorsx.Error {
+		blockData := new(RelationsBlockData)
+		err := proto.Unmarshal(blockDataBytes.Bytes(), blockData)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		for _, relation := range blockData.Relations {
+			err = onRelationFunc(relation)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		}
+
+		return nil
+	})
+}
+
 func (db *MapmakerDBConn) addRelationsToRelationMap(
 	file io.ReadSeeker,
 	relationMap map[int64]*ownmap.OSMRelation,
@@ -510,7 +629,10 @@ func (db *MapmakerDBConn) addRelationsToRelationMap(
 			case ownmap.OSM_MEMBER_TYPE_WAY:
 				wayMap[member.ObjectID] = nil
 			case ownmap.OSM_MEMBER_TYPE_RELATION:
-				relationMap[member.ObjectID] = nil
+				// don't overwrite sub-relations that have already been fetched (e.g. relations in the tag index, or in a cycle)
+				if _, ok := relationMap[member.ObjectID]; !ok {
+					relationMap[member.ObjectID] = nil
+				}
 			default:
 				return errorsx.Errorf("unrecognized member type: %v", member.MemberType)
 			}
@@ -569,10 +691,13 @@ func (db *MapmakerDBConn) GetInBounds(ctx context.Context, bounds osm.Bounds, fi
 		}
 	}
 
-	file := db.fileHandlerPool.Get()
+	file, err := db.fileHandlerPool.Get()
+	if err != nil {
+		return nil, nil, nil, errorsx.Wrap(err)
+	}
 	defer db.fileHandlerPool.Release(file)
 
-	err := db.addItemIDsForTagCollectionKeys(file, wantedTagIndexSections)
+	err = db.addItemIDsForTagCollectionKeys(file, wantedTagIndexSections)
 	if err != nil {
 		return nil, nil, nil, errorsx.Wrap(err)
 	}
@@ -708,6 +833,7 @@ func (db *MapmakerDBConn) GetInBounds(ctx context.Context, bounds osm.Bounds, fi
 				RelationID: relation.ID,
 				Tags:       relation.Tags,
 				Members:    relationMembers,
+				Area:       relation.Area,
 			}
 			relationTagMap[tagKey] = append(relationTagMap[tagKey], relationData)
 		}
@@ -1,11 +1,15 @@
 package ownmapdb
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
 	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
 	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_getBlockIndexesForKeys(t *testing.T) {
@@ -92,3 +96,54 @@ func Test_getBlockIndexesForKeys(t *testing.T) {
 		})
 	}
 }
+
+// closeCountingFile counts how many times it has been closed. Only Close can be called on it.
+type closeCountingFile struct {
+	gofs.File
+	closes   int
+	closeErr error
+}
+
+func (f *closeCountingFile) Close() error {
+	f.closes++
+	return f.closeErr
+}
+
+func TestFileHandlerPool_Close(t *testing.T) {
+	files := []*closeCountingFile{
+		{closeErr: errors.New("first error")},
+		{closeErr: errors.New("second error")},
+		{},
+	}
+
+	var filesOpened int
+	openFileFunc := func() (gofs.File, errorsx.Error) {
+		file := files[filesOpened]
+		filesOpened++
+		return file, nil
+	}
+
+	pool, err := NewFileHandlerPool(openFileFunc, uint(len(files)))
+	require.NoError(t, err)
+
+	// all the handlers are closed, even though closing the first ones failed, and the first error is returned
+	err = pool.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "first error")
+
+	for _, file := range files {
+		assert.Equal(t, 1, file.closes)
+	}
+
+	// closing again doesn't panic or close the handlers again
+	err = pool.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "first error")
+
+	for _, file := range files {
+		assert.Equal(t, 1, file.closes)
+	}
+
+	_, err = pool.Get()
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package ownmapdb
+
+import (
+	"context"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/paulmach/osm"
+)
+
+// GetStats goes through every object in the DB and collects the stats. The filter can be nil.
+func (db *MapmakerDBConn) GetStats(ctx context.Context, filter *ownmapdal.StatsFilter, topN int) (*ownmapdal.DatasetStats, errorsx.Error) {
+	file, err := db.fileHandlerPool.Get()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer db.fileHandlerPool.Release(file)
+
+	collector := ownmapdal.NewStatsCollector(filter, topN)
+
+	err = db.forEachNode(file, func(node *ownmap.OSMNode) errorsx.Error {
+		if ctx.Err() != nil {
+			return errorsx.Wrap(ctx.Err())
+		}
+
+		collector.AddNode(node)
+		return nil
+	})
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = db.forEachWay(file, func(way *ownmap.OSMWay) errorsx.Error {
+		if ctx.Err() != nil {
+			return errorsx.Wrap(ctx.Err())
+		}
+
+		collector.AddWay(way)
+		return nil
+	})
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = db.forEachRelation(file, func(relation *ownmap.OSMRelation) errorsx.Error {
+		if ctx.Err() != nil {
+			return errorsx.Wrap(ctx.Err())
+		}
+
+		collector.AddRelation(relation)
+		return nil
+	})
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	var headerBounds *osm.Bounds
+	if db.header.DatasetInfo != nil && db.header.DatasetInfo.Bounds != nil {
+		bounds := db.header.DatasetInfo.Bounds
+		headerBounds = &osm.Bounds{
+			MinLat: bounds.MinLat,
+			MaxLat: bounds.MaxLat,
+			MinLon: bounds.MinLon,
+			MaxLon: bounds.MaxLon,
+		}
+	}
+
+	return collector.Stats(headerBounds), nil
+}
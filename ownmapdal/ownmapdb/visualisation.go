@@ -240,7 +240,11 @@ func renderSectionData(db *MapmakerDBConn, w http.ResponseWriter, r *http.Reques
 
 	data["BlockMetadata"] = blockMetadata
 
-	file := db.fileHandlerPool.Get()
+	file, err := db.fileHandlerPool.Get()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	defer db.fileHandlerPool.Release(file)
 
 	err = db.decodeBlock(file, blockMetadata, section.SectionStartOffset, section.onFoundBlockDataFunc, nil)
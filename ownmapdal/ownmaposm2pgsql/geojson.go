@@ -0,0 +1,192 @@
+package ownmaposm2pgsql
+
+import (
+	"encoding/json"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// geoJSONGeometry is a geometry from ST_AsGeoJSON. Only the geometry types osm2pgsql creates are handled.
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// geoJSONPosition is [lon, lat]
+type geoJSONPosition []float64
+
+func decodeGeoJSON(geoJSON string) (*geoJSONGeometry, errorsx.Error) {
+	geometry := new(geoJSONGeometry)
+	err := json.Unmarshal([]byte(geoJSON), geometry)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return geometry, nil
+}
+
+func (g *geoJSONGeometry) decodeCoordinates(coordinates interface{}) errorsx.Error {
+	err := json.Unmarshal(g.Coordinates, coordinates)
+	if err != nil {
+		return errorsx.Wrap(err, "geometry type", g.Type)
+	}
+
+	return nil
+}
+
+func toLocation(position geoJSONPosition) (*ownmap.Location, errorsx.Error) {
+	if len(position) < 2 {
+		return nil, errorsx.Errorf("expected a position to have at least 2 values, but it had %d", len(position))
+	}
+
+	return &ownmap.Location{Lat: position[1], Lon: position[0]}, nil
+}
+
+func toLocations(positions []geoJSONPosition) ([]*ownmap.Location, errorsx.Error) {
+	var locations []*ownmap.Location
+	for _, position := range positions {
+		location, err := toLocation(position)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
+
+func toAreaPolygon(rings [][]geoJSONPosition) (*ownmap.AreaPolygon, errorsx.Error) {
+	if len(rings) == 0 {
+		return nil, errorsx.Errorf("polygon has no rings")
+	}
+
+	polygon := new(ownmap.AreaPolygon)
+	for i, ring := range rings {
+		points, err := toLocations(ring)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		// the first ring is the outer one
+		if i == 0 {
+			polygon.Outer = &ownmap.AreaRing{Points: points}
+			continue
+		}
+
+		polygon.Inners = append(polygon.Inners, &ownmap.AreaRing{Points: points})
+	}
+
+	return polygon, nil
+}
+
+// location is the location of a Point
+func (g *geoJSONGeometry) location() (*ownmap.Location, errorsx.Error) {
+	if g.Type != "Point" {
+		return nil, errorsx.Errorf("expected a Point, but got a %s", g.Type)
+	}
+
+	var position geoJSONPosition
+	err := g.decodeCoordinates(&position)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return toLocation(position)
+}
+
+// lines are the lines of a LineString or MultiLineString
+func (g *geoJSONGeometry) lines() ([][]*ownmap.Location, errorsx.Error) {
+	var lines [][]geoJSONPosition
+	switch g.Type {
+	case "LineString":
+		var line []geoJSONPosition
+		err := g.decodeCoordinates(&line)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		lines = append(lines, line)
+	case "MultiLineString":
+		err := g.decodeCoordinates(&lines)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	default:
+		return nil, errorsx.Errorf("expected a LineString or MultiLineString, but got a %s", g.Type)
+	}
+
+	var locationLines [][]*ownmap.Location
+	for _, line := range lines {
+		locations, err := toLocations(line)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		locationLines = append(locationLines, locations)
+	}
+
+	return locationLines, nil
+}
+
+// areaGeometry is the area of a Polygon or MultiPolygon
+func (g *geoJSONGeometry) areaGeometry() (*ownmap.AreaGeometry, errorsx.Error) {
+	var polygons [][][]geoJSONPosition
+	switch g.Type {
+	case "Polygon":
+		var rings [][]geoJSONPosition
+		err := g.decodeCoordinates(&rings)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		polygons = append(polygons, rings)
+	case "MultiPolygon":
+		err := g.decodeCoordinates(&polygons)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	default:
+		return nil, errorsx.Errorf("expected a Polygon or MultiPolygon, but got a %s", g.Type)
+	}
+
+	area := new(ownmap.AreaGeometry)
+	for _, rings := range polygons {
+		polygon, err := toAreaPolygon(rings)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		area.Polygons = append(area.Polygons, polygon)
+	}
+
+	return area, nil
+}
+
+// wayPoints are the points of a way, from a LineString, or the outer ring of a Polygon for a closed way.
+// osm2pgsql doesn't keep the node IDs, so the NodeIDs are 0.
+func (g *geoJSONGeometry) wayPoints() ([]*ownmap.WayPoint, errorsx.Error) {
+	var locations []*ownmap.Location
+	switch g.Type {
+	case "LineString":
+		lines, err := g.lines()
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		locations = lines[0]
+	case "Polygon":
+		area, err := g.areaGeometry()
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		locations = area.Polygons[0].Outer.Points
+	default:
+		return nil, errorsx.Errorf("expected a LineString or Polygon for a way, but got a %s", g.Type)
+	}
+
+	var wayPoints []*ownmap.WayPoint
+	for _, location := range locations {
+		wayPoints = append(wayPoints, &ownmap.WayPoint{Point: location})
+	}
+
+	return wayPoints, nil
+}
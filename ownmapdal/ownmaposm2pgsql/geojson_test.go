@@ -0,0 +1,148 @@
+package ownmaposm2pgsql
+
+import (
+	"testing"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_geoJSONGeometry_location(t *testing.T) {
+	tests := []struct {
+		name    string
+		geoJSON string
+		want    *ownmap.Location
+		wantErr bool
+	}{
+		{"point", `{"type":"Point","coordinates":[-0.1,51.5]}`, &ownmap.Location{Lat: 51.5, Lon: -0.1}, false},
+		{"not a point", `{"type":"LineString","coordinates":[[0,0],[1,1]]}`, nil, true},
+		{"too few values", `{"type":"Point","coordinates":[1]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometry, err := decodeGeoJSON(tt.geoJSON)
+			require.NoError(t, err)
+
+			got, err := geometry.location()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_geoJSONGeometry_lines(t *testing.T) {
+	tests := []struct {
+		name    string
+		geoJSON string
+		want    [][]*ownmap.Location
+		wantErr bool
+	}{
+		{"line string", `{"type":"LineString","coordinates":[[0,1],[2,3]]}`, [][]*ownmap.Location{
+			{{Lat: 1, Lon: 0}, {Lat: 3, Lon: 2}},
+		}, false},
+		{"multi line string", `{"type":"MultiLineString","coordinates":[[[0,1],[2,3]],[[4,5],[6,7]]]}`, [][]*ownmap.Location{
+			{{Lat: 1, Lon: 0}, {Lat: 3, Lon: 2}},
+			{{Lat: 5, Lon: 4}, {Lat: 7, Lon: 6}},
+		}, false},
+		{"polygon", `{"type":"Polygon","coordinates":[[[0,0],[0,1],[1,1],[0,0]]]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometry, err := decodeGeoJSON(tt.geoJSON)
+			require.NoError(t, err)
+
+			got, err := geometry.lines()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_geoJSONGeometry_areaGeometry(t *testing.T) {
+	ring := func(points ...float64) *ownmap.AreaRing {
+		r := new(ownmap.AreaRing)
+		for i := 0; i < len(points); i += 2 {
+			r.Points = append(r.Points, &ownmap.Location{Lat: points[i+1], Lon: points[i]})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		geoJSON string
+		want    *ownmap.AreaGeometry
+		wantErr bool
+	}{
+		{"polygon with a hole", `{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,0]],[[1,1],[2,1],[2,2],[1,1]]]}`, &ownmap.AreaGeometry{Polygons: []*ownmap.AreaPolygon{
+			{Outer: ring(0, 0, 4, 0, 4, 4, 0, 0), Inners: []*ownmap.AreaRing{ring(1, 1, 2, 1, 2, 2, 1, 1)}},
+		}}, false},
+		{"multi polygon", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[10,10],[11,10],[11,11],[10,10]]]]}`, &ownmap.AreaGeometry{Polygons: []*ownmap.AreaPolygon{
+			{Outer: ring(0, 0, 1, 0, 1, 1, 0, 0)},
+			{Outer: ring(10, 10, 11, 10, 11, 11, 10, 10)},
+		}}, false},
+		{"polygon without rings", `{"type":"Polygon","coordinates":[]}`, nil, true},
+		{"line string", `{"type":"LineString","coordinates":[[0,1],[2,3]]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometry, err := decodeGeoJSON(tt.geoJSON)
+			require.NoError(t, err)
+
+			got, err := geometry.areaGeometry()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_geoJSONGeometry_wayPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		geoJSON string
+		want    []*ownmap.WayPoint
+		wantErr bool
+	}{
+		{"line string", `{"type":"LineString","coordinates":[[0,1],[2,3]]}`, []*ownmap.WayPoint{
+			{Point: &ownmap.Location{Lat: 1, Lon: 0}},
+			{Point: &ownmap.Location{Lat: 3, Lon: 2}},
+		}, false},
+		{"polygon of a closed way", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`, []*ownmap.WayPoint{
+			{Point: &ownmap.Location{Lat: 0, Lon: 0}},
+			{Point: &ownmap.Location{Lat: 0, Lon: 1}},
+			{Point: &ownmap.Location{Lat: 1, Lon: 1}},
+			{Point: &ownmap.Location{Lat: 0, Lon: 0}},
+		}, false},
+		{"multi polygon", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometry, err := decodeGeoJSON(tt.geoJSON)
+			require.NoError(t, err)
+
+			got, err := geometry.wayPoints()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
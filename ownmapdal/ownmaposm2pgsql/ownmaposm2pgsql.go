@@ -0,0 +1,672 @@
+package ownmaposm2pgsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/paulmach/osm"
+)
+
+var _ ownmapdal.DataSourceConn = &Conn{}
+
+// defaultPrefix is the prefix of the table names osm2pgsql uses, unless it is run with --prefix
+const defaultPrefix = "planet_osm"
+
+// prefixRegexp is the prefixes that can be used in a table name without quoting
+var prefixRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// maxMercatorLat is the furthest north or south that can be transformed to web mercator (EPSG:3857), the projection osm2pgsql uses by default
+const maxMercatorLat = 85.0511
+
+const webMercatorSRID = 3857
+
+// replicationInfoMaxAge is how long the replication details are cached for. osm2pgsql-replication updates them while the database is in use, but DatasetInfo is called for every tile.
+const replicationInfoMaxAge = time.Minute
+
+// maxTagColumnsPerObject is how many tag columns go in one jsonb_build_object call, which takes at most 100 arguments (a key and a value for each column)
+const maxTagColumnsPerObject = 50
+
+type table struct {
+	name string
+	srid int
+	// tagColumns are the columns that have the value of a tag. The column is named after the tag key.
+	tagColumns []string
+	// hasHstoreTags is true if the table has a "tags" hstore column, with the tags that don't have their own column (osm2pgsql --hstore)
+	hasHstoreTags bool
+}
+
+// Conn reads a database imported by osm2pgsql, with its "pgsql" output (the default). The database is only read; it is kept up to date with osm2pgsql itself.
+//
+// Nodes come from the point table. Ways come from the line and polygon tables, and relations (which have negative IDs in osm2pgsql) from the polygon table (multipolygons and boundaries, as areas) and the line table (routes, as a way for each line).
+// The roads table isn't read, since it has a copy of some of the rows of the line table.
+// osm2pgsql doesn't keep the nodes of a way, so the way points have a node ID of 0.
+type Conn struct {
+	db           *sqlx.DB
+	name         string
+	pointTable   *table
+	lineTable    *table
+	polygonTable *table
+
+	// datasetInfoMu protects datasetInfo and replicationInfoReadAt. The bounds are read once, when connecting, and the replication details are re-read once they are older than replicationInfoMaxAge.
+	datasetInfoMu         sync.Mutex
+	datasetInfo           *ownmap.DatasetInfo
+	replicationInfoReadAt time.Time
+}
+
+// NewDBConn connects to an osm2pgsql database. A "prefix" parameter in the connection string sets the prefix of the table names, if osm2pgsql was run with --prefix.
+func NewDBConn(connStr string) (ownmapdal.DataSourceConn, errorsx.Error) {
+	var err error
+
+	prefix := defaultPrefix
+	connStrParts := strings.SplitN(connStr, "?", 2)
+	if len(connStrParts) == 2 {
+		params, err := url.ParseQuery(connStrParts[1])
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		if params.Get("prefix") != "" {
+			prefix = params.Get("prefix")
+			if !prefixRegexp.MatchString(prefix) {
+				return nil, errorsx.Errorf("invalid prefix %q. Prefixes can have lower case letters, numbers and underscores", prefix)
+			}
+
+			params.Del("prefix")
+			connStr = connStrParts[0] + "?" + params.Encode()
+		}
+	}
+
+	db, err := sqlx.Open("postgres", "postgresql://"+connStr)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	conn := &Conn{db: db, name: "osm2pgsql database"}
+	if prefix != defaultPrefix {
+		conn.name = fmt.Sprintf("osm2pgsql database (prefix %q)", prefix)
+	}
+
+	for _, tableToLoad := range []struct {
+		suffix string
+		table  **table
+	}{
+		{"_point", &conn.pointTable},
+		{"_line", &conn.lineTable},
+		{"_polygon", &conn.polygonTable},
+	} {
+		*tableToLoad.table, err = loadTable(db, prefix+tableToLoad.suffix)
+		if err != nil {
+			db.Close()
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	if conn.pointTable == nil && conn.lineTable == nil && conn.polygonTable == nil {
+		db.Close()
+		return nil, errorsx.Errorf("no osm2pgsql tables (%s_point, %s_line or %s_polygon) found", prefix, prefix, prefix)
+	}
+
+	conn.datasetInfo, err = conn.readBounds()
+	if err != nil {
+		db.Close()
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = conn.setReplicationInfo(conn.datasetInfo)
+	if err != nil {
+		db.Close()
+		return nil, errorsx.Wrap(err)
+	}
+	conn.replicationInfoReadAt = time.Now()
+
+	return conn, nil
+}
+
+// loadTable finds out which columns a table has. It returns nil if there is no table with the name.
+func loadTable(db *sqlx.DB, name string) (*table, errorsx.Error) {
+	var err error
+
+	var columns []struct {
+		Name string `db:"column_name"`
+		Type string `db:"udt_name"`
+	}
+	err = db.Select(&columns, `
+		SELECT column_name, udt_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position`, name)
+	if err != nil {
+		return nil, errorsx.Wrap(err, "table", name)
+	}
+
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	t := &table{name: name}
+	hasWayColumn := false
+	for _, column := range columns {
+		switch {
+		case column.Name == "way":
+			hasWayColumn = true
+		case column.Name == "tags" && column.Type == "hstore":
+			t.hasHstoreTags = true
+		case column.Type == "text":
+			// the other osm2pgsql columns (osm_id, z_order, way_area) aren't text
+			t.tagColumns = append(t.tagColumns, column.Name)
+		}
+	}
+
+	if !hasWayColumn {
+		return nil, errorsx.Errorf("table %q doesn't have a \"way\" geometry column. Is it an osm2pgsql table?", name)
+	}
+
+	err = db.Get(&t.srid, `
+		SELECT srid
+		FROM geometry_columns
+		WHERE f_table_schema = current_schema() AND f_table_name = $1 AND f_geometry_column = 'way'`, name)
+	if err != nil {
+		return nil, errorsx.Wrap(err, "table", name)
+	}
+
+	return t, nil
+}
+
+// tagsSQL is a JSON object of all the tags of a row
+func (t *table) tagsSQL() string {
+	var objects []string
+	for i := 0; i < len(t.tagColumns); i += maxTagColumnsPerObject {
+		end := i + maxTagColumnsPerObject
+		if end > len(t.tagColumns) {
+			end = len(t.tagColumns)
+		}
+
+		var args []string
+		for _, column := range t.tagColumns[i:end] {
+			args = append(args, pq.QuoteLiteral(column), pq.QuoteIdentifier(column))
+		}
+
+		objects = append(objects, "jsonb_build_object("+strings.Join(args, ", ")+")")
+	}
+
+	if t.hasHstoreTags {
+		objects = append(objects, "COALESCE(hstore_to_jsonb(tags), '{}')")
+	}
+
+	if len(objects) == 0 {
+		return "'{}'"
+	}
+
+	// the tag columns are NULL for the rows without the tag
+	return "jsonb_strip_nulls(" + strings.Join(objects, " || ") + ")::text"
+}
+
+// hasAnyTagSQL is the condition for rows with one of the tags. The tags that don't have their own column are looked up in the hstore column, with the keys passed as the placeholder.
+func (t *table) hasAnyTagSQL(tagKeys []string, placeholder string) (string, []string) {
+	var conditions []string
+	var hstoreTagKeys []string
+	for _, tagKey := range tagKeys {
+		if t.hasTagColumn(tagKey) {
+			conditions = append(conditions, pq.QuoteIdentifier(tagKey)+" IS NOT NULL")
+			continue
+		}
+
+		if t.hasHstoreTags {
+			hstoreTagKeys = append(hstoreTagKeys, tagKey)
+		}
+	}
+
+	if len(hstoreTagKeys) != 0 {
+		conditions = append(conditions, "tags ?| "+placeholder)
+	}
+
+	if len(conditions) == 0 {
+		return "false", nil
+	}
+
+	return strings.Join(conditions, " OR "), hstoreTagKeys
+}
+
+func (t *table) hasTagColumn(tagKey string) bool {
+	for _, column := range t.tagColumns {
+		if column == tagKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+type rowType struct {
+	OSMID    int64  `db:"osm_id"`
+	GeoJSON  string `db:"geojson"`
+	TagsJSON string `db:"tags"`
+}
+
+// getRows gets the rows in the bounds with one of the tags. The bounds use the GiST index osm2pgsql creates on the way column.
+func (c *Conn) getRows(ctx context.Context, tx *sqlx.Tx, t *table, bounds osm.Bounds, idCondition string, tagKeys []string) ([]*rowType, errorsx.Error) {
+	minLat, maxLat := bounds.MinLat, bounds.MaxLat
+	if t.srid == webMercatorSRID {
+		minLat = clamp(minLat, -maxMercatorLat, maxMercatorLat)
+		maxLat = clamp(maxLat, -maxMercatorLat, maxMercatorLat)
+	}
+
+	args := []interface{}{minLat, maxLat, bounds.MinLon, bounds.MaxLon}
+	hasAnyTagSQL, hstoreTagKeys := t.hasAnyTagSQL(tagKeys, "$5")
+	if len(hstoreTagKeys) != 0 {
+		args = append(args, pq.Array(hstoreTagKeys))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT osm_id, ST_AsGeoJSON(ST_Transform(way, 4326)) AS geojson, %s AS tags
+	FROM %s
+	WHERE way && ST_Transform(ST_MakeEnvelope($3, $1, $4, $2, 4326), %d)
+	AND %s
+	AND (%s)`, t.tagsSQL(), pq.QuoteIdentifier(t.name), t.srid, idCondition, hasAnyTagSQL)
+
+	var rows []*rowType
+	err := tx.SelectContext(ctx, &rows, query, args...)
+	if err != nil {
+		return nil, errorsx.Wrap(err, "table", t.name)
+	}
+
+	return rows, nil
+}
+
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// parseTags parses the tags JSON object, with the tags sorted by key
+func parseTags(tagsJSON string) ([]*ownmap.OSMTag, errorsx.Error) {
+	tagMap := make(map[string]string)
+	err := json.Unmarshal([]byte(tagsJSON), &tagMap)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	var tags []*ownmap.OSMTag
+	for key, value := range tagMap {
+		tags = append(tags, &ownmap.OSMTag{Key: key, Value: value})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Key < tags[j].Key
+	})
+
+	return tags, nil
+}
+
+// matchingTagKeys are the tag keys the object has, out of the wanted ones
+func matchingTagKeys(tags []*ownmap.OSMTag, tagKeys []string) []ownmap.TagKey {
+	var matching []ownmap.TagKey
+	for _, tagKey := range tagKeys {
+		for _, tag := range tags {
+			if tag.Key == tagKey {
+				matching = append(matching, ownmap.TagKey(tagKey))
+				break
+			}
+		}
+	}
+
+	return matching
+}
+
+func (c *Conn) Name() string {
+	return c.name
+}
+
+// DatasetInfo has the estimated extent of the tables as the bounds, as they were when connecting. osm2pgsql 1.9 and later keep the replication details in the osm2pgsql_properties table; they are empty for databases without it.
+func (c *Conn) DatasetInfo() (*ownmap.DatasetInfo, errorsx.Error) {
+	c.datasetInfoMu.Lock()
+	defer c.datasetInfoMu.Unlock()
+
+	if time.Since(c.replicationInfoReadAt) > replicationInfoMaxAge {
+		datasetInfo := &ownmap.DatasetInfo{
+			Bounds:       c.datasetInfo.Bounds,
+			BoundsSource: c.datasetInfo.BoundsSource,
+		}
+
+		err := c.setReplicationInfo(datasetInfo)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		c.datasetInfo = datasetInfo
+		c.replicationInfoReadAt = time.Now()
+	}
+
+	// copy it, so that the cached info can't be changed by callers
+	datasetInfo := *c.datasetInfo
+
+	return &datasetInfo, nil
+}
+
+// readBounds reads the estimated extent of the tables
+func (c *Conn) readBounds() (*ownmap.DatasetInfo, errorsx.Error) {
+	var err error
+
+	datasetInfo := &ownmap.DatasetInfo{
+		Bounds:       &ownmap.DatasetInfo_Bounds{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180},
+		BoundsSource: ownmap.BOUNDS_SOURCE_UNKNOWN,
+	}
+
+	var dataExtent *osm.Bounds
+	for _, t := range []*table{c.pointTable, c.lineTable, c.polygonTable} {
+		if t == nil {
+			continue
+		}
+
+		var extent struct {
+			MinLat sql.NullFloat64 `db:"min_lat"`
+			MaxLat sql.NullFloat64 `db:"max_lat"`
+			MinLon sql.NullFloat64 `db:"min_lon"`
+			MaxLon sql.NullFloat64 `db:"max_lon"`
+		}
+		// the extent is estimated from the table statistics, so it is quick to get. It is NULL if the table hasn't been analyzed yet.
+		err = c.db.Get(&extent, fmt.Sprintf(`
+			SELECT ST_YMin(e) AS min_lat, ST_YMax(e) AS max_lat, ST_XMin(e) AS min_lon, ST_XMax(e) AS max_lon
+			FROM (
+				SELECT ST_Transform(ST_SetSRID(ST_EstimatedExtent(current_schema(), $1, 'way')::geometry, %d), 4326) AS e
+			) extent`, t.srid), t.name)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "table", t.name)
+		}
+
+		if !extent.MinLat.Valid {
+			continue
+		}
+
+		if dataExtent == nil {
+			dataExtent = &osm.Bounds{MinLat: extent.MinLat.Float64, MaxLat: extent.MaxLat.Float64, MinLon: extent.MinLon.Float64, MaxLon: extent.MaxLon.Float64}
+			continue
+		}
+
+		dataExtent.MinLat = minFloat(dataExtent.MinLat, extent.MinLat.Float64)
+		dataExtent.MaxLat = maxFloat(dataExtent.MaxLat, extent.MaxLat.Float64)
+		dataExtent.MinLon = minFloat(dataExtent.MinLon, extent.MinLon.Float64)
+		dataExtent.MaxLon = maxFloat(dataExtent.MaxLon, extent.MaxLon.Float64)
+	}
+
+	if dataExtent != nil {
+		datasetInfo.Bounds = &ownmap.DatasetInfo_Bounds{MinLat: dataExtent.MinLat, MaxLat: dataExtent.MaxLat, MinLon: dataExtent.MinLon, MaxLon: dataExtent.MaxLon}
+		datasetInfo.BoundsSource = ownmap.BOUNDS_SOURCE_DATA_EXTENT
+	}
+
+	return datasetInfo, nil
+}
+
+func (c *Conn) setReplicationInfo(datasetInfo *ownmap.DatasetInfo) errorsx.Error {
+	var err error
+
+	var hasPropertiesTable bool
+	err = c.db.Get(&hasPropertiesTable, `SELECT to_regclass('osm2pgsql_properties') IS NOT NULL`)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	if !hasPropertiesTable {
+		return nil
+	}
+
+	var properties []struct {
+		Property string `db:"property"`
+		Value    string `db:"value"`
+	}
+	err = c.db.Select(&properties, `SELECT property, value FROM osm2pgsql_properties`)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, property := range properties {
+		switch property.Property {
+		case "replication_base_url":
+			datasetInfo.ReplicationBaseURL = property.Value
+		case "replication_sequence_number":
+			datasetInfo.ReplicationSequenceNumber, err = strconv.ParseUint(property.Value, 10, 64)
+			if err != nil {
+				return errorsx.Wrap(err, "property", property.Property)
+			}
+		case "replication_timestamp":
+			replicationTime, err := time.Parse(time.RFC3339, property.Value)
+			if err != nil {
+				return errorsx.Wrap(err, "property", property.Property)
+			}
+			datasetInfo.ReplicationTimeMs = uint64(replicationTime.UnixNano() / (1000 * 1000))
+		}
+	}
+
+	return nil
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (c *Conn) GetInBounds(ctx context.Context, bounds osm.Bounds, filter *ownmapdal.GetInBoundsFilter) (ownmapdal.TagNodeMap, ownmapdal.TagWayMap, ownmapdal.TagRelationMap, errorsx.Error) {
+	var err error
+
+	tagKeysByObjectType := make(map[ownmap.ObjectType][]string)
+	for _, object := range filter.Objects {
+		tagKeysByObjectType[object.ObjectType] = append(tagKeysByObjectType[object.ObjectType], string(object.TagKey))
+	}
+
+	tx, err := c.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, nil, errorsx.Wrap(err)
+	}
+	defer tx.Rollback()
+
+	nodeMap, err := c.getNodes(ctx, tx, bounds, tagKeysByObjectType[ownmap.ObjectTypeNode])
+	if err != nil {
+		return nil, nil, nil, errorsx.Wrap(err)
+	}
+
+	wayMap, err := c.getWays(ctx, tx, bounds, tagKeysByObjectType[ownmap.ObjectTypeWay])
+	if err != nil {
+		return nil, nil, nil, errorsx.Wrap(err)
+	}
+
+	relationMap, err := c.getRelations(ctx, tx, bounds, tagKeysByObjectType[ownmap.ObjectTypeRelation])
+	if err != nil {
+		return nil, nil, nil, errorsx.Wrap(err)
+	}
+
+	return nodeMap, wayMap, relationMap, nil
+}
+
+func (c *Conn) getNodes(ctx context.Context, tx *sqlx.Tx, bounds osm.Bounds, tagKeys []string) (ownmapdal.TagNodeMap, errorsx.Error) {
+	nodeMap := make(ownmapdal.TagNodeMap)
+	if c.pointTable == nil || len(tagKeys) == 0 {
+		return nodeMap, nil
+	}
+
+	rows, err := c.getRows(ctx, tx, c.pointTable, bounds, "true", tagKeys)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	for _, row := range rows {
+		tags, err := parseTags(row.TagsJSON)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+		}
+
+		geometry, err := decodeGeoJSON(row.GeoJSON)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+		}
+
+		location, err := geometry.location()
+		if err != nil {
+			return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+		}
+
+		node := &ownmap.OSMNode{ID: row.OSMID, Tags: tags, Lat: location.Lat, Lon: location.Lon}
+		for _, tagKey := range matchingTagKeys(tags, tagKeys) {
+			nodeMap[tagKey] = append(nodeMap[tagKey], node)
+		}
+	}
+
+	return nodeMap, nil
+}
+
+// getWays gets the ways from the line and polygon tables. osm2pgsql splits very long lines into several rows, which are returned as ways with the same ID.
+// A way with both linear and area tags is in both tables, and is only returned once.
+func (c *Conn) getWays(ctx context.Context, tx *sqlx.Tx, bounds osm.Bounds, tagKeys []string) (ownmapdal.TagWayMap, errorsx.Error) {
+	wayMap := make(ownmapdal.TagWayMap)
+	if len(tagKeys) == 0 {
+		return wayMap, nil
+	}
+
+	tableByWayID := make(map[int64]*table)
+	for _, t := range []*table{c.lineTable, c.polygonTable} {
+		if t == nil {
+			continue
+		}
+
+		rows, err := c.getRows(ctx, tx, t, bounds, "osm_id > 0", tagKeys)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		for _, row := range rows {
+			tableWithWay, ok := tableByWayID[row.OSMID]
+			if ok && tableWithWay != t {
+				continue
+			}
+			tableByWayID[row.OSMID] = t
+
+			tags, err := parseTags(row.TagsJSON)
+			if err != nil {
+				return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+			}
+
+			geometry, err := decodeGeoJSON(row.GeoJSON)
+			if err != nil {
+				return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+			}
+
+			wayPoints, err := geometry.wayPoints()
+			if err != nil {
+				return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+			}
+
+			way := &ownmap.OSMWay{ID: row.OSMID, Tags: tags, WayPoints: wayPoints}
+			for _, tagKey := range matchingTagKeys(tags, tagKeys) {
+				wayMap[tagKey] = append(wayMap[tagKey], way)
+			}
+		}
+	}
+
+	return wayMap, nil
+}
+
+// getRelations gets the relations from the polygon table (with their area) and the line table (with a way member for each line, for routes).
+// A relation that is in both tables (such as a boundary) is taken from the polygon table. A relation split into several rows is put back together.
+func (c *Conn) getRelations(ctx context.Context, tx *sqlx.Tx, bounds osm.Bounds, tagKeys []string) (ownmapdal.TagRelationMap, errorsx.Error) {
+	relationMap := make(ownmapdal.TagRelationMap)
+	if len(tagKeys) == 0 {
+		return relationMap, nil
+	}
+
+	relationsByID := make(map[int64]*ownmap.RelationData)
+	tableByRelationID := make(map[int64]*table)
+	for _, t := range []*table{c.polygonTable, c.lineTable} {
+		if t == nil {
+			continue
+		}
+
+		rows, err := c.getRows(ctx, tx, t, bounds, "osm_id < 0", tagKeys)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		for _, row := range rows {
+			relationID := -row.OSMID
+
+			tableWithRelation, ok := tableByRelationID[relationID]
+			if ok && tableWithRelation != t {
+				continue
+			}
+			tableByRelationID[relationID] = t
+
+			relation, ok := relationsByID[relationID]
+			if !ok {
+				tags, err := parseTags(row.TagsJSON)
+				if err != nil {
+					return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+				}
+
+				relation = &ownmap.RelationData{RelationID: relationID, Tags: tags}
+				relationsByID[relationID] = relation
+				for _, tagKey := range matchingTagKeys(tags, tagKeys) {
+					relationMap[tagKey] = append(relationMap[tagKey], relation)
+				}
+			}
+
+			geometry, err := decodeGeoJSON(row.GeoJSON)
+			if err != nil {
+				return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+			}
+
+			if t == c.polygonTable {
+				area, err := geometry.areaGeometry()
+				if err != nil {
+					return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+				}
+
+				if relation.Area == nil {
+					relation.Area = new(ownmap.AreaGeometry)
+				}
+				relation.Area.Polygons = append(relation.Area.Polygons, area.Polygons...)
+				continue
+			}
+
+			lines, err := geometry.lines()
+			if err != nil {
+				return nil, errorsx.Wrap(err, "osm_id", row.OSMID)
+			}
+
+			for _, line := range lines {
+				way := new(ownmap.OSMWay)
+				for _, location := range line {
+					way.WayPoints = append(way.WayPoints, &ownmap.WayPoint{Point: location})
+				}
+				relation.Members = append(relation.Members, &ownmap.RelationMemberData{Object: way})
+			}
+		}
+	}
+
+	return relationMap, nil
+}
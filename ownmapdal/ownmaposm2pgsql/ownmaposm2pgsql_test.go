@@ -0,0 +1,240 @@
+package ownmaposm2pgsql
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jmoiron/sqlx"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_table_tagsSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		table *table
+		want  string
+	}{
+		{"no tags", &table{}, "'{}'"},
+		{"tag columns", &table{tagColumns: []string{"amenity", "addr:street"}}, `jsonb_strip_nulls(jsonb_build_object('amenity', "amenity", 'addr:street', "addr:street"))::text`},
+		{"hstore", &table{hasHstoreTags: true}, `jsonb_strip_nulls(COALESCE(hstore_to_jsonb(tags), '{}'))::text`},
+		{"tag columns and hstore", &table{tagColumns: []string{"name"}, hasHstoreTags: true}, `jsonb_strip_nulls(jsonb_build_object('name', "name") || COALESCE(hstore_to_jsonb(tags), '{}'))::text`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.table.tagsSQL())
+		})
+	}
+}
+
+func Test_table_tagsSQL_manyColumns(t *testing.T) {
+	tbl := new(table)
+	for i := 0; i < maxTagColumnsPerObject+1; i++ {
+		tbl.tagColumns = append(tbl.tagColumns, fmt.Sprintf("tag%d", i))
+	}
+
+	tagsSQL := tbl.tagsSQL()
+	assert.Equal(t, 2, strings.Count(tagsSQL, "jsonb_build_object("))
+	assert.True(t, strings.HasSuffix(tagsSQL, `|| jsonb_build_object('tag50', "tag50"))::text`), tagsSQL)
+}
+
+func Test_table_hasAnyTagSQL(t *testing.T) {
+	tests := []struct {
+		name              string
+		table             *table
+		tagKeys           []string
+		wantSQL           string
+		wantHstoreTagKeys []string
+	}{
+		{"tag columns", &table{tagColumns: []string{"highway", "railway"}}, []string{"highway", "railway"}, `"highway" IS NOT NULL OR "railway" IS NOT NULL`, nil},
+		{"tag without a column or hstore", &table{tagColumns: []string{"highway"}}, []string{"natural"}, "false", nil},
+		{"hstore", &table{tagColumns: []string{"highway"}, hasHstoreTags: true}, []string{"highway", "natural", "leisure"}, `"highway" IS NOT NULL OR tags ?| $5`, []string{"natural", "leisure"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotHstoreTagKeys := tt.table.hasAnyTagSQL(tt.tagKeys, "$5")
+			assert.Equal(t, tt.wantSQL, gotSQL)
+			assert.Equal(t, tt.wantHstoreTagKeys, gotHstoreTagKeys)
+		})
+	}
+}
+
+func Test_parseTags(t *testing.T) {
+	tags, err := parseTags(`{"name": "Hyde Park", "leisure": "park"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []*ownmap.OSMTag{{Key: "leisure", Value: "park"}, {Key: "name", Value: "Hyde Park"}}, tags)
+
+	_, err = parseTags(`not json`)
+	require.Error(t, err)
+}
+
+// testConnStrEnvVar is the connection string (without the postgresql:// prefix) of a database the tests can create other databases with.
+// The tests are skipped if it isn't set. The database needs the PostGIS and hstore extensions available.
+const testConnStrEnvVar = "OWNMAP_TEST_POSTGRESQL"
+
+func createTestDB(t *testing.T) string {
+	adminConnStr := os.Getenv(testConnStrEnvVar)
+	if adminConnStr == "" {
+		t.Skipf("%s not set; skipping PostgreSQL integration test", testConnStrEnvVar)
+	}
+
+	adminDB, err := sqlx.Open("postgres", "postgresql://"+adminConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { adminDB.Close() })
+
+	dbName := fmt.Sprintf("ownmap_test_%d", time.Now().UnixNano())
+	_, err = adminDB.Exec("CREATE DATABASE " + dbName)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := adminDB.Exec("DROP DATABASE " + dbName + " WITH (FORCE)")
+		assert.NoError(t, err)
+	})
+
+	connURL, err := url.Parse("postgresql://" + adminConnStr)
+	require.NoError(t, err)
+	connURL.Path = "/" + dbName
+
+	return strings.TrimPrefix(connURL.String(), "postgresql://")
+}
+
+// osm2pgsqlTablesSQL creates tables like the ones osm2pgsql creates with its default style and --hstore, with a few objects in London
+const osm2pgsqlTablesSQL = `
+CREATE EXTENSION postgis;
+CREATE EXTENSION hstore;
+
+CREATE TABLE planet_osm_point (osm_id BIGINT, amenity TEXT, name TEXT, tags HSTORE, way GEOMETRY(Point, 3857));
+CREATE TABLE planet_osm_line (osm_id BIGINT, highway TEXT, route TEXT, name TEXT, z_order INT, tags HSTORE, way GEOMETRY(LineString, 3857));
+CREATE TABLE planet_osm_polygon (osm_id BIGINT, leisure TEXT, boundary TEXT, name TEXT, z_order INT, way_area REAL, tags HSTORE, way GEOMETRY(Geometry, 3857));
+CREATE INDEX ON planet_osm_point USING GIST (way);
+CREATE INDEX ON planet_osm_line USING GIST (way);
+CREATE INDEX ON planet_osm_polygon USING GIST (way);
+
+INSERT INTO planet_osm_point (osm_id, amenity, name, tags, way) VALUES
+	(1, 'pub', 'The Lamb', 'real_ale=>yes', ST_Transform(ST_SetSRID(ST_MakePoint(-0.1185, 51.5231), 4326), 3857)),
+	(2, NULL, NULL, 'natural=>tree', ST_Transform(ST_SetSRID(ST_MakePoint(-0.1186, 51.5232), 4326), 3857)),
+	-- Paris, outside of the bounds
+	(3, 'pub', 'Le Pub', NULL, ST_Transform(ST_SetSRID(ST_MakePoint(2.35, 48.85), 4326), 3857));
+
+INSERT INTO planet_osm_line (osm_id, highway, route, name, tags, way) VALUES
+	(10, 'residential', NULL, 'Lamb''s Conduit Street', NULL, ST_Transform(ST_GeomFromText('LINESTRING(-0.1190 51.5220, -0.1185 51.5235)', 4326), 3857)),
+	(-100, NULL, 'bus', '19', NULL, ST_Transform(ST_GeomFromText('LINESTRING(-0.1200 51.5200, -0.1190 51.5220)', 4326), 3857)),
+	(-100, NULL, 'bus', '19', NULL, ST_Transform(ST_GeomFromText('LINESTRING(-0.1190 51.5220, -0.1180 51.5240)', 4326), 3857));
+
+INSERT INTO planet_osm_polygon (osm_id, leisure, boundary, name, tags, way) VALUES
+	(20, 'park', NULL, 'Coram''s Fields', NULL, ST_Transform(ST_GeomFromText('POLYGON((-0.1200 51.5240, -0.1190 51.5240, -0.1190 51.5250, -0.1200 51.5240))', 4326), 3857)),
+	(-200, NULL, 'administrative', 'Camden', 'admin_level=>8', ST_Transform(ST_GeomFromText('MULTIPOLYGON(((-0.13 51.51, -0.11 51.51, -0.11 51.53, -0.13 51.51)))', 4326), 3857));
+
+ANALYZE planet_osm_point, planet_osm_line, planet_osm_polygon;
+`
+
+func TestConn_DatasetInfo_cached(t *testing.T) {
+	// there is no database, so the cached info has to be used
+	conn := &Conn{
+		datasetInfo: &ownmap.DatasetInfo{
+			Bounds:                    &ownmap.DatasetInfo_Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1},
+			BoundsSource:              ownmap.BOUNDS_SOURCE_DATA_EXTENT,
+			ReplicationSequenceNumber: 100,
+		},
+		replicationInfoReadAt: time.Now(),
+	}
+
+	datasetInfo, err := conn.DatasetInfo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), datasetInfo.ReplicationSequenceNumber)
+	assert.Equal(t, 52.0, datasetInfo.Bounds.MaxLat)
+
+	// callers get a copy
+	datasetInfo.ReplicationSequenceNumber = 200
+
+	datasetInfo, err = conn.DatasetInfo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), datasetInfo.ReplicationSequenceNumber)
+}
+
+func TestConn(t *testing.T) {
+	connStr := createTestDB(t)
+
+	db, err := sqlx.Open("postgres", "postgresql://"+connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(osm2pgsqlTablesSQL)
+	require.NoError(t, err)
+
+	conn, err := NewDBConn(connStr)
+	require.NoError(t, err)
+
+	assert.Equal(t, "osm2pgsql database", conn.Name())
+
+	datasetInfo, err := conn.DatasetInfo()
+	require.NoError(t, err)
+	assert.Equal(t, ownmap.BOUNDS_SOURCE_DATA_EXTENT, datasetInfo.BoundsSource)
+	// the estimated extent is a little bigger than the data
+	assert.InDelta(t, 48.85, datasetInfo.Bounds.MinLat, 0.01)
+	assert.InDelta(t, 2.35, datasetInfo.Bounds.MaxLon, 0.01)
+
+	bounds := osm.Bounds{MinLat: 51.5, MaxLat: 51.6, MinLon: -0.2, MaxLon: 0}
+	filter := &ownmapdal.GetInBoundsFilter{Objects: []*ownmapdal.TagKeyWithType{
+		{ObjectType: ownmap.ObjectTypeNode, TagKey: "amenity"},
+		{ObjectType: ownmap.ObjectTypeNode, TagKey: "natural"},
+		{ObjectType: ownmap.ObjectTypeWay, TagKey: "highway"},
+		{ObjectType: ownmap.ObjectTypeWay, TagKey: "leisure"},
+		{ObjectType: ownmap.ObjectTypeRelation, TagKey: "route"},
+		{ObjectType: ownmap.ObjectTypeRelation, TagKey: "boundary"},
+	}}
+
+	nodeMap, wayMap, relationMap, err := conn.GetInBounds(context.Background(), bounds, filter)
+	require.NoError(t, err)
+
+	require.Len(t, nodeMap["amenity"], 1)
+	pub := nodeMap["amenity"][0]
+	assert.Equal(t, int64(1), pub.ID)
+	assert.InDelta(t, 51.5231, pub.Lat, 0.000001)
+	assert.InDelta(t, -0.1185, pub.Lon, 0.000001)
+	assert.Equal(t, []*ownmap.OSMTag{
+		{Key: "amenity", Value: "pub"},
+		{Key: "name", Value: "The Lamb"},
+		{Key: "real_ale", Value: "yes"},
+	}, pub.Tags)
+
+	require.Len(t, nodeMap["natural"], 1)
+	assert.Equal(t, int64(2), nodeMap["natural"][0].ID)
+
+	require.Len(t, wayMap["highway"], 1)
+	assert.Equal(t, int64(10), wayMap["highway"][0].ID)
+	assert.Len(t, wayMap["highway"][0].WayPoints, 2)
+
+	require.Len(t, wayMap["leisure"], 1)
+	assert.Equal(t, int64(20), wayMap["leisure"][0].ID)
+	assert.Len(t, wayMap["leisure"][0].WayPoints, 4)
+
+	require.Len(t, relationMap["route"], 1)
+	route := relationMap["route"][0]
+	assert.Equal(t, int64(100), route.RelationID)
+	assert.Len(t, route.Members, 2)
+	assert.Nil(t, route.Area)
+
+	require.Len(t, relationMap["boundary"], 1)
+	boundary := relationMap["boundary"][0]
+	assert.Equal(t, int64(200), boundary.RelationID)
+	require.NotNil(t, boundary.Area)
+	assert.Len(t, boundary.Area.Polygons, 1)
+	assert.Contains(t, boundary.Tags, &ownmap.OSMTag{Key: "admin_level", Value: "8"})
+}
+
+func TestNewDBConn_noTables(t *testing.T) {
+	connStr := createTestDB(t)
+
+	_, err := NewDBConn(connStr + "?prefix=other")
+	require.Error(t, err)
+}
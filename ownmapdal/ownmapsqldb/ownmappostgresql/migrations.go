@@ -0,0 +1,200 @@
+package ownmappostgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
+	"github.com/jmoiron/sqlx"
+)
+
+// numberRowsSQL sets an index column to the position of each row within its parent, in the order the rows were inserted in.
+// It is used for databases created before the order was stored; the rows were inserted in order, and are kept in that order on disk unless they have been updated.
+const numberRowsSQL = `
+UPDATE %[1]s SET %[3]s = numbered.%[3]s
+FROM (
+	SELECT ctid AS row_ctid, ROW_NUMBER() OVER (PARTITION BY %[2]s ORDER BY ctid) - 1 AS %[3]s FROM %[1]s
+) numbered
+WHERE %[1]s.ctid = numbered.row_ctid;
+`
+
+var schemaMigrations = ownmapsqldb.MigrationSet{
+	Name: "schema",
+	Migrations: []ownmapsqldb.Migration{
+		{Version: 1, Description: "create tables", SQL: `
+CREATE TABLE nodes (
+	id BIGINT PRIMARY KEY,
+	lat DOUBLE PRECISION NOT NULL,
+	lon DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX ON nodes (lat, lon);
+
+CREATE TABLE ways (
+	id BIGINT PRIMARY KEY
+);
+CREATE TABLE relations (
+	id BIGINT PRIMARY KEY
+);
+
+-- Join tables
+
+CREATE TABLE way_nodes (
+	node_id BIGINT NOT NULL REFERENCES nodes(id),
+	way_id BIGINT NOT NULL REFERENCES ways(id)
+);
+
+CREATE INDEX ON way_nodes (node_id);
+CREATE INDEX ON way_nodes (way_id);
+
+CREATE TABLE relation_members (
+	member_id BIGINT NOT NULL,
+	member_type SMALLINT NOT NULL, -- see ownmap.OSMRelationMember_OSMMemberType
+	role TEXT NOT NULL,
+	orientation SMALLINT NOT NULL,
+	parent_id BIGINT NOT NULL REFERENCES relations(id)
+);
+
+CREATE INDEX ON relation_members (parent_id);
+
+-- Tags
+
+CREATE TABLE tags (
+	object_id BIGINT NOT NULL,
+	object_type_id BIGINT NOT NULL, -- see ownmap.ObjectType
+	key TEXT NOT NULL,
+	value TEXT NOT NULL
+);
+
+CREATE INDEX ON tags (object_type_id, key);
+CREATE INDEX ON tags (object_type_id, object_id);
+
+-- dataset info
+
+CREATE TABLE dataset_info (
+	bounds_min_lat DOUBLE PRECISION NOT NULL,
+	bounds_max_lat DOUBLE PRECISION NOT NULL,
+	bounds_min_lon DOUBLE PRECISION NOT NULL,
+	bounds_max_lon DOUBLE PRECISION NOT NULL,
+	replication_time TIMESTAMP WITHOUT TIME ZONE
+)`},
+		{Version: 2, Description: "replication sequence number and base URL", SQL: `
+ALTER TABLE dataset_info
+	ADD COLUMN replication_sequence_number BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN replication_base_url TEXT NOT NULL DEFAULT ''`},
+		{Version: 3, Description: "dataset bounds source", SQL: `
+ALTER TABLE dataset_info ADD COLUMN bounds_source SMALLINT NOT NULL DEFAULT 0 -- see ownmap.DatasetInfo_BoundsSource`},
+		{Version: 4, Description: "object bounds", SQL: `
+-- bounding boxes of ways and relations, for finding the ones in an area
+
+CREATE TABLE object_bounds (
+	object_id BIGINT NOT NULL,
+	object_type_id BIGINT NOT NULL, -- see ownmap.ObjectType
+	min_lat DOUBLE PRECISION NOT NULL,
+	max_lat DOUBLE PRECISION NOT NULL,
+	min_lon DOUBLE PRECISION NOT NULL,
+	max_lon DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (object_type_id, object_id)
+);
+
+CREATE INDEX ON object_bounds (object_type_id, min_lat, max_lat, min_lon, max_lon)`, Run: ownmapsqldb.FillObjectBounds},
+		{Version: 5, Description: "relation areas and member order", SQL: `
+-- protobuf encoded ownmap.AreaGeometry, for multipolygons and boundaries. NULL otherwise.
+-- Relations imported before this are left without an area.
+ALTER TABLE relations ADD COLUMN area BYTEA;
+
+-- position of the member in the relation
+ALTER TABLE relation_members ADD COLUMN member_index INTEGER NOT NULL DEFAULT 0;
+` + fmt.Sprintf(numberRowsSQL, "relation_members", "parent_id", "member_index") + `
+ALTER TABLE relation_members ALTER COLUMN member_index DROP DEFAULT`},
+		{Version: 6, Description: "way node order", SQL: `
+-- position of the node in the way
+ALTER TABLE way_nodes ADD COLUMN node_index INTEGER NOT NULL DEFAULT 0;
+` + fmt.Sprintf(numberRowsSQL, "way_nodes", "way_id", "node_index") + `
+ALTER TABLE way_nodes ALTER COLUMN node_index DROP DEFAULT`},
+		{Version: 7, Description: "dataset import bounds", SQL: `
+-- the bounds the data was imported with, which new objects from changes must be in. NULL for datasets imported before these were recorded.
+ALTER TABLE dataset_info
+	ADD COLUMN import_bounds_min_lat DOUBLE PRECISION,
+	ADD COLUMN import_bounds_max_lat DOUBLE PRECISION,
+	ADD COLUMN import_bounds_min_lon DOUBLE PRECISION,
+	ADD COLUMN import_bounds_max_lon DOUBLE PRECISION`},
+	},
+	LegacyVersion: legacySchemaVersion,
+}
+
+// postGISMigrations add geometry columns to the tables, for databases used with PostGIS.
+// The extension is created in the public schema, so that it can be used by the datasets in the other schemas.
+var postGISMigrations = ownmapsqldb.MigrationSet{
+	Name: "postgis",
+	Migrations: []ownmapsqldb.Migration{
+		{Version: 1, Description: "geometry columns", SQL: `
+CREATE EXTENSION IF NOT EXISTS postgis SCHEMA public;
+
+ALTER TABLE nodes ADD COLUMN geom geometry(Point, 4326);
+ALTER TABLE ways ADD COLUMN geom geometry(LineString, 4326);
+ALTER TABLE relations ADD COLUMN geom geometry(MultiPolygon, 4326);
+
+CREATE INDEX ON nodes USING GIST (geom);
+CREATE INDEX ON ways USING GIST (geom);
+CREATE INDEX ON relations USING GIST (geom)`, Run: ownmapsqldb.FillPostGISGeometries},
+	},
+	LegacyVersion: legacyPostGISVersion,
+}
+
+func hasColumn(ctx context.Context, tx *sqlx.Tx, table, column string) (bool, errorsx.Error) {
+	var count int
+	err := tx.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2`,
+		table, column,
+	)
+	if err != nil {
+		return false, errorsx.Wrap(err)
+	}
+
+	return count != 0, nil
+}
+
+// legacySchemaVersion finds the version of a database created before the schema version was recorded, from the last column or table that was added to it
+func legacySchemaVersion(ctx context.Context, tx *sqlx.Tx) (int, errorsx.Error) {
+	versionChecks := []struct {
+		version int
+		table   string
+		column  string
+	}{
+		{6, "way_nodes", "node_index"},
+		{5, "relations", "area"},
+		{4, "object_bounds", "object_id"},
+		{3, "dataset_info", "bounds_source"},
+		{2, "dataset_info", "replication_base_url"},
+		{1, "nodes", "id"},
+	}
+
+	for _, versionCheck := range versionChecks {
+		ok, err := hasColumn(ctx, tx, versionCheck.table, versionCheck.column)
+		if err != nil {
+			return 0, errorsx.Wrap(err)
+		}
+
+		if ok {
+			return versionCheck.version, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func legacyPostGISVersion(ctx context.Context, tx *sqlx.Tx) (int, errorsx.Error) {
+	ok, err := hasColumn(ctx, tx, "nodes", "geom")
+	if err != nil {
+		return 0, errorsx.Wrap(err)
+	}
+
+	if ok {
+		return 1, nil
+	}
+
+	return 0, nil
+}
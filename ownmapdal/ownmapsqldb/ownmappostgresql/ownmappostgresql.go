@@ -1,95 +1,254 @@
 package ownmappostgresql
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/jamesrr39/ownmap-app/ownmapdal"
 	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/paulmach/osm/osmpbf"
 )
 
-const postgresqlSchema = `
-CREATE TABLE nodes (
-	id BIGINT PRIMARY KEY,
-	lat DOUBLE PRECISION NOT NULL,
-	lon DOUBLE PRECISION NOT NULL
-);
-
-CREATE INDEX ON nodes (lat, lon);
-
-CREATE TABLE ways (
-	id BIGINT PRIMARY KEY
-);
-CREATE TABLE relations (
-	id BIGINT PRIMARY KEY
-);
-
--- Join tables
-
-CREATE TABLE way_nodes (
-	node_id BIGINT NOT NULL REFERENCES nodes(id),
-	way_id BIGINT NOT NULL REFERENCES ways(id)
-);
-
-CREATE INDEX ON way_nodes (node_id);
-CREATE INDEX ON way_nodes (way_id);
-
-CREATE TABLE relation_members (
-	member_id BIGINT NOT NULL,
-	member_type SMALLINT NOT NULL, -- see ownmap.OSMRelationMember_OSMMemberType
-	role TEXT NOT NULL,
-	orientation SMALLINT NOT NULL,
-	parent_id BIGINT NOT NULL REFERENCES relations(id)
-);
-
-CREATE INDEX ON relation_members (parent_id);
-
--- Tags
-
-CREATE TABLE tags (
-	object_id BIGINT NOT NULL,
-	object_type_id BIGINT NOT NULL, -- see ownmap.ObjectType
-	key TEXT NOT NULL,
-	value TEXT NOT NULL
-);
-
-CREATE INDEX ON tags (object_type_id, key);
-CREATE INDEX ON tags (object_type_id, object_id);
-
--- dataset info
-
-CREATE TABLE dataset_info (
-	bounds_min_lat DOUBLE PRECISION NOT NULL, 
-	bounds_max_lat DOUBLE PRECISION NOT NULL, 
-	bounds_min_lon DOUBLE PRECISION NOT NULL, 
-	bounds_max_lon DOUBLE PRECISION NOT NULL, 
-	replication_time TIMESTAMP WITHOUT TIME ZONE
-)`
-
-func NewImporter(connStr string, pbfHeader *osmpbf.Header) (ownmapdal.Importer, errorsx.Error) {
+// beforeImportSQL makes the tables quicker to load: the indexes other than the primary keys are dropped (they are created again by indexesSQL), and the tables are made UNLOGGED (they are made logged again by afterImportSQL).
+// The definitions of the dropped indexes are kept in a temporary table until the end of the transaction, so that exactly the same indexes are created again, including any added outside of the migrations.
+// An unlogged table can't be referenced by a logged one, so the referencing tables go first.
+const beforeImportSQL = `
+CREATE TEMPORARY TABLE dropped_indexes (definition TEXT NOT NULL) ON COMMIT DROP;
+
+DO $$
+DECLARE
+	dropped_index RECORD;
+BEGIN
+	FOR dropped_index IN
+		SELECT index_class.relname AS name, pg_get_indexdef(i.indexrelid) AS definition
+		FROM pg_index i
+		INNER JOIN pg_class index_class ON index_class.oid = i.indexrelid
+		INNER JOIN pg_class table_class ON table_class.oid = i.indrelid
+		INNER JOIN pg_namespace n ON n.oid = table_class.relnamespace
+		WHERE n.nspname = current_schema()
+			AND table_class.relname IN ('nodes', 'ways', 'relations', 'way_nodes', 'relation_members', 'tags', 'object_bounds')
+			AND NOT i.indisprimary
+	LOOP
+		INSERT INTO dropped_indexes (definition) VALUES (dropped_index.definition);
+		EXECUTE format('DROP INDEX %I', dropped_index.name);
+	END LOOP;
+END $$;
+
+ALTER TABLE way_nodes SET UNLOGGED;
+ALTER TABLE relation_members SET UNLOGGED;
+ALTER TABLE tags SET UNLOGGED;
+ALTER TABLE object_bounds SET UNLOGGED;
+ALTER TABLE nodes SET UNLOGGED;
+ALTER TABLE ways SET UNLOGGED;
+ALTER TABLE relations SET UNLOGGED;
+`
+
+// indexesSQL creates the indexes dropped by beforeImportSQL again, from their definitions
+const indexesSQL = `
+DO $$
+DECLARE
+	index_definition TEXT;
+BEGIN
+	FOR index_definition IN SELECT definition FROM dropped_indexes LOOP
+		EXECUTE index_definition;
+	END LOOP;
+END $$;
+
+TRUNCATE dropped_indexes;
+`
+
+// afterImportSQL makes the tables logged again. A logged table can't reference an unlogged one, so the referenced tables go first.
+// ANALYZE updates the statistics used for planning queries, which are out of date after loading everything at once.
+const afterImportSQL = `
+ALTER TABLE nodes SET LOGGED;
+ALTER TABLE ways SET LOGGED;
+ALTER TABLE relations SET LOGGED;
+ALTER TABLE way_nodes SET LOGGED;
+ALTER TABLE relation_members SET LOGGED;
+ALTER TABLE tags SET LOGGED;
+ALTER TABLE object_bounds SET LOGGED;
+ANALYZE nodes, ways, relations, way_nodes, relation_members, tags, object_bounds;
+`
+
+type dialect struct {
+	usePostGIS bool
+}
+
+func (dialect) AnyOf(expression, placeholder string) string {
+	return expression + " = ANY(" + placeholder + ")"
+}
+
+func (dialect) Int64Array(values []int64) interface{} {
+	return pq.Array(values)
+}
+
+func (dialect) StringArray(values []string) interface{} {
+	return pq.Array(values)
+}
+
+func (d dialect) HasPostGIS() bool {
+	return d.usePostGIS
+}
+
+// CopyRows uses COPY FROM STDIN
+func (dialect) CopyRows(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) errorsx.Error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		_, err = stmt.ExecContext(ctx, row...)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	// the rows are sent when Exec is called without arguments
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+func (dialect) BeforeImportSQL() string {
+	return beforeImportSQL
+}
+
+func (dialect) IndexesSQL() string {
+	return indexesSQL
+}
+
+func (dialect) AfterImportSQL() string {
+	return afterImportSQL
+}
+
+// datasetNameRegexp is the names that can be used for a schema without quoting
+var datasetNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// openDB opens the database. With a "dataset" parameter in the connection string, the dataset is kept in the schema with that name, so that there can be more than one dataset in a database.
+// Otherwise, the dataset is in the default (public) schema.
+func openDB(connStr string) (*sqlx.DB, string, errorsx.Error) {
+	var err error
+
+	dataset := ""
+	connStrParts := strings.SplitN(connStr, "?", 2)
+	if len(connStrParts) == 2 {
+		params, err := url.ParseQuery(connStrParts[1])
+		if err != nil {
+			return nil, "", errorsx.Wrap(err)
+		}
+
+		dataset = params.Get("dataset")
+		if dataset != "" {
+			if !datasetNameRegexp.MatchString(dataset) {
+				return nil, "", errorsx.Errorf("invalid dataset name %q. Dataset names can have lower case letters, numbers and underscores", dataset)
+			}
+
+			params.Del("dataset")
+			// the public schema is where the PostGIS extension is
+			params.Set("search_path", dataset+",public")
+			connStr = connStrParts[0] + "?" + params.Encode()
+		}
+	}
+
 	db, err := sqlx.Open("postgres", "postgresql://"+connStr)
+	if err != nil {
+		return nil, "", errorsx.Wrap(err)
+	}
+
+	return db, dataset, nil
+}
+
+func migrate(db *sqlx.DB, dataset string, usePostGIS bool) ([]ownmapsqldb.MigrationResult, errorsx.Error) {
+	var err error
+
+	if dataset != "" {
+		_, err = db.Exec(`CREATE SCHEMA IF NOT EXISTS ` + dataset)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	migrationSets := []ownmapsqldb.MigrationSet{schemaMigrations}
+	if usePostGIS {
+		migrationSets = append(migrationSets, postGISMigrations)
+	}
+
+	return ownmapsqldb.Migrate(context.Background(), db, migrationSets...)
+}
+
+// openMigratedDB opens the database, and brings the schema up to date
+func openMigratedDB(connStr string, usePostGIS bool) (*sqlx.DB, string, errorsx.Error) {
+	db, dataset, err := openDB(connStr)
+	if err != nil {
+		return nil, "", errorsx.Wrap(err)
+	}
+
+	_, err = migrate(db, dataset, usePostGIS)
+	if err != nil {
+		db.Close()
+		return nil, "", errorsx.Wrap(err)
+	}
+
+	return db, dataset, nil
+}
+
+// Migrate brings the schema of the dataset up to date, creating the tables if it is a new dataset
+func Migrate(connStr string, usePostGIS bool) ([]ownmapsqldb.MigrationResult, errorsx.Error) {
+	db, dataset, err := openDB(connStr)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
+	defer db.Close()
+
+	return migrate(db, dataset, usePostGIS)
+}
+
+func newDatasourceConnFunc(db *sqlx.DB, dataset string, usePostGIS bool) func() (ownmapdal.DataSourceConn, errorsx.Error) {
+	name := "postgresql database"
+	if dataset != "" {
+		name = fmt.Sprintf("postgresql dataset %q", dataset)
+	}
 
-	_, err = db.Exec(postgresqlSchema)
+	return func() (ownmapdal.DataSourceConn, errorsx.Error) {
+		return ownmapsqldb.NewMapmakerSQLDB(db, dialect{usePostGIS}, name), nil
+	}
+}
+
+// NewImporter imports into a new dataset. With usePostGIS, the tables also have PostGIS geometry columns; the database needs the PostGIS extension to be available.
+func NewImporter(connStr string, pbfHeader *osmpbf.Header, usePostGIS bool) (ownmapdal.Importer, errorsx.Error) {
+	db, dataset, err := openMigratedDB(connStr, usePostGIS)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	toDatasourceConnFunc := func() (ownmapdal.DataSourceConn, errorsx.Error) {
-		return ownmapsqldb.NewMapmakerSQLDB(db, "postgresql database"), nil
+	return ownmapsqldb.NewImporter(db, dialect{usePostGIS}, pbfHeader, newDatasourceConnFunc(db, dataset, usePostGIS))
+}
+
+func NewDBConn(connStr string, usePostGIS bool) (ownmapdal.DataSourceConn, errorsx.Error) {
+	db, dataset, err := openMigratedDB(connStr, usePostGIS)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
 	}
 
-	return ownmapsqldb.NewImporter(db, pbfHeader, toDatasourceConnFunc)
+	return newDatasourceConnFunc(db, dataset, usePostGIS)()
 }
 
-func NewDBConn(connStr string) (ownmapdal.DataSourceConn, errorsx.Error) {
-	db, err := sqlx.Open("postgres", "postgresql://"+connStr)
+func NewChangeApplier(connStr string, usePostGIS bool) (ownmapdal.ChangeApplier, errorsx.Error) {
+	db, dataset, err := openMigratedDB(connStr, usePostGIS)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	return ownmapsqldb.NewMapmakerSQLDB(db, "postgresql database"), nil
+	return ownmapsqldb.NewChangeApplier(db, dialect{usePostGIS}, newDatasourceConnFunc(db, dataset, usePostGIS)), nil
 }
@@ -0,0 +1,235 @@
+package ownmappostgresql
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb/sqldbtest"
+	"github.com/jmoiron/sqlx"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConnStrEnvVar is the connection string (without the postgresql:// prefix) of a database the tests can create other databases with.
+// The tests are skipped if it isn't set. `make test_integration` starts the database in docker-compose.yml and sets it.
+const testConnStrEnvVar = "OWNMAP_TEST_POSTGRESQL"
+
+// createTestDB creates a new, empty database, which is dropped when the test finishes. It returns the connection string for it.
+func createTestDB(t *testing.T) string {
+	adminConnStr := os.Getenv(testConnStrEnvVar)
+	if adminConnStr == "" {
+		t.Skipf("%s not set; skipping PostgreSQL integration test", testConnStrEnvVar)
+	}
+
+	adminDB, err := sqlx.Open("postgres", "postgresql://"+adminConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { adminDB.Close() })
+
+	dbName := fmt.Sprintf("ownmap_test_%d", time.Now().UnixNano())
+	_, err = adminDB.Exec("CREATE DATABASE " + dbName)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := adminDB.Exec("DROP DATABASE " + dbName + " WITH (FORCE)")
+		assert.NoError(t, err)
+	})
+
+	connURL, err := url.Parse("postgresql://" + adminConnStr)
+	require.NoError(t, err)
+	connURL.Path = "/" + dbName
+
+	return strings.TrimPrefix(connURL.String(), "postgresql://")
+}
+
+func withDataset(connStr, dataset string) string {
+	if strings.Contains(connStr, "?") {
+		return connStr + "&dataset=" + dataset
+	}
+
+	return connStr + "?dataset=" + dataset
+}
+
+func TestSQLDB(t *testing.T) {
+	tests := []struct {
+		name       string
+		usePostGIS bool
+		// dataset is the dataset parameter to add to the connection string, if any
+		dataset string
+	}{
+		{"public schema", false, ""},
+		{"PostGIS", true, ""},
+		{"dataset schema", false, "test_dataset"},
+		{"PostGIS dataset schema", true, "test_dataset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqldbtest.RunTests(t, func(t *testing.T) sqldbtest.Backend {
+				connStr := createTestDB(t)
+				if tt.dataset != "" {
+					connStr = withDataset(connStr, tt.dataset)
+				}
+
+				db, _, err := openDB(connStr)
+				require.NoError(t, err)
+				t.Cleanup(func() { db.Close() })
+
+				return sqldbtest.Backend{
+					NewImporter: func(pbfHeader *osmpbf.Header) (ownmapdal.Importer, errorsx.Error) {
+						return NewImporter(connStr, pbfHeader, tt.usePostGIS)
+					},
+					NewChangeApplier: func() (ownmapdal.ChangeApplier, errorsx.Error) {
+						return NewChangeApplier(connStr, tt.usePostGIS)
+					},
+					DB: db,
+				}
+			})
+		})
+	}
+}
+
+func TestMultipleDatasets(t *testing.T) {
+	var err error
+
+	ctx := context.Background()
+	connStr := createTestDB(t)
+
+	for i, dataset := range []string{"dataset_a", "dataset_b"} {
+		datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+		importer, err := NewImporter(withDataset(connStr, dataset), &osmpbf.Header{Bounds: &datasetBounds}, false)
+		require.NoError(t, err)
+
+		// both datasets have a node with the same ID
+		err = importer.ImportNode(ctx, &ownmap.OSMNode{ID: 1, Lat: 51 + float64(i), Lon: 0})
+		require.NoError(t, err)
+
+		_, err = importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+		require.NoError(t, err)
+	}
+
+	for i, dataset := range []string{"dataset_a", "dataset_b"} {
+		var db *sqlx.DB
+		db, _, err = openDB(withDataset(connStr, dataset))
+		require.NoError(t, err)
+		defer db.Close()
+
+		var lats []float64
+		err = db.Select(&lats, `SELECT lat FROM nodes`)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{51 + float64(i)}, lats)
+	}
+
+	_, _, err = openDB(withDataset(connStr, "Not-Valid"))
+	require.Error(t, err)
+}
+
+func TestImporter_keepsIndexes(t *testing.T) {
+	var err error
+
+	ctx := context.Background()
+	connStr := createTestDB(t)
+
+	_, err = Migrate(connStr, true)
+	require.NoError(t, err)
+
+	db, _, err := openDB(connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// an index added outside of the migrations, e.g. by hand for a particular query
+	_, err = db.Exec(`CREATE INDEX tags_value_custom ON tags (value)`)
+	require.NoError(t, err)
+
+	getIndexDefinitions := func() []string {
+		var definitions []string
+		err := db.Select(&definitions, `SELECT indexdef FROM pg_indexes WHERE schemaname = current_schema() ORDER BY indexdef`)
+		require.NoError(t, err)
+		return definitions
+	}
+
+	indexDefinitionsBefore := getIndexDefinitions()
+	require.Contains(t, indexDefinitionsBefore, "CREATE INDEX tags_value_custom ON public.tags USING btree (value)")
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := NewImporter(connStr, &osmpbf.Header{Bounds: &datasetBounds}, true)
+	require.NoError(t, err)
+
+	err = importer.ImportNode(ctx, &ownmap.OSMNode{ID: 1, Lat: 51, Lon: 0, Tags: []*ownmap.OSMTag{{Key: "place", Value: "town"}}})
+	require.NoError(t, err)
+
+	_, err = importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	// the indexes dropped for the import are created again, including the one from outside of the migrations
+	assert.Equal(t, indexDefinitionsBefore, getIndexDefinitions())
+}
+
+func TestMigrate(t *testing.T) {
+	var err error
+
+	connStr := createTestDB(t)
+
+	db, _, err := openDB(connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// a database created before the schema version was recorded, with the first version of the schema
+	_, err = db.Exec(schemaMigrations.Migrations[0].SQL)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		INSERT INTO nodes (id, lat, lon) VALUES (1, 51, 0), (2, 51.1, 0.1), (3, 51.2, 0.2);
+		INSERT INTO ways (id) VALUES (10);
+		INSERT INTO way_nodes (way_id, node_id) VALUES (10, 3), (10, 1);
+		INSERT INTO relations (id) VALUES (20);
+		INSERT INTO relation_members (member_id, member_type, role, orientation, parent_id) VALUES (10, 2, '', 0, 20), (2, 1, '', 0, 20)`)
+	require.NoError(t, err)
+
+	results, err := Migrate(connStr, true)
+	require.NoError(t, err)
+	assert.Equal(t, []ownmapsqldb.MigrationResult{
+		{Name: "schema", FromVersion: 1, ToVersion: 7},
+		{Name: "postgis", FromVersion: 0, ToVersion: 1},
+	}, results)
+
+	var nodeIDs []int64
+	err = db.Select(&nodeIDs, `SELECT node_id FROM way_nodes WHERE way_id = 10 ORDER BY node_index`)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 1}, nodeIDs)
+
+	var memberIDs []int64
+	err = db.Select(&memberIDs, `SELECT member_id FROM relation_members WHERE parent_id = 20 ORDER BY member_index`)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{10, 2}, memberIDs)
+
+	// the bounds have been filled in, from the way nodes and the relation members
+	var relationBounds osm.Bounds
+	err = db.QueryRow(`SELECT min_lat, max_lat, min_lon, max_lon FROM object_bounds WHERE object_type_id = $1 AND object_id = 20`, ownmap.ObjectTypeRelation).Scan(
+		&relationBounds.MinLat, &relationBounds.MaxLat, &relationBounds.MinLon, &relationBounds.MaxLon,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, osm.Bounds{MinLat: 51, MaxLat: 51.2, MinLon: 0, MaxLon: 0.2}, relationBounds)
+
+	var wayGeom string
+	err = db.Get(&wayGeom, `SELECT ST_AsText(geom) FROM ways WHERE id = 10`)
+	require.NoError(t, err)
+	assert.Equal(t, "LINESTRING(0.2 51.2,0 51)", wayGeom)
+
+	// already up to date
+	results, err = Migrate(connStr, true)
+	require.NoError(t, err)
+	assert.Equal(t, []ownmapsqldb.MigrationResult{
+		{Name: "schema", FromVersion: 7, ToVersion: 7},
+		{Name: "postgis", FromVersion: 1, ToVersion: 1},
+	}, results)
+}
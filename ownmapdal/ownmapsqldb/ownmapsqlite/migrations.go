@@ -0,0 +1,144 @@
+package ownmapsqlite
+
+import (
+	"context"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
+	"github.com/jmoiron/sqlx"
+)
+
+// schemaMigrations are the same as the PostgreSQL ones, with SQLite types. Indexes need names in SQLite.
+// Databases were first created in SQLite after the relation areas had been added, so there are fewer migrations.
+var schemaMigrations = ownmapsqldb.MigrationSet{
+	Name: "schema",
+	Migrations: []ownmapsqldb.Migration{
+		{Version: 1, Description: "create tables", SQL: `
+CREATE TABLE nodes (
+	id INTEGER PRIMARY KEY,
+	lat REAL NOT NULL,
+	lon REAL NOT NULL
+);
+
+CREATE INDEX nodes_lat_lon ON nodes (lat, lon);
+
+CREATE TABLE ways (
+	id INTEGER PRIMARY KEY
+);
+CREATE TABLE relations (
+	id INTEGER PRIMARY KEY,
+	area BLOB -- protobuf encoded ownmap.AreaGeometry, for multipolygons and boundaries. NULL otherwise
+);
+
+-- Join tables
+
+CREATE TABLE way_nodes (
+	node_id INTEGER NOT NULL REFERENCES nodes(id),
+	way_id INTEGER NOT NULL REFERENCES ways(id)
+);
+
+CREATE INDEX way_nodes_node_id ON way_nodes (node_id);
+CREATE INDEX way_nodes_way_id ON way_nodes (way_id);
+
+CREATE TABLE relation_members (
+	member_id INTEGER NOT NULL,
+	member_type INTEGER NOT NULL, -- see ownmap.OSMRelationMember_OSMMemberType
+	role TEXT NOT NULL,
+	orientation INTEGER NOT NULL,
+	parent_id INTEGER NOT NULL REFERENCES relations(id),
+	member_index INTEGER NOT NULL -- position of the member in the relation
+);
+
+CREATE INDEX relation_members_parent_id ON relation_members (parent_id);
+
+-- Tags
+
+CREATE TABLE tags (
+	object_id INTEGER NOT NULL,
+	object_type_id INTEGER NOT NULL, -- see ownmap.ObjectType
+	key TEXT NOT NULL,
+	value TEXT NOT NULL
+);
+
+CREATE INDEX tags_object_type_id_key ON tags (object_type_id, key);
+CREATE INDEX tags_object_type_id_object_id ON tags (object_type_id, object_id);
+
+-- bounding boxes of ways and relations, for finding the ones in an area
+
+CREATE TABLE object_bounds (
+	object_id INTEGER NOT NULL,
+	object_type_id INTEGER NOT NULL, -- see ownmap.ObjectType
+	min_lat REAL NOT NULL,
+	max_lat REAL NOT NULL,
+	min_lon REAL NOT NULL,
+	max_lon REAL NOT NULL,
+	PRIMARY KEY (object_type_id, object_id)
+);
+
+CREATE INDEX object_bounds_bounds ON object_bounds (object_type_id, min_lat, max_lat, min_lon, max_lon);
+
+-- dataset info
+
+CREATE TABLE dataset_info (
+	bounds_min_lat REAL NOT NULL,
+	bounds_max_lat REAL NOT NULL,
+	bounds_min_lon REAL NOT NULL,
+	bounds_max_lon REAL NOT NULL,
+	bounds_source INTEGER NOT NULL DEFAULT 0, -- see ownmap.DatasetInfo_BoundsSource
+	replication_time TIMESTAMP,
+	replication_sequence_number INTEGER NOT NULL DEFAULT 0,
+	replication_base_url TEXT NOT NULL DEFAULT ''
+)`},
+		{Version: 2, Description: "way node order", SQL: `
+ALTER TABLE way_nodes ADD COLUMN node_index INTEGER NOT NULL DEFAULT 0; -- position of the node in the way
+
+-- the nodes of each way were inserted in order
+UPDATE way_nodes SET node_index = numbered.node_index
+FROM (
+	SELECT rowid AS row_id, ROW_NUMBER() OVER (PARTITION BY way_id ORDER BY rowid) - 1 AS node_index FROM way_nodes
+) numbered
+WHERE way_nodes.rowid = numbered.row_id`},
+		{Version: 3, Description: "dataset import bounds", SQL: `
+-- the bounds the data was imported with, which new objects from changes must be in. NULL for datasets imported before these were recorded.
+ALTER TABLE dataset_info ADD COLUMN import_bounds_min_lat REAL;
+ALTER TABLE dataset_info ADD COLUMN import_bounds_max_lat REAL;
+ALTER TABLE dataset_info ADD COLUMN import_bounds_min_lon REAL;
+ALTER TABLE dataset_info ADD COLUMN import_bounds_max_lon REAL`},
+	},
+	LegacyVersion: legacySchemaVersion,
+}
+
+// legacySchemaVersion finds the version of a database created before the schema version was recorded
+func legacySchemaVersion(ctx context.Context, tx *sqlx.Tx) (int, errorsx.Error) {
+	var err error
+
+	hasColumn := func(table, column string) (bool, errorsx.Error) {
+		var count int
+		err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2`, table, column)
+		if err != nil {
+			return false, errorsx.Wrap(err)
+		}
+
+		return count != 0, nil
+	}
+
+	hasNodesTable, err := hasColumn("nodes", "id")
+	if err != nil {
+		return 0, errorsx.Wrap(err)
+	}
+
+	if !hasNodesTable {
+		return 0, nil
+	}
+
+	hasNodeIndex, err := hasColumn("way_nodes", "node_index")
+	if err != nil {
+		return 0, errorsx.Wrap(err)
+	}
+
+	if hasNodeIndex {
+		return 2, nil
+	}
+
+	return 1, nil
+}
@@ -0,0 +1,176 @@
+package ownmapsqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
+	"github.com/jmoiron/sqlx"
+	"github.com/paulmach/osm/osmpbf"
+	_ "modernc.org/sqlite" // pure Go, so builds with CGO_ENABLED=0 keep working
+)
+
+// beforeImportSQL drops the indexes created by the migrations, so that the tables are quicker to load. They are created again by indexesSQL.
+const beforeImportSQL = `
+DROP INDEX nodes_lat_lon;
+DROP INDEX way_nodes_node_id;
+DROP INDEX way_nodes_way_id;
+DROP INDEX relation_members_parent_id;
+DROP INDEX tags_object_type_id_key;
+DROP INDEX tags_object_type_id_object_id;
+DROP INDEX object_bounds_bounds;
+`
+
+const indexesSQL = `
+CREATE INDEX nodes_lat_lon ON nodes (lat, lon);
+CREATE INDEX way_nodes_node_id ON way_nodes (node_id);
+CREATE INDEX way_nodes_way_id ON way_nodes (way_id);
+CREATE INDEX relation_members_parent_id ON relation_members (parent_id);
+CREATE INDEX tags_object_type_id_key ON tags (object_type_id, key);
+CREATE INDEX tags_object_type_id_object_id ON tags (object_type_id, object_id);
+CREATE INDEX object_bounds_bounds ON object_bounds (object_type_id, min_lat, max_lat, min_lon, max_lon);
+`
+
+// dialect passes arrays as JSON, since SQLite doesn't have an array type
+type dialect struct{}
+
+func (dialect) AnyOf(expression, placeholder string) string {
+	return expression + " IN (SELECT value FROM json_each(" + placeholder + "))"
+}
+
+func (dialect) Int64Array(values []int64) interface{} {
+	return jsonArray(values)
+}
+
+func (dialect) StringArray(values []string) interface{} {
+	return jsonArray(values)
+}
+
+func (dialect) HasPostGIS() bool {
+	return false
+}
+
+// CopyRows inserts the rows one at a time. That is fast in SQLite, since it runs in process, as long as the statement is only prepared once.
+func (dialect) CopyRows(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) errorsx.Error {
+	var placeholders []string
+	for i := range columns {
+		placeholders = append(placeholders, "$"+strconv.Itoa(i+1))
+	}
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		table,
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	))
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		_, err = stmt.ExecContext(ctx, row...)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (dialect) BeforeImportSQL() string {
+	return beforeImportSQL
+}
+
+func (dialect) IndexesSQL() string {
+	return indexesSQL
+}
+
+func (dialect) AfterImportSQL() string {
+	return ""
+}
+
+func jsonArray(values interface{}) string {
+	// marshalling a slice of int64s or strings can't fail
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+func openDB(path string) (*sqlx.DB, errorsx.Error) {
+	db, err := sqlx.Open("sqlite", path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	// SQLite only allows one writer at a time. With one connection, reading waits while changes are being applied, instead of failing because the database is locked.
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+func newDatasourceConnFunc(db *sqlx.DB, path string) func() (ownmapdal.DataSourceConn, errorsx.Error) {
+	return func() (ownmapdal.DataSourceConn, errorsx.Error) {
+		return ownmapsqldb.NewMapmakerSQLDB(db, dialect{}, filepath.Base(path)), nil
+	}
+}
+
+// Migrate brings the schema of the database up to date, creating the tables if it is a new database
+func Migrate(path string) ([]ownmapsqldb.MigrationResult, errorsx.Error) {
+	db, err := openDB(path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer db.Close()
+
+	return ownmapsqldb.Migrate(context.Background(), db, schemaMigrations)
+}
+
+// openMigratedDB opens the database, and brings the schema up to date
+func openMigratedDB(path string) (*sqlx.DB, errorsx.Error) {
+	db, err := openDB(path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	_, err = ownmapsqldb.Migrate(context.Background(), db, schemaMigrations)
+	if err != nil {
+		db.Close()
+		return nil, errorsx.Wrap(err)
+	}
+
+	return db, nil
+}
+
+// NewImporter imports into a new SQLite database. Each file is one dataset.
+func NewImporter(path string, pbfHeader *osmpbf.Header) (ownmapdal.Importer, errorsx.Error) {
+	db, err := openMigratedDB(path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return ownmapsqldb.NewImporter(db, dialect{}, pbfHeader, newDatasourceConnFunc(db, path))
+}
+
+func NewDBConn(path string) (ownmapdal.DataSourceConn, errorsx.Error) {
+	db, err := openMigratedDB(path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return newDatasourceConnFunc(db, path)()
+}
+
+func NewChangeApplier(path string) (ownmapdal.ChangeApplier, errorsx.Error) {
+	db, err := openMigratedDB(path)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return ownmapsqldb.NewChangeApplier(db, dialect{}, newDatasourceConnFunc(db, path)), nil
+}
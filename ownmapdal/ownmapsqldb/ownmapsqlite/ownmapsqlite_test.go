@@ -0,0 +1,81 @@
+package ownmapsqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb/sqldbtest"
+	"github.com/jmoiron/sqlx"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLDB(t *testing.T) {
+	sqldbtest.RunTests(t, func(t *testing.T) sqldbtest.Backend {
+		path := filepath.Join(t.TempDir(), "test.sqlite")
+
+		db, err := sqlx.Open("sqlite", path)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		return sqldbtest.Backend{
+			NewImporter: func(pbfHeader *osmpbf.Header) (ownmapdal.Importer, errorsx.Error) {
+				return NewImporter(path, pbfHeader)
+			},
+			NewChangeApplier: func() (ownmapdal.ChangeApplier, errorsx.Error) {
+				return NewChangeApplier(path)
+			},
+			DB: db,
+		}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	var err error
+
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+
+	db, err := sqlx.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// a database created before the schema version was recorded, without the way node order
+	_, err = db.Exec(schemaMigrations.Migrations[0].SQL)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		INSERT INTO nodes (id, lat, lon) VALUES (1, 51, 0), (2, 51.1, 0.1), (3, 51.2, 0.2);
+		INSERT INTO ways (id) VALUES (10), (11);
+		INSERT INTO way_nodes (way_id, node_id) VALUES (10, 3), (10, 1), (11, 2), (10, 2), (11, 3)`)
+	require.NoError(t, err)
+
+	results, err := Migrate(path)
+	require.NoError(t, err)
+	assert.Equal(t, []ownmapsqldb.MigrationResult{{Name: "schema", FromVersion: 1, ToVersion: 3}}, results)
+
+	type wayNodeRow struct {
+		WayID     int64 `db:"way_id"`
+		NodeID    int64 `db:"node_id"`
+		NodeIndex int   `db:"node_index"`
+	}
+
+	var wayNodes []wayNodeRow
+	err = db.Select(&wayNodes, `SELECT way_id, node_id, node_index FROM way_nodes ORDER BY way_id, node_index`)
+	require.NoError(t, err)
+	assert.Equal(t, []wayNodeRow{
+		{10, 3, 0},
+		{10, 1, 1},
+		{10, 2, 2},
+		{11, 2, 0},
+		{11, 3, 1},
+	}, wayNodes)
+
+	// already up to date
+	results, err = Migrate(path)
+	require.NoError(t, err)
+	assert.Equal(t, []ownmapsqldb.MigrationResult{{Name: "schema", FromVersion: 3, ToVersion: 3}}, results)
+}
@@ -0,0 +1,305 @@
+package ownmapsqldb
+
+import (
+	"context"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jmoiron/sqlx"
+)
+
+var _ ownmapdal.ChangeApplier = &ChangeApplier{}
+
+// ChangeApplier applies changes to a SQL database in place, inside a single transaction.
+// Way geometry is joined from the nodes table when it is read; only the bounds of the ways and relations containing moved nodes need updating.
+type ChangeApplier struct {
+	db               *sqlx.DB
+	dialect          Dialect
+	toDatasourceConn toDatasourceConnFunc
+}
+
+func NewChangeApplier(db *sqlx.DB, dialect Dialect, toDatasourceConn toDatasourceConnFunc) *ChangeApplier {
+	return &ChangeApplier{db, dialect, toDatasourceConn}
+}
+
+func (a *ChangeApplier) ApplyChanges(ctx context.Context, changeIndex *ownmapdal.ChangeIndex) (ownmapdal.DataSourceConn, errorsx.Error) {
+	var err error
+
+	datasourceConn, err := a.toDatasourceConn()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	datasetInfo, err := datasourceConn.DatasetInfo()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	// new nodes are filtered with the bounds the dataset was imported with, not the (usually tighter) extent of the data imported so far
+	boundsForNewObjects := datasetInfo.GetBoundsForNewObjects()
+
+	// the transaction is rolled back if the context is cancelled
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer tx.Rollback()
+
+	// the importer methods are used to write the objects, since they share the same transaction
+	importer := newTxImporter(tx, a.dialect)
+
+	// delete everything that is changed, parents first, so that nothing is left referencing a deleted node.
+	// Modified objects are re-inserted afterwards.
+	for id := range changeIndex.DeletedRelations {
+		err = deleteRelation(tx, id)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+	for id := range changeIndex.UpsertedRelations {
+		err = deleteRelation(tx, id)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+	for id := range changeIndex.DeletedWays {
+		err = deleteWay(tx, id)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+	for id := range changeIndex.UpsertedWays {
+		err = deleteWay(tx, id)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+	for id := range changeIndex.DeletedNodes {
+		err = deleteNode(tx, a.dialect, id)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	// nodes are updated in place, so that unchanged ways referencing them keep working
+	for id, obj := range changeIndex.UpsertedNodes {
+		_, err = importer.GetNodeByID(id)
+		if err != nil && errorsx.Cause(err) != errorsx.ObjectNotFound {
+			return nil, errorsx.Wrap(err)
+		}
+		nodeExists := err == nil
+
+		if !nodeExists && !boundsForNewObjects.ContainsNode(obj) {
+			// new node, but not in the area of this dataset
+			continue
+		}
+
+		if nodeExists {
+			err = deleteObjectTags(tx, id, ownmap.ObjectTypeNode)
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+
+			_, err = tx.Exec(`UPDATE nodes SET lat = $1, lon = $2 WHERE id = $3`, obj.Lat, obj.Lon, id)
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+
+			err = updateWayBoundsForNode(tx, id)
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+
+			if a.dialect.HasPostGIS() {
+				err = updatePostGISGeometriesForNode(tx, id)
+				if err != nil {
+					return nil, errorsx.Wrap(err)
+				}
+			}
+
+			err = importer.addTags(id, ownmap.ObjectTypeNode, ownmap.NewMapmakerTagsFromOSMTags(obj.Tags))
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+
+			continue
+		}
+
+		err = importer.ImportNode(ctx, ownmapdal.NewOSMNodeFromChangeNode(obj))
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	for id, obj := range changeIndex.UpsertedWays {
+		wayPoints, err := ownmapdal.ResolveWayPoints(importer, ownmapdal.WayNodeIDs(obj))
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		if len(wayPoints) == 0 {
+			// none of the nodes are in this dataset
+			continue
+		}
+
+		err = importer.ImportWay(ctx, &ownmap.OSMWay{
+			ID:        id,
+			Tags:      ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
+			WayPoints: wayPoints,
+		})
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	for _, obj := range changeIndex.UpsertedRelations {
+		relation, err := ownmap.NewMapmakerRelationFromOSMRelation(obj)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		isInDataset, err := ownmapdal.IsAnyRelationMemberInDataset(importer, relation)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		if !isInDataset {
+			continue
+		}
+
+		err = importer.ImportRelation(ctx, relation)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
+	err = importer.rows.flush(ctx, tx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = updateRelationBoundsFromMembers(ctx, tx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(
+		`UPDATE dataset_info SET replication_time = $1, replication_sequence_number = $2`,
+		changeIndex.ReplicationTimeAfterChange(datasetInfo),
+		changeIndex.ReplicationSequenceNumberAfterChange(datasetInfo),
+	)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return datasourceConn, nil
+}
+
+func deleteObjectTags(tx *sqlx.Tx, id int64, objectType ownmap.ObjectType) errorsx.Error {
+	_, err := tx.Exec(`DELETE FROM tags WHERE object_id = $1 AND object_type_id = $2`, id, objectType)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+func deleteNode(tx *sqlx.Tx, dialect Dialect, id int64) errorsx.Error {
+	var err error
+
+	err = deleteObjectTags(tx, id, ownmap.ObjectTypeNode)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	// ways that weren't part of the change might still reference this node. Their bounds and geometry are rebuilt without it.
+	var wayIDs []int64
+	err = tx.Select(&wayIDs, `SELECT DISTINCT way_id FROM way_nodes WHERE node_id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM way_nodes WHERE node_id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, wayID := range wayIDs {
+		err = updateWayBounds(tx, wayID)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		if dialect.HasPostGIS() {
+			err = updatePostGISWayGeometry(tx, wayID)
+			if err != nil {
+				return errorsx.Wrap(err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM nodes WHERE id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+func deleteWay(tx *sqlx.Tx, id int64) errorsx.Error {
+	var err error
+
+	err = deleteObjectTags(tx, id, ownmap.ObjectTypeWay)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	err = deleteObjectBounds(tx, id, ownmap.ObjectTypeWay)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM way_nodes WHERE way_id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM ways WHERE id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+func deleteRelation(tx *sqlx.Tx, id int64) errorsx.Error {
+	var err error
+
+	err = deleteObjectTags(tx, id, ownmap.ObjectTypeRelation)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	err = deleteObjectBounds(tx, id, ownmap.ObjectTypeRelation)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM relation_members WHERE parent_id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM relations WHERE id = $1`, id)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package ownmapsqldb
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 	"time"
@@ -10,21 +11,22 @@ import (
 	"github.com/jamesrr39/ownmap-app/ownmap"
 	"github.com/jamesrr39/ownmap-app/ownmapdal"
 	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
 	"github.com/paulmach/osm"
 )
 
 var _ ownmapdal.DataSourceConn = &MapmakerSQLDB{}
 
 type MapmakerSQLDB struct {
-	name string
-	db   *sqlx.DB
+	name    string
+	db      *sqlx.DB
+	dialect Dialect
 }
 
-func NewMapmakerSQLDB(db *sqlx.DB, name string) *MapmakerSQLDB {
+func NewMapmakerSQLDB(db *sqlx.DB, dialect Dialect, name string) *MapmakerSQLDB {
 	return &MapmakerSQLDB{
-		name: name,
-		db:   db,
+		name:    name,
+		db:      db,
+		dialect: dialect,
 	}
 }
 
@@ -45,13 +47,21 @@ func (db *MapmakerSQLDB) DatasetInfo() (*ownmap.DatasetInfo, errorsx.Error) {
 			bounds_max_lat, 
 			bounds_min_lon, 
 			bounds_max_lon, 
-			replication_time 
+			bounds_source,
+			replication_time,
+			replication_sequence_number,
+			replication_base_url,
+			import_bounds_min_lat,
+			import_bounds_max_lat,
+			import_bounds_min_lon,
+			import_bounds_max_lon
 		FROM dataset_info`)
 	if row.Err() != nil {
 		return nil, errorsx.Wrap(row.Err())
 	}
 
 	var replicationTime time.Time
+	var importMinLat, importMaxLat, importMinLon, importMaxLon sql.NullFloat64
 	datasetInfo := new(ownmap.DatasetInfo)
 	datasetInfo.Bounds = new(ownmap.DatasetInfo_Bounds)
 	err = row.Scan(
@@ -59,27 +69,81 @@ func (db *MapmakerSQLDB) DatasetInfo() (*ownmap.DatasetInfo, errorsx.Error) {
 		&datasetInfo.Bounds.MaxLat,
 		&datasetInfo.Bounds.MinLon,
 		&datasetInfo.Bounds.MaxLon,
-		&replicationTime)
+		&datasetInfo.BoundsSource,
+		&replicationTime,
+		&datasetInfo.ReplicationSequenceNumber,
+		&datasetInfo.ReplicationBaseURL,
+		&importMinLat,
+		&importMaxLat,
+		&importMinLon,
+		&importMaxLon)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
 	datasetInfo.ReplicationTimeMs = uint64(replicationTime.UnixNano() / (1000 * 1000))
 
+	// the import bounds are NULL for datasets imported before they were recorded
+	if importMinLat.Valid {
+		datasetInfo.ImportBounds = &ownmap.DatasetInfo_Bounds{
+			MinLat: importMinLat.Float64,
+			MaxLat: importMaxLat.Float64,
+			MinLon: importMinLon.Float64,
+			MaxLon: importMaxLon.Float64,
+		}
+	}
+
 	return datasetInfo, nil
 }
 
 func (db *MapmakerSQLDB) GetInBounds(ctx context.Context, bounds osm.Bounds, filter *ownmapdal.GetInBoundsFilter) (ownmapdal.TagNodeMap, ownmapdal.TagWayMap, ownmapdal.TagRelationMap, errorsx.Error) {
-	var whereClauseLines []string
-	var args []interface{}
+	tagKeysByObjectType := make(map[ownmap.ObjectType][]string)
+	for _, object := range filter.Objects {
+		tagKeysByObjectType[object.ObjectType] = append(tagKeysByObjectType[object.ObjectType], string(object.TagKey))
+	}
+
+	// $1 to $4 are the bounds
+	args := []interface{}{bounds.MinLat, bounds.MaxLat, bounds.MinLon, bounds.MaxLon}
+	var selects []string
+
+	// nodes are in bounds if their location is, ways and relations if their bounding box overlaps the bounds.
+	// Relations without an area don't have a PostGIS geometry, so relations always use their bounding box.
+	boundsBox := `
+	INNER JOIN object_bounds ob ON ob.object_type_id = t.object_type_id AND ob.object_id = t.object_id
+	WHERE ob.min_lat <= $2 AND ob.max_lat >= $1
+	AND ob.min_lon <= $4 AND ob.max_lon >= $3`
+	inBoundsSQLByObjectType := map[ownmap.ObjectType]string{
+		ownmap.ObjectTypeNode: `
+	INNER JOIN nodes n ON n.id = t.object_id
+	WHERE n.lat BETWEEN $1 AND $2
+	AND n.lon BETWEEN $3 AND $4`,
+		ownmap.ObjectTypeWay:      boundsBox,
+		ownmap.ObjectTypeRelation: boundsBox,
+	}
+	if db.dialect.HasPostGIS() {
+		inBoundsSQLByObjectType[ownmap.ObjectTypeNode] = `
+	INNER JOIN nodes n ON n.id = t.object_id
+	WHERE n.geom && ` + postGISEnvelopeSQL
+		inBoundsSQLByObjectType[ownmap.ObjectTypeWay] = `
+	INNER JOIN ways w ON w.id = t.object_id
+	WHERE w.geom && ` + postGISEnvelopeSQL
+	}
 
-	for i, object := range filter.Objects {
-		if object.ObjectType == ownmap.ObjectTypeRelation {
-			panic("relation getting not supported yet")
+	for _, objectType := range []ownmap.ObjectType{ownmap.ObjectTypeNode, ownmap.ObjectTypeWay, ownmap.ObjectTypeRelation} {
+		tagKeys := tagKeysByObjectType[objectType]
+		if len(tagKeys) == 0 {
+			continue
 		}
 
-		whereClauseLines = append(whereClauseLines, fmt.Sprintf(`(t.object_type_id = $%d AND t.key = $%d)`, (i*2)+1, (i*2)+2))
-		args = append(args, object.ObjectType, object.TagKey)
+		args = append(args, db.dialect.StringArray(tagKeys))
+		selects = append(selects, fmt.Sprintf(`
+	SELECT t.object_id, t.object_type_id, t.key, t.value
+	FROM tags t%s
+	AND t.object_type_id = %d AND %s`, inBoundsSQLByObjectType[objectType], objectType, db.dialect.AnyOf("t.key", fmt.Sprintf("$%d", len(args)))))
+	}
+
+	if len(selects) == 0 {
+		return make(ownmapdal.TagNodeMap), make(ownmapdal.TagWayMap), make(ownmapdal.TagRelationMap), nil
 	}
 
 	tx, err := db.db.Beginx()
@@ -88,15 +152,8 @@ func (db *MapmakerSQLDB) GetInBounds(ctx context.Context, bounds osm.Bounds, fil
 	}
 	defer tx.Rollback()
 
-	query := fmt.Sprintf(`
-	SELECT object_id, object_type_id, key, value
-	FROM tags t
-	WHERE
-		%s
-	`, strings.Join(whereClauseLines, " OR\n"))
-
 	var tagRows []*tagRowType
-	err = tx.Select(&tagRows, query, args...)
+	err = tx.SelectContext(ctx, &tagRows, strings.Join(selects, "\n\tUNION ALL"), args...)
 	if err != nil {
 		return nil, nil, nil, errorsx.Wrap(err)
 	}
@@ -105,40 +162,48 @@ func (db *MapmakerSQLDB) GetInBounds(ctx context.Context, bounds osm.Bounds, fil
 }
 
 func (db *MapmakerSQLDB) getWayNodesWanted(tx *sqlx.Tx, wayIDs []int64) (map[int64][]*ownmap.WayPoint, errorsx.Error) {
-	type nodeLocationWithWay struct {
-		WayID int64 `db:"way_id"`
-		*ownmap.WayPoint
+	type wayNodeLocationType struct {
+		WayID    int64 `db:"way_id"`
+		NodeID   int64 `db:"node_id"`
+		Lat, Lon float64
 	}
 
-	var wayNodeLocations []*nodeLocationWithWay
+	var wayNodeLocations []*wayNodeLocationType
 
-	err := tx.Select(&wayNodeLocations, `
+	err := tx.Select(&wayNodeLocations, fmt.Sprintf(`
 		SELECT wn.way_id as way_id, n.id as node_id, n.lat, n.lon
 		FROM nodes n
 		INNER JOIN way_nodes wn
 		ON n.id = wn.node_id
-		WHERE wn.way_id = ANY($1)
-	`, pq.Array(wayIDs))
+		WHERE %s
+		ORDER BY wn.way_id, wn.node_index
+	`, db.dialect.AnyOf("wn.way_id", "$1")), db.dialect.Int64Array(wayIDs))
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
 	wayIDsWantedMap := make(map[int64][]*ownmap.WayPoint)
 	for _, wayNodeLocation := range wayNodeLocations {
-		wayIDsWantedMap[wayNodeLocation.WayID] = append(wayIDsWantedMap[wayNodeLocation.WayID], wayNodeLocation.WayPoint)
+		wayIDsWantedMap[wayNodeLocation.WayID] = append(wayIDsWantedMap[wayNodeLocation.WayID], &ownmap.WayPoint{
+			NodeID: wayNodeLocation.NodeID,
+			Point: &ownmap.Location{
+				Lat: wayNodeLocation.Lat,
+				Lon: wayNodeLocation.Lon,
+			},
+		})
 	}
 
 	return wayIDsWantedMap, nil
 }
 
-func (db *MapmakerSQLDB) getAllTagsForObjectIDs(tx *sqlx.Tx, wayIDsWanted []int64) (map[int64][]*ownmap.OSMTag, errorsx.Error) {
+func (db *MapmakerSQLDB) getAllTagsForObjectIDs(tx *sqlx.Tx, objectType ownmap.ObjectType, objectIDsWanted []int64) (map[int64][]*ownmap.OSMTag, errorsx.Error) {
 	var x []tagRowType
-	err := tx.Select(&x, `
+	err := tx.Select(&x, fmt.Sprintf(`
 		SELECT t.object_id, t.key, t.value
 		FROM tags t
 		WHERE t.object_type_id = $1
-		AND t.object_id = ANY ($2)
-	`, ownmap.ObjectTypeWay, pq.Array(wayIDsWanted))
+		AND %s
+	`, db.dialect.AnyOf("t.object_id", "$2")), objectType, db.dialect.Int64Array(objectIDsWanted))
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
@@ -161,12 +226,15 @@ func (db *MapmakerSQLDB) getObjectsFromTagRows(tx *sqlx.Tx, tagRows []*tagRowTyp
 
 	var nodeIDsWanted []int64
 	var wayIDsWanted []int64
+	var relationTagRows []*tagRowType
 	for _, tagRow := range tagRows {
 		switch tagRow.ObjectTypeID {
 		case ownmap.ObjectTypeWay:
 			wayIDsWanted = append(wayIDsWanted, tagRow.ObjectID)
 		case ownmap.ObjectTypeNode:
 			nodeIDsWanted = append(nodeIDsWanted, tagRow.ObjectID)
+		case ownmap.ObjectTypeRelation:
+			relationTagRows = append(relationTagRows, tagRow)
 		}
 	}
 
@@ -180,7 +248,12 @@ func (db *MapmakerSQLDB) getObjectsFromTagRows(tx *sqlx.Tx, tagRows []*tagRowTyp
 		return nil, nil, nil, errorsx.Wrap(err)
 	}
 
-	return tagNodeMap, tagWayMap, make(ownmapdal.TagRelationMap), nil
+	tagRelationMap, err := db.getRelationsFromTagRows(tx, relationTagRows)
+	if err != nil {
+		return nil, nil, nil, errorsx.Wrap(err)
+	}
+
+	return tagNodeMap, tagWayMap, tagRelationMap, nil
 }
 
 type tagNodeRecordType struct {
@@ -189,17 +262,21 @@ type tagNodeRecordType struct {
 	Key, Value string
 }
 
-func (db *MapmakerSQLDB) getNodesFromNodeIDList(tx *sqlx.Tx, nodeIDsWanted []int64) (ownmapdal.TagNodeMap, errorsx.Error) {
+// getNodesByIDs returns the nodes that are in the database, with their tags
+func (db *MapmakerSQLDB) getNodesByIDs(tx *sqlx.Tx, nodeIDsWanted []int64) (map[int64]*ownmap.OSMNode, errorsx.Error) {
+	if len(nodeIDsWanted) == 0 {
+		return make(map[int64]*ownmap.OSMNode), nil
+	}
 
+	// nodes without tags have one row, with an empty key
 	var tagNodeRecords []*tagNodeRecordType
-	err := tx.Select(&tagNodeRecords, `
-		SELECT n.id as node_id, n.lat, n.lon, t.key, t.value
+	err := tx.Select(&tagNodeRecords, fmt.Sprintf(`
+		SELECT n.id as node_id, n.lat, n.lon, COALESCE(t.key, '') AS key, COALESCE(t.value, '') AS value
 		FROM nodes n
 		LEFT JOIN tags t
-		ON n.id = t.object_id
-		WHERE t.object_type_id = $1
-		AND t.object_id = ANY ($2)
-	`, ownmap.ObjectTypeNode, pq.Array(nodeIDsWanted))
+		ON n.id = t.object_id AND t.object_type_id = $1
+		WHERE %s
+	`, db.dialect.AnyOf("n.id", "$2")), ownmap.ObjectTypeNode, db.dialect.Int64Array(nodeIDsWanted))
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
@@ -225,6 +302,15 @@ func (db *MapmakerSQLDB) getNodesFromNodeIDList(tx *sqlx.Tx, nodeIDsWanted []int
 		}
 	}
 
+	return wantedNodesMap, nil
+}
+
+func (db *MapmakerSQLDB) getNodesFromNodeIDList(tx *sqlx.Tx, nodeIDsWanted []int64) (ownmapdal.TagNodeMap, errorsx.Error) {
+	wantedNodesMap, err := db.getNodesByIDs(tx, nodeIDsWanted)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	tagNodeMap := make(ownmapdal.TagNodeMap)
 	for _, node := range wantedNodesMap {
 		for _, tag := range node.Tags {
@@ -235,39 +321,234 @@ func (db *MapmakerSQLDB) getNodesFromNodeIDList(tx *sqlx.Tx, nodeIDsWanted []int
 	return tagNodeMap, nil
 }
 
-func (db *MapmakerSQLDB) getWaysFromWayIDList(tx *sqlx.Tx, wayIDsWanted []int64) (ownmapdal.TagWayMap, errorsx.Error) {
+// getWaysByIDs returns the ways that are in the database, with their tags and way points
+func (db *MapmakerSQLDB) getWaysByIDs(tx *sqlx.Tx, wayIDsWanted []int64) (map[int64]*ownmap.OSMWay, errorsx.Error) {
+	wayMap := make(map[int64]*ownmap.OSMWay)
 	if len(wayIDsWanted) == 0 {
-		return make(ownmapdal.TagWayMap), nil
+		return wayMap, nil
 	}
 
-	wayNodesWanted, err := db.getWayNodesWanted(tx, wayIDsWanted)
+	var wayIDs []int64
+	err := tx.Select(&wayIDs, `SELECT id FROM ways WHERE `+db.dialect.AnyOf("id", "$1"), db.dialect.Int64Array(wayIDsWanted))
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	tagMap, err := db.getAllTagsForObjectIDs(tx, wayIDsWanted)
+	wayNodesWanted, err := db.getWayNodesWanted(tx, wayIDs)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
-	wayTagMap := make(ownmapdal.TagWayMap)
-	for _, wayID := range wayIDsWanted {
-		tags := tagMap[wayID]
-		waypoints := wayNodesWanted[wayID]
+	tagMap, err := db.getAllTagsForObjectIDs(tx, ownmap.ObjectTypeWay, wayIDs)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
 
-		way := &ownmap.OSMWay{
+	for _, wayID := range wayIDs {
+		wayMap[wayID] = &ownmap.OSMWay{
 			ID:        wayID,
-			Tags:      tags,
-			WayPoints: waypoints,
+			Tags:      tagMap[wayID],
+			WayPoints: wayNodesWanted[wayID],
 		}
+	}
 
-		for _, tag := range tags {
+	return wayMap, nil
+}
+
+func (db *MapmakerSQLDB) getWaysFromWayIDList(tx *sqlx.Tx, wayIDsWanted []int64) (ownmapdal.TagWayMap, errorsx.Error) {
+	wayMap, err := db.getWaysByIDs(tx, wayIDsWanted)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	wayTagMap := make(ownmapdal.TagWayMap)
+	for _, way := range wayMap {
+		for _, tag := range way.Tags {
 			wayTagMap[ownmap.TagKey(tag.Key)] = append(wayTagMap[ownmap.TagKey(tag.Key)], way)
 		}
 	}
 
 	return wayTagMap, nil
+}
+
+// getRelationsByIDs returns the relations that are in the database, with their tags, members and area
+func (db *MapmakerSQLDB) getRelationsByIDs(tx *sqlx.Tx, relationIDsWanted []int64) (map[int64]*ownmap.OSMRelation, errorsx.Error) {
+	type relationRowType struct {
+		ID   int64
+		Area []byte
+	}
+
+	var relationRows []*relationRowType
+	err := tx.Select(&relationRows, `SELECT id, area FROM relations WHERE `+db.dialect.AnyOf("id", "$1"), db.dialect.Int64Array(relationIDsWanted))
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	var relationIDs []int64
+	relationMap := make(map[int64]*ownmap.OSMRelation)
+	for _, relationRow := range relationRows {
+		relation := &ownmap.OSMRelation{ID: relationRow.ID}
+
+		if len(relationRow.Area) != 0 {
+			relation.Area = new(ownmap.AreaGeometry)
+			err = relation.Area.Unmarshal(relationRow.Area)
+			if err != nil {
+				return nil, errorsx.Wrap(err, "relationID", relationRow.ID)
+			}
+		}
+
+		relationMap[relation.ID] = relation
+		relationIDs = append(relationIDs, relation.ID)
+	}
+
+	tagMap, err := db.getAllTagsForObjectIDs(tx, ownmap.ObjectTypeRelation, relationIDs)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	for id, tags := range tagMap {
+		relationMap[id].Tags = tags
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT parent_id, member_id, member_type, role, orientation
+		FROM relation_members rm
+		WHERE %s
+		ORDER BY rm.parent_id, rm.member_index
+	`, db.dialect.AnyOf("rm.parent_id", "$1")), db.dialect.Int64Array(relationIDs))
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID int64
+		member := new(ownmap.OSMRelationMember)
+		err = rows.Scan(&parentID, &member.ObjectID, &member.MemberType, &member.Role, &member.Orientation)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		relationMap[parentID].Members = append(relationMap[parentID].Members, member)
+	}
+
+	if rows.Err() != nil {
+		return nil, errorsx.Wrap(rows.Err())
+	}
+
+	return relationMap, nil
+}
+
+// getRelationsFromTagRows fetches the relations of the tag rows, with their members.
+// Members that are relations are fetched with their own members, however deeply they are nested, so the result is the same as from an ownmapdb file.
+// Members that aren't in the database (for example, because they are outside of the dataset) are nil.
+func (db *MapmakerSQLDB) getRelationsFromTagRows(tx *sqlx.Tx, relationTagRows []*tagRowType) (ownmapdal.TagRelationMap, errorsx.Error) {
+	tagRelationMap := make(ownmapdal.TagRelationMap)
+	if len(relationTagRows) == 0 {
+		return tagRelationMap, nil
+	}
+
+	// fetch a level of nesting at a time. Relations that have been asked for are in the map (nil if they weren't found), so that each one is only asked for once, even if relations contain each other.
+	relationMap := make(map[int64]*ownmap.OSMRelation)
+	var relationIDsToFetch []int64
+	for _, tagRow := range relationTagRows {
+		if _, ok := relationMap[tagRow.ObjectID]; ok {
+			continue
+		}
+		relationMap[tagRow.ObjectID] = nil
+		relationIDsToFetch = append(relationIDsToFetch, tagRow.ObjectID)
+	}
+
+	for len(relationIDsToFetch) != 0 {
+		fetchedRelations, err := db.getRelationsByIDs(tx, relationIDsToFetch)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		relationIDsToFetch = nil
+		for id, relation := range fetchedRelations {
+			relationMap[id] = relation
+
+			for _, member := range relation.Members {
+				if member.MemberType != ownmap.OSM_MEMBER_TYPE_RELATION {
+					continue
+				}
+
+				if _, ok := relationMap[member.ObjectID]; ok {
+					continue
+				}
+				relationMap[member.ObjectID] = nil
+				relationIDsToFetch = append(relationIDsToFetch, member.ObjectID)
+			}
+		}
+	}
+
+	var memberNodeIDs, memberWayIDs []int64
+	for _, relation := range relationMap {
+		if relation == nil {
+			continue
+		}
+
+		for _, member := range relation.Members {
+			switch member.MemberType {
+			case ownmap.OSM_MEMBER_TYPE_NODE:
+				memberNodeIDs = append(memberNodeIDs, member.ObjectID)
+			case ownmap.OSM_MEMBER_TYPE_WAY:
+				memberWayIDs = append(memberWayIDs, member.ObjectID)
+			case ownmap.OSM_MEMBER_TYPE_RELATION:
+				// already fetched
+			default:
+				return nil, errorsx.Errorf("unrecognized member type: %v", member.MemberType)
+			}
+		}
+	}
+
+	nodeMap, err := db.getNodesByIDs(tx, memberNodeIDs)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	wayMap, err := db.getWaysByIDs(tx, memberWayIDs)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	relationDataMap := make(map[int64]*ownmap.RelationData)
+	for _, tagRow := range relationTagRows {
+		relation := relationMap[tagRow.ObjectID]
+		if relation == nil {
+			continue
+		}
+
+		relationData, ok := relationDataMap[relation.ID]
+		if !ok {
+			relationMembers := []*ownmap.RelationMemberData{}
+			for _, member := range relation.Members {
+				memberData := &ownmap.RelationMemberData{Role: member.Role}
+				switch member.MemberType {
+				case ownmap.OSM_MEMBER_TYPE_NODE:
+					memberData.Object = nodeMap[member.ObjectID]
+				case ownmap.OSM_MEMBER_TYPE_WAY:
+					memberData.Object = wayMap[member.ObjectID]
+				case ownmap.OSM_MEMBER_TYPE_RELATION:
+					memberData.Object = relationMap[member.ObjectID]
+				}
+				relationMembers = append(relationMembers, memberData)
+			}
+
+			relationData = &ownmap.RelationData{
+				RelationID: relation.ID,
+				Tags:       relation.Tags,
+				Members:    relationMembers,
+				Area:       relation.Area,
+			}
+			relationDataMap[relation.ID] = relationData
+		}
+
+		tagRelationMap[tagRow.Key] = append(tagRelationMap[tagRow.Key], relationData)
+	}
 
+	return tagRelationMap, nil
 }
 
 type tagRowType struct {
@@ -0,0 +1,28 @@
+package ownmapsqldb
+
+import (
+	"context"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect is the SQL that differs between the databases. The rest of the SQL in this package is written to work with all of them.
+type Dialect interface {
+	// AnyOf returns a condition that the expression is one of the values in the array parameter
+	AnyOf(expression, placeholder string) string
+	// Int64Array returns the parameter for an array of int64s, for use with AnyOf
+	Int64Array(values []int64) interface{}
+	// StringArray returns the parameter for an array of strings, for use with AnyOf
+	StringArray(values []string) interface{}
+	// HasPostGIS is whether the tables have PostGIS geometry columns. They are written on import, and used to find the nodes and ways in an area.
+	HasPostGIS() bool
+	// CopyRows writes rows to a table, as fast as the database allows (COPY for PostgreSQL)
+	CopyRows(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) errorsx.Error
+	// BeforeImportSQL is run at the start of an import, to make the tables quicker to load, for example by dropping the indexes that aren't needed while importing
+	BeforeImportSQL() string
+	// IndexesSQL creates the indexes dropped by BeforeImportSQL. They are created after the rows have been loaded, which is faster than keeping them up to date on every row.
+	IndexesSQL() string
+	// AfterImportSQL is run at the end of an import, before it is committed. It can be empty.
+	AfterImportSQL() string
+}
@@ -1,44 +1,125 @@
 package ownmapsqldb
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/jamesrr39/ownmap-app/ownmap"
 	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/nodelocations"
 	"github.com/jmoiron/sqlx"
 	"github.com/paulmach/osm/osmpbf"
 )
 
 var _ ownmapdal.Importer = &Importer{}
 
+// ErrDatasetNotEmpty is returned when importing into a dataset that has already been imported into
+var ErrDatasetNotEmpty = errors.New("the dataset has already been imported. Import into a new SQLite file, or a new PostgreSQL dataset, instead")
+
 type toDatasourceConnFunc func() (ownmapdal.DataSourceConn, errorsx.Error)
 
+// Importer imports into a SQL database, inside a single transaction.
+// Rows are buffered and written in batches with Dialect.CopyRows, and the indexes that aren't needed for importing are only created once the rows have been loaded (or when an object is first read back).
 type Importer struct {
 	tx               *sqlx.Tx
+	dialect          Dialect
 	pbfHeader        *osmpbf.Header
 	toDatasourceConn toDatasourceConnFunc
+	rows             *rowBuffer
+	// nodeLocations has the locations of the imported nodes, so that ways can be resolved without querying the database. nil when applying changes.
+	nodeLocations  nodelocations.Store
+	indexesCreated bool
 }
 
-func NewImporter(db *sqlx.DB, pbfHeader *osmpbf.Header, toDatasourceConn toDatasourceConnFunc) (*Importer, errorsx.Error) {
+// NewImporter imports into a database with an up to date schema. The dataset must be empty.
+func NewImporter(db *sqlx.DB, dialect Dialect, pbfHeader *osmpbf.Header, toDatasourceConn toDatasourceConnFunc) (*Importer, errorsx.Error) {
+	var err error
+
 	tx, err := db.Beginx()
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
+	var datasetCount int
+	err = tx.Get(&datasetCount, `SELECT COUNT(*) FROM dataset_info`)
+	if err != nil {
+		tx.Rollback()
+		return nil, errorsx.Wrap(err)
+	}
+
+	if datasetCount != 0 {
+		tx.Rollback()
+		return nil, errorsx.Wrap(ErrDatasetNotEmpty)
+	}
+
+	_, err = tx.Exec(dialect.BeforeImportSQL())
+	if err != nil {
+		tx.Rollback()
+		return nil, errorsx.Wrap(err)
+	}
+
 	return &Importer{
-		tx, pbfHeader, toDatasourceConn,
+		tx:               tx,
+		dialect:          dialect,
+		pbfHeader:        pbfHeader,
+		toDatasourceConn: toDatasourceConn,
+		rows:             newRowBuffer(dialect),
+		nodeLocations:    nodelocations.NewSparseStore(),
 	}, nil
 }
 
+// newTxImporter creates an importer for writing to an existing database, in the transaction
+func newTxImporter(tx *sqlx.Tx, dialect Dialect) *Importer {
+	return &Importer{
+		tx:             tx,
+		dialect:        dialect,
+		rows:           newRowBuffer(dialect),
+		indexesCreated: true,
+	}
+}
+
+// prepareRead makes sure the object is in the database before it is read. It is only flushed if it is still buffered, so that looking up objects that haven't been imported doesn't stop rows being written in batches.
+func (importer *Importer) prepareRead(table string, id int64) errorsx.Error {
+	if !importer.rows.isBuffered(table, id) {
+		return nil
+	}
+
+	return importer.rows.flush(context.Background(), importer.tx)
+}
+
+// createIndexes creates the indexes, if they haven't been already. They are needed to read the tags and members of an object.
+func (importer *Importer) createIndexes(ctx context.Context) errorsx.Error {
+	if importer.indexesCreated {
+		return nil
+	}
+
+	_, err := importer.tx.ExecContext(ctx, importer.dialect.IndexesSQL())
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	importer.indexesCreated = true
+
+	return nil
+}
+
 func (importer *Importer) GetNodeByID(id int64) (*ownmap.OSMNode, error) {
+	var err error
+
+	err = importer.prepareRead("nodes", id)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	row := importer.tx.QueryRow("SELECT id, lat, lon FROM nodes WHERE id = $1", id)
 	if row.Err() != nil {
 		return nil, errorsx.Wrap(row.Err())
 	}
 
 	node := new(ownmap.OSMNode)
-	err := row.Scan(&node.ID, &node.Lat, &node.Lon)
+	err = row.Scan(&node.ID, &node.Lat, &node.Lon)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errorsx.ObjectNotFound
@@ -47,6 +128,11 @@ func (importer *Importer) GetNodeByID(id int64) (*ownmap.OSMNode, error) {
 		return nil, errorsx.Wrap(err)
 	}
 
+	err = importer.createIndexes(context.Background())
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	rows, err := importer.tx.Query("SELECT key, value FROM tags t WHERE t.object_id = $1 AND t.object_type_id = $2", id, ownmap.ObjectTypeNode)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
@@ -69,14 +155,50 @@ func (importer *Importer) GetNodeByID(id int64) (*ownmap.OSMNode, error) {
 
 	return node, nil
 }
+func (importer *Importer) GetNodeLocation(id int64) (*ownmap.Location, error) {
+	var err error
+
+	if importer.nodeLocations != nil {
+		return importer.nodeLocations.Get(id)
+	}
+
+	err = importer.prepareRead("nodes", id)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	row := importer.tx.QueryRow("SELECT lat, lon FROM nodes WHERE id = $1", id)
+	if row.Err() != nil {
+		return nil, errorsx.Wrap(row.Err())
+	}
+
+	location := new(ownmap.Location)
+	err = row.Scan(&location.Lat, &location.Lon)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errorsx.ObjectNotFound
+		}
+
+		return nil, errorsx.Wrap(err)
+	}
+
+	return location, nil
+}
 func (importer *Importer) GetWayByID(id int64) (*ownmap.OSMWay, error) {
+	var err error
+
+	err = importer.prepareRead("ways", id)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	row := importer.tx.QueryRow("SELECT id FROM ways WHERE id = $1", id)
 	if row.Err() != nil {
 		return nil, errorsx.Wrap(row.Err())
 	}
 
 	var discard int64
-	err := row.Scan(&discard)
+	err = row.Scan(&discard)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errorsx.ObjectNotFound
@@ -84,6 +206,11 @@ func (importer *Importer) GetWayByID(id int64) (*ownmap.OSMWay, error) {
 		return nil, errorsx.Wrap(err)
 	}
 
+	err = importer.createIndexes(context.Background())
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	way := &ownmap.OSMWay{ID: id}
 
 	rows, err := importer.tx.Query("SELECT key, value FROM tags t WHERE t.object_id = $1 AND t.object_type_id = $2", id, ownmap.ObjectTypeWay)
@@ -106,24 +233,22 @@ func (importer *Importer) GetWayByID(id int64) (*ownmap.OSMWay, error) {
 		return nil, errorsx.Wrap(rows.Err())
 	}
 
-	// nodes in way
+	// nodes in way, with their locations, for building relation areas
 
-	rows, err = importer.tx.Query("SELECT node_id FROM way_nodes wn WHERE wn.way_id = $1", id)
+	rows, err = importer.tx.Query("SELECT wn.node_id, n.lat, n.lon FROM way_nodes wn INNER JOIN nodes n ON n.id = wn.node_id WHERE wn.way_id = $1 ORDER BY wn.node_index", id)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var nodeID int64
-		err = rows.Scan(&nodeID)
+		wayPoint := &ownmap.WayPoint{Point: new(ownmap.Location)}
+		err = rows.Scan(&wayPoint.NodeID, &wayPoint.Point.Lat, &wayPoint.Point.Lon)
 		if err != nil {
 			return nil, errorsx.Wrap(err)
 		}
 
-		way.WayPoints = append(way.WayPoints, &ownmap.WayPoint{
-			NodeID: nodeID,
-		})
+		way.WayPoints = append(way.WayPoints, wayPoint)
 	}
 
 	if rows.Err() != nil {
@@ -133,13 +258,20 @@ func (importer *Importer) GetWayByID(id int64) (*ownmap.OSMWay, error) {
 	return way, nil
 }
 func (importer *Importer) GetRelationByID(id int64) (*ownmap.OSMRelation, error) {
+	var err error
+
+	err = importer.prepareRead("relations", id)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	row := importer.tx.QueryRow("SELECT id FROM relations WHERE id = $1", id)
 	if row.Err() != nil {
 		return nil, errorsx.Wrap(row.Err())
 	}
 
 	var discard int64
-	err := row.Scan(&discard)
+	err = row.Scan(&discard)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errorsx.ObjectNotFound
@@ -147,6 +279,11 @@ func (importer *Importer) GetRelationByID(id int64) (*ownmap.OSMRelation, error)
 		return nil, errorsx.Wrap(err)
 	}
 
+	err = importer.createIndexes(context.Background())
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	relation := &ownmap.OSMRelation{ID: id}
 
 	rows, err := importer.tx.Query("SELECT key, value FROM tags t WHERE t.object_id = $1 AND t.object_type_id = $2", id, ownmap.ObjectTypeRelation)
@@ -171,7 +308,7 @@ func (importer *Importer) GetRelationByID(id int64) (*ownmap.OSMRelation, error)
 
 	// nodes in way
 
-	rows, err = importer.tx.Query("SELECT member_id, member_type, role, orientation FROM relation_members rm WHERE rm.parent_id = $1", id)
+	rows, err = importer.tx.Query("SELECT member_id, member_type, role, orientation FROM relation_members rm WHERE rm.parent_id = $1 ORDER BY rm.member_index", id)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
@@ -193,71 +330,134 @@ func (importer *Importer) GetRelationByID(id int64) (*ownmap.OSMRelation, error)
 
 	return relation, nil
 }
-func (importer *Importer) ImportNode(obj *ownmap.OSMNode) errorsx.Error {
-	_, err := importer.tx.Exec(`INSERT INTO nodes (id, lat, lon) VALUES ($1, $2, $3)`, obj.ID, obj.Lat, obj.Lon)
+func (importer *Importer) addTags(objectID int64, objectType ownmap.ObjectType, tags []*ownmap.OSMTag) errorsx.Error {
+	for _, tag := range tags {
+		err := importer.rows.add("tags", objectID, objectType, tag.Key, tag.Value)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (importer *Importer) ImportNode(ctx context.Context, obj *ownmap.OSMNode) errorsx.Error {
+	var err error
+
+	if importer.dialect.HasPostGIS() {
+		err = importer.rows.add("nodes", obj.ID, obj.Lat, obj.Lon, pointEWKT(obj.Lat, obj.Lon))
+	} else {
+		err = importer.rows.add("nodes", obj.ID, obj.Lat, obj.Lon)
+	}
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
 
-	for _, tag := range obj.Tags {
-		_, err = importer.tx.Exec(`INSERT INTO tags (object_id, object_type_id, key, value) VALUES ($1, $2, $3, $4)`, obj.ID, ownmap.ObjectTypeNode, tag.Key, tag.Value)
+	err = importer.addTags(obj.ID, ownmap.ObjectTypeNode, obj.Tags)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	if importer.nodeLocations != nil {
+		err = importer.nodeLocations.Set(obj.ID, obj.Lat, obj.Lon)
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 	}
 
-	return nil
+	return importer.rows.flushIfFull(ctx, importer.tx)
 }
-func (importer *Importer) ImportWay(obj *ownmap.OSMWay) errorsx.Error {
-	_, err := importer.tx.Exec(`INSERT INTO ways (id) VALUES ($1)`, obj.ID)
+func (importer *Importer) ImportWay(ctx context.Context, obj *ownmap.OSMWay) errorsx.Error {
+	var err error
+
+	if importer.dialect.HasPostGIS() {
+		err = importer.rows.add("ways", obj.ID, lineStringEWKT(obj.WayPoints))
+	} else {
+		err = importer.rows.add("ways", obj.ID)
+	}
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
 
-	for _, tag := range obj.Tags {
-		_, err = importer.tx.Exec(`INSERT INTO tags (object_id, object_type_id, key, value) VALUES ($1, $2, $3, $4)`, obj.ID, ownmap.ObjectTypeWay, tag.Key, tag.Value)
+	err = importer.addTags(obj.ID, ownmap.ObjectTypeWay, obj.Tags)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for nodeIndex, waypoint := range obj.WayPoints {
+		err = importer.rows.add("way_nodes", obj.ID, waypoint.NodeID, nodeIndex)
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 	}
 
-	for _, waypoint := range obj.WayPoints {
-		_, err = importer.tx.Exec(`INSERT INTO way_nodes (way_id, node_id) VALUES ($1, $2)`, obj.ID, waypoint.NodeID)
+	bounds, ok := getWayPointsBounds(obj.WayPoints)
+	if ok {
+		err = importer.addObjectBounds(obj.ID, ownmap.ObjectTypeWay, bounds)
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 	}
 
-	return nil
+	return importer.rows.flushIfFull(ctx, importer.tx)
 }
 
-func (importer *Importer) ImportRelation(obj *ownmap.OSMRelation) errorsx.Error {
-	_, err := importer.tx.Exec(`INSERT INTO relations (id) VALUES ($1)`, obj.ID)
+func (importer *Importer) ImportRelation(ctx context.Context, obj *ownmap.OSMRelation) errorsx.Error {
+	var err error
+
+	// NULL if the relation has no area
+	var area interface{}
+	if obj.Area != nil {
+		area, err = obj.Area.Marshal()
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	if importer.dialect.HasPostGIS() {
+		err = importer.rows.add("relations", obj.ID, area, multiPolygonEWKT(obj.Area))
+	} else {
+		err = importer.rows.add("relations", obj.ID, area)
+	}
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
 
-	for _, tag := range obj.Tags {
-		_, err = importer.tx.Exec(`INSERT INTO tags (object_id, object_type_id, key, value) VALUES ($1, $2, $3, $4)`, obj.ID, ownmap.ObjectTypeRelation, tag.Key, tag.Value)
+	err = importer.addTags(obj.ID, ownmap.ObjectTypeRelation, obj.Tags)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for memberIndex, member := range obj.Members {
+		err = importer.rows.add("relation_members", member.ObjectID, member.MemberType, member.Role, member.Orientation, obj.ID, memberIndex)
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 	}
 
-	for _, member := range obj.Members {
-		_, err = importer.tx.Exec(`INSERT INTO relation_members (member_id, member_type, role, orientation, parent_id) VALUES ($1, $2, $3, $4, $5)`,
-			member.ObjectID, member.MemberType, member.Role, member.Orientation, obj.ID,
-		)
+	// relations without an area get their bounds from their members, once they have all been imported
+	bounds, ok := getAreaBounds(obj.Area)
+	if ok {
+		err = importer.addObjectBounds(obj.ID, ownmap.ObjectTypeRelation, bounds)
 		if err != nil {
 			return errorsx.Wrap(err)
 		}
 	}
 
-	return nil
+	return importer.rows.flushIfFull(ctx, importer.tx)
 }
 
 func (importer *Importer) Rollback() errorsx.Error {
-	err := importer.tx.Rollback()
+	var err error
+
+	if importer.nodeLocations != nil {
+		err = importer.nodeLocations.Close()
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	err = importer.tx.Rollback()
 	if err != nil {
 		return errorsx.Wrap(err)
 	}
@@ -265,29 +465,73 @@ func (importer *Importer) Rollback() errorsx.Error {
 	return nil
 }
 
-func (importer *Importer) Commit() (ownmapdal.DataSourceConn, errorsx.Error) {
-	_, err := importer.tx.Exec(`
+func (importer *Importer) Commit(ctx context.Context, datasetBounds ownmapdal.DatasetBounds) (ownmapdal.DataSourceConn, errorsx.Error) {
+	var err error
+
+	err = importer.rows.flush(ctx, importer.tx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = importer.createIndexes(ctx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	err = updateRelationBoundsFromMembers(ctx, importer.tx)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	_, err = importer.tx.ExecContext(ctx, `
 	INSERT INTO dataset_info (
 		bounds_min_lat,
 		bounds_max_lat,
 		bounds_min_lon,
 		bounds_max_lon,
-		replication_time
-	) VALUES ($1, $2, $3, $4, $5)`,
-		importer.pbfHeader.Bounds.MinLat,
-		importer.pbfHeader.Bounds.MaxLat,
-		importer.pbfHeader.Bounds.MinLon,
-		importer.pbfHeader.Bounds.MaxLon,
+		bounds_source,
+		replication_time,
+		replication_sequence_number,
+		replication_base_url,
+		import_bounds_min_lat,
+		import_bounds_max_lat,
+		import_bounds_min_lon,
+		import_bounds_max_lon
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		datasetBounds.Bounds.MinLat,
+		datasetBounds.Bounds.MaxLat,
+		datasetBounds.Bounds.MinLon,
+		datasetBounds.Bounds.MaxLon,
+		datasetBounds.Source,
 		importer.pbfHeader.ReplicationTimestamp,
+		importer.pbfHeader.ReplicationSeqNum,
+		importer.pbfHeader.ReplicationBaseURL,
+		datasetBounds.ImportBounds.MinLat,
+		datasetBounds.ImportBounds.MaxLat,
+		datasetBounds.ImportBounds.MinLon,
+		datasetBounds.ImportBounds.MaxLon,
 	)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
+	afterImportSQL := importer.dialect.AfterImportSQL()
+	if afterImportSQL != "" {
+		_, err = importer.tx.ExecContext(ctx, afterImportSQL)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+	}
+
 	err = importer.tx.Commit()
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
 
+	err = importer.nodeLocations.Close()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
 	return importer.toDatasourceConn()
 }
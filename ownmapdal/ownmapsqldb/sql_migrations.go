@@ -0,0 +1,165 @@
+package ownmapsqldb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration is one change to the schema. The migrations of a set are run in order of version, and the version reached is recorded in the schema_version table.
+// Once released, a migration must not be changed; change the schema with a new migration instead.
+type Migration struct {
+	Version     int
+	Description string
+	SQL         string
+	// Run is for changes that can't be written in SQL, such as filling in data. It is run after the SQL, and can be nil.
+	Run func(ctx context.Context, tx *sqlx.Tx) errorsx.Error
+}
+
+// MigrationSet is a list of migrations, versioned separately from other sets, so that optional parts of the schema (such as PostGIS) can have their own.
+type MigrationSet struct {
+	Name       string
+	Migrations []Migration
+	// LegacyVersion returns which version a database created before the schema version was recorded is at, or 0 for an empty database. It can be nil.
+	LegacyVersion func(ctx context.Context, tx *sqlx.Tx) (int, errorsx.Error)
+}
+
+// LatestVersion is the version of the last migration in the set
+func (s MigrationSet) LatestVersion() int {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// MigrationResult is the versions a migration set was migrated between. They are the same if the set was already up to date.
+type MigrationResult struct {
+	Name        string
+	FromVersion int
+	ToVersion   int
+}
+
+const schemaVersionTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_version (
+	name TEXT PRIMARY KEY,
+	version INTEGER NOT NULL
+)`
+
+// Migrate brings the schema up to date, in a single transaction
+func Migrate(ctx context.Context, db *sqlx.DB, migrationSets ...MigrationSet) ([]MigrationResult, errorsx.Error) {
+	var err error
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, schemaVersionTableSQL)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	var results []MigrationResult
+	for _, migrationSet := range migrationSets {
+		result, err := migrateSet(ctx, tx, migrationSet)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "migration set", migrationSet.Name)
+		}
+
+		results = append(results, result)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return results, nil
+}
+
+func migrateSet(ctx context.Context, tx *sqlx.Tx, migrationSet MigrationSet) (MigrationResult, errorsx.Error) {
+	var err error
+
+	var version int
+	err = tx.GetContext(ctx, &version, `SELECT version FROM schema_version WHERE name = $1`, migrationSet.Name)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return MigrationResult{}, errorsx.Wrap(err)
+		}
+
+		if migrationSet.LegacyVersion != nil {
+			version, err = migrationSet.LegacyVersion(ctx, tx)
+			if err != nil {
+				return MigrationResult{}, errorsx.Wrap(err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_version (name, version) VALUES ($1, $2)`, migrationSet.Name, version)
+		if err != nil {
+			return MigrationResult{}, errorsx.Wrap(err)
+		}
+	}
+
+	result := MigrationResult{Name: migrationSet.Name, FromVersion: version, ToVersion: version}
+
+	if version > migrationSet.LatestVersion() {
+		return MigrationResult{}, errorsx.Errorf("the database schema is at version %d, which is newer than this version of ownmap supports (%d). Please upgrade ownmap", version, migrationSet.LatestVersion())
+	}
+
+	for _, migration := range migrationSet.Migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, migration.SQL)
+		if err != nil {
+			return MigrationResult{}, errorsx.Wrap(err, "version", migration.Version, "description", migration.Description)
+		}
+
+		if migration.Run != nil {
+			err = migration.Run(ctx, tx)
+			if err != nil {
+				return MigrationResult{}, errorsx.Wrap(err, "version", migration.Version, "description", migration.Description)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE schema_version SET version = $1 WHERE name = $2`, migration.Version, migrationSet.Name)
+		if err != nil {
+			return MigrationResult{}, errorsx.Wrap(err)
+		}
+
+		result.ToVersion = migration.Version
+	}
+
+	return result, nil
+}
+
+// FillObjectBounds adds the bounds of the ways and relations that don't have them, for databases created before the object_bounds table existed
+func FillObjectBounds(ctx context.Context, tx *sqlx.Tx) errorsx.Error {
+	var err error
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO object_bounds (object_id, object_type_id, min_lat, max_lat, min_lon, max_lon)
+		SELECT wn.way_id, CAST($1 AS BIGINT), MIN(n.lat), MAX(n.lat), MIN(n.lon), MAX(n.lon)
+		FROM way_nodes wn
+		INNER JOIN nodes n ON n.id = wn.node_id
+		GROUP BY wn.way_id
+		ON CONFLICT (object_type_id, object_id) DO NOTHING`,
+		ownmap.ObjectTypeWay,
+	)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	err = updateRelationBoundsFromMembers(ctx, tx)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
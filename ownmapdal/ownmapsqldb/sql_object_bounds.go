@@ -0,0 +1,160 @@
+package ownmapsqldb
+
+import (
+	"context"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jmoiron/sqlx"
+	"github.com/paulmach/osm"
+)
+
+// The object_bounds table has the bounding box of every way and relation, so that the ones in an area can be found without reading their nodes.
+// Way bounds are set when the way is imported. Relation bounds come from their area, if they have one, or otherwise from their members (see updateRelationBoundsFromMembers).
+
+func (importer *Importer) addObjectBounds(objectID int64, objectType ownmap.ObjectType, bounds osm.Bounds) errorsx.Error {
+	return importer.rows.add("object_bounds", objectID, objectType, bounds.MinLat, bounds.MaxLat, bounds.MinLon, bounds.MaxLon)
+}
+
+func deleteObjectBounds(tx *sqlx.Tx, objectID int64, objectType ownmap.ObjectType) errorsx.Error {
+	_, err := tx.Exec(`DELETE FROM object_bounds WHERE object_id = $1 AND object_type_id = $2`, objectID, objectType)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// getWayPointsBounds returns the bounds of the way points, or false if there are none
+func getWayPointsBounds(wayPoints []*ownmap.WayPoint) (osm.Bounds, bool) {
+	var points []*ownmap.Location
+	for _, wayPoint := range wayPoints {
+		points = append(points, wayPoint.Point)
+	}
+
+	if len(points) == 0 {
+		return osm.Bounds{}, false
+	}
+
+	return ownmap.GetRingBounds(points), true
+}
+
+// getAreaBounds returns the bounds of the outer rings of the area, or false if there are none
+func getAreaBounds(area *ownmap.AreaGeometry) (osm.Bounds, bool) {
+	var points []*ownmap.Location
+	for _, polygon := range area.GetPolygons() {
+		if polygon.Outer == nil {
+			continue
+		}
+		points = append(points, polygon.Outer.Points...)
+	}
+
+	if len(points) == 0 {
+		return osm.Bounds{}, false
+	}
+
+	return ownmap.GetRingBounds(points), true
+}
+
+// updateWayBoundsForNode recalculates the bounds of the ways containing the node, after it has been moved
+func updateWayBoundsForNode(tx *sqlx.Tx, nodeID int64) errorsx.Error {
+	_, err := tx.Exec(`
+		UPDATE object_bounds
+		SET min_lat = b.min_lat, max_lat = b.max_lat, min_lon = b.min_lon, max_lon = b.max_lon
+		FROM (
+			SELECT wn.way_id, MIN(n.lat) AS min_lat, MAX(n.lat) AS max_lat, MIN(n.lon) AS min_lon, MAX(n.lon) AS max_lon
+			FROM way_nodes wn
+			INNER JOIN nodes n ON n.id = wn.node_id
+			WHERE wn.way_id IN (SELECT way_id FROM way_nodes WHERE node_id = $1)
+			GROUP BY wn.way_id
+		) b
+		WHERE object_bounds.object_type_id = $2 AND object_bounds.object_id = b.way_id`,
+		nodeID, ownmap.ObjectTypeWay,
+	)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// updateWayBounds recalculates the bounds of the way from its nodes, after a node has been removed from it. A way without any nodes left has its bounds removed.
+func updateWayBounds(tx *sqlx.Tx, wayID int64) errorsx.Error {
+	var err error
+
+	_, err = tx.Exec(`
+		UPDATE object_bounds
+		SET min_lat = b.min_lat, max_lat = b.max_lat, min_lon = b.min_lon, max_lon = b.max_lon
+		FROM (
+			SELECT MIN(n.lat) AS min_lat, MAX(n.lat) AS max_lat, MIN(n.lon) AS min_lon, MAX(n.lon) AS max_lon
+			FROM way_nodes wn
+			INNER JOIN nodes n ON n.id = wn.node_id
+			WHERE wn.way_id = $1
+			GROUP BY wn.way_id
+		) b
+		WHERE object_bounds.object_type_id = $2 AND object_bounds.object_id = $1`,
+		wayID, ownmap.ObjectTypeWay,
+	)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM object_bounds
+		WHERE object_type_id = $2 AND object_id = $1
+		AND NOT EXISTS (SELECT 1 FROM way_nodes WHERE way_id = $1)`,
+		wayID, ownmap.ObjectTypeWay,
+	)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// relationBoundsFromMembersSQL grows the bounds of each relation to cover the bounds of its members (and adds bounds for relations without them).
+// Only relations whose bounds change are updated, so running it until no rows are affected takes nested relations into account, even if they contain each other.
+// Member types have the same values as object types, so they can be joined on directly.
+// LEAST and GREATEST aren't in SQLite, so CASE is used instead. The SELECT needs a WHERE for SQLite to parse the ON CONFLICT.
+const relationBoundsFromMembersSQL = `
+WITH member_bounds AS (
+	SELECT rm.parent_id, MIN(b.min_lat) AS min_lat, MAX(b.max_lat) AS max_lat, MIN(b.min_lon) AS min_lon, MAX(b.max_lon) AS max_lon
+	FROM relation_members rm
+	INNER JOIN (
+		SELECT object_type_id, object_id, min_lat, max_lat, min_lon, max_lon FROM object_bounds
+		UNION ALL
+		SELECT CAST($1 AS BIGINT) AS object_type_id, id AS object_id, lat AS min_lat, lat AS max_lat, lon AS min_lon, lon AS max_lon FROM nodes
+	) b ON b.object_type_id = rm.member_type AND b.object_id = rm.member_id
+	GROUP BY rm.parent_id
+)
+INSERT INTO object_bounds (object_id, object_type_id, min_lat, max_lat, min_lon, max_lon)
+SELECT parent_id, CAST($2 AS BIGINT), min_lat, max_lat, min_lon, max_lon FROM member_bounds WHERE true
+ON CONFLICT (object_type_id, object_id) DO UPDATE SET
+	min_lat = CASE WHEN EXCLUDED.min_lat < object_bounds.min_lat THEN EXCLUDED.min_lat ELSE object_bounds.min_lat END,
+	max_lat = CASE WHEN EXCLUDED.max_lat > object_bounds.max_lat THEN EXCLUDED.max_lat ELSE object_bounds.max_lat END,
+	min_lon = CASE WHEN EXCLUDED.min_lon < object_bounds.min_lon THEN EXCLUDED.min_lon ELSE object_bounds.min_lon END,
+	max_lon = CASE WHEN EXCLUDED.max_lon > object_bounds.max_lon THEN EXCLUDED.max_lon ELSE object_bounds.max_lon END
+WHERE EXCLUDED.min_lat < object_bounds.min_lat
+	OR EXCLUDED.max_lat > object_bounds.max_lat
+	OR EXCLUDED.min_lon < object_bounds.min_lon
+	OR EXCLUDED.max_lon > object_bounds.max_lon`
+
+// updateRelationBoundsFromMembers sets the bounds of the relations from their members. Each run takes in one more level of nested relations.
+// Relation bounds only ever grow here, so after a change that moves a member inwards they can be bigger than needed; that is fine for finding relations in an area.
+func updateRelationBoundsFromMembers(ctx context.Context, tx *sqlx.Tx) errorsx.Error {
+	for {
+		result, err := tx.ExecContext(ctx, relationBoundsFromMembersSQL, ownmap.ObjectTypeNode, ownmap.ObjectTypeRelation)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		if rowsAffected == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package ownmapsqldb
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jmoiron/sqlx"
+)
+
+// With PostGIS, nodes, ways and relations with an area have a geometry column, in WGS 84 (SRID 4326), with a GiST index.
+// Ways are LINESTRINGs, with their nodes in order. Relation areas are MULTIPOLYGONs.
+
+// Geometries are written as EWKT text, which PostGIS reads with the SRID, so that they can be given to COPY like any other value
+const ewktSRIDPrefix = "SRID=4326;"
+
+// postGISEnvelopeSQL is the box of the bounds, given the $1 to $4 parameters (min lat, max lat, min lon, max lon)
+const postGISEnvelopeSQL = `ST_MakeEnvelope($3, $1, $4, $2, 4326)`
+
+func writeWKTPoints(sb *strings.Builder, points []*ownmap.Location) {
+	sb.WriteString("(")
+	for i, point := range points {
+		if i != 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.FormatFloat(point.Lon, 'f', -1, 64))
+		sb.WriteString(" ")
+		sb.WriteString(strconv.FormatFloat(point.Lat, 'f', -1, 64))
+	}
+	sb.WriteString(")")
+}
+
+// pointEWKT returns the location as an EWKT POINT
+func pointEWKT(lat, lon float64) string {
+	sb := new(strings.Builder)
+	sb.WriteString(ewktSRIDPrefix + "POINT")
+	writeWKTPoints(sb, []*ownmap.Location{{Lat: lat, Lon: lon}})
+
+	return sb.String()
+}
+
+// lineStringEWKT returns the way points as an EWKT LINESTRING, or nil (NULL) if there aren't enough for a line
+func lineStringEWKT(wayPoints []*ownmap.WayPoint) interface{} {
+	if len(wayPoints) < 2 {
+		return nil
+	}
+
+	var points []*ownmap.Location
+	for _, wayPoint := range wayPoints {
+		points = append(points, wayPoint.Point)
+	}
+
+	sb := new(strings.Builder)
+	sb.WriteString(ewktSRIDPrefix + "LINESTRING")
+	writeWKTPoints(sb, points)
+
+	return sb.String()
+}
+
+// multiPolygonEWKT returns the area as an EWKT MULTIPOLYGON, or nil (NULL) if it doesn't have any polygons.
+// Rings that aren't closed, or have too few points to be a ring, are left out.
+func multiPolygonEWKT(area *ownmap.AreaGeometry) interface{} {
+	isRing := func(ring *ownmap.AreaRing) bool {
+		points := ring.GetPoints()
+		if len(points) < 4 {
+			return false
+		}
+
+		first, last := points[0], points[len(points)-1]
+		return first.Lat == last.Lat && first.Lon == last.Lon
+	}
+
+	var polygonWKTs []string
+	for _, polygon := range area.GetPolygons() {
+		if !isRing(polygon.Outer) {
+			continue
+		}
+
+		sb := new(strings.Builder)
+		sb.WriteString("(")
+		writeWKTPoints(sb, polygon.Outer.Points)
+		for _, inner := range polygon.Inners {
+			if !isRing(inner) {
+				continue
+			}
+			sb.WriteString(",")
+			writeWKTPoints(sb, inner.Points)
+		}
+		sb.WriteString(")")
+
+		polygonWKTs = append(polygonWKTs, sb.String())
+	}
+
+	if len(polygonWKTs) == 0 {
+		return nil
+	}
+
+	return ewktSRIDPrefix + "MULTIPOLYGON(" + strings.Join(polygonWKTs, ",") + ")"
+}
+
+// updatePostGISGeometriesForNode updates the point of the node, and the lines of the ways containing it, after it has been moved
+func updatePostGISGeometriesForNode(tx *sqlx.Tx, nodeID int64) errorsx.Error {
+	_, err := tx.Exec(`UPDATE nodes SET geom = ST_SetSRID(ST_MakePoint(lon, lat), 4326) WHERE id = $1`, nodeID)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE ways SET geom = (
+			SELECT CASE WHEN COUNT(*) > 1 THEN ST_MakeLine(n.geom ORDER BY wn.node_index) END
+			FROM way_nodes wn
+			INNER JOIN nodes n ON n.id = wn.node_id
+			WHERE wn.way_id = ways.id
+		)
+		WHERE id IN (SELECT way_id FROM way_nodes WHERE node_id = $1)`,
+		nodeID,
+	)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// updatePostGISWayGeometry rebuilds the geometry of the way from its nodes, after a node has been removed from it. A way with fewer than 2 nodes left has no geometry.
+func updatePostGISWayGeometry(tx *sqlx.Tx, wayID int64) errorsx.Error {
+	_, err := tx.Exec(`
+		UPDATE ways SET geom = (
+			SELECT CASE WHEN COUNT(*) > 1 THEN ST_MakeLine(n.geom ORDER BY wn.node_index) END
+			FROM way_nodes wn
+			INNER JOIN nodes n ON n.id = wn.node_id
+			WHERE wn.way_id = ways.id
+		)
+		WHERE id = $1`,
+		wayID,
+	)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// FillPostGISGeometries sets the geometries of all the nodes, ways and relations, for databases that were imported without PostGIS
+func FillPostGISGeometries(ctx context.Context, tx *sqlx.Tx) errorsx.Error {
+	var err error
+
+	_, err = tx.ExecContext(ctx, `UPDATE nodes SET geom = ST_SetSRID(ST_MakePoint(lon, lat), 4326)`)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE ways SET geom = (
+			SELECT CASE WHEN COUNT(*) > 1 THEN ST_MakeLine(n.geom ORDER BY wn.node_index) END
+			FROM way_nodes wn
+			INNER JOIN nodes n ON n.id = wn.node_id
+			WHERE wn.way_id = ways.id
+		)`)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	// relation areas are stored as protobuf, so they are converted here
+	type relationAreaRow struct {
+		ID   int64  `db:"id"`
+		Area []byte `db:"area"`
+	}
+
+	var rows []relationAreaRow
+	err = tx.SelectContext(ctx, &rows, `SELECT id, area FROM relations WHERE area IS NOT NULL`)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, row := range rows {
+		area := new(ownmap.AreaGeometry)
+		err = area.Unmarshal(row.Area)
+		if err != nil {
+			return errorsx.Wrap(err, "relation ID", row.ID)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE relations SET geom = $1 WHERE id = $2`, multiPolygonEWKT(area), row.ID)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	return nil
+}
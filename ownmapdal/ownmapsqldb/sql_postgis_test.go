@@ -0,0 +1,72 @@
+package ownmapsqldb
+
+import (
+	"testing"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pointEWKT(t *testing.T) {
+	assert.Equal(t, "SRID=4326;POINT(-0.1 51.5)", pointEWKT(51.5, -0.1))
+}
+
+func Test_lineStringEWKT(t *testing.T) {
+	tests := []struct {
+		name      string
+		wayPoints []*ownmap.WayPoint
+		want      interface{}
+	}{
+		{"no points", nil, nil},
+		{"one point", []*ownmap.WayPoint{{NodeID: 1, Point: &ownmap.Location{Lat: 51.5, Lon: -0.1}}}, nil},
+		{"line", []*ownmap.WayPoint{
+			{NodeID: 1, Point: &ownmap.Location{Lat: 51.5, Lon: -0.1}},
+			{NodeID: 2, Point: &ownmap.Location{Lat: 51.25, Lon: 0}},
+			{NodeID: 3, Point: &ownmap.Location{Lat: 52, Lon: 1.125}},
+		}, "SRID=4326;LINESTRING(-0.1 51.5,0 51.25,1.125 52)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lineStringEWKT(tt.wayPoints))
+		})
+	}
+}
+
+func Test_multiPolygonEWKT(t *testing.T) {
+	ring := func(points ...float64) *ownmap.AreaRing {
+		r := new(ownmap.AreaRing)
+		for i := 0; i < len(points); i += 2 {
+			r.Points = append(r.Points, &ownmap.Location{Lat: points[i], Lon: points[i+1]})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		area *ownmap.AreaGeometry
+		want interface{}
+	}{
+		{"no area", nil, nil},
+		{"outer ring not closed", &ownmap.AreaGeometry{Polygons: []*ownmap.AreaPolygon{
+			{Outer: ring(0, 0, 0, 1, 1, 1, 1, 0)},
+		}}, nil},
+		{"polygons with inner rings", &ownmap.AreaGeometry{Polygons: []*ownmap.AreaPolygon{
+			{
+				Outer: ring(0, 0, 0, 4, 4, 4, 0, 0),
+				Inners: []*ownmap.AreaRing{
+					ring(1, 1, 1, 2, 2, 2, 1, 1),
+					// too few points, left out
+					ring(3, 3, 3, 3),
+				},
+			},
+			{Outer: ring(10, 10, 10, 11, 11, 11, 10, 10)},
+		}}, "SRID=4326;MULTIPOLYGON(((0 0,4 0,4 4,0 0),(1 1,2 1,2 2,1 1)),((10 10,11 10,11 11,10 10)))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, multiPolygonEWKT(tt.area))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package ownmapsqldb
+
+import (
+	"context"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jmoiron/sqlx"
+)
+
+// rowBufferMaxRows is how many rows are kept before they are all written
+const rowBufferMaxRows = 10000
+
+type tableRows struct {
+	table   string
+	columns []string
+	rows    [][]interface{}
+	// ids of the rows, for the tables with an id column
+	ids map[int64]struct{}
+}
+
+// rowBuffer keeps the rows to be written to each table, and writes them in batches with Dialect.CopyRows, which is much faster than inserting one row at a time.
+// Rows are only in the database after flush has been called; isBuffered tells whether an object is still waiting to be written.
+type rowBuffer struct {
+	dialect Dialect
+	// tables are written in this order, so that the rows referenced by a foreign key are written first
+	tables   []*tableRows
+	rowCount int
+}
+
+func newRowBuffer(dialect Dialect) *rowBuffer {
+	nodeColumns := []string{"id", "lat", "lon"}
+	wayColumns := []string{"id"}
+	relationColumns := []string{"id", "area"}
+	if dialect.HasPostGIS() {
+		nodeColumns = append(nodeColumns, "geom")
+		wayColumns = append(wayColumns, "geom")
+		relationColumns = append(relationColumns, "geom")
+	}
+
+	return &rowBuffer{
+		dialect: dialect,
+		tables: []*tableRows{
+			{table: "nodes", columns: nodeColumns},
+			{table: "ways", columns: wayColumns},
+			{table: "way_nodes", columns: []string{"way_id", "node_id", "node_index"}},
+			{table: "relations", columns: relationColumns},
+			{table: "relation_members", columns: []string{"member_id", "member_type", "role", "orientation", "parent_id", "member_index"}},
+			{table: "tags", columns: []string{"object_id", "object_type_id", "key", "value"}},
+			{table: "object_bounds", columns: []string{"object_id", "object_type_id", "min_lat", "max_lat", "min_lon", "max_lon"}},
+		},
+	}
+}
+
+// add adds a row, with a value for each of the columns of the table
+func (b *rowBuffer) add(table string, values ...interface{}) errorsx.Error {
+	var tableRowsToAddTo *tableRows
+	for _, tableRows := range b.tables {
+		if tableRows.table == table {
+			tableRowsToAddTo = tableRows
+			break
+		}
+	}
+
+	if tableRowsToAddTo == nil {
+		return errorsx.Errorf("unknown table: %q", table)
+	}
+
+	if len(values) != len(tableRowsToAddTo.columns) {
+		return errorsx.Errorf("expected %d values for table %q, but got %d", len(tableRowsToAddTo.columns), table, len(values))
+	}
+
+	tableRowsToAddTo.rows = append(tableRowsToAddTo.rows, values)
+	if tableRowsToAddTo.columns[0] == "id" {
+		if tableRowsToAddTo.ids == nil {
+			tableRowsToAddTo.ids = make(map[int64]struct{})
+		}
+		tableRowsToAddTo.ids[values[0].(int64)] = struct{}{}
+	}
+	b.rowCount++
+
+	return nil
+}
+
+// flushIfFull writes all the rows if enough have been buffered.
+// It is called after all the rows of an object have been added, so that an object is never only partly written.
+func (b *rowBuffer) flushIfFull(ctx context.Context, tx *sqlx.Tx) errorsx.Error {
+	if b.rowCount < rowBufferMaxRows {
+		return nil
+	}
+
+	return b.flush(ctx, tx)
+}
+
+// isBuffered returns whether the row with the id (in the nodes, ways or relations table) hasn't been written yet
+func (b *rowBuffer) isBuffered(table string, id int64) bool {
+	for _, tableRows := range b.tables {
+		if tableRows.table == table {
+			_, ok := tableRows.ids[id]
+			return ok
+		}
+	}
+
+	return false
+}
+
+// flush writes all the rows
+func (b *rowBuffer) flush(ctx context.Context, tx *sqlx.Tx) errorsx.Error {
+	for _, tableRows := range b.tables {
+		if len(tableRows.rows) == 0 {
+			continue
+		}
+
+		err := b.dialect.CopyRows(ctx, tx, tableRows.table, tableRows.columns, tableRows.rows)
+		if err != nil {
+			return errorsx.Wrap(err, "table", tableRows.table)
+		}
+
+		tableRows.rows = nil
+		tableRows.ids = nil
+	}
+
+	b.rowCount = 0
+
+	return nil
+}
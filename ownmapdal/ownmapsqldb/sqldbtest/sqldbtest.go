@@ -0,0 +1,469 @@
+// Package sqldbtest has the tests that are run against each of the SQL databases
+package sqldbtest
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapsqldb"
+	"github.com/jmoiron/sqlx"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Backend is a new, empty database to run the tests against
+type Backend struct {
+	NewImporter      func(pbfHeader *osmpbf.Header) (ownmapdal.Importer, errorsx.Error)
+	NewChangeApplier func() (ownmapdal.ChangeApplier, errorsx.Error)
+	// DB is used to check what has been written to the tables
+	DB *sqlx.DB
+}
+
+// RunTests runs the tests, each with a new backend
+func RunTests(t *testing.T, newBackend func(t *testing.T) Backend) {
+	t.Run("GetInBounds", func(t *testing.T) {
+		testGetInBounds(t, newBackend(t))
+	})
+	t.Run("GetInBounds relations", func(t *testing.T) {
+		testGetInBoundsRelations(t, newBackend(t))
+	})
+	t.Run("delete a node of an unchanged way", func(t *testing.T) {
+		testDeleteNodeOfUnchangedWay(t, newBackend(t))
+	})
+	t.Run("way node order", func(t *testing.T) {
+		testWayNodeOrder(t, newBackend(t))
+	})
+	t.Run("read while importing", func(t *testing.T) {
+		testReadWhileImporting(t, newBackend(t))
+	})
+	t.Run("import into an imported dataset", func(t *testing.T) {
+		testImportIntoImportedDataset(t, newBackend(t))
+	})
+}
+
+func getIDs(nodeMap ownmapdal.TagNodeMap, wayMap ownmapdal.TagWayMap) ([]int64, []int64) {
+	var nodeIDs, wayIDs []int64
+	for _, nodes := range nodeMap {
+		for _, node := range nodes {
+			nodeIDs = append(nodeIDs, node.ID)
+		}
+	}
+	for _, ways := range wayMap {
+		for _, way := range ways {
+			wayIDs = append(wayIDs, way.ID)
+		}
+	}
+
+	sort.Slice(nodeIDs, func(i, j int) bool { return nodeIDs[i] < nodeIDs[j] })
+	sort.Slice(wayIDs, func(i, j int) bool { return wayIDs[i] < wayIDs[j] })
+
+	return nodeIDs, wayIDs
+}
+
+func testGetInBounds(t *testing.T, backend Backend) {
+	var err error
+
+	ctx := context.Background()
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.NoError(t, err)
+
+	nodes := []*ownmap.OSMNode{
+		{ID: 1, Lat: 51.1, Lon: 0.1, Tags: []*ownmap.OSMTag{{Key: "place", Value: "village"}}},
+		{ID: 2, Lat: 51.2, Lon: 0.2},
+		{ID: 3, Lat: 52.5, Lon: 1.5, Tags: []*ownmap.OSMTag{{Key: "place", Value: "town"}}},
+		{ID: 4, Lat: 52.6, Lon: 1.6},
+		// 5 and 6 are either side of the query bounds
+		{ID: 5, Lat: 50.5, Lon: 0.15},
+		{ID: 6, Lat: 52, Lon: 0.15},
+	}
+	for _, node := range nodes {
+		require.NoError(t, importer.ImportNode(ctx, node))
+	}
+
+	wayPoint := func(node *ownmap.OSMNode) *ownmap.WayPoint {
+		return &ownmap.WayPoint{NodeID: node.ID, Point: &ownmap.Location{Lat: node.Lat, Lon: node.Lon}}
+	}
+	ways := []*ownmap.OSMWay{
+		// inside the query bounds
+		{ID: 10, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "residential"}}, WayPoints: []*ownmap.WayPoint{wayPoint(nodes[0]), wayPoint(nodes[1])}},
+		// outside the query bounds
+		{ID: 11, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "track"}}, WayPoints: []*ownmap.WayPoint{wayPoint(nodes[2]), wayPoint(nodes[3])}},
+		// crosses the query bounds, without a node in them
+		{ID: 12, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "primary"}}, WayPoints: []*ownmap.WayPoint{wayPoint(nodes[4]), wayPoint(nodes[5])}},
+	}
+	for _, way := range ways {
+		require.NoError(t, importer.ImportWay(ctx, way))
+	}
+
+	// a relation containing a relation containing way 11, imported before the relation it contains
+	relations := []*ownmap.OSMRelation{
+		{ID: 101, Tags: []*ownmap.OSMTag{{Key: "type", Value: "route_master"}}, Members: []*ownmap.OSMRelationMember{
+			{ObjectID: 100, MemberType: ownmap.OSM_MEMBER_TYPE_RELATION},
+		}},
+		{ID: 100, Tags: []*ownmap.OSMTag{{Key: "type", Value: "route"}}, Members: []*ownmap.OSMRelationMember{
+			{ObjectID: 11, MemberType: ownmap.OSM_MEMBER_TYPE_WAY},
+			{ObjectID: 1, MemberType: ownmap.OSM_MEMBER_TYPE_NODE},
+		}},
+	}
+	for _, relation := range relations {
+		require.NoError(t, importer.ImportRelation(ctx, relation))
+	}
+
+	// the dataset bounds are the extent of the data, which is tighter than the bounds it was imported with
+	dataExtent := osm.Bounds{MinLat: 50.5, MaxLat: 52.6, MinLon: 0.1, MaxLon: 1.6}
+	dbConn, err := importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: dataExtent, Source: ownmap.BOUNDS_SOURCE_DATA_EXTENT, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	datasetInfo, err := dbConn.DatasetInfo()
+	require.NoError(t, err)
+	assert.Equal(t, dataExtent, datasetInfo.Bounds.ToOSMBounds())
+	assert.Equal(t, datasetBounds, datasetInfo.ImportBounds.ToOSMBounds())
+
+	filter := &ownmapdal.GetInBoundsFilter{
+		Objects: []*ownmapdal.TagKeyWithType{
+			{ObjectType: ownmap.ObjectTypeNode, TagKey: "place"},
+			{ObjectType: ownmap.ObjectTypeWay, TagKey: "highway"},
+		},
+	}
+	queryBounds := osm.Bounds{MinLat: 51, MaxLat: 51.5, MinLon: 0, MaxLon: 0.5}
+
+	nodeMap, wayMap, _, err := dbConn.GetInBounds(ctx, queryBounds, filter)
+	require.NoError(t, err)
+
+	nodeIDs, wayIDs := getIDs(nodeMap, wayMap)
+	assert.Equal(t, []int64{1}, nodeIDs)
+	assert.Equal(t, []int64{10, 12}, wayIDs)
+
+	// the relation bounds cover their members, including through the nested relation
+	for _, relationID := range []int64{100, 101} {
+		var relationBounds osm.Bounds
+		err = backend.DB.QueryRow(
+			`SELECT min_lat, max_lat, min_lon, max_lon FROM object_bounds WHERE object_type_id = $1 AND object_id = $2`,
+			ownmap.ObjectTypeRelation, relationID,
+		).Scan(&relationBounds.MinLat, &relationBounds.MaxLat, &relationBounds.MinLon, &relationBounds.MaxLon)
+		require.NoError(t, err)
+		assert.Equal(t, osm.Bounds{MinLat: 51.1, MaxLat: 52.6, MinLon: 0.1, MaxLon: 1.6}, relationBounds, "relation %d", relationID)
+	}
+
+	// move node 3 (and with it, way 11) into the query bounds, and create node 7 outside of the data extent, but inside the import bounds
+	changeApplier, err := backend.NewChangeApplier()
+	require.NoError(t, err)
+
+	change := &osm.Change{
+		Modify: &osm.OSM{
+			Nodes: osm.Nodes{
+				{ID: 3, Version: 2, Lat: 51.3, Lon: 0.3, Tags: osm.Tags{{Key: "place", Value: "town"}}},
+			},
+		},
+		Create: &osm.OSM{
+			Nodes: osm.Nodes{
+				{ID: 7, Version: 1, Lat: 51.4, Lon: 0.05, Tags: osm.Tags{{Key: "place", Value: "hamlet"}}},
+			},
+		},
+	}
+	dbConn, err = changeApplier.ApplyChanges(ctx, ownmapdal.NewChangeIndex(change))
+	require.NoError(t, err)
+
+	nodeMap, wayMap, _, err = dbConn.GetInBounds(ctx, queryBounds, filter)
+	require.NoError(t, err)
+
+	nodeIDs, wayIDs = getIDs(nodeMap, wayMap)
+	assert.Equal(t, []int64{1, 3, 7}, nodeIDs)
+	assert.Equal(t, []int64{10, 11, 12}, wayIDs)
+}
+
+func testGetInBoundsRelations(t *testing.T, backend Backend) {
+	var err error
+
+	ctx := context.Background()
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.NoError(t, err)
+
+	nodes := []*ownmap.OSMNode{
+		{ID: 1, Lat: 51.1, Lon: 0.1},
+		{ID: 2, Lat: 51.1, Lon: 0.2},
+		{ID: 3, Lat: 51.2, Lon: 0.2},
+		{ID: 4, Lat: 51.3, Lon: 0.3, Tags: []*ownmap.OSMTag{{Key: "natural", Value: "peak"}}},
+	}
+	for _, node := range nodes {
+		require.NoError(t, importer.ImportNode(ctx, node))
+	}
+
+	ring := []*ownmap.WayPoint{
+		{NodeID: 1, Point: &ownmap.Location{Lat: 51.1, Lon: 0.1}},
+		{NodeID: 2, Point: &ownmap.Location{Lat: 51.1, Lon: 0.2}},
+		{NodeID: 3, Point: &ownmap.Location{Lat: 51.2, Lon: 0.2}},
+		{NodeID: 1, Point: &ownmap.Location{Lat: 51.1, Lon: 0.1}},
+	}
+	err = importer.ImportWay(ctx, &ownmap.OSMWay{ID: 10, WayPoints: ring})
+	require.NoError(t, err)
+
+	area := &ownmap.AreaGeometry{Polygons: []*ownmap.AreaPolygon{{Outer: &ownmap.AreaRing{Points: []*ownmap.Location{
+		{Lat: 51.1, Lon: 0.1}, {Lat: 51.1, Lon: 0.2}, {Lat: 51.2, Lon: 0.2}, {Lat: 51.1, Lon: 0.1},
+	}}}}}
+
+	relations := []*ownmap.OSMRelation{
+		{ID: 100, Tags: []*ownmap.OSMTag{{Key: "type", Value: "multipolygon"}, {Key: "natural", Value: "wood"}}, Area: area, Members: []*ownmap.OSMRelationMember{
+			{ObjectID: 10, MemberType: ownmap.OSM_MEMBER_TYPE_WAY, Role: "outer"},
+		}},
+		// contains the multipolygon, a node and a way that isn't in the dataset
+		{ID: 101, Tags: []*ownmap.OSMTag{{Key: "type", Value: "site"}}, Members: []*ownmap.OSMRelationMember{
+			{ObjectID: 100, MemberType: ownmap.OSM_MEMBER_TYPE_RELATION},
+			{ObjectID: 4, MemberType: ownmap.OSM_MEMBER_TYPE_NODE, Role: "label"},
+			{ObjectID: 99, MemberType: ownmap.OSM_MEMBER_TYPE_WAY},
+		}},
+	}
+	for _, relation := range relations {
+		require.NoError(t, importer.ImportRelation(ctx, relation))
+	}
+
+	dbConn, err := importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	filter := &ownmapdal.GetInBoundsFilter{
+		Objects: []*ownmapdal.TagKeyWithType{
+			{ObjectType: ownmap.ObjectTypeRelation, TagKey: "type"},
+			{ObjectType: ownmap.ObjectTypeRelation, TagKey: "natural"},
+		},
+	}
+
+	_, _, relationMap, err := dbConn.GetInBounds(ctx, osm.Bounds{MinLat: 51, MaxLat: 51.15, MinLon: 0, MaxLon: 0.15}, filter)
+	require.NoError(t, err)
+
+	require.Len(t, relationMap["natural"], 1)
+	require.Len(t, relationMap["type"], 2)
+
+	multipolygon := relationMap["natural"][0]
+	assert.Equal(t, int64(100), multipolygon.RelationID)
+	assert.Equal(t, area, multipolygon.Area)
+	require.Len(t, multipolygon.Members, 1)
+	assert.Equal(t, "outer", multipolygon.Members[0].Role)
+	way := multipolygon.Members[0].Object.(*ownmap.OSMWay)
+	assert.Equal(t, int64(10), way.ID)
+	assert.Len(t, way.WayPoints, 4)
+
+	// the same relation data is returned for each of its tags
+	var site *ownmap.RelationData
+	for _, relationData := range relationMap["type"] {
+		if relationData.RelationID == 101 {
+			site = relationData
+		} else {
+			assert.Same(t, multipolygon, relationData)
+		}
+	}
+	require.NotNil(t, site)
+	assert.Nil(t, site.Area)
+	require.Len(t, site.Members, 3)
+
+	subRelation := site.Members[0].Object.(*ownmap.OSMRelation)
+	assert.Equal(t, int64(100), subRelation.ID)
+	require.Len(t, subRelation.Members, 1)
+	assert.Equal(t, int64(10), subRelation.Members[0].ObjectID)
+
+	node := site.Members[1].Object.(*ownmap.OSMNode)
+	assert.Equal(t, "label", site.Members[1].Role)
+	assert.Equal(t, &ownmap.OSMNode{ID: 4, Lat: 51.3, Lon: 0.3, Tags: []*ownmap.OSMTag{{Key: "natural", Value: "peak"}}}, node)
+
+	// not in the dataset
+	assert.Nil(t, site.Members[2].Object.(*ownmap.OSMWay))
+
+	// the relations are outside of these bounds
+	_, _, relationMap, err = dbConn.GetInBounds(ctx, osm.Bounds{MinLat: 52, MaxLat: 52.5, MinLon: 1, MaxLon: 1.5}, filter)
+	require.NoError(t, err)
+	assert.Empty(t, relationMap["type"])
+}
+
+func testDeleteNodeOfUnchangedWay(t *testing.T, backend Backend) {
+	var err error
+
+	ctx := context.Background()
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.NoError(t, err)
+
+	nodes := []*ownmap.OSMNode{
+		{ID: 1, Lat: 51.1, Lon: 0.1},
+		{ID: 2, Lat: 51.2, Lon: 0.2},
+		{ID: 3, Lat: 52.5, Lon: 1.5},
+	}
+	var wayPoints []*ownmap.WayPoint
+	for _, node := range nodes {
+		require.NoError(t, importer.ImportNode(ctx, node))
+		wayPoints = append(wayPoints, &ownmap.WayPoint{NodeID: node.ID, Point: &ownmap.Location{Lat: node.Lat, Lon: node.Lon}})
+	}
+
+	err = importer.ImportWay(ctx, &ownmap.OSMWay{ID: 10, Tags: []*ownmap.OSMTag{{Key: "highway", Value: "residential"}}, WayPoints: wayPoints})
+	require.NoError(t, err)
+
+	_, err = importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	// node 3 is deleted, but the change doesn't have the way (for example because the way was changed in an earlier change)
+	changeApplier, err := backend.NewChangeApplier()
+	require.NoError(t, err)
+
+	change := &osm.Change{
+		Delete: &osm.OSM{
+			Nodes: osm.Nodes{{ID: 3, Version: 2}},
+		},
+	}
+	dbConn, err := changeApplier.ApplyChanges(ctx, ownmapdal.NewChangeIndex(change))
+	require.NoError(t, err)
+
+	// the way bounds no longer cover where node 3 was
+	var wayBounds osm.Bounds
+	err = backend.DB.QueryRow(
+		`SELECT min_lat, max_lat, min_lon, max_lon FROM object_bounds WHERE object_type_id = $1 AND object_id = 10`,
+		ownmap.ObjectTypeWay,
+	).Scan(&wayBounds.MinLat, &wayBounds.MaxLat, &wayBounds.MinLon, &wayBounds.MaxLon)
+	require.NoError(t, err)
+	assert.Equal(t, osm.Bounds{MinLat: 51.1, MaxLat: 51.2, MinLon: 0.1, MaxLon: 0.2}, wayBounds)
+
+	filter := &ownmapdal.GetInBoundsFilter{
+		Objects: []*ownmapdal.TagKeyWithType{
+			{ObjectType: ownmap.ObjectTypeWay, TagKey: "highway"},
+		},
+	}
+
+	// so the way isn't found around there any more (with PostGIS, because its geometry has been rebuilt)
+	_, wayMap, _, err := dbConn.GetInBounds(ctx, osm.Bounds{MinLat: 52.4, MaxLat: 52.6, MinLon: 1.4, MaxLon: 1.6}, filter)
+	require.NoError(t, err)
+	assert.Empty(t, wayMap["highway"])
+
+	_, wayMap, _, err = dbConn.GetInBounds(ctx, osm.Bounds{MinLat: 51, MaxLat: 51.5, MinLon: 0, MaxLon: 0.5}, filter)
+	require.NoError(t, err)
+	require.Len(t, wayMap["highway"], 1)
+	assert.Len(t, wayMap["highway"][0].WayPoints, 2)
+}
+
+func testWayNodeOrder(t *testing.T, backend Backend) {
+	var err error
+
+	ctx := context.Background()
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.NoError(t, err)
+
+	var wayPoints []*ownmap.WayPoint
+	for _, nodeID := range []int64{3, 1, 4, 2, 3} {
+		location := &ownmap.Location{Lat: 51 + float64(nodeID)/10, Lon: float64(nodeID) / 10}
+		wayPoints = append(wayPoints, &ownmap.WayPoint{NodeID: nodeID, Point: location})
+	}
+
+	for _, wayPoint := range wayPoints[:4] {
+		err = importer.ImportNode(ctx, &ownmap.OSMNode{ID: wayPoint.NodeID, Lat: wayPoint.Point.Lat, Lon: wayPoint.Point.Lon})
+		require.NoError(t, err)
+	}
+
+	way := &ownmap.OSMWay{ID: 10, Tags: []*ownmap.OSMTag{{Key: "landuse", Value: "grass"}}, WayPoints: wayPoints}
+	err = importer.ImportWay(ctx, way)
+	require.NoError(t, err)
+
+	dbConn, err := importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	filter := &ownmapdal.GetInBoundsFilter{
+		Objects: []*ownmapdal.TagKeyWithType{{ObjectType: ownmap.ObjectTypeWay, TagKey: "landuse"}},
+	}
+
+	_, wayMap, _, err := dbConn.GetInBounds(ctx, datasetBounds, filter)
+	require.NoError(t, err)
+
+	require.Len(t, wayMap["landuse"], 1)
+	assert.Equal(t, way, wayMap["landuse"][0])
+}
+
+// testReadWhileImporting checks objects can be read back before the import is committed, whether they have been written to the database yet or not
+func testReadWhileImporting(t *testing.T, backend Backend) {
+	var err error
+
+	ctx := context.Background()
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.NoError(t, err)
+
+	// more nodes than are buffered at once, so that some have been written to the database before the import is committed
+	const nodeCount = 12000
+	for id := int64(1); id <= nodeCount; id++ {
+		err = importer.ImportNode(ctx, &ownmap.OSMNode{ID: id, Lat: 51 + float64(id)/nodeCount, Lon: float64(id) / nodeCount})
+		require.NoError(t, err)
+	}
+
+	location, err := importer.GetNodeLocation(nodeCount)
+	require.NoError(t, err)
+	assert.Equal(t, &ownmap.Location{Lat: 52, Lon: 1}, location)
+
+	_, err = importer.GetNodeLocation(nodeCount + 1)
+	assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+
+	way := &ownmap.OSMWay{
+		ID:   10,
+		Tags: []*ownmap.OSMTag{{Key: "highway", Value: "path"}},
+		WayPoints: []*ownmap.WayPoint{
+			{NodeID: 1, Point: &ownmap.Location{Lat: 51 + 1.0/nodeCount, Lon: 1.0 / nodeCount}},
+			{NodeID: nodeCount, Point: &ownmap.Location{Lat: 52, Lon: 1}},
+		},
+	}
+	err = importer.ImportWay(ctx, way)
+	require.NoError(t, err)
+
+	fetchedWay, err := importer.GetWayByID(10)
+	require.NoError(t, err)
+	assert.Equal(t, way, fetchedWay)
+
+	_, err = importer.GetWayByID(11)
+	assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+
+	relation := &ownmap.OSMRelation{
+		ID:      20,
+		Tags:    []*ownmap.OSMTag{{Key: "type", Value: "route"}},
+		Members: []*ownmap.OSMRelationMember{{ObjectID: 10, MemberType: ownmap.OSM_MEMBER_TYPE_WAY, Role: "forward"}},
+	}
+	err = importer.ImportRelation(ctx, relation)
+	require.NoError(t, err)
+
+	fetchedRelation, err := importer.GetRelationByID(20)
+	require.NoError(t, err)
+	assert.Equal(t, relation, fetchedRelation)
+
+	_, err = importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	var count int
+	err = backend.DB.Get(&count, "SELECT COUNT(*) FROM nodes")
+	require.NoError(t, err)
+	assert.Equal(t, nodeCount, count)
+}
+
+func testImportIntoImportedDataset(t *testing.T, backend Backend) {
+	var err error
+
+	ctx := context.Background()
+
+	datasetBounds := osm.Bounds{MinLat: 50, MaxLat: 53, MinLon: -2, MaxLon: 2}
+	importer, err := backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.NoError(t, err)
+
+	err = importer.ImportNode(ctx, &ownmap.OSMNode{ID: 1, Lat: 51, Lon: 0})
+	require.NoError(t, err)
+
+	_, err = importer.Commit(ctx, ownmapdal.DatasetBounds{Bounds: datasetBounds, Source: ownmap.BOUNDS_SOURCE_REQUESTED, ImportBounds: datasetBounds})
+	require.NoError(t, err)
+
+	_, err = backend.NewImporter(&osmpbf.Header{Bounds: &datasetBounds})
+	require.Error(t, err)
+	assert.Equal(t, ownmapsqldb.ErrDatasetNotEmpty, errorsx.Cause(err))
+}
@@ -12,6 +12,8 @@ type PathsConfig struct {
 	RawDataFilesDir string
 	TempDir         string
 	TraceDir        string
+	// DatasetsConfigPath is the JSON file with the DatasetConfigs. It doesn't need to exist.
+	DatasetsConfigPath string
 }
 
 func (pc *PathsConfig) EnsurePaths() errorsx.Error {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"runtime"
+	"strings"
 
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/jamesrr39/goutil/gofs"
@@ -20,6 +21,54 @@ type PBFReader interface {
 	Reset() errorsx.Error
 	FullyScannedBytes() int64
 	TotalSize() int64
+	Close() error
+}
+
+const (
+	PBFFileSuffix         = ".pbf"
+	OSMXMLFileSuffix      = ".osm"
+	OSMXMLGzipFileSuffix  = ".osm.gz"
+	OSMXMLBzip2FileSuffix = ".osm.bz2"
+)
+
+var rawDataFileSuffixes = []string{
+	PBFFileSuffix,
+	OSMXMLFileSuffix,
+	OSMXMLGzipFileSuffix,
+	OSMXMLBzip2FileSuffix,
+}
+
+// SplitRawDataFileSuffix splits a file name into the part before the suffix, and a recognised raw data file suffix.
+// For example, "my-region.osm.bz2" is split into "my-region" and ".osm.bz2"
+func SplitRawDataFileSuffix(fileName string) (string, string, errorsx.Error) {
+	for _, suffix := range rawDataFileSuffixes {
+		if strings.HasSuffix(fileName, suffix) {
+			return strings.TrimSuffix(fileName, suffix), suffix, nil
+		}
+	}
+
+	return "", "", errorsx.Errorf("unrecognised file type for file %q. Supported file types: %s", fileName, strings.Join(rawDataFileSuffixes, ", "))
+}
+
+// NewPBFReaderForFile chooses a reader for the file, based on the extension of the file name
+func NewPBFReaderForFile(file gofs.File, fileName string) (PBFReader, errorsx.Error) {
+	_, suffix, err := SplitRawDataFileSuffix(fileName)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	switch suffix {
+	case PBFFileSuffix:
+		return NewDefaultPBFReader(file)
+	case OSMXMLFileSuffix:
+		return NewOSMXMLReader(file, CompressionTypeNone)
+	case OSMXMLGzipFileSuffix:
+		return NewOSMXMLReader(file, CompressionTypeGzip)
+	case OSMXMLBzip2FileSuffix:
+		return NewOSMXMLReader(file, CompressionTypeBzip2)
+	default:
+		return nil, errorsx.Errorf("no reader for suffix %q", suffix)
+	}
 }
 
 type DefaultPBFReader struct {
@@ -58,8 +107,18 @@ func (r *DefaultPBFReader) Reset() errorsx.Error {
 type ImportRunType struct {
 	Bounds             osm.Bounds
 	RequiredTagKeysMap map[string]bool
-	Rescan             *Rescan
 	MaxItemsPerBatch   uint64
+	Progress           *ImportProgressTracker
+	// CoastlineWays are the points of the coastline ways imported, for building the sea areas at the end of the import
+	CoastlineWays [][]*ownmap.Location
+	// DataExtent are the bounds of the nodes imported so far. nil until a node is imported.
+	DataExtent *osm.Bounds
+	// DeduplicateByID skips objects that have already been imported. Needed when the raw data is several files, which can have the same objects at their borders.
+	DeduplicateByID bool
+	// UnassembledAreaRelationIDs are the multipolygon and boundary relations imported without an area, because their member ways couldn't be joined into closed rings
+	UnassembledAreaRelationIDs []int64
+
+	relationMemberships *relationMembershipIndex
 }
 
 func (importRun *ImportRunType) Validate() errorsx.Error {
@@ -67,6 +126,10 @@ func (importRun *ImportRunType) Validate() errorsx.Error {
 		return errorsx.Errorf("no MaxItemsPerBatch specified")
 	}
 
+	if importRun.Progress == nil {
+		return errorsx.Errorf("no progress tracker set")
+	}
+
 	zeroBounds := osm.Bounds{}
 	if importRun.Bounds == zeroBounds {
 		return errorsx.Errorf("bounds not set")
@@ -83,22 +146,32 @@ type GetNodeByIDType func(id int64) (*ownmap.OSMNode, errorsx.Error)
 type GetWayByIDType func(id int64) (*ownmap.OSMWay, errorsx.Error)
 type GetRelationByIDType func(id int64) (*ownmap.OSMRelation, errorsx.Error)
 
-// scanBatch returns (reader has more objects to scan, error)
+// scanBatch returns (reader has more objects to scan, error). It returns an error if the context is cancelled.
 func scanBatch(
+	ctx context.Context,
 	pbfReader PBFReader,
 	importRun *ImportRunType,
 	scanObject scanObjectFunc,
 ) (bool, errorsx.Error) {
 	var err error
 
+	if ctx.Err() != nil {
+		return false, errorsx.Wrap(ctx.Err())
+	}
+
 	// scan batch
 	for i := uint64(0); i < importRun.MaxItemsPerBatch; i++ {
 		cont := pbfReader.Scan()
 		if !cont {
+			// the reader stops scanning at the end of the data, or when reading fails, e.g. for a truncated file
+			if pbfReader.Err() != nil {
+				return false, errorsx.Wrap(pbfReader.Err())
+			}
+
 			return false, nil
 		}
 
-		err = scanObject(pbfReader.Object())
+		err = scanObject(ctx, pbfReader.Object())
 		if err != nil {
 			return false, errorsx.Wrap(err)
 		}
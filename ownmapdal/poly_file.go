@@ -0,0 +1,105 @@
+package ownmapdal
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+)
+
+// ParsePolyFile reads a polygon filter file in the Osmosis .poly format (as used by extract providers to describe the area of an extract).
+// The first line is the name of the polygon, then each ring is a line with its name (starting with "!" for a hole),
+// one "lon lat" line per point, and "END". The file ends with another "END".
+func ParsePolyFile(reader io.Reader) (*ownmap.AreaGeometry, errorsx.Error) {
+	scanner := bufio.NewScanner(reader)
+
+	var outerRings, innerRings [][]*ownmap.Location
+	var currentRing []*ownmap.Location
+	isInRing := false
+	isCurrentRingInner := false
+	lineNumber := 0
+	hasEnded := false
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if lineNumber == 1 || line == "" {
+			// name of the polygon, or blank line
+			continue
+		}
+
+		if hasEnded {
+			return nil, errorsx.Errorf("unexpected line after the end of the file (line %d)", lineNumber)
+		}
+
+		if !isInRing {
+			if line == "END" {
+				hasEnded = true
+				continue
+			}
+
+			// ring name
+			isInRing = true
+			isCurrentRingInner = strings.HasPrefix(line, "!")
+			currentRing = nil
+			continue
+		}
+
+		if line == "END" {
+			if len(currentRing) < 3 {
+				return nil, errorsx.Errorf("ring ending on line %d has fewer than 3 points", lineNumber)
+			}
+
+			// close the ring, if the file hasn't already
+			first, last := currentRing[0], currentRing[len(currentRing)-1]
+			if first.Lat != last.Lat || first.Lon != last.Lon {
+				currentRing = append(currentRing, &ownmap.Location{Lat: first.Lat, Lon: first.Lon})
+			}
+
+			if isCurrentRingInner {
+				innerRings = append(innerRings, currentRing)
+			} else {
+				outerRings = append(outerRings, currentRing)
+			}
+			isInRing = false
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errorsx.Errorf("expected 2 coordinates on line %d, but found %d", lineNumber, len(fields))
+		}
+
+		lon, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "line", lineNumber)
+		}
+
+		lat, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, errorsx.Wrap(err, "line", lineNumber)
+		}
+
+		currentRing = append(currentRing, &ownmap.Location{Lat: lat, Lon: lon})
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	if !hasEnded {
+		return nil, errorsx.Errorf("unexpected end of file. Expected a line with END")
+	}
+
+	area := ownmap.AssembleAreaGeometry(outerRings, innerRings)
+	if area == nil {
+		return nil, errorsx.Errorf("no outer rings found in poly file")
+	}
+
+	return area, nil
+}
@@ -0,0 +1,60 @@
+package ownmapdal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolyFile = `test_area
+1
+   -0.2   51.1
+   -0.1   51.1
+   -0.1   51.2
+   -0.2   51.2
+END
+!2
+   -0.16  51.14
+   -0.14  51.14
+   -0.14  51.16
+   -0.16  51.16
+   -0.16  51.14
+END
+END
+`
+
+func TestParsePolyFile(t *testing.T) {
+	area, err := ParsePolyFile(strings.NewReader(testPolyFile))
+	require.NoError(t, err)
+	require.Len(t, area.Polygons, 1)
+
+	polygon := area.Polygons[0]
+	// ring is closed
+	require.Len(t, polygon.Outer.Points, 5)
+	require.Len(t, polygon.Inners, 1)
+
+	assert.True(t, ownmap.IsPointInArea(&ownmap.Location{Lat: 51.12, Lon: -0.18}, area))
+	assert.False(t, ownmap.IsPointInArea(&ownmap.Location{Lat: 51.15, Lon: -0.15}, area))
+	assert.False(t, ownmap.IsPointInArea(&ownmap.Location{Lat: 51.3, Lon: -0.15}, area))
+}
+
+func TestParsePolyFile_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no end", "name\n1\n0 0\n1 0\n1 1\nEND\n"},
+		{"too few points", "name\n1\n0 0\n1 0\nEND\nEND\n"},
+		{"bad coordinate", "name\n1\n0 0\n1 x\n1 1\nEND\nEND\n"},
+		{"only holes", "name\n!1\n0 0\n1 0\n1 1\nEND\nEND\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePolyFile(strings.NewReader(tt.data))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package ownmapdal
+
+import (
+	"encoding/binary"
+	"path/filepath"
+
+	"github.com/jamesrr39/goutil/binaryx"
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/ownmap-app/ownmapdal/ownmapdb/diskfilemap"
+	"github.com/paulmach/osm"
+)
+
+// relationMembershipIndexCacheBytes is roughly how much memory each of the relation membership index collections can use
+const relationMembershipIndexCacheBytes = 16 * 1024 * 1024
+
+// relationMembershipIndex is a reverse index of relation members, built in the first pass through the raw data.
+// For every way and relation that is a member of a relation, it has the IDs of the relations it is a member of.
+// When a way (or a node member) is found to be in the import bounds, every relation containing it, directly or through other relations, is marked as in bounds.
+// This way the raw data is read the same amount of times, however deeply the relations are nested.
+type relationMembershipIndex struct {
+	fs  gofs.Fs
+	dir string
+	// wayParents are the relations each way is a member of. This is the set of ways needed by relations.
+	wayParents diskfilemap.OnDiskCollection
+	// relationParents are the relations each relation is a member of
+	relationParents diskfilemap.OnDiskCollection
+	// relationsInBounds are the relations with at least one member (or member of a member) in the import bounds
+	relationsInBounds diskfilemap.OnDiskCollection
+}
+
+func newRelationMembershipIndex(fs gofs.Fs, dir string) (*relationMembershipIndex, errorsx.Error) {
+	index := &relationMembershipIndex{fs: fs, dir: dir}
+
+	collections := []struct {
+		name       string
+		collection *diskfilemap.OnDiskCollection
+	}{
+		{"way_parents", &index.wayParents},
+		{"relation_parents", &index.relationParents},
+		{"relations_in_bounds", &index.relationsInBounds},
+	}
+
+	for _, c := range collections {
+		collection, err := diskfilemap.NewDiskCollection(fs, filepath.Join(dir, c.name), makeIDBucketName, isIDKey1Larger, relationMembershipIndexCacheBytes)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+		*c.collection = collection
+	}
+
+	return index, nil
+}
+
+// addRelation adds the way and relation members of the relation to the index
+func (index *relationMembershipIndex) addRelation(relation *osm.Relation) errorsx.Error {
+	for _, member := range relation.Members {
+		var err errorsx.Error
+		switch member.Type {
+		case osm.TypeWay:
+			err = addIDToList(index.wayParents, member.Ref, int64(relation.ID))
+		case osm.TypeRelation:
+			err = addIDToList(index.relationParents, member.Ref, int64(relation.ID))
+		}
+		if err != nil {
+			return errorsx.Wrap(err, "relation ID", relation.ID)
+		}
+	}
+
+	return nil
+}
+
+// markWayInBounds marks all the relations containing the way as in bounds
+func (index *relationMembershipIndex) markWayInBounds(wayID int64) errorsx.Error {
+	parentIDs, err := getIDList(index.wayParents, wayID)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, parentID := range parentIDs {
+		err = index.markRelationInBounds(parentID)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+// markRelationInBounds marks the relation, and all the relations containing it, as in bounds
+func (index *relationMembershipIndex) markRelationInBounds(relationID int64) errorsx.Error {
+	toMark := []int64{relationID}
+	for len(toMark) != 0 {
+		id := toMark[len(toMark)-1]
+		toMark = toMark[:len(toMark)-1]
+
+		isInBounds, err := index.isRelationInBounds(id)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		if isInBounds {
+			// already marked, along with its parents. This also stops relations that contain each other going round in circles
+			continue
+		}
+
+		err = index.relationsInBounds.Set(binaryx.LittleEndianPutUint64(uint64(id)), nil)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		parentIDs, err := getIDList(index.relationParents, id)
+		if err != nil {
+			return errorsx.Wrap(err)
+		}
+
+		toMark = append(toMark, parentIDs...)
+	}
+
+	return nil
+}
+
+func (index *relationMembershipIndex) isRelationInBounds(relationID int64) (bool, errorsx.Error) {
+	_, err := index.relationsInBounds.Get(binaryx.LittleEndianPutUint64(uint64(relationID)))
+	if err != nil {
+		if errorsx.Cause(err) == errorsx.ObjectNotFound {
+			return false, nil
+		}
+		return false, errorsx.Wrap(err)
+	}
+
+	return true, nil
+}
+
+// close removes the index files from disk
+func (index *relationMembershipIndex) close() errorsx.Error {
+	err := index.fs.RemoveAll(index.dir)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// addIDToList adds the ID to the list of IDs stored under the key, if it isn't already in it
+func addIDToList(collection diskfilemap.OnDiskCollection, key, id int64) errorsx.Error {
+	ids, err := getIDList(collection, key)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	for _, existingID := range ids {
+		if existingID == id {
+			return nil
+		}
+	}
+
+	value := make([]byte, 8*(len(ids)+1))
+	for i, existingID := range append(ids, id) {
+		binary.LittleEndian.PutUint64(value[i*8:], uint64(existingID))
+	}
+
+	err = collection.Set(binaryx.LittleEndianPutUint64(uint64(key)), value)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	return nil
+}
+
+// getIDList returns the IDs stored under the key, or nil if there are none
+func getIDList(collection diskfilemap.OnDiskCollection, key int64) ([]int64, errorsx.Error) {
+	value, err := collection.Get(binaryx.LittleEndianPutUint64(uint64(key)))
+	if err != nil {
+		if errorsx.Cause(err) == errorsx.ObjectNotFound {
+			return nil, nil
+		}
+		return nil, errorsx.Wrap(err)
+	}
+
+	ids := make([]int64, len(value)/8)
+	for i := range ids {
+		ids[i] = int64(binary.LittleEndian.Uint64(value[i*8:]))
+	}
+
+	return ids, nil
+}
+
+const idsPerBucket = 1024
+
+func makeIDBucketName(key []byte) (diskfilemap.BucketName, errorsx.Error) {
+	id := int64(binary.LittleEndian.Uint64(key))
+	return binaryx.LittleEndianPutUint64(uint64(id / idsPerBucket)), nil
+}
+
+func isIDKey1Larger(key1, key2 []byte) (bool, errorsx.Error) {
+	return int64(binary.LittleEndian.Uint64(key1)) > int64(binary.LittleEndian.Uint64(key2)), nil
+}
@@ -0,0 +1,317 @@
+package ownmapdal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs"
+	"github.com/jamesrr39/goutil/logpkg"
+)
+
+// ReplicationState is the content of an osmosis replication state file (state.txt)
+type ReplicationState struct {
+	SequenceNumber uint64
+	Timestamp      time.Time
+}
+
+// ParseReplicationState parses a state file. The state file is in the Java properties format, for example:
+//
+//	#Sat Jan 02 12:00:02 UTC 2021
+//	sequenceNumber=4321
+//	timestamp=2021-01-02T12\:00\:00Z
+func ParseReplicationState(reader io.Reader) (*ReplicationState, errorsx.Error) {
+	state := new(ReplicationState)
+	var foundSequenceNumber, foundTimestamp bool
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			continue
+		}
+
+		key := line[:idx]
+		value := strings.ReplaceAll(line[idx+1:], `\:`, ":")
+
+		switch key {
+		case "sequenceNumber":
+			sequenceNumber, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, errorsx.Wrap(err, "line", line)
+			}
+			state.SequenceNumber = sequenceNumber
+			foundSequenceNumber = true
+		case "timestamp":
+			timestamp, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, errorsx.Wrap(err, "line", line)
+			}
+			state.Timestamp = timestamp.UTC()
+			foundTimestamp = true
+		}
+	}
+
+	if scanner.Err() != nil {
+		return nil, errorsx.Wrap(scanner.Err())
+	}
+
+	if !foundSequenceNumber || !foundTimestamp {
+		return nil, errorsx.Errorf("state file is missing the sequence number or timestamp")
+	}
+
+	return state, nil
+}
+
+// ReplicationSequencePath gives the path of a sequence number in a replication directory, without the file suffix.
+// For example, 4321 => "000/004/321"
+func ReplicationSequencePath(sequenceNumber uint64) string {
+	return fmt.Sprintf(
+		"%03d/%03d/%03d",
+		sequenceNumber/1000000,
+		(sequenceNumber/1000)%1000,
+		sequenceNumber%1000,
+	)
+}
+
+// ReplicationSource gives access to a replication directory, such as https://planet.openstreetmap.org/replication/minute/, or a local mirror of one.
+type ReplicationSource struct {
+	location string
+	openFunc func(ctx context.Context, relativePath string) (io.ReadCloser, errorsx.Error)
+}
+
+// NewReplicationSource creates a ReplicationSource. The location can either be a http(s) URL or a local directory.
+func NewReplicationSource(fs gofs.Fs, client *http.Client, location string) *ReplicationSource {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return &ReplicationSource{location, newHTTPOpenFunc(client, location)}
+	}
+
+	return &ReplicationSource{location, newDirOpenFunc(fs, location)}
+}
+
+func newHTTPOpenFunc(client *http.Client, baseURL string) func(ctx context.Context, relativePath string) (io.ReadCloser, errorsx.Error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(ctx context.Context, relativePath string) (io.ReadCloser, errorsx.Error) {
+		url := baseURL + "/" + relativePath
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, errorsx.Wrap(err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return resp.Body, nil
+		case http.StatusNotFound:
+			resp.Body.Close()
+			return nil, errorsx.Wrap(errorsx.ObjectNotFound)
+		default:
+			resp.Body.Close()
+			return nil, errorsx.Errorf("unexpected status code %d when fetching %q", resp.StatusCode, url)
+		}
+	}
+}
+
+func newDirOpenFunc(fs gofs.Fs, dirPath string) func(ctx context.Context, relativePath string) (io.ReadCloser, errorsx.Error) {
+	return func(ctx context.Context, relativePath string) (io.ReadCloser, errorsx.Error) {
+		file, err := fs.Open(filepath.Join(dirPath, filepath.FromSlash(relativePath)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, errorsx.Wrap(errorsx.ObjectNotFound)
+			}
+			return nil, errorsx.Wrap(err)
+		}
+
+		return file, nil
+	}
+}
+
+func (rs *ReplicationSource) Location() string {
+	return rs.location
+}
+
+func (rs *ReplicationSource) getState(ctx context.Context, relativePath string) (*ReplicationState, errorsx.Error) {
+	reader, err := rs.openFunc(ctx, relativePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ParseReplicationState(reader)
+}
+
+// GetLatestState fetches the state of the newest change available
+func (rs *ReplicationSource) GetLatestState(ctx context.Context) (*ReplicationState, errorsx.Error) {
+	return rs.getState(ctx, "state.txt")
+}
+
+// GetState fetches the state of a given sequence number
+func (rs *ReplicationSource) GetState(ctx context.Context, sequenceNumber uint64) (*ReplicationState, errorsx.Error) {
+	return rs.getState(ctx, ReplicationSequencePath(sequenceNumber)+".state.txt")
+}
+
+// GetChangeIndex fetches the change for a given sequence number
+func (rs *ReplicationSource) GetChangeIndex(ctx context.Context, sequenceNumber uint64) (*ChangeIndex, errorsx.Error) {
+	relativePath := ReplicationSequencePath(sequenceNumber) + OSMChangeGzipFileSuffix
+	reader, err := rs.openFunc(ctx, relativePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	change, err := ReadChangeFile(reader, relativePath)
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	changeIndex := NewChangeIndex(change)
+	changeIndex.ReplicationSequenceNumber = sequenceNumber
+
+	return changeIndex, nil
+}
+
+type closableDataSourceConn interface {
+	Close() errorsx.Error
+}
+
+// ReplicationFollower keeps a dataset up to date, by applying changes from a replication source as they become available.
+// After each change, the updated dataset is swapped into the DBConnSet, so the running server picks it up straight away.
+type ReplicationFollower struct {
+	logger        *logpkg.Logger
+	source        *ReplicationSource
+	changeApplier ChangeApplier
+	dbConnSet     *DBConnSet
+	currentConn   DataSourceConn
+	// startSequenceNumber is the sequence number the dataset is up to date with, if it has no replication information itself. 0 if not set.
+	startSequenceNumber uint64
+}
+
+func NewReplicationFollower(logger *logpkg.Logger, source *ReplicationSource, changeApplier ChangeApplier, dbConnSet *DBConnSet, currentConn DataSourceConn, startSequenceNumber uint64) *ReplicationFollower {
+	return &ReplicationFollower{logger, source, changeApplier, dbConnSet, currentConn, startSequenceNumber}
+}
+
+// Run catches up with the replication source every interval, until the context is cancelled.
+// Errors while catching up are logged and retried on the next interval, since they are often temporary (e.g. network failures).
+func (f *ReplicationFollower) Run(ctx context.Context, interval time.Duration) {
+	for {
+		_, err := f.CatchUp(ctx)
+		if err != nil {
+			f.logger.Error("error following replication source %q: %q\nStack:\n%s", f.source.Location(), err.Error(), err.Stack())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// CatchUp applies all the changes that are available from the replication source, and returns how many were applied.
+func (f *ReplicationFollower) CatchUp(ctx context.Context) (int, errorsx.Error) {
+	datasetInfo, err := f.currentConn.DatasetInfo()
+	if err != nil {
+		return 0, errorsx.Wrap(err)
+	}
+
+	currentSequenceNumber := datasetInfo.ReplicationSequenceNumber
+	if currentSequenceNumber == 0 {
+		currentSequenceNumber = f.startSequenceNumber
+	}
+
+	if currentSequenceNumber == 0 {
+		return 0, errorsx.Errorf("dataset %q has no replication sequence number. It should be imported from a file with replication information, or given a starting sequence number", f.currentConn.Name())
+	}
+
+	latestState, err := f.source.GetLatestState(ctx)
+	if err != nil {
+		return 0, errorsx.Wrap(err)
+	}
+
+	appliedCount := 0
+	for sequenceNumber := currentSequenceNumber + 1; sequenceNumber <= latestState.SequenceNumber; sequenceNumber++ {
+		if ctx.Err() != nil {
+			return appliedCount, errorsx.Wrap(ctx.Err())
+		}
+
+		err = f.applySequence(ctx, sequenceNumber)
+		if err != nil {
+			return appliedCount, errorsx.Wrap(err, "sequenceNumber", sequenceNumber)
+		}
+
+		appliedCount++
+	}
+
+	return appliedCount, nil
+}
+
+func (f *ReplicationFollower) applySequence(ctx context.Context, sequenceNumber uint64) errorsx.Error {
+	startTime := time.Now()
+
+	state, err := f.source.GetState(ctx, sequenceNumber)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	changeIndex, err := f.source.GetChangeIndex(ctx, sequenceNumber)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	// the state timestamp is when the replication was run up to, which is more accurate than the newest object in the change
+	if state.Timestamp.After(changeIndex.LatestTimestamp) {
+		changeIndex.LatestTimestamp = state.Timestamp
+	}
+
+	newConn, err := f.changeApplier.ApplyChanges(ctx, changeIndex)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	oldConn := f.currentConn
+
+	err = f.dbConnSet.ReplaceDBConn(oldConn, newConn)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	f.currentConn = newConn
+
+	if closable, ok := oldConn.(closableDataSourceConn); ok && oldConn != newConn {
+		// close in the background; this waits for any in-flight requests on the old connection to finish
+		go func() {
+			err := closable.Close()
+			if err != nil {
+				f.logger.Error("error closing replaced connection %q: %q\nStack:\n%s", oldConn.Name(), err.Error(), err.Stack())
+			}
+		}()
+	}
+
+	f.logger.Info(
+		"applied replication sequence %d (%s) to %q in %s",
+		sequenceNumber,
+		state.Timestamp.Format(time.RFC3339),
+		newConn.Name(),
+		time.Now().Sub(startTime),
+	)
+
+	return nil
+}
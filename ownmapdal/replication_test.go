@@ -0,0 +1,237 @@
+package ownmapdal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/jamesrr39/goutil/logpkg"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReplicationState(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *ReplicationState
+		wantErr bool
+	}{
+		{
+			"osmosis state file",
+			"#Sat Jan 02 12:00:02 UTC 2021\nsequenceNumber=4321\ntimestamp=2021-01-02T12\\:00\\:00Z\n",
+			&ReplicationState{SequenceNumber: 4321, Timestamp: time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)},
+			false,
+		}, {
+			"unescaped timestamp",
+			"timestamp=2021-01-02T12:00:00Z\nsequenceNumber=1\n",
+			&ReplicationState{SequenceNumber: 1, Timestamp: time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)},
+			false,
+		}, {
+			"missing timestamp",
+			"sequenceNumber=4321\n",
+			nil,
+			true,
+		}, {
+			"bad sequence number",
+			"sequenceNumber=abc\ntimestamp=2021-01-02T12\\:00\\:00Z\n",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReplicationState(strings.NewReader(tt.input))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReplicationSequencePath(t *testing.T) {
+	assert.Equal(t, "000/000/001", ReplicationSequencePath(1))
+	assert.Equal(t, "000/004/321", ReplicationSequencePath(4321))
+	assert.Equal(t, "004/321/000", ReplicationSequencePath(4321000))
+}
+
+type fakeDataSourceConn struct {
+	name        string
+	datasetInfo *ownmap.DatasetInfo
+}
+
+func (c *fakeDataSourceConn) Name() string {
+	return c.name
+}
+
+func (c *fakeDataSourceConn) DatasetInfo() (*ownmap.DatasetInfo, errorsx.Error) {
+	return c.datasetInfo, nil
+}
+
+func (c *fakeDataSourceConn) GetInBounds(ctx context.Context, bounds osm.Bounds, filter *GetInBoundsFilter) (TagNodeMap, TagWayMap, TagRelationMap, errorsx.Error) {
+	return nil, nil, nil, nil
+}
+
+type fakeChangeApplier struct {
+	appliedChanges []*ChangeIndex
+}
+
+func (a *fakeChangeApplier) ApplyChanges(ctx context.Context, changeIndex *ChangeIndex) (DataSourceConn, errorsx.Error) {
+	a.appliedChanges = append(a.appliedChanges, changeIndex)
+
+	return &fakeDataSourceConn{
+		name: fmt.Sprintf("after-%d", changeIndex.ReplicationSequenceNumber),
+		datasetInfo: &ownmap.DatasetInfo{
+			ReplicationSequenceNumber: changeIndex.ReplicationSequenceNumber,
+		},
+	}, nil
+}
+
+func gzipString(t *testing.T, s string) []byte {
+	buffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(buffer)
+	_, err := gzipWriter.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	return buffer.Bytes()
+}
+
+// testReplicationFiles creates the files of a replication directory, with changes 1 to latestSequenceNumber
+func testReplicationFiles(t *testing.T, latestSequenceNumber uint64) map[string][]byte {
+	stateFile := func(sequenceNumber uint64) []byte {
+		return []byte(fmt.Sprintf("sequenceNumber=%d\ntimestamp=2021-01-02T12\\:%02d\\:00Z\n", sequenceNumber, sequenceNumber))
+	}
+
+	files := map[string][]byte{
+		"state.txt": stateFile(latestSequenceNumber),
+	}
+
+	for sequenceNumber := uint64(1); sequenceNumber <= latestSequenceNumber; sequenceNumber++ {
+		path := ReplicationSequencePath(sequenceNumber)
+		files[path+".state.txt"] = stateFile(sequenceNumber)
+		files[path+".osc.gz"] = gzipString(t, testOSMChange)
+	}
+
+	return files
+}
+
+func TestReplicationFollower_CatchUp(t *testing.T) {
+	logger := logpkg.NewLogger(os.Stderr, logpkg.LogLevelError)
+	files := testReplicationFiles(t, 4)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/replication/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	fs := mockfs.NewMockFs()
+	for path, data := range files {
+		require.NoError(t, fs.MkdirAll("/mirror/000/000", 0755))
+		require.NoError(t, fs.WriteFile("/mirror/"+path, data, 0644))
+	}
+
+	sources := []struct {
+		name   string
+		source *ReplicationSource
+	}{
+		{"http", NewReplicationSource(fs, server.Client(), server.URL+"/replication/")},
+		{"local directory", NewReplicationSource(fs, nil, "/mirror")},
+	}
+
+	for _, s := range sources {
+		t.Run(s.name, func(t *testing.T) {
+			tests := []struct {
+				name                  string
+				datasetSequenceNumber uint64
+				startSequenceNumber   uint64
+				wantAppliedSequences  []uint64
+				wantErr               bool
+				wantFinalConnName     string
+			}{
+				{"from dataset sequence number", 2, 0, []uint64{3, 4}, false, "after-4"},
+				{"from start sequence number", 0, 1, []uint64{2, 3, 4}, false, "after-4"},
+				{"already up to date", 4, 0, nil, false, "initial"},
+				{"no sequence number", 0, 0, nil, true, "initial"},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					initialConn := &fakeDataSourceConn{
+						name: "initial",
+						datasetInfo: &ownmap.DatasetInfo{
+							ReplicationSequenceNumber: tt.datasetSequenceNumber,
+						},
+					}
+					dbConnSet := NewDBConnSet([]DataSourceConn{initialConn})
+					changeApplier := &fakeChangeApplier{}
+
+					follower := NewReplicationFollower(logger, s.source, changeApplier, dbConnSet, initialConn, tt.startSequenceNumber)
+					appliedCount, err := follower.CatchUp(context.Background())
+					if tt.wantErr {
+						require.Error(t, err)
+						return
+					}
+					require.NoError(t, err)
+
+					assert.Equal(t, len(tt.wantAppliedSequences), appliedCount)
+
+					var appliedSequences []uint64
+					for _, changeIndex := range changeApplier.appliedChanges {
+						appliedSequences = append(appliedSequences, changeIndex.ReplicationSequenceNumber)
+						// the change file's content should have been read
+						assert.Len(t, changeIndex.UpsertedNodes, 1)
+					}
+					assert.Equal(t, tt.wantAppliedSequences, appliedSequences)
+
+					if len(changeApplier.appliedChanges) != 0 {
+						// the state timestamp is newer than the objects in the change, so it should be used
+						lastChange := changeApplier.appliedChanges[len(changeApplier.appliedChanges)-1]
+						assert.Equal(t, time.Date(2021, 1, 2, 12, 4, 0, 0, time.UTC), lastChange.LatestTimestamp)
+					}
+
+					conns := dbConnSet.GetConns()
+					require.Len(t, conns, 1)
+					assert.Equal(t, tt.wantFinalConnName, conns[0].Name())
+
+					// nothing new, so catching up again should be a no-op
+					appliedCount, err = follower.CatchUp(context.Background())
+					require.NoError(t, err)
+					assert.Equal(t, 0, appliedCount)
+				})
+			}
+		})
+	}
+}
+
+func TestReplicationSource_notFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	for _, source := range []*ReplicationSource{
+		NewReplicationSource(mockfs.NewMockFs(), server.Client(), server.URL),
+		NewReplicationSource(mockfs.NewMockFs(), nil, "/mirror"),
+	} {
+		_, err := source.GetState(context.Background(), 1)
+		require.Error(t, err)
+		assert.Equal(t, errorsx.ObjectNotFound, errorsx.Cause(err))
+	}
+}
@@ -0,0 +1,346 @@
+package ownmapdal
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/paulmach/osm"
+)
+
+// maxTrackedTagValues limits how many different tag values are counted, so that keys with lots of different values (e.g. name) don't use up all the memory.
+// Once the limit is reached, values that haven't been seen yet are not counted, so the top tag values of a large file are approximate.
+const maxTrackedTagValues = 100 * 1000
+
+type TagCount struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	Count uint64 `json:"count"`
+}
+
+// DatasetStats is a summary of what is in a dataset (or the part of it inside the stats filter)
+type DatasetStats struct {
+	Nodes        uint64      `json:"nodes"`
+	Ways         uint64      `json:"ways"`
+	Relations    uint64      `json:"relations"`
+	TopTagKeys   []*TagCount `json:"topTagKeys"`
+	TopTagValues []*TagCount `json:"topTagValues"`
+	// HeaderBounds are the bounds the file says it has. nil if the file doesn't say.
+	HeaderBounds *osm.Bounds `json:"headerBounds"`
+	// DataBounds are the bounds of the nodes in the dataset. nil if there are no nodes.
+	DataBounds *osm.Bounds `json:"dataBounds"`
+	// MaxRelationDepth is the most relations nested inside each other. A relation with no relation members has a depth of 1.
+	MaxRelationDepth int `json:"maxRelationDepth"`
+}
+
+// StatsFilter limits the stats to the objects inside the bounds and/or area.
+// Ways are inside the filter if one of their nodes is, and relations are inside if one of their members is.
+type StatsFilter struct {
+	Bounds *osm.Bounds
+	Area   *ownmap.AreaGeometry
+}
+
+func (f *StatsFilter) containsLocation(location *ownmap.Location) bool {
+	if f.Bounds != nil && !f.Bounds.ContainsNode(&osm.Node{Lat: location.Lat, Lon: location.Lon}) {
+		return false
+	}
+
+	if f.Area != nil && !ownmap.IsPointInArea(location, f.Area) {
+		return false
+	}
+
+	return true
+}
+
+// idSet is a set of object IDs. Objects are sorted by ID in most files, so a slice (with a sort when it's out of order) uses much less memory than a map.
+type idSet struct {
+	ids    []int64
+	sorted bool
+}
+
+func newIDSet() *idSet {
+	return &idSet{sorted: true}
+}
+
+func (s *idSet) add(id int64) {
+	if len(s.ids) != 0 && s.ids[len(s.ids)-1] >= id {
+		s.sorted = false
+	}
+	s.ids = append(s.ids, id)
+}
+
+func (s *idSet) contains(id int64) bool {
+	if !s.sorted {
+		sort.Slice(s.ids, func(i, j int) bool {
+			return s.ids[i] < s.ids[j]
+		})
+		s.sorted = true
+	}
+
+	idx := sort.Search(len(s.ids), func(i int) bool {
+		return s.ids[i] >= id
+	})
+
+	return idx < len(s.ids) && s.ids[idx] == id
+}
+
+// StatsCollector builds up the stats of a dataset, as its objects are added in the order they are in the file
+type StatsCollector struct {
+	filter *StatsFilter
+	topN   int
+
+	stats           *DatasetStats
+	tagKeyCounts    map[string]uint64
+	tagValueCounts  map[ownmap.OSMTag]uint64
+	nodeIDs         *idSet // only filled when there is a filter
+	wayIDs          *idSet
+	relationIDs     *idSet
+	relationMembers map[int64][]int64 // relation member IDs of the relations that have relation members
+}
+
+// NewStatsCollector creates a stats collector. The filter can be nil, to get the stats for the whole dataset.
+func NewStatsCollector(filter *StatsFilter, topN int) *StatsCollector {
+	return &StatsCollector{
+		filter:          filter,
+		topN:            topN,
+		stats:           new(DatasetStats),
+		tagKeyCounts:    make(map[string]uint64),
+		tagValueCounts:  make(map[ownmap.OSMTag]uint64),
+		nodeIDs:         newIDSet(),
+		wayIDs:          newIDSet(),
+		relationIDs:     newIDSet(),
+		relationMembers: make(map[int64][]int64),
+	}
+}
+
+func (c *StatsCollector) addTags(tags []*ownmap.OSMTag) {
+	for _, tag := range tags {
+		c.tagKeyCounts[tag.Key]++
+
+		_, ok := c.tagValueCounts[*tag]
+		if ok || len(c.tagValueCounts) < maxTrackedTagValues {
+			c.tagValueCounts[*tag]++
+		}
+	}
+}
+
+func (c *StatsCollector) AddNode(node *ownmap.OSMNode) {
+	location := &ownmap.Location{Lat: node.Lat, Lon: node.Lon}
+	if c.filter != nil {
+		if !c.filter.containsLocation(location) {
+			return
+		}
+
+		c.nodeIDs.add(node.ID)
+	}
+
+	c.stats.Nodes++
+	c.addTags(node.Tags)
+
+	c.stats.DataBounds = extendBounds(c.stats.DataBounds, node.Lat, node.Lon)
+}
+
+func (c *StatsCollector) AddWay(way *ownmap.OSMWay) {
+	if c.filter != nil && !c.isWayInFilter(way) {
+		return
+	}
+
+	c.stats.Ways++
+	c.addTags(way.Tags)
+	c.wayIDs.add(way.ID)
+}
+
+func (c *StatsCollector) isWayInFilter(way *ownmap.OSMWay) bool {
+	for _, wayPoint := range way.WayPoints {
+		if wayPoint.Point != nil {
+			if c.filter.containsLocation(wayPoint.Point) {
+				return true
+			}
+			continue
+		}
+
+		if c.nodeIDs.contains(wayPoint.NodeID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *StatsCollector) AddRelation(relation *ownmap.OSMRelation) {
+	isInFilter := c.filter == nil
+	var memberRelationIDs []int64
+
+	for _, member := range relation.Members {
+		switch member.MemberType {
+		case ownmap.OSM_MEMBER_TYPE_NODE:
+			if c.filter != nil && c.nodeIDs.contains(member.ObjectID) {
+				isInFilter = true
+			}
+		case ownmap.OSM_MEMBER_TYPE_WAY:
+			if c.wayIDs.contains(member.ObjectID) {
+				isInFilter = true
+			}
+		case ownmap.OSM_MEMBER_TYPE_RELATION:
+			memberRelationIDs = append(memberRelationIDs, member.ObjectID)
+			if c.relationIDs.contains(member.ObjectID) {
+				isInFilter = true
+			}
+		}
+	}
+
+	if !isInFilter {
+		return
+	}
+
+	c.stats.Relations++
+	c.addTags(relation.Tags)
+	c.relationIDs.add(relation.ID)
+	if len(memberRelationIDs) != 0 {
+		c.relationMembers[relation.ID] = memberRelationIDs
+	}
+}
+
+// Stats returns the stats of the objects added so far
+func (c *StatsCollector) Stats(headerBounds *osm.Bounds) *DatasetStats {
+	stats := *c.stats
+	stats.HeaderBounds = headerBounds
+	if stats.DataBounds != nil {
+		dataBounds := *stats.DataBounds
+		stats.DataBounds = &dataBounds
+	}
+
+	var tagKeyCounts []*TagCount
+	for key, count := range c.tagKeyCounts {
+		tagKeyCounts = append(tagKeyCounts, &TagCount{Key: key, Count: count})
+	}
+	stats.TopTagKeys = topTagCounts(tagKeyCounts, c.topN)
+
+	var tagValueCounts []*TagCount
+	for tag, count := range c.tagValueCounts {
+		tagValueCounts = append(tagValueCounts, &TagCount{Key: tag.Key, Value: tag.Value, Count: count})
+	}
+	stats.TopTagValues = topTagCounts(tagValueCounts, c.topN)
+
+	stats.MaxRelationDepth = c.getMaxRelationDepth()
+
+	return &stats
+}
+
+func (c *StatsCollector) getMaxRelationDepth() int {
+	depths := make(map[int64]int)
+	var getDepth func(id int64, visiting map[int64]bool) int
+	getDepth = func(id int64, visiting map[int64]bool) int {
+		depth, ok := depths[id]
+		if ok {
+			return depth
+		}
+
+		if visiting[id] {
+			// relations that contain themselves (through other relations) would go on forever
+			return 0
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+
+		maxMemberDepth := 0
+		for _, memberID := range c.relationMembers[id] {
+			if !c.relationIDs.contains(memberID) {
+				// member not in the dataset
+				continue
+			}
+
+			memberDepth := getDepth(memberID, visiting)
+			if memberDepth > maxMemberDepth {
+				maxMemberDepth = memberDepth
+			}
+		}
+
+		depths[id] = maxMemberDepth + 1
+		return maxMemberDepth + 1
+	}
+
+	maxDepth := 0
+	if c.stats.Relations != 0 {
+		// relations without relation members
+		maxDepth = 1
+	}
+
+	for id := range c.relationMembers {
+		depth := getDepth(id, make(map[int64]bool))
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+
+	return maxDepth
+}
+
+// topTagCounts sorts by count (most first), then by key and value so that the result is the same every time
+func topTagCounts(tagCounts []*TagCount, topN int) []*TagCount {
+	sort.Slice(tagCounts, func(i, j int) bool {
+		a, b := tagCounts[i], tagCounts[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Value < b.Value
+	})
+
+	if len(tagCounts) > topN {
+		tagCounts = tagCounts[:topN]
+	}
+
+	return tagCounts
+}
+
+// GetStatsFromPBFReader streams through the file once and collects the stats. The filter can be nil.
+func GetStatsFromPBFReader(ctx context.Context, pbfReader PBFReader, filter *StatsFilter, topN int) (*DatasetStats, errorsx.Error) {
+	header, err := pbfReader.Header()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	collector := NewStatsCollector(filter, topN)
+
+	for pbfReader.Scan() {
+		if ctx.Err() != nil {
+			return nil, errorsx.Wrap(ctx.Err())
+		}
+
+		switch obj := pbfReader.Object().(type) {
+		case *osm.Node:
+			collector.AddNode(&ownmap.OSMNode{
+				ID:   int64(obj.ID),
+				Lat:  obj.Lat,
+				Lon:  obj.Lon,
+				Tags: ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
+			})
+		case *osm.Way:
+			way := &ownmap.OSMWay{
+				ID:   int64(obj.ID),
+				Tags: ownmap.NewMapmakerTagsFromOSMTags(obj.Tags),
+			}
+			for _, wayNode := range obj.Nodes {
+				way.WayPoints = append(way.WayPoints, &ownmap.WayPoint{NodeID: int64(wayNode.ID)})
+			}
+			collector.AddWay(way)
+		case *osm.Relation:
+			relation, err := ownmap.NewMapmakerRelationFromOSMRelation(obj)
+			if err != nil {
+				return nil, errorsx.Wrap(err)
+			}
+			collector.AddRelation(relation)
+		}
+	}
+
+	err = pbfReader.Err()
+	if err != nil {
+		return nil, errorsx.Wrap(err)
+	}
+
+	return collector.Stats(header.Bounds), nil
+}
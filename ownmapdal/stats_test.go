@@ -0,0 +1,143 @@
+package ownmapdal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jamesrr39/goutil/gofs/mockfs"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStatsOSMXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="JOSM">
+	<bounds minlat="51" minlon="-1" maxlat="52" maxlon="1"/>
+	<node id="1" lat="51.15" lon="-0.15">
+		<tag k="place" v="village"/>
+	</node>
+	<node id="2" lat="51.16" lon="-0.16"/>
+	<node id="3" lat="51.5" lon="0.5"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<way id="11">
+		<nd ref="3"/>
+		<tag k="highway" v="residential"/>
+	</way>
+	<relation id="100">
+		<member type="way" ref="10" role="outer"/>
+		<member type="relation" ref="101" role=""/>
+		<tag k="type" v="multipolygon"/>
+	</relation>
+	<relation id="101">
+		<member type="way" ref="11" role=""/>
+		<member type="relation" ref="102" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+	<relation id="102">
+		<member type="node" ref="3" role=""/>
+		<tag k="type" v="route"/>
+	</relation>
+</osm>`
+
+func TestGetStatsFromPBFReader(t *testing.T) {
+	polygon, err := ParsePolyFile(strings.NewReader(`west
+1
+   -0.5  51
+   0     51
+   0     51.3
+   -0.5  51.3
+END
+END
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		filter *StatsFilter
+		want   *DatasetStats
+	}{
+		{
+			"no filter",
+			nil,
+			&DatasetStats{
+				Nodes:     3,
+				Ways:      2,
+				Relations: 3,
+				TopTagKeys: []*TagCount{
+					{Key: "type", Count: 3},
+					{Key: "highway", Count: 2},
+				},
+				TopTagValues: []*TagCount{
+					{Key: "highway", Value: "residential", Count: 2},
+					{Key: "type", Value: "route", Count: 2},
+				},
+				HeaderBounds:     &osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1},
+				DataBounds:       &osm.Bounds{MinLat: 51.15, MaxLat: 51.5, MinLon: -0.16, MaxLon: 0.5},
+				MaxRelationDepth: 3,
+			},
+		}, {
+			"bounds filter",
+			&StatsFilter{Bounds: &osm.Bounds{MinLat: 51.4, MaxLat: 51.6, MinLon: 0.4, MaxLon: 0.6}},
+			&DatasetStats{
+				Nodes:     1,
+				Ways:      1,
+				Relations: 2,
+				TopTagKeys: []*TagCount{
+					{Key: "type", Count: 2},
+					{Key: "highway", Count: 1},
+				},
+				TopTagValues: []*TagCount{
+					{Key: "type", Value: "route", Count: 2},
+					{Key: "highway", Value: "residential", Count: 1},
+				},
+				HeaderBounds:     &osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1},
+				DataBounds:       &osm.Bounds{MinLat: 51.5, MaxLat: 51.5, MinLon: 0.5, MaxLon: 0.5},
+				MaxRelationDepth: 2,
+			},
+		}, {
+			"polygon filter",
+			&StatsFilter{Area: polygon},
+			&DatasetStats{
+				Nodes:     2,
+				Ways:      1,
+				Relations: 1,
+				TopTagKeys: []*TagCount{
+					{Key: "highway", Count: 1},
+					{Key: "place", Count: 1},
+				},
+				TopTagValues: []*TagCount{
+					{Key: "highway", Value: "residential", Count: 1},
+					{Key: "place", Value: "village", Count: 1},
+				},
+				HeaderBounds:     &osm.Bounds{MinLat: 51, MaxLat: 52, MinLon: -1, MaxLon: 1},
+				DataBounds:       &osm.Bounds{MinLat: 51.15, MaxLat: 51.16, MinLon: -0.16, MaxLon: -0.15},
+				MaxRelationDepth: 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := mockfs.NewMockFs()
+			err := fs.WriteFile("test.osm", []byte(testStatsOSMXML), 0600)
+			require.NoError(t, err)
+
+			file, err := fs.Open("test.osm")
+			require.NoError(t, err)
+			defer file.Close()
+
+			reader, err := NewPBFReaderForFile(file, "test.osm")
+			require.NoError(t, err)
+			defer reader.Close()
+
+			stats, err := GetStatsFromPBFReader(context.Background(), reader, tt.filter, 2)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, stats)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package ownmapdal
 
 import (
 	"errors"
+	"path/filepath"
 	"strings"
 
 	"github.com/jamesrr39/goutil/errorsx"
@@ -53,8 +54,25 @@ type DBFileType string
 const (
 	DBFileTypeMapmakerDB DBFileType = "ownmapdb"
 	DBFileTypePostgresql DBFileType = "postgresql"
+	DBFileTypeSQLite     DBFileType = "sqlite"
+	// DBFileTypePostGIS is a PostgreSQL database, with PostGIS geometry columns
+	DBFileTypePostGIS DBFileType = "postgis"
+	// DBFileTypeOsm2pgsql is a PostgreSQL database imported by osm2pgsql. It is only read from.
+	DBFileTypeOsm2pgsql DBFileType = "osm2pgsql"
 )
 
+// DBFileTypeForDataFile returns the type of a dataset file in the data directory, by its file extension
+func DBFileTypeForDataFile(name string) (DBFileType, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ownmapdb":
+		return DBFileTypeMapmakerDB, true
+	case ".sqlite", ".sqlite3":
+		return DBFileTypeSQLite, true
+	default:
+		return "", false
+	}
+}
+
 type DBFileConnectionURL struct {
 	Type           DBFileType
 	ConnectionPath string
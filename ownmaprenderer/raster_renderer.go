@@ -19,6 +19,7 @@ import (
 	"github.com/jamesrr39/ownmap-app/ownmap"
 	"github.com/jamesrr39/ownmap-app/ownmapdal"
 	"github.com/jamesrr39/ownmap-app/styling"
+	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
 	"github.com/paulmach/osm"
 )
@@ -71,7 +72,7 @@ func (rr *RasterRenderer) RenderRaster(ctx context.Context, dbConnSet *ownmapdal
 
 	dbConnsSpan := tracing.StartSpan(ctx, "get dbConns")
 
-	dbConns, err := dbConnSet.GetConnsForBounds(bounds)
+	dbConns, err := dbConnSet.GetConnsForBounds(bounds, zoomLevel)
 	if err != nil {
 		return nil, errorsx.Wrap(err)
 	}
@@ -84,38 +85,16 @@ func (rr *RasterRenderer) RenderRaster(ctx context.Context, dbConnSet *ownmapdal
 
 	getDataSpan := tracing.StartSpan(ctx, "get data")
 
-	nodeMap := make(ownmapdal.TagNodeMap)
-	wayMap := make(ownmapdal.TagWayMap)
-	relationMap := make(ownmapdal.TagRelationMap)
+	// objects in more than one dataset (where extracts overlap) are only drawn once
+	merger := ownmapdal.NewMerger(dbConnSet)
 
-	nodeMapChan := make(chan ownmapdal.TagNodeMap)
-	wayMapChan := make(chan ownmapdal.TagWayMap)
-	relationMapChan := make(chan ownmapdal.TagRelationMap)
 	errChan := make(chan errorsx.Error)
 
 	var wg sync.WaitGroup
 
 	go func() {
-		for {
-			select {
-			case errFromChan := <-errChan:
-				err = errFromChan
-			case nodeMapFromChan := <-nodeMapChan:
-				// TODO on conflicting datasets?
-				for k, v := range nodeMapFromChan {
-					nodeMap[k] = append(nodeMap[k], v...)
-				}
-			case wayMapFromChan := <-wayMapChan:
-				// TODO on conflicting datasets?
-				for k, v := range wayMapFromChan {
-					wayMap[k] = append(wayMap[k], v...)
-				}
-			case relationMapFromChan := <-relationMapChan:
-				// TODO on conflicting datasets?
-				for k, v := range relationMapFromChan {
-					relationMap[k] = append(relationMap[k], v...)
-				}
-			}
+		for errFromChan := range errChan {
+			err = errFromChan
 			wg.Done()
 		}
 	}()
@@ -160,14 +139,17 @@ func (rr *RasterRenderer) RenderRaster(ctx context.Context, dbConnSet *ownmapdal
 
 			log.Printf("datasource: %q. Nodemap len: %d, Waymap len: %d\n", dbConn.Name(), len(nodeMap), len(wayMap))
 
-			wg.Add(3)
-			nodeMapChan <- nodeMap
-			wayMapChan <- wayMap
-			relationMapChan <- relationMap
+			err = merger.Add(dbConn.DataSourceConn, nodeMap, wayMap, relationMap)
+			if err != nil {
+				wg.Add(1)
+				errChan <- errorsx.Wrap(err)
+				return
+			}
 		}(dbConn)
 	}
 
 	wg.Wait()
+	close(errChan)
 
 	getDataSpan.End(ctx)
 
@@ -175,6 +157,8 @@ func (rr *RasterRenderer) RenderRaster(ctx context.Context, dbConnSet *ownmapdal
 		return nil, errorsx.Wrap(err)
 	}
 
+	nodeMap, wayMap, relationMap := merger.Result()
+
 	log.Printf("found %d node tag keys and %d way tag keys\n", len(nodeMap), len(wayMap))
 
 	if len(nodeMap) == 0 && len(wayMap) == 0 {
@@ -367,8 +351,6 @@ func (rr *RasterRenderer) drawMap(ctx context.Context, nodeMap ownmapdal.TagNode
 }
 
 func drawRelation(img *image.RGBA, bounds osm.Bounds, relationData *ownmap.RelationData, style styling.Style, zoomLevel ownmap.ZoomLevel) errorsx.Error {
-	relationImg := NewImageWithBackground(img.Rect, color.Transparent)
-
 	wayStyle, err := style.GetWayStyle(relationData.Tags, zoomLevel)
 	if err != nil {
 		return err
@@ -377,48 +359,65 @@ func drawRelation(img *image.RGBA, bounds osm.Bounds, relationData *ownmap.Relat
 	if wayStyle == nil {
 		return nil
 	}
-	var outerWayPoints []*ownmap.WayPoint
-	var innerWays []*ownmap.OSMWay
-
-	for _, m := range relationData.Members {
-		switch member := m.Object.(type) {
-		case *ownmap.OSMRelation:
-			panic(fmt.Sprintf("relation as child member of relation. Relation ID: %d, child member: %#v", relationData.RelationID, member))
-		case *ownmap.OSMWay:
-			switch m.Role {
-			case "outer":
-				outerWayPoints = append(outerWayPoints, member.WayPoints...)
-			case "inner":
-				innerWays = append(innerWays, member)
-			default:
-				println("TODO: unhandled role: ", m.Role)
-			}
-		default:
-			println(fmt.Sprintf("TODO: unhandled relation member type: %T", m.Object))
-		}
-	}
 
-	if len(outerWayPoints) == 0 {
+	area := relationData.GetAreaGeometry()
+	if area == nil {
 		return nil
 	}
 
-	err = drawWay(relationImg, bounds, outerWayPoints, wayStyle)
-	if err != nil {
-		return errorsx.Wrap(err)
+	gc := draw2dimg.NewGraphicContext(img)
+	defer gc.Close()
+
+	return drawArea(gc, img, bounds, area, wayStyle)
+}
+
+// drawArea draws all the rings of the area as one path. Holes are left unfilled by the even-odd fill rule, so whatever is underneath shows through.
+func drawArea(gc *draw2dimg.GraphicContext, img draw.Image, bounds osm.Bounds, area *ownmap.AreaGeometry, lineStyle *styling.WayStyle) errorsx.Error {
+	if lineStyle.FillColor != nil {
+		gc.SetFillColor(lineStyle.FillColor)
+	}
+	if lineStyle.LineColor != nil {
+		gc.SetStrokeColor(lineStyle.LineColor)
 	}
-	holeInRelationStyle := &styling.WayStyle{
-		FillColor: style.GetBackground(),
-		LineColor: style.GetBackground(),
+	if lineStyle.LineWidth != 0 {
+		gc.SetLineWidth(lineStyle.LineWidth)
 	}
-	for _, innerWay := range innerWays {
-		err = drawWay(relationImg, bounds, innerWay.WayPoints, holeInRelationStyle)
-		if err != nil {
-			return errorsx.Wrap(err)
+	if lineStyle.LineDashPolicy != nil {
+		gc.SetLineDash(lineStyle.LineDashPolicy, 0)
+	}
+	gc.SetFillRule(draw2d.FillRuleEvenOdd)
+	gc.BeginPath()
+
+	lonBetweenMinAndMax := bounds.MaxLon - bounds.MinLon
+	latBetweenMinAndMax := bounds.MaxLat - bounds.MinLat
+	imgWidth := float64(img.Bounds().Max.X)
+	imgHeight := float64(img.Bounds().Max.Y)
+
+	addRing := func(ring *ownmap.AreaRing) {
+		for i, location := range ring.Points {
+			pointX := (location.Lon - bounds.MinLon) / lonBetweenMinAndMax * imgWidth
+			pointY := (1 - (location.Lat-bounds.MinLat)/latBetweenMinAndMax) * imgHeight
+
+			if i == 0 {
+				gc.MoveTo(pointX, pointY)
+			} else {
+				gc.LineTo(pointX, pointY)
+			}
+		}
+		gc.Close()
+	}
+
+	for _, polygon := range area.Polygons {
+		addRing(polygon.Outer)
+		for _, inner := range polygon.Inners {
+			addRing(inner)
 		}
 	}
 
-	// draw non-transparent parts onto the map image
-	draw.Draw(img, img.Bounds(), relationImg, image.Point{}, draw.Over)
+	if lineStyle.FillColor != nil {
+		gc.Fill()
+	}
+	gc.Stroke()
 
 	return nil
 }
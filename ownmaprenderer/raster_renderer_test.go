@@ -309,3 +309,48 @@ func TestRasterRenderer_drawRelation(t *testing.T) {
 		})
 	}
 }
+
+func TestRasterRenderer_drawRelation_splitRingsAndHoles(t *testing.T) {
+	style := &styling.CustomBasicStyle{}
+	tags := []*ownmap.OSMTag{{Key: "landuse", Value: "forest"}, {Key: "type", Value: "multipolygon"}}
+
+	wayStyle, err := style.GetWayStyle(tags, 15)
+	require.NoError(t, err)
+
+	wayPoints := func(latLons ...float64) []*ownmap.WayPoint {
+		var wayPoints []*ownmap.WayPoint
+		for i := 0; i < len(latLons); i += 2 {
+			wayPoints = append(wayPoints, &ownmap.WayPoint{Point: &ownmap.Location{Lat: latLons[i], Lon: latLons[i+1]}})
+		}
+		return wayPoints
+	}
+
+	// the outer ring is split into 2 ways, pointing in opposite directions
+	relationData := &ownmap.RelationData{
+		Tags: tags,
+		Members: []*ownmap.RelationMemberData{
+			{Role: "outer", Object: &ownmap.OSMWay{WayPoints: wayPoints(0.8, 0.8, 0.8, -0.8, -0.8, -0.8)}},
+			{Role: "outer", Object: &ownmap.OSMWay{WayPoints: wayPoints(0.8, 0.8, -0.8, 0.8, -0.8, -0.8)}},
+			{Role: "inner", Object: &ownmap.OSMWay{WayPoints: wayPoints(0.6, 0.6, 0.6, 0, 0, 0, 0, 0.6, 0.6, 0.6)}},
+		},
+	}
+
+	// something already drawn underneath the relation, which should show through the hole
+	underneathColor := color.RGBA{R: 255, A: 255}
+	img := NewImageWithBackground(image.Rect(0, 0, 256, 256), underneathColor)
+
+	err = drawRelation(img, osm.Bounds{MinLat: -1, MaxLat: 1, MinLon: -1, MaxLon: 1}, relationData, style, 15)
+	require.NoError(t, err)
+
+	fillR, fillG, fillB, fillA := wayStyle.FillColor.RGBA()
+
+	// in the area (lat: -0.4, lon: -0.4)
+	r, g, b, a := img.At(77, 179).RGBA()
+	assert.Equal(t, []uint32{fillR, fillG, fillB, fillA}, []uint32{r, g, b, a})
+
+	// in the hole (lat: 0.3, lon: 0.3)
+	assert.Equal(t, underneathColor, img.RGBAAt(166, 90))
+
+	// outside the area
+	assert.Equal(t, underneathColor, img.RGBAAt(5, 5))
+}
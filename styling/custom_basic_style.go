@@ -97,13 +97,19 @@ func (*CustomBasicStyle) GetNodeStyle(node *ownmap.OSMNode, zoomLevel ownmap.Zoo
 }
 
 const (
-	zindexForest      = 1
-	zindexResidential = 2
-	zindexRailway     = 3
-	zindexHighway     = 4
-	zindexPlace       = 5
+	zindexWater       = 1
+	zindexForest      = 2
+	zindexResidential = 3
+	zindexRailway     = 4
+	zindexHighway     = 5
+	zindexPlace       = 6
 )
 
+var waterStyle = &WayStyle{
+	FillColor: color.RGBA{170, 211, 223, 0xff},
+	ZIndex:    zindexWater,
+}
+
 var forestStyle = &WayStyle{
 	FillColor: color.RGBA{172, 200, 160, 0xff},
 	ZIndex:    zindexForest,
@@ -127,6 +133,8 @@ func (*CustomBasicStyle) GetWayStyle(tags []*ownmap.OSMTag, zoomLevel ownmap.Zoo
 			switch tag.Value {
 			case "wood":
 				return forestStyle, nil
+			case "water":
+				return waterStyle, nil
 			}
 		case "landuse":
 			switch tag.Value {
@@ -51,6 +51,7 @@ const (
 	SourceLayerTransportation string = "transportation"
 	SourceLayerWaterway       string = "waterway"
 	SourceLayerPlace          string = "place"
+	SourceLayerWater          string = "water"
 )
 
 type FilterType int
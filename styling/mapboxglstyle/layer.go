@@ -80,7 +80,7 @@ func (l *Layer) GetLayerNodeStyle(node *ownmap.OSMNode, zoomLevel ownmap.ZoomLev
 
 func (l *Layer) GetLayerWayStyle(tags []*ownmap.OSMTag, zoomLevel ownmap.ZoomLevel, layerIndex int) *styling.WayStyle {
 	switch l.Type {
-	case "line", "symbol":
+	case "line", "symbol", "fill":
 		tagsInSourceLayer := areTagsInSourceLayer(l.SourceLayer, tags)
 		if !tagsInSourceLayer {
 			// OSM Way doesn't "belong" in this sourceLayer, skip everything
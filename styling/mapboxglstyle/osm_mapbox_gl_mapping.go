@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/jamesrr39/ownmap-app/ownmap"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
 )
 
 type TagWithObjectTypes struct {
@@ -134,6 +135,11 @@ func mapMapboxGLClassToOSMTags(className, sourceLayer string) []*ownmap.OSMTag {
 		return []*ownmap.OSMTag{
 			{Key: sourceLayer, Value: className},
 		}
+	case SourceLayerWater:
+		// https://openmaptiles.org/schema/#water
+		return []*ownmap.OSMTag{
+			{Key: "water", Value: className},
+		}
 	default:
 		log.Printf("unknown sourcelayer: %q (className: %q)\n", sourceLayer, className)
 		return nil
@@ -176,6 +182,11 @@ func areTagsInSourceLayer(sourceLayer string, tags []*ownmap.OSMTag) bool {
 			if tag.Key == "landcover" || tag.Key == "landuse" {
 				return true
 			}
+		case SourceLayerWater:
+			// includes the sea generated from coastlines, which is tagged with natural=water and water=ocean
+			if tag.Key == "water" || (tag.Key == "natural" && tag.Value == "water") {
+				return true
+			}
 		default:
 			if tag.Key == sourceLayer {
 				return true
@@ -184,3 +195,21 @@ func areTagsInSourceLayer(sourceLayer string, tags []*ownmap.OSMTag) bool {
 	}
 	return false
 }
+
+// getDefaultTagKeysForSourceLayer gives the tag keys to fetch for a layer where the filter doesn't say which classes are shown.
+// For example, the OpenMapTiles "water" layer is usually only filtered by geometry type.
+func getDefaultTagKeysForSourceLayer(sourceLayer string) []*ownmapdal.TagKeyWithType {
+	switch sourceLayer {
+	case SourceLayerWater:
+		var tagKeys []*ownmapdal.TagKeyWithType
+		for _, objectType := range []ownmap.ObjectType{ownmap.ObjectTypeWay, ownmap.ObjectTypeRelation} {
+			tagKeys = append(tagKeys,
+				&ownmapdal.TagKeyWithType{ObjectType: objectType, TagKey: "water"},
+				&ownmapdal.TagKeyWithType{ObjectType: objectType, TagKey: "natural"},
+			)
+		}
+		return tagKeys
+	default:
+		return nil
+	}
+}
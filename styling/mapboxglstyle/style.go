@@ -106,6 +106,9 @@ func (s *MapboxGLStyle) GetWantedObjects(zoomLevel ownmap.ZoomLevel) []*ownmapda
 		}
 
 		tagKeysToFetch := layer.Filter.GetTagKeysToFetch(layer.SourceLayer)
+		if len(tagKeysToFetch) == 0 {
+			tagKeysToFetch = getDefaultTagKeysForSourceLayer(layer.SourceLayer)
+		}
 		objects = append(objects, tagKeysToFetch...)
 	}
 	return objects
@@ -0,0 +1,87 @@
+package styling
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jamesrr39/goutil/errorsx"
+	"github.com/jamesrr39/ownmap-app/ownmapdal"
+)
+
+var _ ownmapdal.DirEntryHandler = &StyleDirHandler{}
+
+// LoadStyleFunc loads the style in a style directory
+type LoadStyleFunc func(styleDirPath string) (Style, errorsx.Error)
+
+// StyleDirHandler keeps the styles in a StyleSet up to date with the style directories in the styles directory. Each style directory has a style.json file.
+type StyleDirHandler struct {
+	styleSet *StyleSet
+	loadFunc LoadStyleFunc
+	mu       sync.Mutex
+	styleIDs map[string]string // map[style dir path]Style ID
+}
+
+func NewStyleDirHandler(styleSet *StyleSet, loadFunc LoadStyleFunc) *StyleDirHandler {
+	return &StyleDirHandler{
+		styleSet: styleSet,
+		loadFunc: loadFunc,
+		styleIDs: make(map[string]string),
+	}
+}
+
+func (h *StyleDirHandler) Watches(fileInfo os.FileInfo) bool {
+	return fileInfo.IsDir()
+}
+
+func (h *StyleDirHandler) StatPath(entryPath string) string {
+	return filepath.Join(entryPath, "style.json")
+}
+
+func (h *StyleDirHandler) Add(entryPath string) errorsx.Error {
+	style, err := h.loadFunc(entryPath)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err = h.styleSet.AddStyle(style)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.styleIDs[entryPath] = style.GetStyleID()
+
+	return nil
+}
+
+func (h *StyleDirHandler) Replace(entryPath string) errorsx.Error {
+	style, err := h.loadFunc(entryPath)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err = h.styleSet.ReplaceStyle(h.styleIDs[entryPath], style)
+	if err != nil {
+		return errorsx.Wrap(err)
+	}
+
+	h.styleIDs[entryPath] = style.GetStyleID()
+
+	return nil
+}
+
+func (h *StyleDirHandler) Remove(entryPath string) errorsx.Error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	styleID := h.styleIDs[entryPath]
+	delete(h.styleIDs, entryPath)
+
+	return h.styleSet.RemoveStyle(styleID)
+}
@@ -2,6 +2,8 @@ package styling
 
 import (
 	"image/color"
+	"sort"
+	"sync"
 
 	"github.com/jamesrr39/goutil/errorsx"
 	"github.com/jamesrr39/ownmap-app/ownmap"
@@ -53,15 +55,18 @@ type Style interface {
 	GetWantedObjects(zoomLevel ownmap.ZoomLevel) []*ownmapdal.TagKeyWithType
 }
 
+// StyleSet is the styles that can be used. Styles can be added, replaced and removed while it is being used.
 type StyleSet struct {
 	stylesMap      map[string]Style // map[Style ID]Style
 	defaultStyleID string
+	mu             *sync.RWMutex
 }
 
 func NewStyleSet(styles []Style, defaultStyleID string) (*StyleSet, errorsx.Error) {
 	styleSet := &StyleSet{
 		stylesMap:      make(map[string]Style),
 		defaultStyleID: defaultStyleID,
+		mu:             new(sync.RWMutex),
 	}
 
 	defaultIDFound := false
@@ -88,19 +93,91 @@ func NewStyleSet(styles []Style, defaultStyleID string) (*StyleSet, errorsx.Erro
 }
 
 func (s *StyleSet) GetStyleByID(id string) Style {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.stylesMap[id]
 }
 
 func (s *StyleSet) GetDefaultStyle() Style {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.stylesMap[s.defaultStyleID]
 }
 
 func (s *StyleSet) GetAllStyleIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	var styleIDs []string
 
 	for id := range s.stylesMap {
 		styleIDs = append(styleIDs, id)
 	}
 
+	sort.Strings(styleIDs)
+
 	return styleIDs
 }
+
+func (s *StyleSet) AddStyle(style Style) errorsx.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	styleID := style.GetStyleID()
+	_, ok := s.stylesMap[styleID]
+	if ok {
+		return errorsx.Errorf("duplicate style ID found: %q", styleID)
+	}
+
+	s.stylesMap[styleID] = style
+
+	return nil
+}
+
+// ReplaceStyle swaps a style for a new version of it, which can have a different ID.
+// Requests that already got the old style carry on using it.
+func (s *StyleSet) ReplaceStyle(oldStyleID string, newStyle Style) errorsx.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.stylesMap[oldStyleID]
+	if !ok {
+		return errorsx.Errorf("style %q not found", oldStyleID)
+	}
+
+	newStyleID := newStyle.GetStyleID()
+	if newStyleID != oldStyleID {
+		_, ok = s.stylesMap[newStyleID]
+		if ok {
+			return errorsx.Errorf("duplicate style ID found: %q", newStyleID)
+		}
+
+		if oldStyleID == s.defaultStyleID {
+			return errorsx.Errorf("the default style (%q) can't be replaced with a style with a different ID (%q)", oldStyleID, newStyleID)
+		}
+
+		delete(s.stylesMap, oldStyleID)
+	}
+
+	s.stylesMap[newStyleID] = newStyle
+
+	return nil
+}
+
+func (s *StyleSet) RemoveStyle(styleID string) errorsx.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if styleID == s.defaultStyleID {
+		return errorsx.Errorf("the default style (%q) can't be removed", styleID)
+	}
+
+	_, ok := s.stylesMap[styleID]
+	if !ok {
+		return errorsx.Errorf("style %q not found", styleID)
+	}
+
+	delete(s.stylesMap, styleID)
+
+	return nil
+}